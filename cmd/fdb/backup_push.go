@@ -25,6 +25,8 @@ var backupPushCmd = &cobra.Command{
 
 		uploader, err := internal.ConfigureUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
+		internal.ConfigureChecksumTracking(uploader)
+		uploader = uploader.WithContext(ctx)
 		uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
 
 		backupCmd, err := internal.GetCommandSetting(internal.NameStreamCreateCmd)
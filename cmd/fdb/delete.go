@@ -38,6 +38,14 @@ var deleteRetainCmd = &cobra.Command{
 	},
 }
 
+var deleteGFSCmd = &cobra.Command{
+	Use:       internal.DeleteGFSUsageExample,
+	Example:   internal.DeleteGFSExamples,
+	ValidArgs: internal.StringModifiers,
+	Args:      internal.DeleteGFSArgsValidator,
+	Run:       runDeleteGFS,
+}
+
 var deleteEverythingCmd = &cobra.Command{
 	Use:       internal.DeleteEverythingUsageExample,
 	Example:   internal.DeleteEverythingExamples,
@@ -86,10 +94,20 @@ func runDeleteRetainAfter(args []string) {
 	deleteHandler.HandleDeleteRetainAfter(args, confirmed)
 }
 
+func runDeleteGFS(cmd *cobra.Command, args []string) {
+	folder, err := internal.ConfigureFolder()
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	deleteHandler, err := newFdbDeleteHandler(folder)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	deleteHandler.HandleDeleteGFS(args, confirmed)
+}
+
 func init() {
 	cmd.AddCommand(deleteCmd)
 	deleteRetainCmd.Flags().StringP("after", "a", "", "Set the time after which retain backups")
-	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd)
+	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteGFSCmd, deleteEverythingCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
 }
 
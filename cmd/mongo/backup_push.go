@@ -43,6 +43,8 @@ var backupPushCmd = &cobra.Command{
 
 		uplProvider, err := internal.ConfigureUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
+		internal.ConfigureChecksumTracking(uplProvider)
+		uplProvider = uplProvider.WithContext(ctx)
 		uplProvider.UploadingFolder = uplProvider.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
 
 		backupCmd, err := internal.GetCommandSettingContext(ctx, internal.NameStreamCreateCmd)
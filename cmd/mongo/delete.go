@@ -13,6 +13,7 @@ import (
 const (
 	retainAfterFlag  = "retain-after"
 	retainCountFlag  = "retain-count"
+	retainGFSFlag    = "retain-gfs"
 	purgeOplogFlag   = "purge-oplog"
 	purgeGarbageFlag = "purge-garbage"
 )
@@ -23,6 +24,7 @@ var (
 	purgeGarbage bool
 	retainAfter  string
 	retainCount  uint
+	retainGFS    []string
 )
 
 // deleteCmd represents the delete command
@@ -52,6 +54,12 @@ func runPurge(cmd *cobra.Command, args []string) {
 		opts = append(opts, mongo.PurgeRetainCount(int(retainCount)))
 	}
 
+	if cmd.Flags().Changed(retainGFSFlag) {
+		policy, err := internal.ParseGFSPolicy(retainGFS)
+		tracelog.ErrorLogger.FatalfOnError("Can not parse GFS retention: %v", err)
+		opts = append(opts, mongo.PurgeRetainGFS(policy))
+	}
+
 	// set up storage downloader client
 	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -71,4 +79,6 @@ func init() {
 	deleteCmd.Flags().BoolVar(&purgeGarbage, purgeGarbageFlag, false, "Purge garbage in backup folder")
 	deleteCmd.Flags().StringVar(&retainAfter, retainAfterFlag, "", "Keep backups newer")
 	deleteCmd.Flags().UintVar(&retainCount, retainCountFlag, 0, "Keep minimum count, except permanent backups")
+	deleteCmd.Flags().StringSliceVar(&retainGFS, retainGFSFlag, nil,
+		"Keep the latest backup of each of the last days, weeks, months and years, e.g. 7,4,12,3")
 }
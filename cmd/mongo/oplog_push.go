@@ -58,6 +58,7 @@ func runOplogPush(ctx context.Context, pushArgs oplogPushRunArgs, statsArgs oplo
 	if err != nil {
 		return err
 	}
+	uplProvider = uplProvider.WithContext(ctx)
 	uplProvider.UploadingFolder = uplProvider.UploadingFolder.GetSubFolder(models.OplogArchBasePath)
 	uploader := archive.NewStorageUploader(uplProvider)
 
@@ -32,6 +32,14 @@ var deleteRetainCmd = &cobra.Command{
 	Run:       runDeleteRetain,
 }
 
+var deleteGFSCmd = &cobra.Command{
+	Use:       internal.DeleteGFSUsageExample, // TODO : improve description
+	Example:   internal.DeleteGFSExamples,
+	ValidArgs: internal.StringModifiers,
+	Args:      internal.DeleteGFSArgsValidator,
+	Run:       runDeleteGFS,
+}
+
 var deleteEverythingCmd = &cobra.Command{
 	Use:       internal.DeleteEverythingUsageExample, // TODO : improve description
 	Example:   internal.DeleteEverythingExamples,
@@ -88,9 +96,16 @@ func runDeleteRetain(cmd *cobra.Command, args []string) {
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
 
+func runDeleteGFS(cmd *cobra.Command, args []string) {
+	deleteHandler, err := NewMySQLDeleteHandler()
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	deleteHandler.HandleDeleteGFS(args, confirmed)
+}
+
 func init() {
 	cmd.AddCommand(deleteCmd)
-	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd, deleteTargetCmd)
+	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteGFSCmd, deleteEverythingCmd, deleteTargetCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
 }
 
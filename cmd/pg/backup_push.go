@@ -1,7 +1,10 @@
 package pg
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"syscall"
 
 	"github.com/wal-g/wal-g/utility"
 
@@ -42,6 +45,10 @@ var (
 		Short: backupPushShortDescription, // TODO : improve description
 		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
+			defer func() { _ = signalHandler.Close() }()
+
 			var dataDirectory string
 
 			if len(args) > 0 {
@@ -82,7 +89,7 @@ var (
 
 			backupHandler, err := postgres.NewBackupHandler(arguments)
 			tracelog.ErrorLogger.FatalOnError(err)
-			backupHandler.HandleBackupPush()
+			backupHandler.WithContext(ctx).HandleBackupPush()
 		},
 	}
 	permanent             = false
@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	backupTierShortDescription = "Moves backups to another storage class"
+	backupTierLongDescription  = `Moves all the objects of the given backups, or of the backups older than --older-than,
+	to the storage class, e.g. GLACIER or STANDARD_IA for S3. The storage class is recorded in the backup metadata.`
+	olderThanFlag        = "older-than"
+	olderThanDescription = "Move the backups finished earlier than the duration ago, e.g. 720h"
+)
+
+var (
+	// backupTierCmd represents the backupTier command
+	backupTierCmd = &cobra.Command{
+		Use:   "backup-tier storage_class [backup_name...]",
+		Short: backupTierShortDescription,
+		Long:  backupTierLongDescription,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("storage class is required")
+			}
+			if len(args) == 1 && olderThan <= 0 {
+				return errors.New("either backup names or --" + olderThanFlag + " is required")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+			before := utility.TimeNowCrossPlatformUTC().Add(-olderThan)
+			internal.HandleBackupTier(folder, postgres.NewGenericMetaInteractor(), args[1:], before, args[0])
+		},
+	}
+	olderThan time.Duration
+)
+
+func init() {
+	backupTierCmd.Flags().DurationVar(&olderThan, olderThanFlag, 0, olderThanDescription)
+	Cmd.AddCommand(backupTierCmd)
+}
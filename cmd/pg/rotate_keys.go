@@ -0,0 +1,58 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	rotateKeysShortDescription = "Re-encrypts backups and WAL with the current key"
+	rotateKeysLongDescription  = `Decrypts the objects with the old key configured in --old-config and encrypts them
+	with the current key, either in the whole storage or in the backup given by --backup and its WAL range.
+	The progress is kept in --journal, so the interrupted rotation may be repeated with the same journal.
+	The identity of the current key is recorded in the backup metadata.`
+	oldConfigFlag                = "old-config"
+	oldConfigDescription         = "Path to the config file with the old encryption key"
+	rotateBackupFlag             = "backup"
+	rotateBackupDescription      = "Re-encrypt only the backup and the WAL segments up to the next backup"
+	rotateJournalFlag            = "journal"
+	rotateJournalDescription     = "Path to the local file keeping the progress of the rotation"
+	rotateConcurrencyFlag        = "concurrency"
+	rotateConcurrencyDescription = "Number of objects re-encrypted in parallel"
+	rotateDryRunFlag             = "dry-run"
+	rotateDryRunDescription      = "Only list the objects to re-encrypt"
+)
+
+var (
+	// rotateKeysCmd represents the rotateKeys command
+	rotateKeysCmd = &cobra.Command{
+		Use:   "rotate-keys",
+		Short: rotateKeysShortDescription,
+		Long:  rotateKeysLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+			oldCrypter := internal.CrypterFromConfig(rotateOldConfig)
+			internal.HandleKeyRotation(folder, postgres.NewGenericMetaInteractor(), oldCrypter, internal.ConfigureCrypter(),
+				rotateBackupName, rotateJournal, rotateConcurrency, rotateDryRun)
+		},
+	}
+	rotateOldConfig   string
+	rotateBackupName  string
+	rotateJournal     string
+	rotateConcurrency int
+	rotateDryRun      bool
+)
+
+func init() {
+	rotateKeysCmd.Flags().StringVar(&rotateOldConfig, oldConfigFlag, "", oldConfigDescription)
+	rotateKeysCmd.Flags().StringVar(&rotateBackupName, rotateBackupFlag, "", rotateBackupDescription)
+	rotateKeysCmd.Flags().StringVar(&rotateJournal, rotateJournalFlag, "walg_rotate_keys.journal", rotateJournalDescription)
+	rotateKeysCmd.Flags().IntVar(&rotateConcurrency, rotateConcurrencyFlag, 4, rotateConcurrencyDescription)
+	rotateKeysCmd.Flags().BoolVar(&rotateDryRun, rotateDryRunFlag, false, rotateDryRunDescription)
+	_ = rotateKeysCmd.MarkFlagRequired(oldConfigFlag)
+	Cmd.AddCommand(rotateKeysCmd)
+}
@@ -0,0 +1,44 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	walDictionaryTrainShortDescription = "Trains the zstd dictionary for WAL compression"
+	walDictionaryTrainLongDescription  = `Trains the zstd dictionary on the beginnings of the last WAL segments in the storage
+	and stores it next to WAL. The following wal-push with WALG_ZSTD_DICTIONARY compresses WAL with it,
+	the dictionaries trained before stay in the storage for the WAL compressed with them.`
+	dictionarySegmentsFlag        = "segments"
+	dictionarySegmentsDescription = "Number of the last WAL segments to train on"
+	dictionarySizeFlag            = "size"
+	dictionarySizeDescription     = "Size of the dictionary in bytes"
+)
+
+var (
+	// walDictionaryTrainCmd represents the walDictionaryTrain command
+	walDictionaryTrainCmd = &cobra.Command{
+		Use:   "wal-dictionary-train",
+		Short: walDictionaryTrainShortDescription,
+		Long:  walDictionaryTrainLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+			postgres.HandleWalDictionaryTrain(folder, dictionarySegments, dictionarySize)
+		},
+	}
+	dictionarySegments int
+	dictionarySize     int
+)
+
+func init() {
+	walDictionaryTrainCmd.Flags().IntVar(&dictionarySegments, dictionarySegmentsFlag, 32, dictionarySegmentsDescription)
+	walDictionaryTrainCmd.Flags().IntVar(&dictionarySize, dictionarySizeFlag, compression.DefaultDictionarySize,
+		dictionarySizeDescription)
+	Cmd.AddCommand(walDictionaryTrainCmd)
+}
@@ -36,6 +36,8 @@ var backupPushCmd = &cobra.Command{
 
 		uploader, err := internal.ConfigureUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
+		internal.ConfigureChecksumTracking(uploader)
+		uploader = uploader.WithContext(ctx)
 
 		// Configure folder
 		uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
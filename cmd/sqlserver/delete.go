@@ -31,6 +31,14 @@ var deleteRetainCmd = &cobra.Command{
 	Run:       runDeleteRetain,
 }
 
+var deleteGFSCmd = &cobra.Command{
+	Use:       internal.DeleteGFSUsageExample,
+	Example:   internal.DeleteGFSExamples,
+	ValidArgs: internal.StringModifiers,
+	Args:      internal.DeleteGFSArgsValidator,
+	Run:       runDeleteGFS,
+}
+
 var deleteEverythingCmd = &cobra.Command{
 	Use:       internal.DeleteEverythingUsageExample,
 	Example:   internal.DeleteEverythingExamples,
@@ -60,9 +68,16 @@ func runDeleteRetain(cmd *cobra.Command, args []string) {
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
 
+func runDeleteGFS(cmd *cobra.Command, args []string) {
+	deleteHandler, err := newSQLServerDeleteHandler()
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	deleteHandler.HandleDeleteGFS(args, confirmed)
+}
+
 func init() {
 	cmd.AddCommand(deleteCmd)
-	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd)
+	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteGFSCmd, deleteEverythingCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
 }
 
@@ -0,0 +1,47 @@
+package st
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/storagetools"
+)
+
+const (
+	abortUploadsShortDescription = "Aborts the stale uploads which were interrupted and left their parts in the storage"
+
+	olderThanFlag        = "older-than"
+	olderThanDescription = "Abort only the uploads started earlier than this duration ago"
+	dryRunFlag           = "dry-run"
+	dryRunDescription    = "Only print the uploads to abort"
+)
+
+var (
+	olderThan   time.Duration
+	abortDryRun bool
+)
+
+// abortUploadsCmd represents the abortUploads command
+var abortUploadsCmd = &cobra.Command{
+	Use:   "abort-uploads [relative folder path]",
+	Short: abortUploadsShortDescription,
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		if len(args) > 0 {
+			folder = folder.GetSubFolder(args[0])
+		}
+
+		storagetools.HandleAbortUploads(folder, olderThan, abortDryRun)
+	},
+}
+
+func init() {
+	abortUploadsCmd.Flags().DurationVar(&olderThan, olderThanFlag, 24*time.Hour, olderThanDescription)
+	abortUploadsCmd.Flags().BoolVar(&abortDryRun, dryRunFlag, false, dryRunDescription)
+	StorageToolsCmd.AddCommand(abortUploadsCmd)
+}
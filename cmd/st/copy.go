@@ -0,0 +1,62 @@
+package st
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal/storagetools"
+)
+
+const (
+	copyShortDescription = "Copy the objects from one storage to another according to configs"
+
+	fromFlag               = "from"
+	fromShorthand          = "f"
+	fromDescription        = "Storage config from where the objects are copied"
+	toFlag                 = "to"
+	toShorthand            = "t"
+	toDescription          = "Storage config to where the objects are copied"
+	concurrencyFlag        = "concurrency"
+	concurrencyDescription = "Number of the objects copied concurrently"
+	copyDryRunDescription  = "Only print the objects to copy"
+)
+
+var (
+	fromConfigFile  string
+	toConfigFile    string
+	copyConcurrency int
+	copyDryRun      bool
+)
+
+// copyCmd represents the copy command
+var copyCmd = &cobra.Command{
+	Use:   "cp [relative folder path]",
+	Short: copyShortDescription,
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		storagetools.HandleSync(fromConfigFile, toConfigFile, folderPathArg(args), storagetools.SyncOptions{
+			CopyAll:     true,
+			DryRun:      copyDryRun,
+			Concurrency: copyConcurrency,
+		})
+	},
+}
+
+func folderPathArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return ""
+}
+
+func addCopyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&fromConfigFile, fromFlag, fromShorthand, "", fromDescription)
+	cmd.Flags().StringVarP(&toConfigFile, toFlag, toShorthand, "", toDescription)
+	cmd.Flags().IntVar(&copyConcurrency, concurrencyFlag, 8, concurrencyDescription)
+	_ = cmd.MarkFlagRequired(fromFlag)
+	_ = cmd.MarkFlagRequired(toFlag)
+}
+
+func init() {
+	addCopyFlags(copyCmd)
+	copyCmd.Flags().BoolVar(&copyDryRun, dryRunFlag, false, copyDryRunDescription)
+	StorageToolsCmd.AddCommand(copyCmd)
+}
@@ -0,0 +1,39 @@
+package st
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/storagetools"
+)
+
+const (
+	duShortDescription = "Prints the storage usage by the backups, WAL timelines, oplog and binlog archives and garbage"
+
+	jsonFlag   = "json"
+	prettyFlag = "pretty"
+)
+
+var (
+	duJSON   bool
+	duPretty bool
+)
+
+// duCmd represents the du command
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: duShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		storagetools.HandleStorageUsage(folder, duJSON, duPretty)
+	},
+}
+
+func init() {
+	duCmd.Flags().BoolVar(&duJSON, jsonFlag, false, "Prints output in json format")
+	duCmd.Flags().BoolVar(&duPretty, prettyFlag, false, "Prints more readable json")
+	StorageToolsCmd.AddCommand(duCmd)
+}
@@ -0,0 +1,31 @@
+package st
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/storagetools"
+)
+
+const mirrorCheckShortDescription = "Compares the objects of the mirror storages with the primary storage"
+
+// mirrorCheckCmd represents the mirrorCheck command
+var mirrorCheckCmd = &cobra.Command{
+	Use:   "check-mirrors [relative folder path]",
+	Short: mirrorCheckShortDescription,
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		if len(args) > 0 {
+			folder = folder.GetSubFolder(args[0])
+		}
+
+		storagetools.HandleMirrorCheck(folder)
+	},
+}
+
+func init() {
+	StorageToolsCmd.AddCommand(mirrorCheckCmd)
+}
@@ -0,0 +1,47 @@
+package st
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/storagetools"
+	"github.com/wal-g/wal-g/internal/storagetools/s3server"
+)
+
+const (
+	serveShortDescription = "Serves the storage through an S3-compatible HTTP API"
+
+	addressFlag      = "address"
+	bucketFlag       = "bucket"
+	decryptFlag      = "decrypt"
+	decompressFlag   = "decompress"
+	readOnlyFlag     = "read-only"
+	defaultS3Address = "127.0.0.1:9000"
+)
+
+var (
+	serveAddress string
+	serveOptions s3server.Options
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: serveShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		storagetools.HandleServe(folder, serveAddress, serveOptions)
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddress, addressFlag, defaultS3Address, "The address to listen at")
+	serveCmd.Flags().StringVar(&serveOptions.Bucket, bucketFlag, s3server.DefaultBucket, "The name of the served bucket")
+	serveCmd.Flags().BoolVar(&serveOptions.Decrypt, decryptFlag, false, "Decrypt the downloaded objects")
+	serveCmd.Flags().BoolVar(&serveOptions.Decompress, decompressFlag, false, "Decompress the downloaded objects")
+	serveCmd.Flags().BoolVar(&serveOptions.ReadOnly, readOnlyFlag, false, "Reject the requests modifying the storage")
+	StorageToolsCmd.AddCommand(serveCmd)
+}
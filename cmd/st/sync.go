@@ -0,0 +1,49 @@
+package st
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/storagetools"
+)
+
+const (
+	syncShortDescription = "Copy the new and changed objects from one storage to another according to configs"
+
+	compareFlag           = "compare"
+	compareDescription    = "How to find the identical objects which are not copied: mtime, size or checksum"
+	deleteFlag            = "delete"
+	deleteDescription     = "Delete the objects missing in the source storage from the destination one"
+	syncDryRunDescription = "Only print the objects to copy and to delete"
+)
+
+var (
+	compareMode      string
+	deleteExtraneous bool
+	syncDryRun       bool
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync [relative folder path]",
+	Short: syncShortDescription,
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		compare, err := storagetools.ParseCompareMode(compareMode)
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		storagetools.HandleSync(fromConfigFile, toConfigFile, folderPathArg(args), storagetools.SyncOptions{
+			Compare:          compare,
+			DeleteExtraneous: deleteExtraneous,
+			DryRun:           syncDryRun,
+			Concurrency:      copyConcurrency,
+		})
+	},
+}
+
+func init() {
+	addCopyFlags(syncCmd)
+	syncCmd.Flags().StringVar(&compareMode, compareFlag, string(storagetools.CompareByModTime), compareDescription)
+	syncCmd.Flags().BoolVar(&deleteExtraneous, deleteFlag, false, deleteDescription)
+	syncCmd.Flags().BoolVar(&syncDryRun, dryRunFlag, false, syncDryRunDescription)
+	StorageToolsCmd.AddCommand(syncCmd)
+}
@@ -104,7 +104,16 @@ func (backup *Backup) UploadMetadata(metadataDto interface{}) error {
 	if err != nil {
 		return err
 	}
-	return backup.Folder.PutObject(metaFilePath, bytes.NewReader(dtoBody))
+	err = backup.Folder.PutObject(metaFilePath, bytes.NewReader(dtoBody))
+	if err != nil {
+		return err
+	}
+	// the metadata of the backup in progress is signed with the sentinel later
+	sentinelExists, err := backup.SentinelExists()
+	if err != nil || !sentinelExists {
+		return err
+	}
+	return backup.Sign()
 }
 
 func (backup *Backup) UploadSentinel(sentinelDto interface{}) error {
@@ -113,7 +122,11 @@ func (backup *Backup) UploadSentinel(sentinelDto interface{}) error {
 	if err != nil {
 		return err
 	}
-	return backup.Folder.PutObject(sentinelPath, bytes.NewReader(dtoBody))
+	err = backup.Folder.PutObject(sentinelPath, bytes.NewReader(dtoBody))
+	if err != nil {
+		return err
+	}
+	return backup.Sign()
 }
 
 func (backup *Backup) CheckExistence() (bool, error) {
@@ -169,7 +182,12 @@ func UploadSentinel(uploader UploaderProvider, sentinelDto interface{}, backupNa
 		return NewSentinelMarshallingError(sentinelName, err)
 	}
 
-	return uploader.Upload(sentinelName, bytes.NewReader(dtoBody))
+	err = uploader.Upload(sentinelName, bytes.NewReader(dtoBody))
+	if err != nil {
+		return err
+	}
+	backup := NewBackup(uploader.Folder(), backupName)
+	return backup.SignUploaded(uploader.UploadedChecksums())
 }
 
 type ErrWaiter interface {
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 
@@ -32,7 +33,7 @@ func GetCommandStreamFetcher(cmd *exec.Cmd) func(folder storage.Folder, backup B
 		cmd.Stderr = stderr
 		err = cmd.Start()
 		tracelog.ErrorLogger.FatalfOnError("Failed to start restore command: %v\n", err)
-		err = downloadAndDecompressStream(backup, stdin)
+		err = downloadAndDecompressStream(context.Background(), backup, stdin)
 		cmdErr := cmd.Wait()
 		if err != nil || cmdErr != nil {
 			tracelog.ErrorLogger.Printf("Restore command output:\n%s", stderr.String())
@@ -46,6 +47,11 @@ func GetCommandStreamFetcher(cmd *exec.Cmd) func(folder storage.Folder, backup B
 
 // StreamBackupToCommandStdin downloads and decompresses backup stream to cmd stdin.
 func StreamBackupToCommandStdin(cmd *exec.Cmd, backup Backup) error {
+	return StreamBackupToCommandStdinWithContext(context.Background(), cmd, backup)
+}
+
+// StreamBackupToCommandStdinWithContext is the cancellable version of StreamBackupToCommandStdin
+func StreamBackupToCommandStdinWithContext(ctx context.Context, cmd *exec.Cmd, backup Backup) error {
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return fmt.Errorf("failed to fetch backup: %v", err)
@@ -55,7 +61,7 @@ func StreamBackupToCommandStdin(cmd *exec.Cmd, backup Backup) error {
 	if err != nil {
 		return fmt.Errorf("failed to start command: %v", err)
 	}
-	err = downloadAndDecompressStream(backup, stdin)
+	err = downloadAndDecompressStream(ctx, backup, stdin)
 	if err != nil {
 		return errors.Wrap(err, "failed to download and decompress stream")
 	}
@@ -79,6 +85,11 @@ func HandleBackupFetch(folder storage.Folder,
 	tracelog.DebugLogger.Printf("HandleBackupFetch(%s, folder,)\n", backupName)
 	backup, err := GetBackupByName(backupName, utility.BaseBackupPath, folder)
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+	CheckBackupStorageClass(backup)
+	err = CheckBackupSignature(backup)
+	tracelog.ErrorLogger.FatalOnError(err)
+	folder, backup, err = VerifySignedObjects(folder, backup)
+	tracelog.ErrorLogger.FatalOnError(err)
 
 	fetcher(folder, backup)
 }
@@ -0,0 +1,456 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/crypto/signature"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type BackupNotSignedError struct {
+	error
+}
+
+func NewBackupNotSignedError(backupName string) BackupNotSignedError {
+	return BackupNotSignedError{errors.Errorf("backup '%s' is not signed", backupName)}
+}
+
+func (err BackupNotSignedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type InvalidBackupSignatureError struct {
+	error
+}
+
+func NewInvalidBackupSignatureError(backupName string, reason string) InvalidBackupSignatureError {
+	return InvalidBackupSignatureError{errors.Errorf("backup '%s' signature is invalid: %s", backupName, reason)}
+}
+
+func (err InvalidBackupSignatureError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// BackupSignatureDto is stored in the backup folder next to the metadata. It is signed over the checksums of
+// the sentinel, the metadata and the backup objects, so none of them can be altered unnoticed.
+type BackupSignatureDto struct {
+	Algorithm      string                     `json:"algorithm"`
+	KeyID          string                     `json:"key_id"`
+	SentinelSha256 string                     `json:"sentinel_sha256"`
+	MetadataSha256 string                     `json:"metadata_sha256,omitempty"`
+	Partitions     []BackupPartitionSignature `json:"partitions"`
+	Signature      []byte                     `json:"signature,omitempty"`
+}
+
+// BackupPartitionSignature describes the backup object. The checksum is computed while the object
+// is uploaded, it is empty for the objects of the backups signed after they were made.
+type BackupPartitionSignature struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256,omitempty"`
+}
+
+func (dto *BackupSignatureDto) signedData() ([]byte, error) {
+	unsigned := *dto
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+func (backup *Backup) getSignaturePath() string {
+	return backup.Name + "/" + utility.SignatureFileName
+}
+
+// Sign signs the current sentinel, metadata and objects of the backup with the configured key,
+// the caller asserts that no object is rewritten since the backup was signed before.
+// The backup is left unsigned if no key is configured.
+func (backup *Backup) Sign() error {
+	return backup.SignUploaded(nil)
+}
+
+// SignUploaded is Sign which signs the checksums of the backup objects uploaded or rewritten since
+// the backup was signed before, e.g. the ones recorded by Uploader.UploadedChecksums.
+// The rewritten objects with the empty checksum are hashed anew.
+func (backup *Backup) SignUploaded(uploaded map[string]string) error {
+	key, err := ConfigureSignatureKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		tracelog.DebugLogger.Printf("No signature key is configured, backup '%s' is not signed\n", backup.Name)
+		return nil
+	}
+	return backup.SignWithKey(key, uploaded)
+}
+
+// SignWithKey signs the backup with the key. The checksums of the objects uploaded or rewritten since the previous
+// signature are taken from uploaded, keyed by the object paths (see storage.JoinPath), the objects with the empty
+// checksum are read to hash them. The checksums of the other objects, which are not touched, are taken from
+// the previous valid signature if their size is the same, and then from the object checksums storage
+// if WALG_OBJECT_CHECKSUMS is enabled.
+func (backup *Backup) SignWithKey(key signature.Key, uploaded map[string]string) error {
+	dto, err := backup.collectSignature()
+	if err == nil {
+		err = backup.addPartitionChecksums(&dto, uploaded, backup.previouslySigned(key))
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to collect the signed content of backup '%s'", backup.Name)
+	}
+	dto.Algorithm = key.Algorithm()
+	dto.KeyID = key.KeyID()
+	data, err := dto.signedData()
+	if err != nil {
+		return err
+	}
+	dto.Signature, err = key.Sign(data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to sign backup '%s'", backup.Name)
+	}
+	dtoBody, err := json.Marshal(dto)
+	if err != nil {
+		return err
+	}
+	tracelog.DebugLogger.Printf("Signing backup '%s' with %s key %s\n", backup.Name, dto.Algorithm, dto.KeyID)
+	return backup.Folder.PutObject(backup.getSignaturePath(), bytes.NewReader(dtoBody))
+}
+
+// VerifySignature checks that the backup is signed by the key and is not altered since it was signed.
+// The content of the objects is compared with the signed checksums only if their checksums are stored
+// (see WALG_OBJECT_CHECKSUMS), otherwise it is verified when they are read, see VerifySignedObjects.
+func (backup *Backup) VerifySignature(key signature.Key) error {
+	signed, err := backup.fetchSignature()
+	if err != nil {
+		return err
+	}
+	if signed.Algorithm != key.Algorithm() || signed.KeyID != key.KeyID() {
+		return NewInvalidBackupSignatureError(backup.Name, fmt.Sprintf("signed by %s key %s, but %s key %s is configured",
+			signed.Algorithm, signed.KeyID, key.Algorithm(), key.KeyID()))
+	}
+	signedData, err := signed.signedData()
+	if err != nil {
+		return err
+	}
+	if !key.Verify(signedData, signed.Signature) {
+		return NewInvalidBackupSignatureError(backup.Name, "the signature doesn't match the signed content")
+	}
+
+	current, err := backup.collectSignature()
+	if err == nil && viper.GetBool(ObjectChecksumsSetting) {
+		err = backup.addStoredChecksums(&current, signed.Partitions)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to collect the signed content of backup '%s'", backup.Name)
+	}
+	if changes := compareSignedContent(signed, current); len(changes) > 0 {
+		return NewInvalidBackupSignatureError(backup.Name, strings.Join(changes, ", "))
+	}
+	return nil
+}
+
+func (backup *Backup) fetchSignature() (BackupSignatureDto, error) {
+	var signed BackupSignatureDto
+	data, err := backup.fetchStorageBytes(backup.getSignaturePath())
+	if _, ok := errors.Cause(err).(storage.ObjectNotFoundError); ok {
+		return signed, NewBackupNotSignedError(backup.Name)
+	}
+	if err != nil {
+		return signed, errors.Wrap(err, "failed to fetch backup signature")
+	}
+	if err = json.Unmarshal(data, &signed); err != nil {
+		return signed, NewInvalidBackupSignatureError(backup.Name, err.Error())
+	}
+	return signed, nil
+}
+
+// previouslySigned returns the partitions of the current signature of the backup if it is made with the key
+// and is valid, the checksums of such partitions are trusted
+func (backup *Backup) previouslySigned(key signature.Key) map[string]BackupPartitionSignature {
+	signed, err := backup.fetchSignature()
+	if err != nil || signed.Algorithm != key.Algorithm() || signed.KeyID != key.KeyID() {
+		return nil
+	}
+	signedData, err := signed.signedData()
+	if err != nil || !key.Verify(signedData, signed.Signature) {
+		tracelog.WarningLogger.Printf("The current signature of backup '%s' is invalid, it is signed anew\n", backup.Name)
+		return nil
+	}
+	partitions := make(map[string]BackupPartitionSignature, len(signed.Partitions))
+	for _, partition := range signed.Partitions {
+		partitions[partition.Name] = partition
+	}
+	return partitions
+}
+
+// collectSignature gathers the current content of the backup to sign, except for the checksums of the objects
+func (backup *Backup) collectSignature() (BackupSignatureDto, error) {
+	var dto BackupSignatureDto
+	var err error
+	dto.SentinelSha256, err = backup.objectSha256(backup.getStopSentinelPath())
+	if err != nil {
+		return dto, errors.Wrap(err, "failed to read sentinel")
+	}
+	metadataExists, err := backup.Folder.Exists(backup.getMetadataPath())
+	if err != nil {
+		return dto, err
+	}
+	if metadataExists {
+		dto.MetadataSha256, err = backup.objectSha256(backup.getMetadataPath())
+		if err != nil {
+			return dto, errors.Wrap(err, "failed to read metadata")
+		}
+	}
+
+	backupFolder := backup.Folder.GetSubFolder(backup.Name)
+	objects, err := storage.ListFolderRecursively(backupFolder)
+	if err != nil {
+		return dto, err
+	}
+	dto.Partitions = make([]BackupPartitionSignature, 0, len(objects))
+	for _, object := range objects {
+		name := object.GetName()
+		if name == utility.MetadataFileName || name == utility.SignatureFileName || storage.IsChecksumObject(name) {
+			continue
+		}
+		dto.Partitions = append(dto.Partitions, BackupPartitionSignature{Name: name, Size: object.GetSize()})
+	}
+	sort.Slice(dto.Partitions, func(i, j int) bool {
+		return dto.Partitions[i].Name < dto.Partitions[j].Name
+	})
+	return dto, nil
+}
+
+func (backup *Backup) addPartitionChecksums(dto *BackupSignatureDto, uploaded map[string]string,
+	previous map[string]BackupPartitionSignature) error {
+	backupFolder := backup.Folder.GetSubFolder(backup.Name)
+	withStoredChecksums := viper.GetBool(ObjectChecksumsSetting)
+	for i := range dto.Partitions {
+		partition := &dto.Partitions[i]
+		if checksum, ok := uploaded[storage.JoinPath(backupFolder.GetPath(), partition.Name)]; ok {
+			if checksum == "" {
+				// the object is rewritten, but its checksum is unknown
+				var err error
+				checksum, err = backup.objectSha256(backup.Name + "/" + partition.Name)
+				if err != nil {
+					return errors.Wrapf(err, "failed to hash '%s'", partition.Name)
+				}
+			}
+			partition.Sha256 = checksum
+			continue
+		}
+		if signed, ok := previous[partition.Name]; ok && signed.Size == partition.Size && signed.Sha256 != "" {
+			partition.Sha256 = signed.Sha256
+			continue
+		}
+		if withStoredChecksums {
+			checksum, err := readObjectChecksum(backupFolder, partition.Name)
+			if err != nil {
+				return err
+			}
+			partition.Sha256 = checksum
+		}
+	}
+	return nil
+}
+
+// addStoredChecksums reads the stored checksums of the objects which are signed with the checksums
+func (backup *Backup) addStoredChecksums(dto *BackupSignatureDto, signedPartitions []BackupPartitionSignature) error {
+	checksummed := make(map[string]bool, len(signedPartitions))
+	for _, partition := range signedPartitions {
+		checksummed[partition.Name] = partition.Sha256 != ""
+	}
+	backupFolder := backup.Folder.GetSubFolder(backup.Name)
+	for i := range dto.Partitions {
+		partition := &dto.Partitions[i]
+		if !checksummed[partition.Name] {
+			continue
+		}
+		checksum, err := readObjectChecksum(backupFolder, partition.Name)
+		if err != nil {
+			return err
+		}
+		partition.Sha256 = checksum
+	}
+	return nil
+}
+
+func (backup *Backup) objectSha256(path string) (string, error) {
+	reader, err := backup.Folder.ReadObject(path)
+	if err != nil {
+		return "", err
+	}
+	defer utility.LoggedClose(reader, "")
+	checksum := sha256.New()
+	if _, err = io.Copy(checksum, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(checksum.Sum(nil)), nil
+}
+
+// readObjectChecksum reads the checksum stored for the object, see storage.ChecksumFolder
+func readObjectChecksum(folder storage.Folder, name string) (string, error) {
+	reader, err := folder.ReadObject(name + storage.ChecksumSuffix)
+	if _, ok := errors.Cause(err).(storage.ObjectNotFoundError); ok {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the checksum of '%s'", name)
+	}
+	defer utility.LoggedClose(reader, "")
+	checksum, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the checksum of '%s'", name)
+	}
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+func compareSignedContent(signed, current BackupSignatureDto) []string {
+	changes := make([]string, 0)
+	if signed.SentinelSha256 != current.SentinelSha256 {
+		changes = append(changes, "the sentinel is changed")
+	}
+	if signed.MetadataSha256 != current.MetadataSha256 {
+		changes = append(changes, "the metadata is changed")
+	}
+	currentPartitions := make(map[string]BackupPartitionSignature, len(current.Partitions))
+	for _, partition := range current.Partitions {
+		currentPartitions[partition.Name] = partition
+	}
+	for _, partition := range signed.Partitions {
+		currentPartition, ok := currentPartitions[partition.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("'%s' is missing", partition.Name))
+		case currentPartition.Size != partition.Size:
+			changes = append(changes, fmt.Sprintf("'%s' is changed", partition.Name))
+		case currentPartition.Sha256 != "" && currentPartition.Sha256 != partition.Sha256:
+			// the checksum is known only if it is stored
+			changes = append(changes, fmt.Sprintf("'%s' is changed", partition.Name))
+		}
+		delete(currentPartitions, partition.Name)
+	}
+	for _, partition := range current.Partitions {
+		if _, ok := currentPartitions[partition.Name]; ok {
+			changes = append(changes, fmt.Sprintf("'%s' is not signed", partition.Name))
+		}
+	}
+	return changes
+}
+
+// CheckBackupSignature verifies the backup signature with the configured key.
+// In the strict mode (see WALG_SIGNATURE_STRICT) the unsigned or invalid backup is an error,
+// otherwise it is only reported. The unsigned backups made before WALG_SIGNATURE_STRICT_SINCE
+// are only reported in the strict mode too.
+func CheckBackupSignature(backup Backup) error {
+	strict := viper.GetBool(SignStrictSetting)
+	key, err := ConfigureSignatureKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		if strict {
+			return errors.Errorf("%s is set, but no signature key is configured", SignStrictSetting)
+		}
+		return nil
+	}
+
+	err = backup.VerifySignature(key)
+	if err == nil {
+		tracelog.DebugLogger.Printf("Backup '%s' signature is valid\n", backup.Name)
+		return nil
+	}
+	if strict {
+		if _, ok := err.(BackupNotSignedError); ok {
+			return backup.checkUnsignedBackup(err)
+		}
+		return err
+	}
+	switch err.(type) {
+	case BackupNotSignedError, InvalidBackupSignatureError:
+		tracelog.WarningLogger.Println(err.Error())
+		return nil
+	}
+	return err
+}
+
+// checkUnsignedBackup accepts the unsigned backup in the strict mode if it is made before the backups
+// were required to be signed. The time of the backup is the time its sentinel is stored at.
+func (backup *Backup) checkUnsignedBackup(notSignedErr error) error {
+	since, err := configureSignStrictSince()
+	if err != nil {
+		return err
+	}
+	if since.IsZero() {
+		return notSignedErr
+	}
+	sentinel, err := storage.StatObject(context.Background(), backup.Folder, backup.getStopSentinelPath())
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the time of backup '%s'", backup.Name)
+	}
+	if !sentinel.GetLastModified().Before(since) {
+		return notSignedErr
+	}
+	tracelog.WarningLogger.Printf("Backup '%s' is not signed, it is accepted as it is made before %s\n",
+		backup.Name, since.Format(time.RFC3339))
+	return nil
+}
+
+// VerifySignedObjects returns the root folder and the backup whose objects are verified against the checksums
+// signed with the backup when they are read to the end, see storage.VerifyingFolder. The signature itself
+// is verified by CheckBackupSignature. They are returned as is if no key is configured or the backup is not signed.
+func VerifySignedObjects(folder storage.Folder, backup Backup) (storage.Folder, Backup, error) {
+	key, err := ConfigureSignatureKey()
+	if err != nil || key == nil {
+		return folder, backup, err
+	}
+	signed, err := backup.fetchSignature()
+	switch err.(type) {
+	case nil:
+	case BackupNotSignedError, InvalidBackupSignatureError:
+		return folder, backup, nil
+	default:
+		return nil, Backup{}, err
+	}
+	backupPath := backup.Folder.GetSubFolder(backup.Name).GetPath()
+	checksums := make(map[string]string, len(signed.Partitions))
+	for _, partition := range signed.Partitions {
+		if partition.Sha256 != "" {
+			checksums[storage.JoinPath(backupPath, partition.Name)] = partition.Sha256
+		}
+	}
+	if len(checksums) == 0 {
+		return folder, backup, nil
+	}
+	backup.Folder = storage.NewVerifyingFolder(backup.Folder, checksums)
+	return storage.NewVerifyingFolder(folder, checksums), backup, nil
+}
+
+// FilterSignedBackups checks the signatures of the listed backups. In the strict mode the unsigned
+// and invalid backups are dropped from the list, otherwise they are only reported.
+func FilterSignedBackups(folder storage.Folder, backups []BackupTime) ([]BackupTime, error) {
+	signed := make([]BackupTime, 0, len(backups))
+	for _, backupTime := range backups {
+		err := CheckBackupSignature(NewBackup(folder, backupTime.BackupName))
+		switch err.(type) {
+		case nil:
+			signed = append(signed, backupTime)
+		case BackupNotSignedError, InvalidBackupSignatureError:
+			tracelog.ErrorLogger.Println(err.Error())
+		default:
+			return nil, err
+		}
+	}
+	return signed, nil
+}
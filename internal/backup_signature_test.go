@@ -0,0 +1,195 @@
+package internal_test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/crypto/signature"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// makeSignedBackup uploads the backup partitions with the uploader recording their checksums.
+// The checksums are also stored next to the partitions if withStoredChecksums is set.
+func makeSignedBackup(t *testing.T, key signature.Key, withStoredChecksums bool) internal.Backup {
+	folder := testtools.MakeDefaultInMemoryStorageFolder().GetSubFolder(utility.BaseBackupPath)
+	if withStoredChecksums {
+		folder = storage.NewChecksumFolder(folder)
+	}
+	uploader := internal.NewUploader(&testtools.MockCompressor{}, folder)
+	uploader.EnableChecksumTracking()
+	backupName := "base_000000010000000000000002"
+	require.NoError(t, uploader.Upload(backupName+"/tar_partitions/part_1.tar.lz4", strings.NewReader("part 1")))
+	require.NoError(t, uploader.Upload(backupName+"/tar_partitions/part_2.tar.lz4", strings.NewReader("part 2")))
+	require.NoError(t, folder.PutObject(backupName+"/"+utility.MetadataFileName, strings.NewReader(`{"is_permanent":true}`)))
+	require.NoError(t, folder.PutObject(backupName+utility.SentinelSuffix, strings.NewReader(`{"LSN":42}`)))
+
+	backup := internal.NewBackup(folder, backupName)
+	require.NoError(t, backup.SignWithKey(key, uploader.UploadedChecksums()))
+	return backup
+}
+
+func readSignature(t *testing.T, backup internal.Backup) internal.BackupSignatureDto {
+	var signed internal.BackupSignatureDto
+	reader, err := backup.Folder.ReadObject(backup.Name + "/" + utility.SignatureFileName)
+	require.NoError(t, err)
+	defer reader.Close()
+	require.NoError(t, json.NewDecoder(reader).Decode(&signed))
+	return signed
+}
+
+// configureHMACKey makes the key the configured signature key, it returns the path of the key file
+func configureHMACKey(t *testing.T, secret string) string {
+	keyFile, err := ioutil.TempFile("", "hmac")
+	require.NoError(t, err)
+	_, err = keyFile.WriteString(secret)
+	require.NoError(t, err)
+	require.NoError(t, keyFile.Close())
+	viper.Set(internal.SignHMACKeyPathSetting, keyFile.Name())
+	return keyFile.Name()
+}
+
+func TestBackupSignature_Valid(t *testing.T) {
+	key := signature.NewHMACKey([]byte(strings.Repeat("k", 32)))
+	backup := makeSignedBackup(t, key, true)
+	assert.NoError(t, backup.VerifySignature(key))
+
+	signed := readSignature(t, backup)
+	require.Len(t, signed.Partitions, 2)
+	// sha256 of "part 1"
+	assert.Equal(t, "e21dcf13079a712a6ee683e2e6718de2bba2b8c11bc326e0c8fff2eb7303a822", signed.Partitions[0].Sha256)
+}
+
+func TestBackupSignature_WrongKey(t *testing.T) {
+	backup := makeSignedBackup(t, signature.NewHMACKey([]byte(strings.Repeat("k", 32))), false)
+	err := backup.VerifySignature(signature.NewHMACKey([]byte(strings.Repeat("o", 32))))
+	assert.IsType(t, internal.InvalidBackupSignatureError{}, err)
+}
+
+func TestBackupSignature_NotSigned(t *testing.T) {
+	key := signature.NewHMACKey([]byte(strings.Repeat("k", 32)))
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	backup := internal.NewBackup(folder, "base_000000010000000000000002")
+	assert.IsType(t, internal.BackupNotSignedError{}, backup.VerifySignature(key))
+}
+
+func TestBackupSignature_Altered(t *testing.T) {
+	key := signature.NewHMACKey([]byte(strings.Repeat("k", 32)))
+	cases := map[string]func(folder storage.Folder, backupName string) error{
+		"metadata": func(folder storage.Folder, backupName string) error {
+			return folder.PutObject(backupName+"/"+utility.MetadataFileName, strings.NewReader(`{"is_permanent":false}`))
+		},
+		"sentinel": func(folder storage.Folder, backupName string) error {
+			return folder.PutObject(backupName+utility.SentinelSuffix, strings.NewReader(`{"LSN":43}`))
+		},
+		"partition content": func(folder storage.Folder, backupName string) error {
+			return folder.PutObject(backupName+"/tar_partitions/part_1.tar.lz4", strings.NewReader("part X"))
+		},
+		"missing partition": func(folder storage.Folder, backupName string) error {
+			return folder.DeleteObjects([]string{backupName + "/tar_partitions/part_2.tar.lz4"})
+		},
+		"extra partition": func(folder storage.Folder, backupName string) error {
+			return folder.PutObject(backupName+"/tar_partitions/part_3.tar.lz4", strings.NewReader("part 3"))
+		},
+	}
+	viper.Set(internal.ObjectChecksumsSetting, true)
+	defer resetToDefaults()
+	for name, alter := range cases {
+		t.Run(name, func(t *testing.T) {
+			backup := makeSignedBackup(t, key, true)
+			require.NoError(t, alter(backup.Folder, backup.Name))
+			assert.IsType(t, internal.InvalidBackupSignatureError{}, backup.VerifySignature(key))
+		})
+	}
+}
+
+func TestBackupSignature_PublicKeyVerifies(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	backup := makeSignedBackup(t, signature.NewEd25519Key(private), false)
+	assert.NoError(t, backup.VerifySignature(signature.NewEd25519PublicKey(public)))
+	assert.Equal(t, signature.ErrCantSign, errors.Cause(backup.SignWithKey(signature.NewEd25519PublicKey(public), nil)))
+}
+
+func TestBackupSignature_ResigningKeepsChecksums(t *testing.T) {
+	key := signature.NewHMACKey([]byte(strings.Repeat("k", 32)))
+	backup := makeSignedBackup(t, key, false)
+	signed := readSignature(t, backup)
+
+	require.NoError(t, backup.Folder.PutObject(backup.Name+"/"+utility.MetadataFileName, strings.NewReader(`{}`)))
+	require.NoError(t, backup.SignWithKey(key, nil))
+	assert.NoError(t, backup.VerifySignature(key))
+	assert.Equal(t, signed.Partitions, readSignature(t, backup).Partitions)
+}
+
+func TestBackupSignature_ResigningRewrittenObjects(t *testing.T) {
+	key := signature.NewHMACKey([]byte(strings.Repeat("k", 32)))
+	backup := makeSignedBackup(t, key, false)
+	backupPath := backup.Folder.GetSubFolder(backup.Name).GetPath()
+	// the objects are rewritten with the content of the same size
+	require.NoError(t, backup.Folder.PutObject(backup.Name+"/tar_partitions/part_1.tar.lz4", strings.NewReader("part X")))
+	require.NoError(t, backup.Folder.PutObject(backup.Name+"/tar_partitions/part_2.tar.lz4", strings.NewReader("part Y")))
+
+	require.NoError(t, backup.SignWithKey(key, map[string]string{
+		storage.JoinPath(backupPath, "tar_partitions/part_1.tar.lz4"): "",
+		storage.JoinPath(backupPath, "tar_partitions/part_2.tar.lz4"): "checksum",
+	}))
+	signed := readSignature(t, backup)
+	require.Len(t, signed.Partitions, 2)
+	// sha256 of "part X"
+	assert.Equal(t, "e6d510636d1c8eba710f0f9357bf83fe3274775877f502e64f52d23ae8b3b46e", signed.Partitions[0].Sha256)
+	assert.Equal(t, "checksum", signed.Partitions[1].Sha256)
+}
+
+func TestVerifySignedObjects(t *testing.T) {
+	secret := strings.Repeat("k", 32)
+	defer os.Remove(configureHMACKey(t, secret))
+	defer resetToDefaults()
+	backup := makeSignedBackup(t, signature.NewHMACKey([]byte(secret)), false)
+	partition := backup.Name + "/tar_partitions/part_1.tar.lz4"
+	require.NoError(t, backup.Folder.PutObject(partition, strings.NewReader("part X")))
+	// the content of the partitions is verified only when they are read
+	assert.NoError(t, internal.CheckBackupSignature(backup))
+
+	_, backup, err := internal.VerifySignedObjects(backup.Folder, backup)
+	require.NoError(t, err)
+	reader, err := backup.Folder.ReadObject(partition)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(reader)
+	assert.IsType(t, storage.ChecksumMismatchError{}, err)
+
+	reader, err = backup.Folder.ReadObject(backup.Name + "/tar_partitions/part_2.tar.lz4")
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "part 2", string(content))
+}
+
+func TestCheckBackupSignature_StrictSince(t *testing.T) {
+	defer os.Remove(configureHMACKey(t, strings.Repeat("k", 32)))
+	viper.Set(internal.SignStrictSetting, true)
+	defer resetToDefaults()
+	folder := testtools.MakeDefaultInMemoryStorageFolder().GetSubFolder(utility.BaseBackupPath)
+	backupName := "base_000000010000000000000002"
+	require.NoError(t, folder.PutObject(backupName+utility.SentinelSuffix, strings.NewReader(`{"LSN":42}`)))
+	backup := internal.NewBackup(folder, backupName)
+
+	assert.IsType(t, internal.BackupNotSignedError{}, internal.CheckBackupSignature(backup))
+
+	viper.Set(internal.SignStrictSinceSetting, time.Now().Add(-time.Hour).Format(time.RFC3339))
+	assert.IsType(t, internal.BackupNotSignedError{}, internal.CheckBackupSignature(backup))
+
+	viper.Set(internal.SignStrictSinceSetting, time.Now().Add(time.Hour).Format(time.RFC3339))
+	assert.NoError(t, internal.CheckBackupSignature(backup))
+}
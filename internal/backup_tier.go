@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupTierHandler moves the objects of the backups to another storage class,
+// e.g. the old backups to the cheaper archive classes.
+// The sentinels and the metadata files are kept in their storage class, since they are read by backup-list and delete.
+type BackupTierHandler struct {
+	metaInteractor   GenericMetaInteractor
+	baseBackupFolder storage.Folder
+	ctx              context.Context
+}
+
+func NewBackupTierHandler(metaInteractor GenericMetaInteractor, storageRootFolder storage.Folder) BackupTierHandler {
+	return BackupTierHandler{
+		metaInteractor:   metaInteractor,
+		baseBackupFolder: storageRootFolder.GetSubFolder(utility.BaseBackupPath),
+		ctx:              context.Background(),
+	}
+}
+
+// HandleBackupTier moves the named backups, or all the backups made before the time if no names are given
+func HandleBackupTier(folder storage.Folder, metaInteractor GenericMetaInteractor,
+	backupNames []string, before time.Time, storageClass string) {
+	tierHandler := NewBackupTierHandler(metaInteractor, folder)
+	if len(backupNames) == 0 {
+		var err error
+		backupNames, err = tierHandler.FindBackupsBefore(before)
+		tracelog.ErrorLogger.FatalfOnError("Failed to find backups: %v", err)
+	}
+	if len(backupNames) == 0 {
+		tracelog.InfoLogger.Println("No backups found to move")
+		return
+	}
+	err := tierHandler.TierBackups(backupNames, storageClass)
+	tracelog.ErrorLogger.FatalfOnError("Failed to move backups: %v", err)
+}
+
+// FindBackupsBefore returns the names of the backups finished before the time
+func (h *BackupTierHandler) FindBackupsBefore(before time.Time) ([]string, error) {
+	backups, err := GetBackups(h.baseBackupFolder)
+	if _, ok := err.(NoBackupsFoundError); ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	SortBackupTimeSlices(backups)
+	backupNames := make([]string, 0)
+	for _, backup := range backups {
+		if backup.Time.Before(before) {
+			backupNames = append(backupNames, backup.BackupName)
+		}
+	}
+	return backupNames, nil
+}
+
+// TierBackups moves the backups to the storage class and records it in the backup metadata.
+// The objects already moved are skipped, so the interrupted move may be repeated.
+func (h *BackupTierHandler) TierBackups(backupNames []string, storageClass string) error {
+	for _, backupName := range backupNames {
+		meta, err := h.metaInteractor.Fetch(backupName, h.baseBackupFolder)
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch the metadata of backup %s", backupName)
+		}
+		if meta.StorageClass == storageClass {
+			tracelog.InfoLogger.Printf("Backup %s is already in storage class %s\n", backupName, storageClass)
+			continue
+		}
+		tracelog.InfoLogger.Printf("Moving backup %s to storage class %s\n", backupName, storageClass)
+		err = h.tierBackup(backupName, storageClass)
+		if err != nil {
+			return errors.Wrapf(err, "failed to move backup %s", backupName)
+		}
+		err = h.metaInteractor.SetStorageClass(backupName, h.baseBackupFolder, storageClass)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *BackupTierHandler) tierBackup(backupName string, storageClass string) error {
+	backupFolder := h.baseBackupFolder.GetSubFolder(backupName)
+	var objectNames []string
+	err := storage.ListFolderPages(h.ctx, backupFolder, storage.ListOptions{Recursive: true},
+		func(objects []storage.Object, _ []storage.Folder) error {
+			for _, object := range objects {
+				if object.GetName() != utility.MetadataFileName && object.GetName() != utility.SignatureFileName {
+					objectNames = append(objectNames, object.GetName())
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	for _, objectName := range objectNames {
+		current, err := storage.GetStorageClass(h.ctx, backupFolder, objectName)
+		if err != nil {
+			return err
+		}
+		if current.Name == storageClass {
+			continue
+		}
+		if current.NeedsRestore {
+			return errors.Errorf("object '%s' is in archive storage class %s and must be restored first",
+				objectName, current.Name)
+		}
+		tracelog.DebugLogger.Printf("Moving '%s' from storage class %s\n", objectName, current.Name)
+		err = storage.SetStorageClass(h.ctx, backupFolder, objectName, storageClass)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckBackupStorageClass warns if the backup objects are in the archive storage class and can't be read until restored.
+// Since the objects of a backup are moved together, only one of them is checked.
+func CheckBackupStorageClass(backup Backup) {
+	backupFolder := backup.Folder.GetSubFolder(backup.Name)
+	var objectName string
+	errFound := errors.New("object found")
+	err := storage.ListFolderPages(context.Background(), backupFolder, storage.ListOptions{Recursive: true},
+		func(objects []storage.Object, _ []storage.Folder) error {
+			for _, object := range objects {
+				if object.GetName() != utility.MetadataFileName && object.GetName() != utility.SignatureFileName {
+					objectName = object.GetName()
+					return errFound
+				}
+			}
+			return nil
+		})
+	if err != nil && err != errFound {
+		tracelog.WarningLogger.Printf("Failed to check the storage class of backup %s: %v\n", backup.Name, err)
+		return
+	}
+	if objectName == "" {
+		return
+	}
+	storageClass, err := storage.GetStorageClass(context.Background(), backupFolder, objectName)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to check the storage class of backup %s: %v\n", backup.Name, err)
+		return
+	}
+	if storageClass.NeedsRestore {
+		tracelog.WarningLogger.Printf("Backup %s is in archive storage class %s, "+
+			"its objects must be restored in the storage before the backup can be fetched\n",
+			backup.Name, storageClass.Name)
+	}
+}
@@ -0,0 +1,97 @@
+package internal_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// tierFolder keeps the storage classes of its objects in memory, GLACIER objects need restore
+type tierFolder struct {
+	storage.Folder
+	classes map[string]string
+}
+
+func (folder tierFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return tierFolder{folder.Folder.GetSubFolder(subFolderRelativePath), folder.classes}
+}
+
+func (folder tierFolder) GetStorageClass(ctx context.Context, objectRelativePath string) (storage.StorageClass, error) {
+	class, ok := folder.classes[storage.JoinPath(folder.GetPath(), objectRelativePath)]
+	if !ok {
+		class = "STANDARD"
+	}
+	return storage.StorageClass{Name: class, NeedsRestore: class == "GLACIER"}, nil
+}
+
+func (folder tierFolder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	folder.classes[storage.JoinPath(folder.GetPath(), objectRelativePath)] = storageClass
+	return nil
+}
+
+// tierMetaInteractor keeps only the storage classes of the backups
+type tierMetaInteractor struct {
+	classes map[string]string
+}
+
+func (mi tierMetaInteractor) Fetch(backupName string, backupFolder storage.Folder) (internal.GenericMetadata, error) {
+	return internal.GenericMetadata{BackupName: backupName, StorageClass: mi.classes[backupName]}, nil
+}
+
+func (mi tierMetaInteractor) SetUserData(backupName string, backupFolder storage.Folder, userData interface{}) error {
+	return nil
+}
+
+func (mi tierMetaInteractor) SetIsPermanent(backupName string, backupFolder storage.Folder, isPermanent bool) error {
+	return nil
+}
+
+func (mi tierMetaInteractor) SetStorageClass(backupName string, backupFolder storage.Folder, storageClass string) error {
+	mi.classes[backupName] = storageClass
+	return nil
+}
+
+func (mi tierMetaInteractor) SetEncryptionKey(backupName string, backupFolder storage.Folder, encryptionKey string) error {
+	return nil
+}
+
+func TestBackupTierHandler_TierBackups(t *testing.T) {
+	folder := tierFolder{testtools.MakeDefaultInMemoryStorageFolder(), map[string]string{}}
+	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	for _, name := range []string{
+		"base_1" + utility.SentinelSuffix,
+		"base_1/" + utility.MetadataFileName,
+		"base_1/tar_partitions/part_1.tar.lz4",
+		"base_1/tar_partitions/part_2.tar.lz4",
+	} {
+		assert.NoError(t, baseBackupFolder.PutObject(name, strings.NewReader("data")))
+	}
+	metaInteractor := tierMetaInteractor{map[string]string{}}
+	tierHandler := internal.NewBackupTierHandler(metaInteractor, folder)
+
+	backupNames, err := tierHandler.FindBackupsBefore(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, backupNames)
+	backupNames, err = tierHandler.FindBackupsBefore(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"base_1"}, backupNames)
+
+	assert.NoError(t, tierHandler.TierBackups(backupNames, "GLACIER"))
+	assert.Equal(t, "GLACIER", metaInteractor.classes["base_1"])
+	basePath := baseBackupFolder.GetPath()
+	assert.Equal(t, map[string]string{
+		basePath + "base_1/tar_partitions/part_1.tar.lz4": "GLACIER",
+		basePath + "base_1/tar_partitions/part_2.tar.lz4": "GLACIER",
+	}, folder.classes)
+
+	// the archived objects must be restored before they are moved again
+	metaInteractor.classes["base_1"] = ""
+	assert.Error(t, tierHandler.TierBackups(backupNames, "STANDARD_IA"))
+}
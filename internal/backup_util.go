@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"sort"
@@ -83,12 +84,19 @@ func GetBackups(folder storage.Folder) (backups []BackupTime, err error) {
 
 // TODO : unit tests
 func GetBackupsAndGarbage(folder storage.Folder) (backups []BackupTime, garbage []string, err error) {
-	backupObjects, subFolders, err := folder.ListFolder()
+	sortTimes := make([]BackupTime, 0)
+	subFolders := make([]storage.Folder, 0)
+	// only sentinels are kept out of every listed page
+	err = storage.ListFolderPages(context.Background(), folder, storage.ListOptions{},
+		func(backupObjects []storage.Object, pageSubFolders []storage.Folder) error {
+			sortTimes = append(sortTimes, GetBackupTimeSlices(backupObjects)...)
+			subFolders = append(subFolders, pageSubFolders...)
+			return nil
+		})
 	if err != nil {
 		return nil, nil, err
 	}
 
-	sortTimes := GetBackupTimeSlices(backupObjects)
 	garbage = GetGarbageFromPrefix(subFolders, sortTimes)
 
 	return sortTimes, garbage, nil
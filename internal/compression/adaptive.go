@@ -0,0 +1,61 @@
+package compression
+
+import (
+	"io"
+
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+)
+
+const (
+	// SampleSize is the size of the single sample taken to estimate the compressibility
+	SampleSize = 64 << 10
+	// IncompressibleRatio is the compressed to the original size ratio
+	// starting from which the data isn't worth compressing
+	IncompressibleRatio = 0.95
+)
+
+// IsCompressible estimates whether the data like the sample is worth compressing.
+// The estimate uses lz4 whatever compression is configured, since it is the fastest one,
+// and the data which lz4 can't compress is most often already compressed or encrypted.
+func IsCompressible(sample []byte) bool {
+	if len(sample) == 0 {
+		return true
+	}
+	counter := &sizeCountingWriter{}
+	writer := lz4.Compressor{}.NewWriter(counter)
+	if _, err := writer.Write(sample); err != nil {
+		return true
+	}
+	if err := writer.Close(); err != nil {
+		return true
+	}
+	return float64(counter.size) < IncompressibleRatio*float64(len(sample))
+}
+
+// IsReaderCompressible estimates the compressibility of the data of the size
+// by the samples from its beginning, middle and end
+func IsReaderCompressible(reader io.ReaderAt, size int64) (bool, error) {
+	offsets := []int64{0}
+	if size > 3*SampleSize {
+		offsets = append(offsets, size/2-SampleSize/2, size-SampleSize)
+	}
+	sample := make([]byte, 0, len(offsets)*SampleSize)
+	for _, offset := range offsets {
+		part := make([]byte, SampleSize)
+		n, err := reader.ReadAt(part, offset)
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		sample = append(sample, part[:n]...)
+	}
+	return IsCompressible(sample), nil
+}
+
+type sizeCountingWriter struct {
+	size int
+}
+
+func (writer *sizeCountingWriter) Write(p []byte) (int, error) {
+	writer.size += len(p)
+	return len(p), nil
+}
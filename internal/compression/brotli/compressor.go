@@ -11,12 +11,24 @@ import (
 const (
 	AlgorithmName = "brotli"
 	FileExtension = "br"
+
+	DefaultQuality = 3
+	MinQuality     = 0
+	MaxQuality     = 11
 )
 
 type Compressor struct{}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
-	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: 3})
+	return compressor.NewWriterLevel(writer, DefaultQuality)
+}
+
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: level})
+}
+
+func (compressor Compressor) LevelRange() (int, int) {
+	return MinQuality, MaxQuality
 }
 
 func (compressor Compressor) FileExtension() string {
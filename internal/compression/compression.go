@@ -2,6 +2,8 @@ package compression
 
 import (
 	"io"
+
+	"github.com/pkg/errors"
 )
 
 type Compressor interface {
@@ -9,6 +11,46 @@ type Compressor interface {
 	FileExtension() string
 }
 
+// LeveledCompressor is the Compressor which can compress with the different levels
+type LeveledCompressor interface {
+	Compressor
+	NewWriterLevel(writer io.Writer, level int) io.WriteCloser
+	// LevelRange returns the lowest and the highest levels
+	LevelRange() (min int, max int)
+}
+
+// StoringCompressor is the Compressor which can write the data as is in its own format,
+// so that the blocks which don't compress well are not compressed in vain
+type StoringCompressor interface {
+	Compressor
+	// StoreFrame writes the data as the single frame which is decompressed along with the compressed ones
+	StoreFrame(writer io.Writer, data []byte) error
+}
+
+var ErrLevelNotSupported = errors.New("the compression doesn't support levels")
+
+// WithLevel returns the compressor which compresses with the level
+func WithLevel(compressor Compressor, level int) (Compressor, error) {
+	leveled, ok := compressor.(LeveledCompressor)
+	if !ok {
+		return nil, ErrLevelNotSupported
+	}
+	min, max := leveled.LevelRange()
+	if level < min || level > max {
+		return nil, errors.Errorf("the compression level should be from %d to %d, got %d", min, max, level)
+	}
+	return levelCompressor{leveled, level}, nil
+}
+
+type levelCompressor struct {
+	LeveledCompressor
+	level int
+}
+
+func (compressor levelCompressor) NewWriter(writer io.Writer) io.WriteCloser {
+	return compressor.NewWriterLevel(writer, compressor.level)
+}
+
 type Decompressor interface {
 	Decompress(dst io.Writer, src io.Reader) error
 	FileExtension() string
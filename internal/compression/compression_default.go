@@ -8,11 +8,12 @@ import (
 	"github.com/wal-g/wal-g/internal/compression/zstd"
 )
 
-var CompressingAlgorithms = []string{lz4.AlgorithmName, lzma.AlgorithmName}
+var CompressingAlgorithms = []string{lz4.AlgorithmName, lzma.AlgorithmName, zstd.AlgorithmName}
 
 var Compressors = map[string]Compressor{
 	lz4.AlgorithmName:  lz4.Compressor{},
 	lzma.AlgorithmName: lzma.Compressor{},
+	zstd.AlgorithmName: zstd.Compressor{},
 }
 
 var Decompressors = []Decompressor{
@@ -20,3 +21,57 @@ var Decompressors = []Decompressor{
 	lzma.Decompressor{},
 	zstd.Decompressor{},
 }
+
+// multiFrameExtensions are the compressions whose decompressors read the concatenated frames as the single stream
+var multiFrameExtensions = map[string]bool{
+	lz4.FileExtension:  true,
+	zstd.FileExtension: true,
+}
+
+// WithLongWindow returns the compressor in the long mode if it has one, see zstd.Compressor
+func WithLongWindow(compressor Compressor) (Compressor, bool) {
+	zstdCompressor, ok := compressor.(zstd.Compressor)
+	if !ok {
+		return compressor, false
+	}
+	zstdCompressor.Long = true
+	return zstdCompressor, true
+}
+
+// WithoutLongWindow returns the compressor without the long mode, it tells whether the long mode was on
+func WithoutLongWindow(compressor Compressor) (Compressor, bool) {
+	switch typed := compressor.(type) {
+	case zstd.Compressor:
+		long := typed.Long
+		typed.Long = false
+		return typed, long
+	case levelCompressor:
+		leveled, long := WithoutLongWindow(typed.LeveledCompressor)
+		return levelCompressor{leveled.(LeveledCompressor), typed.level}, long
+	}
+	return compressor, false
+}
+
+// WithDictionary returns the compressor compressing with the dictionary if it can use one, see zstd.Compressor
+func WithDictionary(compressor Compressor, id uint32, content []byte) (Compressor, bool) {
+	switch typed := compressor.(type) {
+	case zstd.Compressor:
+		typed.Dictionary = &zstd.Dictionary{ID: id, Content: content}
+		return typed, true
+	case levelCompressor:
+		leveled, ok := WithDictionary(typed.LeveledCompressor, id, content)
+		if !ok {
+			return compressor, false
+		}
+		return levelCompressor{leveled.(LeveledCompressor), typed.level}, true
+	}
+	return compressor, false
+}
+
+// WithDictionaries returns the decompressor which loads the dictionaries the data was compressed with
+func WithDictionaries(decompressor Decompressor, loader DictionaryLoader) Decompressor {
+	if _, ok := decompressor.(zstd.Decompressor); !ok {
+		return decompressor
+	}
+	return zstd.Decompressor{Dictionaries: loader}
+}
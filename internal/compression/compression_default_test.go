@@ -0,0 +1,213 @@
+// +build !windows
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+)
+
+// makeRepeatedData makes the random part repeated after the gap of the compressible data
+func makeRepeatedData(partSize, gapSize int64) bytes.Buffer {
+	var part bytes.Buffer
+	io.Copy(&part, io.LimitReader(rand.New(rand.NewSource(0)), partSize))
+	var data bytes.Buffer
+	data.Write(part.Bytes())
+	io.Copy(&data, io.LimitReader(NewBiasedRandomReader(), gapSize))
+	data.Write(part.Bytes())
+	return data
+}
+
+func compressedSize(t *testing.T, compressor Compressor, data []byte) int {
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return compressed.Len()
+}
+
+func TestLongWindowCompression(t *testing.T) {
+	// the repeated part is further back than the usual zstd window
+	const PartSize = 4 << 20
+	testData := makeRepeatedData(PartSize, 8<<20)
+
+	compressor, ok := WithLongWindow(Compressors[zstd.AlgorithmName])
+	assert.True(t, ok)
+	testCompressor(compressor, testData, t)
+	longSize := compressedSize(t, compressor, testData.Bytes())
+	size := compressedSize(t, Compressors[zstd.AlgorithmName], testData.Bytes())
+	assert.Less(t, longSize, size-PartSize/2)
+
+	_, ok = WithLongWindow(Compressors[lz4.AlgorithmName])
+	assert.False(t, ok)
+}
+
+func TestLongWindowWithConcurrency(t *testing.T) {
+	compressor, ok := WithLongWindow(Compressors[zstd.AlgorithmName])
+	assert.True(t, ok)
+	compressor, err := WithLevel(compressor, 5)
+	assert.NoError(t, err)
+
+	parallel, ok := WithConcurrency(compressor, 4)
+	assert.True(t, ok)
+	// every block is compressed by the usual encoder, the long window isn't allocated for it
+	_, long := WithoutLongWindow(parallel.(parallelCompressor).Compressor)
+	assert.False(t, long)
+	_, long = WithoutLongWindow(compressor)
+	assert.True(t, long)
+
+	testCompressor(parallel, makeRepeatedData(1<<20, 6<<20), t)
+}
+
+// BenchmarkCompression compresses the WAL-like data and the data with the far repeats,
+// see benchmarks/compression for the results
+func BenchmarkCompression(b *testing.B) {
+	var walData bytes.Buffer
+	io.Copy(&walData, io.LimitReader(NewBiasedRandomReader(), 16<<20))
+	repeatedData := makeRepeatedData(8<<20, 32<<20)
+	datasets := []struct {
+		name string
+		data []byte
+	}{
+		{"wal", walData.Bytes()},
+		{"repeated", repeatedData.Bytes()},
+	}
+	compressors := []struct {
+		name  string
+		level int
+		long  bool
+	}{
+		{lz4.AlgorithmName, -1, false},
+		{lzma.AlgorithmName, -1, false},
+		{zstd.AlgorithmName, 1, false},
+		{zstd.AlgorithmName, 3, false},
+		{zstd.AlgorithmName, 9, false},
+		{zstd.AlgorithmName, 19, false},
+		{zstd.AlgorithmName, 3, true},
+		{zstd.AlgorithmName, 19, true},
+	}
+	for _, dataset := range datasets {
+		for _, benchmark := range compressors {
+			compressor := Compressors[benchmark.name]
+			name := dataset.name + "/" + benchmark.name
+			if benchmark.long {
+				compressor, _ = WithLongWindow(compressor)
+				name += "-long"
+			}
+			if benchmark.level >= 0 {
+				compressor, _ = WithLevel(compressor, benchmark.level)
+				name += fmt.Sprintf("-%d", benchmark.level)
+			}
+			data := dataset.data
+			b.Run(name, func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				var compressed countingWriter
+				for i := 0; i < b.N; i++ {
+					compressed = 0
+					writer := compressor.NewWriter(&compressed)
+					_, err := writer.Write(data)
+					assert.NoError(b, err)
+					assert.NoError(b, writer.Close())
+				}
+				b.ReportMetric(float64(len(data))/float64(compressed), "ratio")
+			})
+		}
+	}
+}
+
+type countingWriter int64
+
+func (writer *countingWriter) Write(p []byte) (int, error) {
+	*writer += countingWriter(len(p))
+	return len(p), nil
+}
+
+// BenchmarkParallelCompression compresses the WAL-like data by the different numbers of the workers
+func BenchmarkParallelCompression(b *testing.B) {
+	var data bytes.Buffer
+	io.Copy(&data, io.LimitReader(NewBiasedRandomReader(), 64<<20))
+	for _, algorithm := range []string{lz4.AlgorithmName, zstd.AlgorithmName} {
+		for _, concurrency := range []int{1, 2, 4, 8} {
+			compressor, _ := WithConcurrency(Compressors[algorithm], concurrency)
+			b.Run(fmt.Sprintf("%s-%d", algorithm, concurrency), func(b *testing.B) {
+				b.SetBytes(int64(data.Len()))
+				var compressed countingWriter
+				for i := 0; i < b.N; i++ {
+					compressed = 0
+					writer := compressor.NewWriter(&compressed)
+					_, err := writer.Write(data.Bytes())
+					assert.NoError(b, err)
+					assert.NoError(b, writer.Close())
+				}
+				b.ReportMetric(float64(data.Len())/float64(compressed), "ratio")
+			})
+		}
+	}
+}
+
+// makeRecords makes the data like WAL, the records of the few kinds with the different values
+func makeRecords(random *rand.Rand, size int) []byte {
+	var data bytes.Buffer
+	for data.Len() < size {
+		kind := random.Intn(64)
+		fmt.Fprintf(&data, "INSERT INTO orders_%d (id, customer, status, comment) VALUES (%d, 'customer-%d', 'status-%d', '%s');",
+			kind, random.Intn(1000), random.Intn(1000), kind, strings.Repeat(fmt.Sprintf("comment of the kind %d;", kind), 3))
+	}
+	return data.Bytes()[:size]
+}
+
+type mapDictionaryLoader map[uint32][]byte
+
+func (loader mapDictionaryLoader) LoadDictionary(id uint32) ([]byte, error) {
+	dictionary, ok := loader[id]
+	if !ok {
+		return nil, fmt.Errorf("no dictionary %d", id)
+	}
+	return dictionary, nil
+}
+
+func TestDictionaryCompression(t *testing.T) {
+	random := rand.New(rand.NewSource(0))
+	samples := make([][]byte, 0)
+	for i := 0; i < 100; i++ {
+		samples = append(samples, makeRecords(random, 64<<10))
+	}
+	dictionary := TrainDictionary(samples, 16<<10)
+	assert.Len(t, dictionary, 16<<10)
+
+	compressor, ok := WithDictionary(Compressors[zstd.AlgorithmName], 42, dictionary)
+	assert.True(t, ok)
+	leveledCompressor, _ := WithLevel(Compressors[zstd.AlgorithmName], 5)
+	_, ok = WithDictionary(leveledCompressor, 42, dictionary)
+	assert.True(t, ok)
+	_, ok = WithDictionary(Compressors[lz4.AlgorithmName], 42, dictionary)
+	assert.False(t, ok)
+
+	data := makeRecords(random, 4<<10)
+	assert.Less(t, compressedSize(t, compressor, data), compressedSize(t, Compressors[zstd.AlgorithmName], data)*9/10)
+
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	decompressor := WithDictionaries(zstd.Decompressor{}, mapDictionaryLoader{42: dictionary})
+	var decompressed bytes.Buffer
+	assert.NoError(t, decompressor.Decompress(&decompressed, bytes.NewReader(compressed.Bytes())))
+	assert.Equal(t, data, decompressed.Bytes())
+
+	// the dictionary is required to decompress
+	assert.Error(t, zstd.Decompressor{}.Decompress(&decompressed, bytes.NewReader(compressed.Bytes())))
+	assert.Error(t, WithDictionaries(zstd.Decompressor{}, mapDictionaryLoader{}).Decompress(&decompressed, &compressed))
+}
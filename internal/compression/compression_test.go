@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"math/rand"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -59,3 +60,153 @@ func TestBigDataCompression(t *testing.T) {
 		testCompressor(compressor, testData, t)
 	}
 }
+
+func TestCompressionLevels(t *testing.T) {
+	const DataSize = 1 << 20
+	var testData bytes.Buffer
+	io.Copy(&testData, io.LimitReader(NewBiasedRandomReader(), DataSize))
+	for _, compressingAlgorithm := range CompressingAlgorithms {
+		compressor := Compressors[compressingAlgorithm]
+		leveled, ok := compressor.(LeveledCompressor)
+		if !ok {
+			_, err := WithLevel(compressor, 1)
+			assert.Equal(t, ErrLevelNotSupported, err)
+			continue
+		}
+		min, max := leveled.LevelRange()
+		for _, level := range []int{min, max} {
+			levelCompressor, err := WithLevel(compressor, level)
+			assert.NoError(t, err)
+			testCompressor(levelCompressor, testData, t)
+		}
+		_, err := WithLevel(compressor, max+1)
+		assert.Error(t, err)
+	}
+}
+
+func TestIsCompressible(t *testing.T) {
+	incompressible := make([]byte, 4*SampleSize)
+	rand.Read(incompressible)
+	assert.False(t, IsCompressible(incompressible))
+	assert.True(t, IsCompressible(bytes.Repeat([]byte("wal-g "), SampleSize)))
+	assert.True(t, IsCompressible(nil))
+
+	compressible, err := IsReaderCompressible(bytes.NewReader(incompressible), int64(len(incompressible)))
+	assert.NoError(t, err)
+	assert.False(t, compressible)
+
+	// the compressible middle of the data isn't missed by the samples
+	mixed := append(append(incompressible[:2*SampleSize:2*SampleSize], make([]byte, 4*SampleSize)...), incompressible[2*SampleSize:]...)
+	compressible, err = IsReaderCompressible(bytes.NewReader(mixed), int64(len(mixed)))
+	assert.NoError(t, err)
+	assert.True(t, compressible)
+}
+
+func TestParallelCompression(t *testing.T) {
+	const DataSize = 1 << 20
+	var testData bytes.Buffer
+	io.Copy(&testData, io.LimitReader(NewBiasedRandomReader(), DataSize))
+	for _, compressingAlgorithm := range CompressingAlgorithms {
+		compressor := Compressors[compressingAlgorithm]
+		parallelCompressor, ok := WithConcurrency(compressor, 4)
+		if !ok {
+			assert.Equal(t, compressor, parallelCompressor)
+			continue
+		}
+		testCompressor(parallelCompressor, testData, t)
+
+		for _, dataSize := range []int{0, 1, 1000, 4096, DataSize} {
+			var compressed bytes.Buffer
+			writer := NewParallelWriter(compressor, &compressed, 3, 4096)
+			// write in the pieces not aligned with the blocks
+			for data := testData.Bytes()[:dataSize]; len(data) > 0; {
+				n := utility.Min(len(data), 1000)
+				_, err := writer.Write(data[:n])
+				assert.NoError(t, err)
+				data = data[n:]
+			}
+			assert.NoError(t, writer.Close())
+			var decompressed bytes.Buffer
+			assert.NoError(t, GetDecompressorByCompressor(compressor).Decompress(&decompressed, &compressed))
+			assert.True(t, bytes.Equal(testData.Bytes()[:dataSize], decompressed.Bytes()), compressingAlgorithm)
+		}
+	}
+	_, ok := WithConcurrency(Compressors[CompressingAlgorithms[0]], 1)
+	assert.False(t, ok)
+}
+
+// countingStoringCompressor counts the stored frames
+type countingStoringCompressor struct {
+	StoringCompressor
+	stored int32
+}
+
+func (compressor *countingStoringCompressor) StoreFrame(writer io.Writer, data []byte) error {
+	atomic.AddInt32(&compressor.stored, 1)
+	return compressor.StoringCompressor.StoreFrame(writer, data)
+}
+
+func TestAdaptiveBlocks(t *testing.T) {
+	const BlockSize = 3 * SampleSize
+	incompressible := make([]byte, BlockSize)
+	rand.New(rand.NewSource(0)).Read(incompressible)
+	var compressible bytes.Buffer
+	io.Copy(&compressible, io.LimitReader(NewBiasedRandomReader(), BlockSize))
+	data := append(append(append([]byte{}, incompressible...), compressible.Bytes()...), incompressible...)
+
+	for _, compressingAlgorithm := range CompressingAlgorithms {
+		compressor := Compressors[compressingAlgorithm]
+		adaptiveCompressor, ok := WithAdaptiveBlocks(compressor, 2)
+		storingCompressor, canStore := findStoringCompressor(compressor)
+		assert.Equal(t, canStore, ok, compressingAlgorithm)
+		if !ok {
+			assert.Equal(t, compressor, adaptiveCompressor)
+			continue
+		}
+		testCompressor(adaptiveCompressor, *bytes.NewBuffer(data), t)
+
+		for _, dataSize := range []int{0, 1, 1000, len(data)} {
+			counting := &countingStoringCompressor{StoringCompressor: storingCompressor}
+			var compressed bytes.Buffer
+			writer := newParallelWriter(compressor, &compressed, 2, BlockSize, counting)
+			_, err := writer.Write(data[:dataSize])
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Close())
+			var decompressed bytes.Buffer
+			assert.NoError(t, GetDecompressorByCompressor(compressor).Decompress(&decompressed, &compressed))
+			assert.True(t, bytes.Equal(data[:dataSize], decompressed.Bytes()), compressingAlgorithm)
+			if dataSize == len(data) {
+				// only the incompressible blocks are stored
+				assert.Equal(t, int32(2), counting.stored, compressingAlgorithm)
+				assert.Less(t, compressed.Len(), 2*BlockSize+BlockSize/2, compressingAlgorithm)
+			}
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (writer failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestParallelCompression_WriteError(t *testing.T) {
+	writer := NewParallelWriter(Compressors[CompressingAlgorithms[0]], failingWriter{}, 2, 16)
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		_, err = writer.Write(bytes.Repeat([]byte{byte(i)}, 100))
+	}
+	assert.Equal(t, io.ErrShortWrite, writer.Close())
+}
+
+func TestTrainDictionary(t *testing.T) {
+	assert.Equal(t, []byte("short sample"), TrainDictionary([][]byte{[]byte("short "), []byte("sample")}, 1024))
+
+	var data bytes.Buffer
+	io.Copy(&data, io.LimitReader(NewBiasedRandomReader(), 1<<20))
+	samples := [][]byte{data.Bytes()[:1<<19], data.Bytes()[1<<19:]}
+	dictionary := TrainDictionary(samples, 8<<10)
+	assert.True(t, len(dictionary) > 0 && len(dictionary) <= 8<<10)
+	// the dictionary is made of the segments of the samples
+	assert.True(t, bytes.Contains(data.Bytes(), dictionary[len(dictionary)-100:]))
+}
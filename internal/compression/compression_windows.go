@@ -18,3 +18,28 @@ var Decompressors = []Decompressor{
 	lz4.Decompressor{},
 	lzma.Decompressor{},
 }
+
+// multiFrameExtensions are the compressions whose decompressors read the concatenated frames as the single stream
+var multiFrameExtensions = map[string]bool{
+	lz4.FileExtension: true,
+}
+
+// WithLongWindow returns the compressor as is, none of the compressors has the long mode on Windows
+func WithLongWindow(compressor Compressor) (Compressor, bool) {
+	return compressor, false
+}
+
+// WithoutLongWindow returns the compressor as is, none of the compressors has the long mode on Windows
+func WithoutLongWindow(compressor Compressor) (Compressor, bool) {
+	return compressor, false
+}
+
+// WithDictionary returns the compressor as is, none of the compressors uses dictionaries on Windows
+func WithDictionary(compressor Compressor, id uint32, content []byte) (Compressor, bool) {
+	return compressor, false
+}
+
+// WithDictionaries returns the decompressor as is, none of the decompressors uses dictionaries on Windows
+func WithDictionaries(decompressor Decompressor, loader DictionaryLoader) Decompressor {
+	return decompressor
+}
@@ -0,0 +1,113 @@
+package compression
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// DictionaryLoader loads the dictionaries which the data was compressed with
+type DictionaryLoader interface {
+	LoadDictionary(id uint32) ([]byte, error)
+}
+
+const (
+	// DefaultDictionarySize is the dictionary size recommended for zstd
+	DefaultDictionarySize = 112 << 10
+
+	dictionarySegmentSize = 256
+	dictionaryDmerSize    = 8
+	dictionaryHashLog     = 20
+)
+
+type dictionarySegment struct {
+	begin int
+	end   int
+	score uint64
+}
+
+// TrainDictionary builds the raw content dictionary of the size from the samples of the data
+// which is going to be compressed with it. The samples are split into the epochs,
+// and the segment with the most frequent 8-byte strings, not yet present in the dictionary,
+// is taken from each epoch, as the COVER algorithm of zstd does.
+// The best segments are placed at the end of the dictionary, where they are the cheapest to refer to.
+func TrainDictionary(samples [][]byte, size int) []byte {
+	data := make([]byte, 0)
+	for _, sample := range samples {
+		data = append(data, sample...)
+	}
+	if len(data) <= size {
+		return data
+	}
+
+	frequencies := make([]uint32, 1<<dictionaryHashLog)
+	for i := 0; i+dictionaryDmerSize <= len(data); i++ {
+		frequencies[dmerHash(data[i:])]++
+	}
+
+	epochs := size / dictionarySegmentSize
+	if epochs == 0 {
+		epochs = 1
+	}
+	epochSize := len(data) / epochs
+	active := make([]uint16, 1<<dictionaryHashLog)
+	segments := make([]dictionarySegment, 0, epochs)
+	for epoch := 0; epoch < epochs; epoch++ {
+		segment := selectSegment(data[:(epoch+1)*epochSize], epoch*epochSize, frequencies, active)
+		if segment.score == 0 {
+			continue
+		}
+		// the strings of the segment are in the dictionary already
+		for i := segment.begin; i+dictionaryDmerSize <= segment.end; i++ {
+			frequencies[dmerHash(data[i:])] = 0
+		}
+		segments = append(segments, segment)
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].score < segments[j].score
+	})
+	dictionary := make([]byte, 0, size)
+	for _, segment := range segments {
+		dictionary = append(dictionary, data[segment.begin:segment.end]...)
+	}
+	if len(dictionary) > size {
+		dictionary = dictionary[len(dictionary)-size:]
+	}
+	return dictionary
+}
+
+// selectSegment finds the segment of the data starting from the begin with the highest score,
+// the score is the sum of the frequencies of its distinct dmers
+func selectSegment(data []byte, begin int, frequencies []uint32, active []uint16) dictionarySegment {
+	const windowDmers = dictionarySegmentSize - dictionaryDmerSize + 1
+	best := dictionarySegment{}
+	score := uint64(0)
+	windowBegin := begin
+	for i := begin; i+dictionaryDmerSize <= len(data); i++ {
+		hash := dmerHash(data[i:])
+		if active[hash] == 0 {
+			score += uint64(frequencies[hash])
+		}
+		active[hash]++
+		if i-windowBegin >= windowDmers {
+			oldHash := dmerHash(data[windowBegin:])
+			active[oldHash]--
+			if active[oldHash] == 0 {
+				score -= uint64(frequencies[oldHash])
+			}
+			windowBegin++
+		}
+		if score > best.score {
+			best = dictionarySegment{begin: windowBegin, end: i + dictionaryDmerSize, score: score}
+		}
+	}
+	for i := windowBegin; i+dictionaryDmerSize <= len(data); i++ {
+		active[dmerHash(data[i:])] = 0
+	}
+	return best
+}
+
+func dmerHash(data []byte) uint32 {
+	const prime = 0x9E3779B185EBCA87
+	return uint32((binary.LittleEndian.Uint64(data) * prime) >> (64 - dictionaryHashLog))
+}
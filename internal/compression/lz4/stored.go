@@ -0,0 +1,36 @@
+package lz4
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	maxStoredBlockSize = 4 << 20
+	// uncompressedBlockFlag marks the block size of the block stored as is
+	uncompressedBlockFlag = 1 << 31
+)
+
+// storedFrameHeader is the header of the frame with the independent blocks of 4 MB at most and without the checksums
+var storedFrameHeader = []byte{0x04, 0x22, 0x4d, 0x18, 0x60, 0x70, 0x73}
+
+// StoreFrame writes the data as the frame of the uncompressed blocks
+func (compressor Compressor) StoreFrame(writer io.Writer, data []byte) error {
+	frame := make([]byte, 0, len(storedFrameHeader)+len(data)+4*(len(data)/maxStoredBlockSize+2))
+	frame = append(frame, storedFrameHeader...)
+	sizeBuf := make([]byte, 4)
+	for len(data) > 0 {
+		size := len(data)
+		if size > maxStoredBlockSize {
+			size = maxStoredBlockSize
+		}
+		binary.LittleEndian.PutUint32(sizeBuf, uint32(size)|uncompressedBlockFlag)
+		frame = append(frame, sizeBuf...)
+		frame = append(frame, data[:size]...)
+		data = data[size:]
+	}
+	// the end mark
+	frame = append(frame, 0, 0, 0, 0)
+	_, err := writer.Write(frame)
+	return err
+}
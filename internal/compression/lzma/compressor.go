@@ -9,8 +9,16 @@ import (
 const (
 	AlgorithmName = "lzma"
 	FileExtension = "lzma"
+
+	MinLevel = 0
+	MaxLevel = 9
 )
 
+// dictCaps are the dictionary capacities of the xz presets, the level is the index
+var dictCaps = [MaxLevel + 1]int{
+	256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20,
+}
+
 type Compressor struct{}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
@@ -21,6 +29,19 @@ func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
 	return lzmaWriter
 }
 
+// NewWriterLevel creates the writer with the dictionary of the xz preset of the level
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	lzmaWriter, err := lzma.WriterConfig{DictCap: dictCaps[level]}.NewWriter(writer)
+	if err != nil {
+		panic(err)
+	}
+	return lzmaWriter
+}
+
+func (compressor Compressor) LevelRange() (int, int) {
+	return MinLevel, MaxLevel
+}
+
 func (compressor Compressor) FileExtension() string {
 	return FileExtension
 }
@@ -0,0 +1,205 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// ParallelBlockSize is the size of the blocks which are compressed independently by the parallel writer
+const ParallelBlockSize = 4 << 20
+
+// WithConcurrency returns the compressor which compresses the blocks of the stream by the concurrency workers,
+// like pigz or pzstd do. Every block is the separate frame, so it is only possible for the compressions
+// whose decompressors read the concatenated frames as the single stream, see multiFrameExtensions.
+// The long mode is turned off: its window is much larger than the blocks, while every block would allocate it.
+func WithConcurrency(compressor Compressor, concurrency int) (Compressor, bool) {
+	if concurrency <= 1 || !multiFrameExtensions[compressor.FileExtension()] {
+		return compressor, false
+	}
+	compressor, _ = WithoutLongWindow(compressor)
+	return parallelCompressor{compressor, concurrency, nil}, true
+}
+
+// WithAdaptiveBlocks returns the compressor which compresses the blocks of the stream like WithConcurrency does,
+// but stores the blocks which don't compress well as is, see IsCompressible. It is only possible for the compressions
+// which can store the data in their format, see StoringCompressor. The single worker is used if concurrency is below 1.
+func WithAdaptiveBlocks(compressor Compressor, concurrency int) (Compressor, bool) {
+	if _, ok := findStoringCompressor(compressor); !ok || !multiFrameExtensions[compressor.FileExtension()] {
+		return compressor, false
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	compressor, _ = WithoutLongWindow(compressor)
+	storingCompressor, _ := findStoringCompressor(compressor)
+	return parallelCompressor{compressor, concurrency, storingCompressor}, true
+}
+
+func findStoringCompressor(compressor Compressor) (StoringCompressor, bool) {
+	switch typed := compressor.(type) {
+	case StoringCompressor:
+		return typed, true
+	case levelCompressor:
+		return findStoringCompressor(typed.LeveledCompressor)
+	}
+	return nil, false
+}
+
+type parallelCompressor struct {
+	Compressor
+	concurrency int
+	// storingCompressor stores the incompressible blocks, nil if all the blocks are compressed
+	storingCompressor StoringCompressor
+}
+
+func (compressor parallelCompressor) NewWriter(writer io.Writer) io.WriteCloser {
+	return newParallelWriter(compressor.Compressor, writer, compressor.concurrency, ParallelBlockSize,
+		compressor.storingCompressor)
+}
+
+type parallelBlock struct {
+	data       []byte
+	compressed bytes.Buffer
+	err        error
+	done       chan struct{}
+}
+
+// ParallelWriter splits the stream to the blocks, compresses them by the workers
+// and writes the compressed blocks in the original order
+type ParallelWriter struct {
+	compressor        Compressor
+	storingCompressor StoringCompressor
+	blockSize         int
+	block             []byte
+	written           bool
+
+	tasks      chan *parallelBlock
+	ordered    chan *parallelBlock
+	workers    sync.WaitGroup
+	writerDone chan struct{}
+
+	errMutex sync.Mutex
+	err      error
+}
+
+// NewParallelWriter starts the concurrency workers compressing the blocks of the blockSize to the writer
+func NewParallelWriter(compressor Compressor, writer io.Writer, concurrency int, blockSize int) *ParallelWriter {
+	return newParallelWriter(compressor, writer, concurrency, blockSize, nil)
+}
+
+// newParallelWriter also stores the blocks which don't compress well by the storingCompressor, unless it is nil
+func newParallelWriter(compressor Compressor, writer io.Writer, concurrency int, blockSize int,
+	storingCompressor StoringCompressor) *ParallelWriter {
+	parallelWriter := &ParallelWriter{
+		compressor:        compressor,
+		storingCompressor: storingCompressor,
+		blockSize:         blockSize,
+		tasks:             make(chan *parallelBlock, concurrency),
+		// at most concurrency blocks are being compressed and as much are waiting to be written
+		ordered:    make(chan *parallelBlock, concurrency),
+		writerDone: make(chan struct{}),
+	}
+	for i := 0; i < concurrency; i++ {
+		parallelWriter.workers.Add(1)
+		go parallelWriter.compressBlocks()
+	}
+	go parallelWriter.writeBlocks(writer)
+	return parallelWriter
+}
+
+func (writer *ParallelWriter) Write(p []byte) (int, error) {
+	if err := writer.getErr(); err != nil {
+		return 0, err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		if writer.block == nil {
+			writer.block = make([]byte, 0, writer.blockSize)
+		}
+		size := writer.blockSize - len(writer.block)
+		if size > len(p) {
+			size = len(p)
+		}
+		writer.block = append(writer.block, p[:size]...)
+		p = p[size:]
+		if len(writer.block) == writer.blockSize {
+			writer.sendBlock()
+		}
+	}
+	return n, nil
+}
+
+// Close compresses the rest of the data and waits for all the blocks to be written
+func (writer *ParallelWriter) Close() error {
+	if len(writer.block) > 0 || !writer.written {
+		// the empty stream is still the single empty frame, as with the regular writer
+		writer.sendBlock()
+	}
+	close(writer.tasks)
+	close(writer.ordered)
+	writer.workers.Wait()
+	<-writer.writerDone
+	return writer.getErr()
+}
+
+func (writer *ParallelWriter) sendBlock() {
+	block := &parallelBlock{data: writer.block, done: make(chan struct{})}
+	writer.block = nil
+	writer.written = true
+	writer.ordered <- block
+	writer.tasks <- block
+}
+
+func (writer *ParallelWriter) compressBlocks() {
+	defer writer.workers.Done()
+	for block := range writer.tasks {
+		if writer.storingCompressor != nil && !isBlockCompressible(block.data) {
+			block.err = writer.storingCompressor.StoreFrame(&block.compressed, block.data)
+		} else {
+			compressingWriter := writer.compressor.NewWriter(&block.compressed)
+			_, block.err = compressingWriter.Write(block.data)
+			if err := compressingWriter.Close(); block.err == nil {
+				block.err = err
+			}
+		}
+		block.data = nil
+		close(block.done)
+	}
+}
+
+func isBlockCompressible(data []byte) bool {
+	// the bytes.Reader never fails to read what it has
+	compressible, _ := IsReaderCompressible(bytes.NewReader(data), int64(len(data)))
+	return compressible
+}
+
+func (writer *ParallelWriter) writeBlocks(dst io.Writer) {
+	defer close(writer.writerDone)
+	for block := range writer.ordered {
+		<-block.done
+		if writer.getErr() != nil {
+			// keep receiving the blocks, so that Write and Close aren't blocked
+			continue
+		}
+		err := block.err
+		if err == nil {
+			_, err = block.compressed.WriteTo(dst)
+		}
+		if err != nil {
+			writer.setErr(err)
+		}
+	}
+}
+
+func (writer *ParallelWriter) getErr() error {
+	writer.errMutex.Lock()
+	defer writer.errMutex.Unlock()
+	return writer.err
+}
+
+func (writer *ParallelWriter) setErr(err error) {
+	writer.errMutex.Lock()
+	defer writer.errMutex.Unlock()
+	writer.err = err
+}
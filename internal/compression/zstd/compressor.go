@@ -9,12 +9,36 @@ import (
 const (
 	AlgorithmName = "zstd"
 	FileExtension = "zst"
+
+	DefaultLevel = 3
+	MinLevel     = 1
+	MaxLevel     = 22
 )
 
-type Compressor struct{}
+// Compressor is zstd Compressor. The long mode finds the matches as far as LongWindowSize back,
+// which helps with the big tars of the base backups, but every compressing stream keeps the window in memory.
+// With the Dictionary the data is compressed with it, and the long mode is not used.
+type Compressor struct {
+	Long       bool
+	Dictionary *Dictionary
+}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
-	return zstd.NewWriterLevel(writer, 3)
+	return compressor.NewWriterLevel(writer, DefaultLevel)
+}
+
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	if compressor.Dictionary != nil {
+		return newDictionaryWriter(writer, level, compressor.Dictionary)
+	}
+	if compressor.Long {
+		return newLongWriter(writer, level)
+	}
+	return zstd.NewWriterLevel(writer, level)
+}
+
+func (compressor Compressor) LevelRange() (int, int) {
+	return MinLevel, MaxLevel
 }
 
 func (compressor Compressor) FileExtension() string {
@@ -3,17 +3,23 @@ package zstd
 import (
 	"io"
 
-	"github.com/DataDog/zstd"
 	"github.com/pkg/errors"
 	"github.com/wal-g/wal-g/internal/compression/computils"
 	"github.com/wal-g/wal-g/utility"
 )
 
-type Decompressor struct{}
+// Decompressor is zstd Decompressor. The data compressed with a dictionary is decompressed
+// with the dictionary of its ID loaded by Dictionaries.
+type Decompressor struct {
+	Dictionaries DictionaryLoader
+}
 
 func (decompressor Decompressor) Decompress(dst io.Writer, src io.Reader) error {
-	zstdReader := zstd.NewReader(computils.NewUntilEOFReader(src))
-	_, err := utility.FastCopy(dst, zstdReader)
+	zstdReader, err := newDictionaryReader(computils.NewUntilEOFReader(src), decompressor.Dictionaries)
+	if err != nil {
+		return errors.Wrap(err, "DecompressZstd: failed to start decompression")
+	}
+	_, err = utility.FastCopy(dst, zstdReader)
 	if err != nil {
 		return errors.Wrap(err, "DecompressZstd: zstd write failed")
 	}
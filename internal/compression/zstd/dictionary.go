@@ -0,0 +1,108 @@
+package zstd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/DataDog/zstd"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DictionaryFrameMagic starts the skippable frame which precedes the frames compressed with a dictionary,
+	// the frame content is the dictionary ID. The decoders unaware of it skip the frame.
+	DictionaryFrameMagic = 0x184D2A5E
+	dictionaryFrameSize  = 12
+)
+
+// Dictionary is the raw content dictionary, its ID is stored with the compressed data
+type Dictionary struct {
+	ID      uint32
+	Content []byte
+}
+
+// DictionaryLoader loads the dictionaries by their IDs for the Decompressor
+type DictionaryLoader interface {
+	LoadDictionary(id uint32) ([]byte, error)
+}
+
+func dictionaryFrame(id uint32) []byte {
+	frame := make([]byte, dictionaryFrameSize)
+	binary.LittleEndian.PutUint32(frame, DictionaryFrameMagic)
+	binary.LittleEndian.PutUint32(frame[4:], 4)
+	binary.LittleEndian.PutUint32(frame[8:], id)
+	return frame
+}
+
+// parseDictionaryFrame returns the dictionary ID if the data starts with the dictionary frame
+func parseDictionaryFrame(data []byte) (uint32, bool) {
+	if len(data) < dictionaryFrameSize ||
+		binary.LittleEndian.Uint32(data) != DictionaryFrameMagic ||
+		binary.LittleEndian.Uint32(data[4:]) != 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(data[8:]), true
+}
+
+// dictionaryWriter writes the dictionary frame before the compressed data. The frame is written
+// on the first write, since the writer may be created before anyone reads what it writes.
+type dictionaryWriter struct {
+	writer       io.Writer
+	dictionary   *Dictionary
+	zstdWriter   io.WriteCloser
+	frameWritten bool
+}
+
+func newDictionaryWriter(writer io.Writer, level int, dictionary *Dictionary) io.WriteCloser {
+	return &dictionaryWriter{
+		writer:     writer,
+		dictionary: dictionary,
+		zstdWriter: zstd.NewWriterLevelDict(writer, level, dictionary.Content),
+	}
+}
+
+func (writer *dictionaryWriter) writeFrame() error {
+	if writer.frameWritten {
+		return nil
+	}
+	writer.frameWritten = true
+	_, err := writer.writer.Write(dictionaryFrame(writer.dictionary.ID))
+	return err
+}
+
+func (writer *dictionaryWriter) Write(p []byte) (int, error) {
+	if err := writer.writeFrame(); err != nil {
+		return 0, err
+	}
+	return writer.zstdWriter.Write(p)
+}
+
+func (writer *dictionaryWriter) Close() error {
+	if err := writer.writeFrame(); err != nil {
+		return err
+	}
+	return writer.zstdWriter.Close()
+}
+
+// newDictionaryReader reads the data compressed with the dictionary if it starts with the dictionary frame
+func newDictionaryReader(src io.Reader, loader DictionaryLoader) (io.ReadCloser, error) {
+	bufferedSrc := bufio.NewReader(src)
+	header, _ := bufferedSrc.Peek(dictionaryFrameSize)
+	id, ok := parseDictionaryFrame(header)
+	if !ok {
+		return zstd.NewReader(bufferedSrc), nil
+	}
+	if loader == nil {
+		return nil, errors.Errorf("the data is compressed with the zstd dictionary %08x, but no dictionaries are available", id)
+	}
+	dictionary, err := loader.LoadDictionary(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the zstd dictionary %08x", id)
+	}
+	_, err = bufferedSrc.Discard(dictionaryFrameSize)
+	if err != nil {
+		return nil, err
+	}
+	return zstd.NewReaderDict(bufferedSrc, dictionary), nil
+}
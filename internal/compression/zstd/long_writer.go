@@ -0,0 +1,24 @@
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// LongWindowSize is the window of the long mode, the same as `zstd --long` uses by default,
+// so the zstd decoders accept it without the extra options
+const LongWindowSize = 1 << 27
+
+// newLongWriter compresses with the long window. The cgo zstd binding can't set the window parameters,
+// so the pure Go encoder is used, its frames are decompressed by the usual Decompressor.
+func newLongWriter(writer io.Writer, level int) io.WriteCloser {
+	encoder, err := zstd.NewWriter(writer,
+		zstd.WithWindowSize(LongWindowSize),
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+		zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		panic(err)
+	}
+	return encoder
+}
@@ -0,0 +1,42 @@
+package zstd
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	frameMagic = 0xFD2FB528
+	// storedFrameDescriptor describes the single segment frame with the 8 bytes content size,
+	// such frames have no window descriptor
+	storedFrameDescriptor = 3<<6 | 1<<5
+	maxRawBlockSize       = 128 << 10
+	rawBlockHeaderSize    = 3
+)
+
+// StoreFrame writes the data as the frame of the raw blocks, the decoders copy such blocks as is
+func (compressor Compressor) StoreFrame(writer io.Writer, data []byte) error {
+	frame := make([]byte, 13, 13+len(data)+rawBlockHeaderSize*(len(data)/maxRawBlockSize+1))
+	binary.LittleEndian.PutUint32(frame, frameMagic)
+	frame[4] = storedFrameDescriptor
+	binary.LittleEndian.PutUint64(frame[5:], uint64(len(data)))
+	for {
+		size := len(data)
+		if size > maxRawBlockSize {
+			size = maxRawBlockSize
+		}
+		// the block header is the last block flag, the raw block type (0) and the block size
+		header := uint32(size) << 3
+		if size == len(data) {
+			header |= 1
+		}
+		frame = append(frame, byte(header), byte(header>>8), byte(header>>16))
+		frame = append(frame, data[:size]...)
+		data = data[size:]
+		if len(data) == 0 {
+			break
+		}
+	}
+	_, err := writer.Write(frame)
+	return err
+}
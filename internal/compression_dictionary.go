@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/fsutil"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// DictionaryFolderName is the folder of the compression dictionaries,
+	// it is placed in the folder of the data compressed with them
+	DictionaryFolderName  = "zstd_dictionaries"
+	CurrentDictionaryName = "current"
+	DictionarySuffix      = ".dict"
+)
+
+var ErrNoDictionary = errors.New("no compression dictionary is trained")
+
+// the dictionaries are identified by their content, so they never change and may be cached by ID
+var dictionaryCache = struct {
+	sync.Mutex
+	dictionaries map[string][]byte
+}{dictionaries: make(map[string][]byte)}
+
+// DictionaryID identifies the dictionary by its content
+func DictionaryID(content []byte) uint32 {
+	checksum := sha256.Sum256(content)
+	return binary.BigEndian.Uint32(checksum[:4])
+}
+
+func dictionaryName(id uint32) string {
+	return fmt.Sprintf("%08x%s", id, DictionarySuffix)
+}
+
+// UploadDictionary stores the dictionary in the folder of the data which is going to be compressed with it,
+// encrypted as the data is, and makes it the current one
+func UploadDictionary(folder storage.Folder, content []byte) (uint32, error) {
+	id := DictionaryID(content)
+	dictionaryFolder := folder.GetSubFolder(DictionaryFolderName)
+	err := dictionaryFolder.PutObject(dictionaryName(id), CompressAndEncrypt(bytes.NewReader(content), nil, ConfigureCrypter()))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to upload the dictionary")
+	}
+	err = dictionaryFolder.PutObject(CurrentDictionaryName, strings.NewReader(fmt.Sprintf("%08x", id)))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to make the dictionary current")
+	}
+	return id, nil
+}
+
+// GetCurrentDictionary returns the last dictionary uploaded to the folder, or ErrNoDictionary
+func GetCurrentDictionary(folder storage.Folder) (uint32, []byte, error) {
+	id, err := readCurrentDictionaryID(folder)
+	if err != nil {
+		return 0, nil, err
+	}
+	content, err := NewStorageDictionaryLoader(folder).LoadDictionary(id)
+	return id, content, err
+}
+
+func readCurrentDictionaryID(folder storage.Folder) (uint32, error) {
+	reader, err := folder.GetSubFolder(DictionaryFolderName).ReadObject(CurrentDictionaryName)
+	if _, ok := errors.Cause(err).(storage.ObjectNotFoundError); ok {
+		return 0, ErrNoDictionary
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read the current dictionary")
+	}
+	defer reader.Close()
+	idText, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read the current dictionary")
+	}
+	return parseDictionaryID(string(idText))
+}
+
+func parseDictionaryID(idText string) (uint32, error) {
+	id, err := strconv.ParseUint(strings.TrimSpace(idText), 16, 32)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse the current dictionary ID")
+	}
+	return uint32(id), nil
+}
+
+// StorageDictionaryLoader loads the dictionaries stored by UploadDictionary
+type StorageDictionaryLoader struct {
+	folder storage.Folder
+}
+
+// NewStorageDictionaryLoader creates the loader of the dictionaries of the data in the folder
+func NewStorageDictionaryLoader(folder storage.Folder) *StorageDictionaryLoader {
+	return &StorageDictionaryLoader{folder: folder.GetSubFolder(DictionaryFolderName)}
+}
+
+func (loader *StorageDictionaryLoader) LoadDictionary(id uint32) ([]byte, error) {
+	name := dictionaryName(id)
+	cacheKey := loader.folder.GetPath() + name
+	dictionaryCache.Lock()
+	content, ok := dictionaryCache.dictionaries[cacheKey]
+	dictionaryCache.Unlock()
+	if ok {
+		return content, nil
+	}
+
+	reader, err := loader.folder.ReadObject(name)
+	if err != nil {
+		return nil, err
+	}
+	decryptedReader, err := DecryptBytes(reader)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	defer decryptedReader.Close()
+	content, err = ioutil.ReadAll(decryptedReader)
+	if err != nil {
+		return nil, err
+	}
+	if DictionaryID(content) != id {
+		return nil, errors.Errorf("the content of the dictionary %s doesn't match its ID", name)
+	}
+
+	dictionaryCache.Lock()
+	dictionaryCache.dictionaries[cacheKey] = content
+	dictionaryCache.Unlock()
+	return content, nil
+}
+
+// noCurrentDictionary is cached when no dictionary is trained yet
+const noCurrentDictionary = "none"
+
+// DictionaryFileCache keeps the dictionaries of the storage folder and the ID of the current one in the local folder,
+// so that the short-lived processes like wal-push don't read them from the storage every time.
+// The dictionaries never change, they are verified by their IDs when read from the cache,
+// and the current dictionary ID is read from the storage again once the check interval passes.
+type DictionaryFileCache struct {
+	folder        storage.Folder
+	cacheFolder   fsutil.DataFolder
+	checkInterval time.Duration
+}
+
+func NewDictionaryFileCache(folder storage.Folder, cacheFolder fsutil.DataFolder,
+	checkInterval time.Duration) *DictionaryFileCache {
+	return &DictionaryFileCache{folder, cacheFolder, checkInterval}
+}
+
+// GetCurrentDictionary is the cached version of GetCurrentDictionary
+func (cache *DictionaryFileCache) GetCurrentDictionary() (uint32, []byte, error) {
+	idText, ok := cache.readCurrent()
+	if !ok {
+		id, err := readCurrentDictionaryID(cache.folder)
+		switch {
+		case err == ErrNoDictionary:
+			idText = noCurrentDictionary
+		case err != nil:
+			return 0, nil, err
+		default:
+			idText = fmt.Sprintf("%08x", id)
+		}
+		cache.writeFile(CurrentDictionaryName, []byte(fmt.Sprintf("%s %d", idText, utility.TimeNowCrossPlatformUTC().Unix())))
+	}
+	if idText == noCurrentDictionary {
+		return 0, nil, ErrNoDictionary
+	}
+	id, err := parseDictionaryID(idText)
+	if err != nil {
+		return 0, nil, err
+	}
+	content, err := cache.LoadDictionary(id)
+	return id, content, err
+}
+
+// readCurrent returns the cached current dictionary ID if it is checked within the check interval
+func (cache *DictionaryFileCache) readCurrent() (string, bool) {
+	content, err := cache.readFile(CurrentDictionaryName)
+	if err != nil {
+		return "", false
+	}
+	var idText string
+	var checkedAt int64
+	if _, err = fmt.Sscanf(string(content), "%s %d", &idText, &checkedAt); err != nil {
+		return "", false
+	}
+	if utility.TimeNowCrossPlatformUTC().Sub(time.Unix(checkedAt, 0)) >= cache.checkInterval {
+		return "", false
+	}
+	return idText, true
+}
+
+func (cache *DictionaryFileCache) LoadDictionary(id uint32) ([]byte, error) {
+	name := dictionaryName(id)
+	content, err := cache.readFile(name)
+	if err == nil && DictionaryID(content) == id {
+		return content, nil
+	}
+	content, err = NewStorageDictionaryLoader(cache.folder).LoadDictionary(id)
+	if err != nil {
+		return nil, err
+	}
+	cache.writeFile(name, content)
+	return content, nil
+}
+
+func (cache *DictionaryFileCache) readFile(name string) ([]byte, error) {
+	file, err := cache.cacheFolder.OpenReadonlyFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+// writeFile replaces the cached file at once, so the concurrent processes never read it half-written.
+// The cache is only an optimization, so the failure to write it is only reported.
+func (cache *DictionaryFileCache) writeFile(name string, content []byte) {
+	tmpName := fmt.Sprintf("%s.%d.tmp", name, os.Getpid())
+	file, err := cache.cacheFolder.OpenWriteOnlyFile(tmpName)
+	if err == nil {
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		err = cache.cacheFolder.RenameFile(tmpName, name)
+	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to cache the dictionary file %s: %v\n", name, err)
+		_ = cache.cacheFolder.DeleteFile(tmpName)
+	}
+}
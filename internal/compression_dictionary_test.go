@@ -0,0 +1,97 @@
+package internal_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/fsutil"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestCurrentDictionary(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	_, _, err := internal.GetCurrentDictionary(folder)
+	assert.Equal(t, internal.ErrNoDictionary, err)
+
+	_, err = internal.UploadDictionary(folder, []byte("the first dictionary"))
+	require.NoError(t, err)
+	id, err := internal.UploadDictionary(folder, []byte("the second dictionary"))
+	require.NoError(t, err)
+
+	currentID, content, err := internal.GetCurrentDictionary(folder)
+	require.NoError(t, err)
+	assert.Equal(t, id, currentID)
+	assert.Equal(t, []byte("the second dictionary"), content)
+
+	content, err = internal.NewStorageDictionaryLoader(folder).LoadDictionary(internal.DictionaryID([]byte("the first dictionary")))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("the first dictionary"), content)
+}
+
+func TestDownloadWithDictionary(t *testing.T) {
+	zstdCompressor, ok := compression.Compressors["zstd"]
+	if !ok {
+		t.Skip("zstd is not available")
+	}
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	dictionary := bytes.Repeat([]byte("the content of the WAL segments "), 100)
+	id, err := internal.UploadDictionary(folder, dictionary)
+	require.NoError(t, err)
+
+	compressor, ok := compression.WithDictionary(zstdCompressor, id, dictionary)
+	require.True(t, ok)
+	data := []byte("the content of the WAL segment 000000010000000000000001")
+	require.NoError(t, folder.PutObject("000000010000000000000001.zst", internal.CompressAndEncrypt(bytes.NewReader(data), compressor, nil)))
+
+	reader, err := internal.DownloadAndDecompressStorageFile(folder, "000000010000000000000001")
+	require.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestDictionaryFileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dictionaries")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cacheFolder, err := fsutil.NewDiskDataFolder(dir)
+	require.NoError(t, err)
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+
+	_, _, err = internal.NewDictionaryFileCache(folder, cacheFolder, time.Hour).GetCurrentDictionary()
+	assert.Equal(t, internal.ErrNoDictionary, err)
+	firstID, err := internal.UploadDictionary(folder, []byte("the first dictionary"))
+	require.NoError(t, err)
+	// the absence of the dictionary is cached too
+	_, _, err = internal.NewDictionaryFileCache(folder, cacheFolder, time.Hour).GetCurrentDictionary()
+	assert.Equal(t, internal.ErrNoDictionary, err)
+
+	id, content, err := internal.NewDictionaryFileCache(folder, cacheFolder, 0).GetCurrentDictionary()
+	require.NoError(t, err)
+	assert.Equal(t, firstID, id)
+	assert.Equal(t, []byte("the first dictionary"), content)
+
+	// the current dictionary is not checked within the interval, and the cached dictionaries are not read again
+	_, err = internal.UploadDictionary(folder, []byte("the second dictionary"))
+	require.NoError(t, err)
+	firstName := fmt.Sprintf("%08x%s", firstID, internal.DictionarySuffix)
+	assert.True(t, cacheFolder.FileExists(firstName))
+	require.NoError(t, folder.GetSubFolder(internal.DictionaryFolderName).DeleteObjects([]string{firstName}))
+	id, content, err = internal.NewDictionaryFileCache(folder, cacheFolder, time.Hour).GetCurrentDictionary()
+	require.NoError(t, err)
+	assert.Equal(t, firstID, id)
+	assert.Equal(t, []byte("the first dictionary"), content)
+
+	id, content, err = internal.NewDictionaryFileCache(folder, cacheFolder, 0).GetCurrentDictionary()
+	require.NoError(t, err)
+	assert.Equal(t, internal.DictionaryID([]byte("the second dictionary")), id)
+	assert.Equal(t, []byte("the second dictionary"), content)
+}
@@ -29,13 +29,31 @@ const (
 	UploadConcurrencySetting     = "WALG_UPLOAD_CONCURRENCY"
 	UploadDiskConcurrencySetting = "WALG_UPLOAD_DISK_CONCURRENCY"
 	UploadQueueSetting           = "WALG_UPLOAD_QUEUE"
+	UploadStateDirSetting        = "WALG_UPLOAD_STATE_DIR"
 	SentinelUserDataSetting      = "WALG_SENTINEL_USER_DATA"
 	PreventWalOverwriteSetting   = "WALG_PREVENT_WAL_OVERWRITE"
 	UploadWalMetadata            = "WALG_UPLOAD_WAL_METADATA"
 	DeltaMaxStepsSetting         = "WALG_DELTA_MAX_STEPS"
 	DeltaOriginSetting           = "WALG_DELTA_ORIGIN"
 	CompressionMethodSetting     = "WALG_COMPRESSION_METHOD"
+	CompressionLevelSetting      = "WALG_COMPRESSION_LEVEL"
+	ZstdLongSetting              = "WALG_ZSTD_LONG"
+	AdaptiveCompressionSetting   = "WALG_ADAPTIVE_COMPRESSION"
+	CompressConcurrencySetting   = "WALG_COMPRESSION_CONCURRENCY"
 	StoragePrefixSetting         = "WALG_STORAGE_PREFIX"
+	StorageTimeoutSetting        = "WALG_STORAGE_OPERATION_TIMEOUT"
+	StorageRetriesSetting        = "WALG_STORAGE_RETRIES"
+	StorageMinBackoffSetting     = "WALG_STORAGE_RETRY_MIN_BACKOFF"
+	StorageMaxBackoffSetting     = "WALG_STORAGE_RETRY_MAX_BACKOFF"
+	StorageCacheDirSetting       = "WALG_STORAGE_CACHE_DIR"
+	StorageCacheSizeSetting      = "WALG_STORAGE_CACHE_SIZE"
+	StorageListRateSetting       = "WALG_STORAGE_LIST_RATE_LIMIT"
+	StorageReadRateSetting       = "WALG_STORAGE_READ_RATE_LIMIT"
+	StorageWriteRateSetting      = "WALG_STORAGE_WRITE_RATE_LIMIT"
+	StorageDeleteRateSetting     = "WALG_STORAGE_DELETE_RATE_LIMIT"
+	ObjectChecksumsSetting       = "WALG_OBJECT_CHECKSUMS"
+	MirrorStoragesSetting        = "WALG_MIRROR_STORAGES"
+	MirrorWritePolicySetting     = "WALG_MIRROR_WRITE_POLICY"
 	DiskRateLimitSetting         = "WALG_DISK_RATE_LIMIT"
 	NetworkRateLimitSetting      = "WALG_NETWORK_RATE_LIMIT"
 	UseWalDeltaSetting           = "WALG_USE_WAL_DELTA"
@@ -58,6 +76,21 @@ const (
 	PgpKeySetting                = "WALG_PGP_KEY"
 	PgpKeyPathSetting            = "WALG_PGP_KEY_PATH"
 	PgpKeyPassphraseSetting      = "WALG_PGP_KEY_PASSPHRASE"
+	EnvelopeMasterKeyPathSetting = "WALG_ENVELOPE_MASTER_KEY_PATH"
+	EnvelopeMasterKeyCmdSetting  = "WALG_ENVELOPE_MASTER_KEY_CMD"
+	AgeRecipientsSetting         = "WALG_AGE_RECIPIENTS"
+	AgeRecipientsPathSetting     = "WALG_AGE_RECIPIENTS_PATH"
+	AgeIdentitySetting           = "WALG_AGE_IDENTITY"
+	AgeIdentityPathSetting       = "WALG_AGE_IDENTITY_PATH"
+	AgePassphraseSetting         = "WALG_AGE_PASSPHRASE"
+	AgeScryptWorkFactorSetting   = "WALG_AGE_SCRYPT_WORK_FACTOR"
+	PreviousKeysSetting          = "WALG_PREVIOUS_KEYS"
+	EncryptionKeyHeaderSetting   = "WALG_ENCRYPTION_KEY_HEADER"
+	SignHMACKeyPathSetting       = "WALG_SIGNATURE_HMAC_KEY_PATH"
+	SignPrivateKeyPathSetting    = "WALG_SIGNATURE_ED25519_PRIVATE_KEY_PATH"
+	SignPublicKeyPathSetting     = "WALG_SIGNATURE_ED25519_PUBLIC_KEY_PATH"
+	SignStrictSetting            = "WALG_SIGNATURE_STRICT"
+	SignStrictSinceSetting       = "WALG_SIGNATURE_STRICT_SINCE"
 	PgDataSetting                = "PGDATA"
 	UserSetting                  = "USER" // TODO : do something with it
 	PgPortSetting                = "PGPORT"
@@ -74,6 +107,8 @@ const (
 	MaxDelayedSegmentsCount      = "WALG_INTEGRITY_MAX_DELAYED_WALS"
 	PrefetchDir                  = "WALG_PREFETCH_DIR"
 	PgReadyRename                = "PG_READY_RENAME"
+	PgZstdDictionary             = "WALG_ZSTD_DICTIONARY"
+	PgZstdDictionaryInterval     = "WALG_ZSTD_DICTIONARY_CHECK_INTERVAL"
 
 	MongoDBUriSetting               = "MONGODB_URI"
 	MongoDBLastWriteUpdateInterval  = "MONGODB_LAST_WRITE_UPDATE_INTERVAL"
@@ -135,6 +170,7 @@ var (
 		UploadWalMetadata:            "NOMETADATA",
 		DeltaMaxStepsSetting:         "0",
 		CompressionMethodSetting:     "lz4",
+		CompressConcurrencySetting:   "1",
 		UseWalDeltaSetting:           "false",
 		TarSizeThresholdSetting:      "1073741823", // (1 << 30) - 1
 		TotalBgUploadedLimit:         "32",
@@ -162,7 +198,8 @@ var (
 	}
 
 	PGDefaultSettings = map[string]string{
-		PgWalSize: "16",
+		PgWalSize:                "16",
+		PgZstdDictionaryInterval: "5m",
 	}
 
 	AllowedSettings map[string]bool
@@ -173,13 +210,31 @@ var (
 		UploadConcurrencySetting:     true,
 		UploadDiskConcurrencySetting: true,
 		UploadQueueSetting:           true,
+		UploadStateDirSetting:        true,
 		SentinelUserDataSetting:      true,
 		PreventWalOverwriteSetting:   true,
 		UploadWalMetadata:            true,
 		DeltaMaxStepsSetting:         true,
 		DeltaOriginSetting:           true,
 		CompressionMethodSetting:     true,
+		CompressionLevelSetting:      true,
+		ZstdLongSetting:              true,
+		AdaptiveCompressionSetting:   true,
+		CompressConcurrencySetting:   true,
 		StoragePrefixSetting:         true,
+		StorageTimeoutSetting:        true,
+		StorageRetriesSetting:        true,
+		StorageMinBackoffSetting:     true,
+		StorageMaxBackoffSetting:     true,
+		StorageCacheDirSetting:       true,
+		StorageCacheSizeSetting:      true,
+		StorageListRateSetting:       true,
+		StorageReadRateSetting:       true,
+		StorageWriteRateSetting:      true,
+		StorageDeleteRateSetting:     true,
+		ObjectChecksumsSetting:       true,
+		MirrorStoragesSetting:        true,
+		MirrorWritePolicySetting:     true,
 		DiskRateLimitSetting:         true,
 		NetworkRateLimitSetting:      true,
 		UseWalDeltaSetting:           true,
@@ -190,6 +245,21 @@ var (
 		PgpKeySetting:                true,
 		PgpKeyPathSetting:            true,
 		PgpKeyPassphraseSetting:      true,
+		EnvelopeMasterKeyPathSetting: true,
+		EnvelopeMasterKeyCmdSetting:  true,
+		AgeRecipientsSetting:         true,
+		AgeRecipientsPathSetting:     true,
+		AgeIdentitySetting:           true,
+		AgeIdentityPathSetting:       true,
+		AgePassphraseSetting:         true,
+		AgeScryptWorkFactorSetting:   true,
+		PreviousKeysSetting:          true,
+		EncryptionKeyHeaderSetting:   true,
+		SignHMACKeyPathSetting:       true,
+		SignPrivateKeyPathSetting:    true,
+		SignPublicKeyPathSetting:     true,
+		SignStrictSetting:            true,
+		SignStrictSinceSetting:       true,
 		LibsodiumKeySetting:          true,
 		LibsodiumKeyPathSetting:      true,
 		TotalBgUploadedLimit:         true,
@@ -235,6 +305,8 @@ var (
 		"WALG_S3_SSE":                 true,
 		"WALG_S3_SSE_C":               true,
 		"WALG_S3_SSE_KMS_ID":          true,
+		"WALG_S3_OBJECT_LOCK_MODE":    true,
+		"WALG_S3_OBJECT_LOCK_DAYS":    true,
 		"WALG_CSE_KMS_ID":             true,
 		"WALG_CSE_KMS_REGION":         true,
 		"WALG_S3_MAX_PART_SIZE":       true,
@@ -266,6 +338,12 @@ var (
 		"SSH_USERNAME":         true,
 		"SSH_PRIVATE_KEY_PATH": true,
 
+		// WebDAV
+		"WALG_WEBDAV_PREFIX":  true,
+		"WEBDAV_USERNAME":     true,
+		"WEBDAV_PASSWORD":     true,
+		"WEBDAV_CA_CERT_FILE": true,
+
 		//File
 		"WALG_FILE_PREFIX": true,
 
@@ -280,18 +358,20 @@ var (
 
 	PGAllowedSettings = map[string]bool{
 		// Postgres
-		PgPortSetting:     true,
-		PgUserSetting:     true,
-		PgHostSetting:     true,
-		PgDataSetting:     true,
-		PgPasswordSetting: true,
-		PgDatabaseSetting: true,
-		PgSslModeSetting:  true,
-		PgSlotName:        true,
-		PgWalSize:         true,
-		"PGPASSFILE":      true,
-		PrefetchDir:       true,
-		PgReadyRename:     true,
+		PgPortSetting:            true,
+		PgUserSetting:            true,
+		PgHostSetting:            true,
+		PgDataSetting:            true,
+		PgPasswordSetting:        true,
+		PgDatabaseSetting:        true,
+		PgSslModeSetting:         true,
+		PgSlotName:               true,
+		PgWalSize:                true,
+		"PGPASSFILE":             true,
+		PrefetchDir:              true,
+		PgReadyRename:            true,
+		PgZstdDictionary:         true,
+		PgZstdDictionaryInterval: true,
 	}
 
 	MongoAllowedSettings = map[string]bool{
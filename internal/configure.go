@@ -3,11 +3,14 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	"expvar"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/wal-g/wal-g/internal/crypto/yckms"
@@ -18,6 +21,8 @@ import (
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/crypto/awskms"
+	"github.com/wal-g/wal-g/internal/crypto/envelope"
+	"github.com/wal-g/wal-g/internal/crypto/keyring"
 	"github.com/wal-g/wal-g/internal/crypto/openpgp"
 	"github.com/wal-g/wal-g/internal/fsutil"
 	"github.com/wal-g/wal-g/internal/limiters"
@@ -147,6 +152,18 @@ func ConfigureStoragePrefix(folder storage.Folder) storage.Folder {
 // this function will always return only one concrete 'folder'.
 // Chosen folder depends only on 'StorageAdapters' order
 func ConfigureFolderForSpecificConfig(config *viper.Viper) (storage.Folder, error) {
+	folder, err := configureStorageFolder(config)
+	if err != nil {
+		return nil, err
+	}
+	folder, err = configureMirrorStorages(folder, config)
+	if err != nil {
+		return nil, err
+	}
+	return configureStorageCache(folder, config)
+}
+
+func configureStorageFolder(config *viper.Viper) (storage.Folder, error) {
 	skippedPrefixes := make([]string, 0)
 	for _, adapter := range StorageAdapters {
 		prefix, ok := getWaleCompatibleSettingFrom(adapter.prefixName, config)
@@ -159,11 +176,209 @@ func ConfigureFolderForSpecificConfig(config *viper.Viper) (storage.Folder, erro
 		}
 
 		settings := adapter.loadSettings(config)
-		return adapter.configureFolder(prefix, settings)
+		folder, err := adapter.configureFolder(prefix, settings)
+		if err != nil {
+			return nil, err
+		}
+		folder, err = configureStorageRateLimits(folder, config)
+		if err != nil {
+			return nil, err
+		}
+		if config.GetBool(ObjectChecksumsSetting) {
+			folder = storage.NewChecksumFolder(folder)
+		}
+		folder, err = configureStorageTimeout(folder, config)
+		if err != nil {
+			return nil, err
+		}
+		return configureStorageRetries(folder, config)
 	}
 	return nil, newUnconfiguredStorageError(skippedPrefixes)
 }
 
+// inheritedMirrorSettings are the settings of the main storage which mirrors use unless they override them
+var inheritedMirrorSettings = []string{
+	ObjectChecksumsSetting,
+	StorageTimeoutSetting,
+	StorageRetriesSetting,
+	StorageMinBackoffSetting,
+	StorageMaxBackoffSetting,
+	StorageListRateSetting,
+	StorageReadRateSetting,
+	StorageWriteRateSetting,
+	StorageDeleteRateSetting,
+}
+
+// configureMirrorStorages adds the storages configured in the WALG_MIRROR_STORAGES section
+// as mirrors of the main one. Every mirror is configured by its own section of settings,
+// the inheritedMirrorSettings missing there are taken from the main configuration.
+func configureMirrorStorages(folder storage.Folder, config *viper.Viper) (storage.Folder, error) {
+	mirrorConfigs := config.GetStringMap(MirrorStoragesSetting)
+	if len(mirrorConfigs) == 0 {
+		return folder, nil
+	}
+	policy := storage.MirrorWritePolicy(strings.ToLower(config.GetString(MirrorWritePolicySetting)))
+	switch policy {
+	case "":
+		policy = storage.MirrorWriteAll
+	case storage.MirrorWriteAll, storage.MirrorWriteQuorum:
+	default:
+		return nil, fmt.Errorf("%s setting should be one of '%s', '%s' but given '%s'",
+			MirrorWritePolicySetting, storage.MirrorWriteAll, storage.MirrorWriteQuorum, policy)
+	}
+
+	mirrorNames := make([]string, 0, len(mirrorConfigs))
+	for name := range mirrorConfigs {
+		mirrorNames = append(mirrorNames, name)
+	}
+	sort.Strings(mirrorNames)
+
+	mirrors := []storage.Folder{folder}
+	for _, name := range mirrorNames {
+		mirrorSettings, ok := mirrorConfigs[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s.%s should be a section of settings", MirrorStoragesSetting, name)
+		}
+		mirrorConfig := viper.New()
+		for setting, value := range mirrorSettings {
+			mirrorConfig.Set(setting, value)
+		}
+		for _, setting := range inheritedMirrorSettings {
+			if !mirrorConfig.IsSet(setting) && config.IsSet(setting) {
+				mirrorConfig.Set(setting, config.Get(setting))
+			}
+		}
+		mirror, err := configureStorageFolder(mirrorConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to configure mirror storage '%s'", name)
+		}
+		mirrors = append(mirrors, mirror)
+	}
+	return storage.NewMirrorFolder(policy, mirrors...), nil
+}
+
+// configureStorageTimeout bounds every storage operation by the configured timeout, if any
+func configureStorageTimeout(folder storage.Folder, config *viper.Viper) (storage.Folder, error) {
+	if !config.IsSet(StorageTimeoutSetting) {
+		return folder, nil
+	}
+	timeoutStr := config.GetString(StorageTimeoutSetting)
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil {
+		return nil, fmt.Errorf("duration expected for %s setting but given '%s': %w",
+			StorageTimeoutSetting, timeoutStr, err)
+	}
+	if timeout <= 0 {
+		return folder, nil
+	}
+	return storage.NewTimeoutFolder(folder, timeout), nil
+}
+
+// configureStorageRateLimits limits the rates of storage requests of every kind, unless the throttling is turned off
+func configureStorageRateLimits(folder storage.Folder, config *viper.Viper) (storage.Folder, error) {
+	if Turbo {
+		return folder, nil
+	}
+	limiters := storage.RequestLimiters{}
+	limited := false
+	for setting, limiter := range map[string]**rate.Limiter{
+		StorageListRateSetting:   &limiters.List,
+		StorageReadRateSetting:   &limiters.Read,
+		StorageWriteRateSetting:  &limiters.Write,
+		StorageDeleteRateSetting: &limiters.Delete,
+	} {
+		if !config.IsSet(setting) {
+			continue
+		}
+		limitStr := config.GetString(setting)
+		limit, err := strconv.ParseFloat(limitStr, 64)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("non-negative number expected for %s setting but given '%s'", setting, limitStr)
+		}
+		if limit > 0 {
+			*limiter = storage.NewRequestLimiter(limit)
+			limited = true
+		}
+	}
+	if !limited {
+		return folder, nil
+	}
+	return storage.NewRateLimitFolder(folder, limiters), nil
+}
+
+// DefaultStorageCacheSize is the capacity of the storage cache if only its directory is configured
+const DefaultStorageCacheSize = 1 << 30
+
+// configureStorageCache puts a local read-through cache in front of the storage if the cache directory is set
+func configureStorageCache(folder storage.Folder, config *viper.Viper) (storage.Folder, error) {
+	directory := config.GetString(StorageCacheDirSetting)
+	if directory == "" {
+		return folder, nil
+	}
+	capacity := int64(DefaultStorageCacheSize)
+	if config.IsSet(StorageCacheSizeSetting) {
+		capacityStr := config.GetString(StorageCacheSizeSetting)
+		var err error
+		capacity, err = strconv.ParseInt(capacityStr, 10, 64)
+		if err != nil || capacity <= 0 {
+			return nil, fmt.Errorf("positive integer expected for %s setting but given '%s'",
+				StorageCacheSizeSetting, capacityStr)
+		}
+	}
+	cache, err := storage.NewDiskCache(directory, capacity)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewCacheFolder(folder, cache), nil
+}
+
+// StorageRetryStats counts the retries of all the configured storages, it is exposed via expvar
+var StorageRetryStats = storage.NewRetryStats()
+
+func init() {
+	expvar.Publish("storage_retries", expvar.Func(func() interface{} {
+		return map[string]int64{
+			"retries":  StorageRetryStats.Retries(),
+			"failures": StorageRetryStats.Failures(),
+		}
+	}))
+}
+
+// configureStorageRetries makes the failed storage operations repeat if retries are configured
+func configureStorageRetries(folder storage.Folder, config *viper.Viper) (storage.Folder, error) {
+	if !config.IsSet(StorageRetriesSetting) {
+		return folder, nil
+	}
+	retriesStr := config.GetString(StorageRetriesSetting)
+	retries, err := strconv.Atoi(retriesStr)
+	if err != nil {
+		return nil, fmt.Errorf("integer expected for %s setting but given '%s': %w",
+			StorageRetriesSetting, retriesStr, err)
+	}
+	if retries <= 0 {
+		return folder, nil
+	}
+	policy := storage.DefaultRetryPolicy
+	policy.MaxRetries = retries
+	for setting, backoff := range map[string]*time.Duration{
+		StorageMinBackoffSetting: &policy.MinBackoff,
+		StorageMaxBackoffSetting: &policy.MaxBackoff,
+	} {
+		if !config.IsSet(setting) {
+			continue
+		}
+		backoffStr := config.GetString(setting)
+		*backoff, err = time.ParseDuration(backoffStr)
+		if err != nil {
+			return nil, fmt.Errorf("duration expected for %s setting but given '%s': %w", setting, backoffStr, err)
+		}
+	}
+	if policy.MinBackoff > policy.MaxBackoff {
+		return nil, fmt.Errorf("%s should not exceed %s", StorageMinBackoffSetting, StorageMaxBackoffSetting)
+	}
+	return storage.NewRetryFolder(folder, policy, StorageRetryStats), nil
+}
+
 func getWalFolderPath() string {
 	if !viper.IsSet(PgDataSetting) {
 		return DefaultDataFolderPath
@@ -197,10 +412,29 @@ func GetPgSlotName() (pgSlotName string) {
 // TODO : unit tests
 func ConfigureCompressor() (compression.Compressor, error) {
 	compressionMethod := viper.GetString(CompressionMethodSetting)
-	if _, ok := compression.Compressors[compressionMethod]; !ok {
+	compressor, ok := compression.Compressors[compressionMethod]
+	if !ok {
 		return nil, newUnknownCompressionMethodError()
 	}
-	return compression.Compressors[compressionMethod], nil
+	if viper.GetBool(ZstdLongSetting) {
+		compressor, ok = compression.WithLongWindow(compressor)
+		if !ok {
+			tracelog.WarningLogger.Printf("%s is ignored by %s compression\n", ZstdLongSetting, compressionMethod)
+		}
+	}
+	if viper.IsSet(CompressionLevelSetting) {
+		leveled, err := compression.WithLevel(compressor, viper.GetInt(CompressionLevelSetting))
+		if err == compression.ErrLevelNotSupported {
+			tracelog.WarningLogger.Printf("%s is ignored, %s compression doesn't support levels\n",
+				CompressionLevelSetting, compressionMethod)
+			return compressor, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s for %s compression", CompressionLevelSetting, compressionMethod)
+		}
+		compressor = leveled
+	}
+	return compressor, nil
 }
 
 func ConfigureLogging() error {
@@ -227,6 +461,12 @@ func ConfigureArchiveStatusManager() (fsutil.DataFolder, error) {
 	return fsutil.NewDiskDataFolder(getArchiveDataFolderPath())
 }
 
+// ConfigureDictionaryCacheFolder returns the local folder caching the compression dictionaries of WAL,
+// it is placed next to the archive status files
+func ConfigureDictionaryCacheFolder() (fsutil.DataFolder, error) {
+	return fsutil.NewDiskDataFolder(filepath.Join(GetDataFolderPath(), "walg_dictionaries"))
+}
+
 func ConfigurePGArchiveStatusManager() (fsutil.DataFolder, error) {
 	return fsutil.ExistingDiskDataFolder(getPGArchiveStatusFolderPath())
 }
@@ -264,40 +504,123 @@ func ConfigureUploaderWithoutCompressMethod() (uploader *Uploader, err error) {
 // ConfigureCrypter uses environment variables to create and configure a crypter.
 // In case no configuration in environment variables found, return `<nil>` value.
 func ConfigureCrypter() crypto.Crypter {
+	return ConfigureCrypterForSpecificConfig(viper.GetViper())
+}
+
+// ConfigureCrypterForSpecificConfig creates and configures a crypter according to the config.
+// The keys of the WALG_PREVIOUS_KEYS sections are kept in the keyring to decrypt the objects encrypted before.
+// In case no configuration found, return `<nil>` value.
+func ConfigureCrypterForSpecificConfig(config *viper.Viper) crypto.Crypter {
+	current := configureSingleCrypter(config)
+	if current == nil {
+		return nil
+	}
+	previous, err := configurePreviousKeys(config)
+	tracelog.ErrorLogger.FatalOnError(err)
+	return keyring.CrypterFromKeys(current, previous, config.GetBool(EncryptionKeyHeaderSetting))
+}
+
+// settings, which are taken from the main configuration if they are missing in a WALG_PREVIOUS_KEYS section
+var inheritedKeySettings = []string{
+	PgpKeyPassphraseSetting,
+	CseKmsRegionSetting,
+	YcSaKeyFileSetting,
+}
+
+// configurePreviousKeys configures the crypters of the WALG_PREVIOUS_KEYS sections in the alphabetical order of their names
+func configurePreviousKeys(config *viper.Viper) ([]crypto.Crypter, error) {
+	keyConfigs := config.GetStringMap(PreviousKeysSetting)
+	keyNames := make([]string, 0, len(keyConfigs))
+	for name := range keyConfigs {
+		keyNames = append(keyNames, name)
+	}
+	sort.Strings(keyNames)
+
+	crypters := make([]crypto.Crypter, 0, len(keyNames))
+	for _, name := range keyNames {
+		keySettings, ok := keyConfigs[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s.%s should be a section of settings", PreviousKeysSetting, name)
+		}
+		keyConfig := viper.New()
+		for setting, value := range keySettings {
+			keyConfig.Set(setting, value)
+		}
+		for _, setting := range inheritedKeySettings {
+			if !keyConfig.IsSet(setting) && config.IsSet(setting) {
+				keyConfig.Set(setting, config.Get(setting))
+			}
+		}
+		crypter := configureSingleCrypter(keyConfig)
+		if crypter == nil {
+			return nil, fmt.Errorf("%s.%s doesn't configure any key", PreviousKeysSetting, name)
+		}
+		crypters = append(crypters, crypter)
+	}
+	return crypters, nil
+}
+
+// configureSingleCrypter creates the crypter of the key configured by the settings, if any
+func configureSingleCrypter(config *viper.Viper) crypto.Crypter {
 	loadPassphrase := func() (string, bool) {
-		return GetSetting(PgpKeyPassphraseSetting)
+		if config.IsSet(PgpKeyPassphraseSetting) {
+			return config.GetString(PgpKeyPassphraseSetting), true
+		}
+		return "", false
 	}
 
 	// key can be either private (for download) or public (for upload)
-	if viper.IsSet(PgpKeySetting) {
-		return openpgp.CrypterFromKey(viper.GetString(PgpKeySetting), loadPassphrase)
+	if config.IsSet(PgpKeySetting) {
+		return openpgp.CrypterFromKey(config.GetString(PgpKeySetting), loadPassphrase)
 	}
 
 	// key can be either private (for download) or public (for upload)
-	if viper.IsSet(PgpKeyPathSetting) {
-		return openpgp.CrypterFromKeyPath(viper.GetString(PgpKeyPathSetting), loadPassphrase)
+	if config.IsSet(PgpKeyPathSetting) {
+		return openpgp.CrypterFromKeyPath(config.GetString(PgpKeyPathSetting), loadPassphrase)
 	}
 
-	if keyRingID, ok := getWaleCompatibleSetting(GpgKeyIDSetting); ok {
+	if keyRingID, ok := getWaleCompatibleSettingFrom(GpgKeyIDSetting, config); ok {
 		tracelog.WarningLogger.Printf(DeprecatedExternalGpgMessage)
 		return openpgp.CrypterFromKeyRingID(keyRingID, loadPassphrase)
 	}
 
-	if viper.IsSet(CseKmsIDSetting) {
-		return awskms.CrypterFromKeyID(viper.GetString(CseKmsIDSetting), viper.GetString(CseKmsRegionSetting))
+	if config.IsSet(CseKmsIDSetting) {
+		return awskms.CrypterFromKeyID(config.GetString(CseKmsIDSetting), config.GetString(CseKmsRegionSetting))
 	}
 
-	if viper.IsSet(YcKmsKeyIDSetting) {
-		return yckms.YcCrypterFromKeyIDAndCredential(viper.GetString(YcKmsKeyIDSetting), viper.GetString(YcSaKeyFileSetting))
+	if config.IsSet(YcKmsKeyIDSetting) {
+		return yckms.YcCrypterFromKeyIDAndCredential(config.GetString(YcKmsKeyIDSetting), config.GetString(YcSaKeyFileSetting))
 	}
 
-	if crypter := configureLibsodiumCrypter(); crypter != nil {
+	if config.IsSet(EnvelopeMasterKeyPathSetting) {
+		return envelope.CrypterFromMasterKeyPath(config.GetString(EnvelopeMasterKeyPathSetting))
+	}
+
+	if config.IsSet(EnvelopeMasterKeyCmdSetting) {
+		return envelope.CrypterFromMasterKeyCommand(config.GetString(EnvelopeMasterKeyCmdSetting))
+	}
+
+	if crypter := configureAgeCrypter(config); crypter != nil {
+		return crypter
+	}
+
+	if crypter := configureLibsodiumCrypter(config); crypter != nil {
 		return crypter
 	}
 
 	return nil
 }
 
+// CrypterFromConfig prefers the config parameters instead of the current environment variables
+func CrypterFromConfig(configFile string) crypto.Crypter {
+	var config = viper.New()
+	SetDefaultValues(config)
+	ReadConfigFromFile(config, configFile)
+	CheckAllowedSettings(config)
+
+	return ConfigureCrypterForSpecificConfig(config)
+}
+
 func GetMaxDownloadConcurrency() (int, error) {
 	return GetMaxConcurrency(DownloadConcurrencySetting)
 }
@@ -319,6 +642,11 @@ func GetMaxUploadDiskConcurrency() (int, error) {
 	return GetMaxConcurrency(UploadDiskConcurrencySetting)
 }
 
+// GetMaxCompressConcurrency returns the number of the workers compressing a stream
+func GetMaxCompressConcurrency() (int, error) {
+	return GetMaxConcurrency(CompressConcurrencySetting)
+}
+
 func GetMaxConcurrency(concurrencyType string) (int, error) {
 	concurrency := viper.GetInt(concurrencyType)
 
@@ -19,12 +19,12 @@ import (
 	"github.com/wal-g/wal-g/internal/crypto"
 )
 
-func configureLibsodiumCrypter() crypto.Crypter {
-	if viper.IsSet(LibsodiumKeySetting) {
+func configureLibsodiumCrypter(config *viper.Viper) crypto.Crypter {
+	if config.IsSet(LibsodiumKeySetting) {
 		tracelog.ErrorLogger.Fatalf("non-empty WALG_LIBSODIUM_KEY but wal-g was not compiled with libsodium")
 	}
 
-	if viper.IsSet(LibsodiumKeyPathSetting) {
+	if config.IsSet(LibsodiumKeyPathSetting) {
 		tracelog.ErrorLogger.Fatalf("non-empty WALG_LIBSODIUM_KEY_PATH but wal-g was not compiled with libsodium")
 	}
 
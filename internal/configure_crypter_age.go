@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/crypto/age"
+)
+
+func configureAgeCrypter(config *viper.Viper) crypto.Crypter {
+	if config.IsSet(AgePassphraseSetting) {
+		if config.IsSet(AgeRecipientsSetting) || config.IsSet(AgeRecipientsPathSetting) ||
+			config.IsSet(AgeIdentitySetting) || config.IsSet(AgeIdentityPathSetting) {
+			tracelog.ErrorLogger.Fatalf("%s can't be used together with the age recipients and identities", AgePassphraseSetting)
+		}
+		return age.CrypterFromPassphrase(config.GetString(AgePassphraseSetting), config.GetInt(AgeScryptWorkFactorSetting))
+	}
+
+	if config.IsSet(AgeRecipientsSetting) || config.IsSet(AgeRecipientsPathSetting) ||
+		config.IsSet(AgeIdentitySetting) || config.IsSet(AgeIdentityPathSetting) {
+		return age.CrypterFromKeys(config.GetString(AgeRecipientsSetting), config.GetString(AgeRecipientsPathSetting),
+			config.GetString(AgeIdentitySetting), config.GetString(AgeIdentityPathSetting))
+	}
+
+	return nil
+}
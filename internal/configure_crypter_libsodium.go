@@ -8,13 +8,13 @@ import (
 	"github.com/wal-g/wal-g/internal/crypto/libsodium"
 )
 
-func configureLibsodiumCrypter() crypto.Crypter {
-	if viper.IsSet(LibsodiumKeySetting) {
-		return libsodium.CrypterFromKey(viper.GetString(LibsodiumKeySetting))
+func configureLibsodiumCrypter(config *viper.Viper) crypto.Crypter {
+	if config.IsSet(LibsodiumKeySetting) {
+		return libsodium.CrypterFromKey(config.GetString(LibsodiumKeySetting))
 	}
 
-	if viper.IsSet(LibsodiumKeyPathSetting) {
-		return libsodium.CrypterFromKeyPath(viper.GetString(LibsodiumKeyPathSetting))
+	if config.IsSet(LibsodiumKeyPathSetting) {
+		return libsodium.CrypterFromKeyPath(config.GetString(LibsodiumKeyPathSetting))
 	}
 
 	return nil
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/wal-g/internal/crypto/signature"
+)
+
+// ConfigureSignatureKey returns the key signing the backup sentinels and metadata, or nil if none is configured
+func ConfigureSignatureKey() (signature.Key, error) {
+	return configureSignatureKeyForSpecificConfig(viper.GetViper())
+}
+
+func configureSignatureKeyForSpecificConfig(config *viper.Viper) (signature.Key, error) {
+	configured := make([]string, 0, 1)
+	for _, setting := range []string{SignHMACKeyPathSetting, SignPrivateKeyPathSetting, SignPublicKeyPathSetting} {
+		if config.IsSet(setting) {
+			configured = append(configured, setting)
+		}
+	}
+	if len(configured) > 1 {
+		return nil, errors.Errorf("only one of the signature keys can be configured, but %v are set", configured)
+	}
+
+	switch {
+	case config.IsSet(SignHMACKeyPathSetting):
+		return signature.LoadHMACKey(config.GetString(SignHMACKeyPathSetting))
+	case config.IsSet(SignPrivateKeyPathSetting):
+		return signature.LoadEd25519PrivateKey(config.GetString(SignPrivateKeyPathSetting))
+	case config.IsSet(SignPublicKeyPathSetting):
+		return signature.LoadEd25519PublicKey(config.GetString(SignPublicKeyPathSetting))
+	}
+	return nil, nil
+}
+
+// ConfigureChecksumTracking makes the uploader record the checksums of the uploaded objects to sign them
+// with the backup, if a key which can sign the backups is configured
+func ConfigureChecksumTracking(uploader *Uploader) {
+	if viper.IsSet(SignHMACKeyPathSetting) || viper.IsSet(SignPrivateKeyPathSetting) {
+		uploader.EnableChecksumTracking()
+	}
+}
+
+// configureSignStrictSince returns the time since which the backups must be signed in the strict mode,
+// the unsigned backups made before it are accepted. It is zero if all the backups must be signed.
+func configureSignStrictSince() (time.Time, error) {
+	if !viper.IsSet(SignStrictSinceSetting) {
+		return time.Time{}, nil
+	}
+	since, err := time.Parse(time.RFC3339, viper.GetString(SignStrictSinceSetting))
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to parse %s", SignStrictSinceSetting)
+	}
+	return since, nil
+}
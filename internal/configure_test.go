@@ -1,10 +1,12 @@
 package internal_test
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/wal-g/wal-g/testtools"
@@ -13,6 +15,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/pkg/storages/fs"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
 func TestGetMaxConcurrency_InvalidKey(t *testing.T) {
@@ -153,6 +158,106 @@ func TestConfigureLogging_WhenLogLevelSettingIsSet(t *testing.T) {
 	resetToDefaults()
 }
 
+func TestConfigureFolderForSpecificConfig_Mirrors(t *testing.T) {
+	primaryDir := prepareDataFolder(t, "primary")
+	defer testtools.Cleanup(t, primaryDir)
+	mirrorDir := prepareDataFolder(t, "mirror")
+	defer testtools.Cleanup(t, mirrorDir)
+
+	config := viper.New()
+	config.Set("WALG_FILE_PREFIX", primaryDir)
+	config.Set(internal.MirrorStoragesSetting, map[string]interface{}{
+		"dr": map[string]interface{}{"WALG_FILE_PREFIX": mirrorDir},
+	})
+	folder, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.NoError(t, err)
+
+	mirrorFolder, ok := folder.(*storage.MirrorFolder)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(mirrorFolder.Mirrors()))
+
+	err = folder.PutObject("file", strings.NewReader("data"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(mirrorDir, "file"))
+	assert.NoError(t, err)
+}
+
+func TestConfigureFolderForSpecificConfig_InvalidMirrorPolicy(t *testing.T) {
+	primaryDir := prepareDataFolder(t, "primary")
+	defer testtools.Cleanup(t, primaryDir)
+
+	config := viper.New()
+	config.Set("WALG_FILE_PREFIX", primaryDir)
+	config.Set(internal.MirrorWritePolicySetting, "some")
+	config.Set(internal.MirrorStoragesSetting, map[string]interface{}{
+		"dr": map[string]interface{}{"WALG_FILE_PREFIX": primaryDir},
+	})
+	_, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.Error(t, err)
+}
+
+func TestConfigureFolderForSpecificConfig_Retries(t *testing.T) {
+	dir := prepareDataFolder(t, "retries")
+	defer testtools.Cleanup(t, dir)
+
+	config := viper.New()
+	config.Set("WALG_FILE_PREFIX", dir)
+	config.Set(internal.StorageRetriesSetting, "2")
+	folder, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.NoError(t, err)
+	assert.IsType(t, &storage.RetryFolder{}, folder)
+
+	config.Set(internal.StorageMinBackoffSetting, "1m")
+	config.Set(internal.StorageMaxBackoffSetting, "1s")
+	_, err = internal.ConfigureFolderForSpecificConfig(config)
+	assert.Error(t, err)
+
+	config.Set(internal.StorageRetriesSetting, "many")
+	_, err = internal.ConfigureFolderForSpecificConfig(config)
+	assert.Error(t, err)
+}
+
+func TestConfigureFolderForSpecificConfig_RateLimits(t *testing.T) {
+	dir := prepareDataFolder(t, "rate_limits")
+	defer testtools.Cleanup(t, dir)
+
+	config := viper.New()
+	config.Set("WALG_FILE_PREFIX", dir)
+	config.Set(internal.StorageReadRateSetting, "0.5")
+	folder, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.NoError(t, err)
+	assert.IsType(t, &storage.RateLimitFolder{}, folder)
+
+	internal.Turbo = true
+	folder, err = internal.ConfigureFolderForSpecificConfig(config)
+	internal.Turbo = false
+	assert.NoError(t, err)
+	assert.IsType(t, &fs.Folder{}, folder)
+
+	config.Set(internal.StorageDeleteRateSetting, "fast")
+	_, err = internal.ConfigureFolderForSpecificConfig(config)
+	assert.Error(t, err)
+}
+
+func TestConfigureFolderForSpecificConfig_Cache(t *testing.T) {
+	dir := prepareDataFolder(t, "storage")
+	defer testtools.Cleanup(t, dir)
+	cacheDir := prepareDataFolder(t, "cache")
+	defer testtools.Cleanup(t, cacheDir)
+
+	config := viper.New()
+	config.Set("WALG_FILE_PREFIX", dir)
+	config.Set(internal.StorageCacheDirSetting, cacheDir)
+	config.Set(internal.StorageCacheSizeSetting, "1024")
+	folder, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.NoError(t, err)
+	assert.IsType(t, &storage.CacheFolder{}, folder)
+
+	config.Set(internal.StorageCacheSizeSetting, "-1")
+	_, err = internal.ConfigureFolderForSpecificConfig(config)
+	assert.Error(t, err)
+}
+
 func prepareDataFolder(t *testing.T, name string) string {
 	cwd, err := filepath.Abs("./")
 	if err != nil {
@@ -177,3 +282,70 @@ func resetToDefaults() {
 	internal.InitConfig()
 	internal.Configure()
 }
+
+func TestConfigureCrypterForSpecificConfig_PreviousKeys(t *testing.T) {
+	oldConfig := viper.New()
+	oldConfig.Set(internal.PgpKeyPathSetting, "../test/testdata/waleGpgKey")
+	var encrypted bytes.Buffer
+	writer, err := internal.ConfigureCrypterForSpecificConfig(oldConfig).Encrypt(&encrypted)
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte("secret"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	config := viper.New()
+	config.Set(internal.PgpKeyPathSetting, "crypto/openpgp/testdata/pgpTestPrivateKey")
+	_, err = internal.ConfigureCrypterForSpecificConfig(config).Decrypt(bytes.NewReader(encrypted.Bytes()))
+	assert.Error(t, err)
+
+	config.Set(internal.PreviousKeysSetting, map[string]interface{}{
+		"old": map[string]interface{}{internal.PgpKeyPathSetting: "../test/testdata/waleGpgKey"},
+	})
+	reader, err := internal.ConfigureCrypterForSpecificConfig(config).Decrypt(bytes.NewReader(encrypted.Bytes()))
+	assert.NoError(t, err)
+	decrypted, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(decrypted))
+}
+
+func TestConfigureSignatureKey(t *testing.T) {
+	key, err := internal.ConfigureSignatureKey()
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	dir, err := ioutil.TempDir("", "signature")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyPath := filepath.Join(dir, "hmac")
+	assert.NoError(t, ioutil.WriteFile(keyPath, []byte(strings.Repeat("k", 32)), 0600))
+
+	viper.Set(internal.SignHMACKeyPathSetting, keyPath)
+	key, err = internal.ConfigureSignatureKey()
+	assert.NoError(t, err)
+	assert.NotNil(t, key)
+
+	viper.Set(internal.SignPublicKeyPathSetting, keyPath)
+	_, err = internal.ConfigureSignatureKey()
+	assert.Error(t, err)
+	resetToDefaults()
+}
+
+func TestConfigureCompressor_Level(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "zstd")
+	viper.Set(internal.CompressionLevelSetting, 9)
+	compressor, err := internal.ConfigureCompressor()
+	assert.NoError(t, err)
+	_, isLeveled := compressor.(compression.LeveledCompressor)
+	assert.True(t, isLeveled)
+	assert.Equal(t, "zst", compressor.FileExtension())
+
+	viper.Set(internal.CompressionLevelSetting, 23)
+	_, err = internal.ConfigureCompressor()
+	assert.Error(t, err)
+
+	viper.Set(internal.CompressionMethodSetting, "lz4")
+	compressor, err = internal.ConfigureCompressor()
+	assert.NoError(t, err)
+	assert.Equal(t, "lz4", compressor.FileExtension())
+	resetToDefaults()
+}
@@ -0,0 +1,220 @@
+package age
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+)
+
+// scryptKeyID identifies the passphrase, the passphrase itself or its hash can't be revealed
+const scryptKeyID = "scrypt"
+
+// Crypter is age Crypter implementation.
+// The data is encrypted to the X25519 recipients and decrypted with the X25519 identities,
+// so the host which only has the recipients can't decrypt what it has encrypted.
+// The passphrase is used for both instead, if it is set.
+type Crypter struct {
+	Recipients     string
+	RecipientsPath string
+	Identities     string
+	IdentitiesPath string
+
+	Passphrase       string
+	ScryptWorkFactor int
+
+	recipients []age.Recipient
+	identities []age.Identity
+
+	mutex sync.RWMutex
+}
+
+func (crypter *Crypter) Name() string {
+	return "Age"
+}
+
+// CrypterFromKeys creates Crypter from the recipients and the identities, the values or the paths to the files.
+// Every value may contain many keys separated by new lines or commas. The recipients may be omitted
+// if the identities are given, since the recipients are derived from them then.
+func CrypterFromKeys(recipients, recipientsPath, identities, identitiesPath string) crypto.Crypter {
+	return &Crypter{
+		Recipients:     recipients,
+		RecipientsPath: recipientsPath,
+		Identities:     identities,
+		IdentitiesPath: identitiesPath,
+	}
+}
+
+// CrypterFromPassphrase creates Crypter from passphrase, the work factor is the scrypt log2(N) used to encrypt
+// or 0 for the age default
+func CrypterFromPassphrase(passphrase string, scryptWorkFactor int) crypto.Crypter {
+	return &Crypter{Passphrase: passphrase, ScryptWorkFactor: scryptWorkFactor}
+}
+
+func (crypter *Crypter) setupRecipients() error {
+	crypter.mutex.RLock()
+	if crypter.recipients != nil {
+		crypter.mutex.RUnlock()
+		return nil
+	}
+	crypter.mutex.RUnlock()
+
+	crypter.mutex.Lock()
+	defer crypter.mutex.Unlock()
+	if crypter.recipients != nil { // already set up
+		return nil
+	}
+
+	if crypter.Passphrase != "" {
+		recipient, err := age.NewScryptRecipient(crypter.Passphrase)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if crypter.ScryptWorkFactor > 0 {
+			recipient.SetWorkFactor(crypter.ScryptWorkFactor)
+		}
+		crypter.recipients = []age.Recipient{recipient}
+		return nil
+	}
+
+	text, err := readKeys(crypter.Recipients, crypter.RecipientsPath)
+	if err != nil {
+		return err
+	}
+	if text != "" {
+		recipients, err := age.ParseRecipients(strings.NewReader(text))
+		if err != nil {
+			return errors.Wrap(err, "failed to parse age recipients")
+		}
+		crypter.recipients = recipients
+		return nil
+	}
+
+	identities, err := crypter.parseIdentities()
+	if err != nil {
+		return err
+	}
+	recipients := make([]age.Recipient, 0, len(identities))
+	for _, identity := range identities {
+		if x25519Identity, ok := identity.(*age.X25519Identity); ok {
+			recipients = append(recipients, x25519Identity.Recipient())
+		}
+	}
+	if len(recipients) == 0 {
+		return errors.New("age Crypter must have recipients, identities or passphrase")
+	}
+	crypter.recipients = recipients
+	return nil
+}
+
+func (crypter *Crypter) setupIdentities() error {
+	crypter.mutex.RLock()
+	if crypter.identities != nil {
+		crypter.mutex.RUnlock()
+		return nil
+	}
+	crypter.mutex.RUnlock()
+
+	crypter.mutex.Lock()
+	defer crypter.mutex.Unlock()
+	if crypter.identities != nil { // already set up
+		return nil
+	}
+
+	if crypter.Passphrase != "" {
+		identity, err := age.NewScryptIdentity(crypter.Passphrase)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		crypter.identities = []age.Identity{identity}
+		return nil
+	}
+
+	identities, err := crypter.parseIdentities()
+	if err != nil {
+		return err
+	}
+	if len(identities) == 0 {
+		return errors.New("age identities are required to decrypt, the recipients can only encrypt")
+	}
+	crypter.identities = identities
+	return nil
+}
+
+func (crypter *Crypter) parseIdentities() ([]age.Identity, error) {
+	text, err := readKeys(crypter.Identities, crypter.IdentitiesPath)
+	if err != nil || text == "" {
+		return nil, err
+	}
+	identities, err := age.ParseIdentities(strings.NewReader(text))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse age identities")
+	}
+	return identities, nil
+}
+
+// KeyID returns the first recipient, which is the public key.
+// The passphrase is not identified.
+func (crypter *Crypter) KeyID() (string, error) {
+	if crypter.Passphrase != "" {
+		return scryptKeyID, nil
+	}
+	if err := crypter.setupRecipients(); err != nil {
+		return "", err
+	}
+	recipient, ok := crypter.recipients[0].(fmt.Stringer)
+	if !ok {
+		return "", errors.New("age recipient can't be identified")
+	}
+	return recipient.String(), nil
+}
+
+// Encrypt creates encryption writer from ordinary writer
+func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
+	if err := crypter.setupRecipients(); err != nil {
+		return nil, err
+	}
+
+	// age writes the header at once, the buffer defers it to the first write like in the openpgp Crypter
+	bufferedWriter := bufio.NewWriter(writer)
+	encryptedWriter, err := age.Encrypt(bufferedWriter, crypter.recipients...)
+	if err != nil {
+		return nil, errors.Wrap(err, "age encryption error")
+	}
+
+	return ioextensions.NewOnCloseFlusher(encryptedWriter, bufferedWriter), nil
+}
+
+// Decrypt creates decrypted reader from ordinary reader
+func (crypter *Crypter) Decrypt(reader io.Reader) (io.Reader, error) {
+	if err := crypter.setupIdentities(); err != nil {
+		return nil, err
+	}
+
+	decryptedReader, err := age.Decrypt(reader, crypter.identities...)
+	if err != nil {
+		return nil, errors.Wrap(err, "age decryption error")
+	}
+	return decryptedReader, nil
+}
+
+// readKeys joins the keys given as the value and in the file, one key per line
+func readKeys(value, path string) (string, error) {
+	keys := strings.Replace(value, `\n`, "\n", -1)
+	keys = strings.Replace(keys, ",", "\n", -1)
+	if path != "" {
+		fileKeys, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		keys += "\n" + string(fileKeys)
+	}
+	return strings.TrimSpace(keys), nil
+}
@@ -0,0 +1,84 @@
+package age
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/crypto"
+)
+
+const someSecret = "so very secret thingy"
+
+func encrypt(t *testing.T, crypter crypto.Crypter) []byte {
+	buf := new(bytes.Buffer)
+	encrypt, err := crypter.Encrypt(buf)
+	assert.NoErrorf(t, err, "Encryption error: %v", err)
+	_, err = encrypt.Write([]byte(someSecret))
+	assert.NoError(t, err)
+	assert.NoError(t, encrypt.Close())
+	return buf.Bytes()
+}
+
+func decrypt(t *testing.T, crypter crypto.Crypter, encrypted []byte) {
+	decrypt, err := crypter.Decrypt(bytes.NewReader(encrypted))
+	assert.NoErrorf(t, err, "Decryption error: %v", err)
+	decryptedBytes, err := ioutil.ReadAll(decrypt)
+	assert.NoErrorf(t, err, "Decryption read error: %v", err)
+	assert.Equal(t, someSecret, string(decryptedBytes), "Decrypted text not equals open text")
+}
+
+func TestRecipientsCantDecrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	otherIdentity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	archivingCrypter := CrypterFromKeys(identity.Recipient().String()+","+otherIdentity.Recipient().String(), "", "", "")
+	encrypted := encrypt(t, archivingCrypter)
+	_, err = archivingCrypter.Decrypt(bytes.NewReader(encrypted))
+	assert.Error(t, err)
+
+	decrypt(t, CrypterFromKeys("", "", identity.String(), ""), encrypted)
+	decrypt(t, CrypterFromKeys("", "", otherIdentity.String(), ""), encrypted)
+}
+
+func TestIdentityFromPath(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	dir, err := ioutil.TempDir("", "age")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	identityPath := filepath.Join(dir, "key.txt")
+	assert.NoError(t, ioutil.WriteFile(identityPath, []byte("# created: today\n"+identity.String()+"\n"), 0600))
+
+	// the recipient is derived from the identity
+	crypter := CrypterFromKeys("", "", "", identityPath)
+	decrypt(t, crypter, encrypt(t, crypter))
+
+	keyID, err := crypter.(crypto.KeyIdentifier).KeyID()
+	assert.NoError(t, err)
+	assert.Equal(t, identity.Recipient().String(), keyID)
+}
+
+func TestPassphrase(t *testing.T) {
+	crypter := CrypterFromPassphrase("password", 10)
+	encrypted := encrypt(t, crypter)
+	decrypt(t, CrypterFromPassphrase("password", 0), encrypted)
+
+	_, err := CrypterFromPassphrase("wrong", 0).Decrypt(bytes.NewReader(encrypted))
+	assert.Error(t, err)
+
+	keyID, err := crypter.(crypto.KeyIdentifier).KeyID()
+	assert.NoError(t, err)
+	assert.Equal(t, scryptKeyID, keyID)
+}
+
+func TestNoKeys(t *testing.T) {
+	_, err := CrypterFromKeys("", "", "", "").Encrypt(new(bytes.Buffer))
+	assert.Error(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/minio/sio"
+	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/ioextensions"
@@ -19,6 +20,15 @@ func (crypter *Crypter) Name() string {
 	return "AWK_KMS/Crypter"
 }
 
+// KeyID returns the ID of the KMS key encrypting the symmetric keys
+func (crypter *Crypter) KeyID() (string, error) {
+	key, ok := crypter.SymmetricKey.(*SymmetricKey)
+	if !ok {
+		return "", errors.New("AWS KMS key ID is unknown")
+	}
+	return key.KeyID, nil
+}
+
 // Encrypt creates encryption writer from ordinary writer
 func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 	if len(crypter.SymmetricKey.GetKey()) == 0 {
@@ -50,14 +60,20 @@ func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 // Decrypt creates decrypted reader from ordinary reader
 func (crypter *Crypter) Decrypt(reader io.Reader) (io.Reader, error) {
 	encryptedSymmetricKey := make([]byte, crypter.SymmetricKey.GetEncryptedKeyLen())
-	_, err := reader.Read(encryptedSymmetricKey)
-	tracelog.ErrorLogger.FatalfOnError("Can't read encryption key from archive file header: %v", err)
+	_, err := io.ReadFull(reader, encryptedSymmetricKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read encryption key from archive file header")
+	}
 
 	err = crypter.SymmetricKey.SetEncryptedKey(encryptedSymmetricKey)
-	tracelog.ErrorLogger.FatalfOnError("Can't set encrypted key: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't set encrypted key")
+	}
 
 	err = crypter.SymmetricKey.Decrypt()
-	tracelog.ErrorLogger.FatalfOnError("Can't decrypt symmetric key: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't decrypt symmetric key")
+	}
 
 	return sio.DecryptReader(reader, sio.Config{Key: crypter.SymmetricKey.GetKey()})
 }
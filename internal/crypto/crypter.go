@@ -1,6 +1,12 @@
 package crypto
 
-import "io"
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
 
 // Crypter is responsible for making cryptographical pipeline parts when needed
 type Crypter interface {
@@ -8,3 +14,47 @@ type Crypter interface {
 	Encrypt(writer io.Writer) (io.WriteCloser, error)
 	Decrypt(reader io.Reader) (io.Reader, error)
 }
+
+// KeyIdentifier is a Crypter which can identify its key without revealing it
+type KeyIdentifier interface {
+	Crypter
+
+	KeyID() (string, error)
+}
+
+// GetKeyIdentity describes the crypter and its key, e.g. to record which key has encrypted a backup.
+// The key is not identified if the crypter doesn't implement KeyIdentifier.
+func GetKeyIdentity(crypter Crypter) (string, error) {
+	identifier, ok := crypter.(KeyIdentifier)
+	if !ok {
+		return crypter.Name(), nil
+	}
+	keyID, err := identifier.KeyID()
+	if err != nil {
+		return "", err
+	}
+	return crypter.Name() + ":" + keyID, nil
+}
+
+// Rewrapper is a Crypter which can take over the data encrypted by another Crypter by rewriting the header only,
+// e.g. the envelope Crypter re-wraps the data key of the object with its master key
+type Rewrapper interface {
+	Crypter
+
+	CanRewrap(old Crypter) bool
+	// Rewrap copies the data encrypted by the old Crypter from reader to writer, so it can be decrypted by this Crypter
+	Rewrap(writer io.Writer, reader io.Reader, old Crypter) error
+}
+
+type RewrapNotSupportedError struct {
+	error
+}
+
+func NewRewrapNotSupportedError(crypter, old Crypter) RewrapNotSupportedError {
+	return RewrapNotSupportedError{errors.Errorf("%s crypter can't rewrap the data encrypted by %s crypter",
+		crypter.Name(), old.Name())}
+}
+
+func (err RewrapNotSupportedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
@@ -0,0 +1,314 @@
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/minio/sio"
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	magic              = "walgenv"
+	schemeVersion byte = 1
+
+	keyLen = 32
+	// maxHeaderFieldLen is the sanity limit of the master key ID and the wrapped data key lengths
+	maxHeaderFieldLen = 1024
+)
+
+// Crypter is the envelope encryption Crypter with the local master key.
+// Every object is encrypted with the random data key, which is stored in the object header
+// wrapped by the master key, so the master key may be changed by re-wrapping the headers only.
+// The master key is read from the file or printed by the command, e.g. a secret storage CLI call.
+type Crypter struct {
+	MasterKeyPath    string
+	MasterKeyCommand string
+
+	masterKey   cipher.AEAD
+	masterKeyID string
+
+	mutex sync.RWMutex
+}
+
+func (crypter *Crypter) Name() string {
+	return "Envelope"
+}
+
+// CrypterFromMasterKeyPath creates Crypter from master key path
+func CrypterFromMasterKeyPath(path string) crypto.Crypter {
+	return &Crypter{MasterKeyPath: path}
+}
+
+// CrypterFromMasterKeyCommand creates Crypter from the command printing the master key
+func CrypterFromMasterKeyCommand(command string) crypto.Crypter {
+	return &Crypter{MasterKeyCommand: command}
+}
+
+func (crypter *Crypter) setup() error {
+	crypter.mutex.RLock()
+	if crypter.masterKey != nil {
+		crypter.mutex.RUnlock()
+		return nil
+	}
+	crypter.mutex.RUnlock()
+
+	crypter.mutex.Lock()
+	defer crypter.mutex.Unlock()
+	if crypter.masterKey != nil { // already set up
+		return nil
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case crypter.MasterKeyPath != "":
+		data, err = ioutil.ReadFile(crypter.MasterKeyPath)
+	case crypter.MasterKeyCommand != "":
+		data, err = runMasterKeyCommand(crypter.MasterKeyCommand)
+	default:
+		return errors.New("envelope Crypter must have a master key path or command")
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to load the master key")
+	}
+	key, err := decodeMasterKey(data)
+	if err != nil {
+		return err
+	}
+	crypter.masterKey, err = newAEAD(key)
+	if err != nil {
+		return err
+	}
+	checksum := sha256.Sum256(key)
+	crypter.masterKeyID = hex.EncodeToString(checksum[:8])
+	return nil
+}
+
+// KeyID returns the beginning of the SHA-256 hash of the master key
+func (crypter *Crypter) KeyID() (string, error) {
+	if err := crypter.setup(); err != nil {
+		return "", err
+	}
+	return crypter.masterKeyID, nil
+}
+
+// Encrypt creates encryption writer with a new data key from ordinary writer
+func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
+	if err := crypter.setup(); err != nil {
+		return nil, err
+	}
+
+	dataKey := make([]byte, keyLen)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, errors.Wrap(err, "can't generate data key")
+	}
+	header, err := crypter.wrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	bufferedWriter := bufio.NewWriter(writer)
+	if _, err = bufferedWriter.Write(header); err != nil {
+		return nil, err
+	}
+	encryptedWriter, err := sio.EncryptWriter(bufferedWriter, sio.Config{Key: dataKey, CipherSuites: []byte{sio.AES_256_GCM}})
+	if err != nil {
+		return nil, errors.Wrap(err, "envelope can't create encrypted writer")
+	}
+	return ioextensions.NewOnCloseFlusher(encryptedWriter, bufferedWriter), nil
+}
+
+// Decrypt creates decrypted reader from ordinary reader
+func (crypter *Crypter) Decrypt(reader io.Reader) (io.Reader, error) {
+	if err := crypter.setup(); err != nil {
+		return nil, err
+	}
+
+	dataKey, err := crypter.unwrap(reader)
+	if err != nil {
+		return nil, err
+	}
+	return sio.DecryptReader(reader, sio.Config{Key: dataKey, CipherSuites: []byte{sio.AES_256_GCM}})
+}
+
+// CanRewrap is true if the old Crypter is envelope Crypter too
+func (crypter *Crypter) CanRewrap(old crypto.Crypter) bool {
+	_, ok := old.(*Crypter)
+	return ok
+}
+
+// Rewrap copies the encrypted object, the data key in its header is unwrapped by the old master key
+// and wrapped by the current one
+func (crypter *Crypter) Rewrap(writer io.Writer, reader io.Reader, old crypto.Crypter) error {
+	oldCrypter, ok := old.(*Crypter)
+	if !ok {
+		return crypto.NewRewrapNotSupportedError(crypter, old)
+	}
+	if err := oldCrypter.setup(); err != nil {
+		return err
+	}
+	if err := crypter.setup(); err != nil {
+		return err
+	}
+
+	dataKey, err := oldCrypter.unwrap(reader)
+	if err != nil {
+		return err
+	}
+	header, err := crypter.wrap(dataKey)
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(header); err != nil {
+		return err
+	}
+	_, err = utility.FastCopy(writer, reader)
+	return err
+}
+
+func (crypter *Crypter) wrap(dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, crypter.masterKey.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "can't generate nonce")
+	}
+	wrappedKey := crypter.masterKey.Seal(nonce, nonce, dataKey, []byte(crypter.masterKeyID))
+	return serializeHeader(crypter.masterKeyID, wrappedKey), nil
+}
+
+func (crypter *Crypter) unwrap(reader io.Reader) ([]byte, error) {
+	masterKeyID, wrappedKey, err := deserializeHeader(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read data key from archive file header")
+	}
+	if masterKeyID != crypter.masterKeyID {
+		return nil, errors.Errorf("the data key is wrapped by master key %s, but master key %s is configured",
+			masterKeyID, crypter.masterKeyID)
+	}
+	nonceSize := crypter.masterKey.NonceSize()
+	if len(wrappedKey) < nonceSize {
+		return nil, errors.New("envelope: invalid wrapped data key")
+	}
+	dataKey, err := crypter.masterKey.Open(nil, wrappedKey[:nonceSize], wrappedKey[nonceSize:], []byte(masterKeyID))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't unwrap data key")
+	}
+	return dataKey, nil
+}
+
+func serializeHeader(masterKeyID string, wrappedKey []byte) []byte {
+	/*
+		magic value "walgenv"
+		scheme version (current version is 1)
+		uint16 - master key ID len
+		master key ID ...
+		uint16 - wrapped data key len
+		wrapped data key: nonce and AES-GCM sealed data key ...
+	*/
+	header := append([]byte(magic), schemeVersion)
+	header = appendField(header, []byte(masterKeyID))
+	return appendField(header, wrappedKey)
+}
+
+func appendField(header []byte, field []byte) []byte {
+	fieldLen := make([]byte, 2)
+	binary.BigEndian.PutUint16(fieldLen, uint16(len(field)))
+	header = append(header, fieldLen...)
+	return append(header, field...)
+}
+
+func deserializeHeader(reader io.Reader) (string, []byte, error) {
+	magicSchemeBytes := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(reader, magicSchemeBytes); err != nil {
+		return "", nil, err
+	}
+	if string(magicSchemeBytes[:len(magic)]) != magic {
+		return "", nil, errors.New("envelope: invalid encrypted header format")
+	}
+	if magicSchemeBytes[len(magic)] != schemeVersion {
+		return "", nil, errors.New("envelope: scheme version is not supported")
+	}
+	masterKeyID, err := readField(reader)
+	if err != nil {
+		return "", nil, err
+	}
+	wrappedKey, err := readField(reader)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(masterKeyID), wrappedKey, nil
+}
+
+func readField(reader io.Reader) ([]byte, error) {
+	fieldLenBytes := make([]byte, 2)
+	if _, err := io.ReadFull(reader, fieldLenBytes); err != nil {
+		return nil, err
+	}
+	fieldLen := binary.BigEndian.Uint16(fieldLenBytes)
+	if fieldLen > maxHeaderFieldLen {
+		return nil, errors.New("envelope: invalid size of the header field")
+	}
+	field := make([]byte, fieldLen)
+	if _, err := io.ReadFull(reader, field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+// decodeMasterKey accepts the 32 bytes key as is, in hex or in base64
+func decodeMasterKey(data []byte) ([]byte, error) {
+	if len(data) == keyLen {
+		return data, nil
+	}
+	text := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == keyLen {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == keyLen {
+		return key, nil
+	}
+	return nil, errors.Errorf("the master key should be %d bytes, as is, in hex or in base64", keyLen)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return aead, nil
+}
+
+func runMasterKeyCommand(command string) ([]byte, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell, "-c", command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "master key command failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
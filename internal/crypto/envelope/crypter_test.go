@@ -0,0 +1,95 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/crypto/keyring"
+)
+
+const someSecret = "so very secret thingy"
+
+func writeMasterKey(t *testing.T, dir, name string, fill byte) string {
+	keyPath := filepath.Join(dir, name)
+	key := bytes.Repeat([]byte{fill}, keyLen)
+	assert.NoError(t, ioutil.WriteFile(keyPath, []byte(hex.EncodeToString(key)+"\n"), 0600))
+	return keyPath
+}
+
+func encrypt(t *testing.T, crypter crypto.Crypter) []byte {
+	buf := new(bytes.Buffer)
+	encrypt, err := crypter.Encrypt(buf)
+	assert.NoErrorf(t, err, "Encryption error: %v", err)
+	_, err = encrypt.Write([]byte(someSecret))
+	assert.NoError(t, err)
+	assert.NoError(t, encrypt.Close())
+	return buf.Bytes()
+}
+
+func decrypt(crypter crypto.Crypter, encrypted []byte) (string, error) {
+	decrypt, err := crypter.Decrypt(bytes.NewReader(encrypted))
+	if err != nil {
+		return "", err
+	}
+	decryptedBytes, err := ioutil.ReadAll(decrypt)
+	return string(decryptedBytes), err
+}
+
+func TestEncryptionCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envelope")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	crypter := CrypterFromMasterKeyPath(writeMasterKey(t, dir, "master", 1))
+
+	first, second := encrypt(t, crypter), encrypt(t, crypter)
+	// every object has its own data key
+	assert.NotEqual(t, first, second)
+	decrypted, err := decrypt(crypter, first)
+	assert.NoError(t, err)
+	assert.Equal(t, someSecret, decrypted)
+
+	_, err = decrypt(CrypterFromMasterKeyPath(writeMasterKey(t, dir, "other", 2)), first)
+	assert.Error(t, err)
+}
+
+func TestMasterKeyCommand(t *testing.T) {
+	crypter := CrypterFromMasterKeyCommand("echo " + strings.Repeat("ab", keyLen))
+	decrypted, err := decrypt(crypter, encrypt(t, crypter))
+	assert.NoError(t, err)
+	assert.Equal(t, someSecret, decrypted)
+
+	_, err = CrypterFromMasterKeyCommand("echo short").Encrypt(new(bytes.Buffer))
+	assert.Error(t, err)
+	_, err = CrypterFromMasterKeyCommand("exit 1").Encrypt(new(bytes.Buffer))
+	assert.Error(t, err)
+}
+
+func TestRewrap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envelope")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	oldCrypter := keyring.CrypterFromKeys(CrypterFromMasterKeyPath(writeMasterKey(t, dir, "old", 1)), nil, true)
+	newCrypter := keyring.CrypterFromKeys(CrypterFromMasterKeyPath(writeMasterKey(t, dir, "new", 2)), nil, true)
+	encrypted := encrypt(t, oldCrypter)
+
+	rewrapper, ok := newCrypter.(crypto.Rewrapper)
+	assert.True(t, ok)
+	assert.True(t, rewrapper.CanRewrap(oldCrypter))
+	var rewrapped bytes.Buffer
+	assert.NoError(t, rewrapper.Rewrap(&rewrapped, bytes.NewReader(encrypted), oldCrypter))
+
+	decrypted, err := decrypt(newCrypter, rewrapped.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, someSecret, decrypted)
+	_, err = decrypt(oldCrypter, rewrapped.Bytes())
+	assert.Error(t, err)
+	// the data encrypted by the data key is kept as is
+	assert.True(t, bytes.HasSuffix(rewrapped.Bytes(), encrypted[len(encrypted)-len(someSecret):]))
+}
@@ -0,0 +1,253 @@
+package keyring
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/crypto"
+)
+
+const (
+	// headerMagic starts the key header, the version is its last byte
+	headerMagic = "WALGKEY\x01"
+	// maxIdentityLen bounds the key identity stored in the header
+	maxIdentityLen = 1024
+	// trialBufferLimit bounds the beginning of the object kept to try the next key
+	// when the previous one has failed to decrypt it
+	trialBufferLimit = 1 << 20
+)
+
+// Crypter encrypts with the current key and decrypts with the current or one of the previous keys.
+// If the header is enabled, Encrypt writes the identity of the current key before the encrypted data,
+// so Decrypt picks the right key at once. The objects without the header are decrypted by trying the keys in order.
+type Crypter struct {
+	current     crypto.Crypter
+	previous    []crypto.Crypter
+	writeHeader bool
+
+	identities map[crypto.Crypter]string
+	mutex      sync.Mutex
+}
+
+// CrypterFromKeys creates Crypter from the current key and the previous keys, in the order they are tried
+func CrypterFromKeys(current crypto.Crypter, previous []crypto.Crypter, writeHeader bool) crypto.Crypter {
+	return &Crypter{
+		current:     current,
+		previous:    previous,
+		writeHeader: writeHeader,
+		identities:  make(map[crypto.Crypter]string),
+	}
+}
+
+func (crypter *Crypter) Name() string {
+	return crypter.current.Name()
+}
+
+// KeyID identifies the current key
+func (crypter *Crypter) KeyID() (string, error) {
+	identifier, ok := crypter.current.(crypto.KeyIdentifier)
+	if !ok {
+		return "", errors.Errorf("%s crypter can't identify its key", crypter.current.Name())
+	}
+	return identifier.KeyID()
+}
+
+// Encrypt creates encryption writer with the current key from ordinary writer
+func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
+	if err := crypter.writeKeyHeader(writer); err != nil {
+		return nil, err
+	}
+	return crypter.current.Encrypt(writer)
+}
+
+// CanRewrap is true if the current key can rewrap the data of the old key
+func (crypter *Crypter) CanRewrap(old crypto.Crypter) bool {
+	rewrapper, ok := crypter.current.(crypto.Rewrapper)
+	if oldKeyring, isKeyring := old.(*Crypter); isKeyring {
+		old = oldKeyring.current
+	}
+	return ok && rewrapper.CanRewrap(old)
+}
+
+// Rewrap rewraps the data of the old key with the current key, the key header is rewritten too
+func (crypter *Crypter) Rewrap(writer io.Writer, reader io.Reader, old crypto.Crypter) error {
+	rewrapper, ok := crypter.current.(crypto.Rewrapper)
+	if !ok {
+		return crypto.NewRewrapNotSupportedError(crypter, old)
+	}
+	bufferedReader := bufio.NewReaderSize(reader, len(headerMagic))
+	magic, err := bufferedReader.Peek(len(headerMagic))
+	if err != nil && err != io.EOF {
+		return err
+	}
+	oldKeyring, isKeyring := old.(*Crypter)
+	if isKeyring {
+		old = oldKeyring.current
+	}
+	if string(magic) == headerMagic {
+		identity, err := readHeader(bufferedReader)
+		if err != nil {
+			return err
+		}
+		if isKeyring {
+			candidates := oldKeyring.findByIdentity(identity)
+			if len(candidates) == 0 {
+				return errors.Errorf("the object is encrypted with key %s, which is not in the keyring", identity)
+			}
+			old = candidates[0]
+		}
+	}
+	if err = crypter.writeKeyHeader(writer); err != nil {
+		return err
+	}
+	return rewrapper.Rewrap(writer, bufferedReader, old)
+}
+
+func (crypter *Crypter) writeKeyHeader(writer io.Writer) error {
+	if !crypter.writeHeader {
+		return nil
+	}
+	identity, err := crypter.identity(crypter.current)
+	if err != nil {
+		return errors.Wrap(err, "failed to identify the encryption key for the header")
+	}
+	header := make([]byte, 0, len(headerMagic)+2+len(identity))
+	header = append(header, headerMagic...)
+	header = append(header, byte(len(identity)>>8), byte(len(identity)))
+	header = append(header, identity...)
+	_, err = writer.Write(header)
+	return err
+}
+
+// Decrypt creates decrypted reader from ordinary reader with the key named in the header,
+// or with the first of the keys able to decrypt it if there is no header
+func (crypter *Crypter) Decrypt(reader io.Reader) (io.Reader, error) {
+	bufferedReader := bufio.NewReaderSize(reader, len(headerMagic))
+	magic, err := bufferedReader.Peek(len(headerMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	candidates := crypter.all()
+	if string(magic) == headerMagic {
+		identity, err := readHeader(bufferedReader)
+		if err != nil {
+			return nil, err
+		}
+		candidates = crypter.findByIdentity(identity)
+		if len(candidates) == 0 {
+			return nil, errors.Errorf("the object is encrypted with key %s, which is not in the keyring", identity)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0].Decrypt(bufferedReader)
+	}
+	return tryDecrypt(candidates, bufferedReader)
+}
+
+func (crypter *Crypter) all() []crypto.Crypter {
+	return append([]crypto.Crypter{crypter.current}, crypter.previous...)
+}
+
+func (crypter *Crypter) findByIdentity(identity string) []crypto.Crypter {
+	candidates := make([]crypto.Crypter, 0, 1)
+	for _, candidate := range crypter.all() {
+		candidateIdentity, err := crypter.identity(candidate)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to identify %s key of the keyring: %v\n", candidate.Name(), err)
+			continue
+		}
+		if candidateIdentity == identity {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+func (crypter *Crypter) identity(key crypto.Crypter) (string, error) {
+	crypter.mutex.Lock()
+	defer crypter.mutex.Unlock()
+	if identity, ok := crypter.identities[key]; ok {
+		return identity, nil
+	}
+	identity, err := crypto.GetKeyIdentity(key)
+	if err != nil {
+		return "", err
+	}
+	if len(identity) > maxIdentityLen {
+		return "", errors.Errorf("the key identity is longer than %d bytes", maxIdentityLen)
+	}
+	crypter.identities[key] = identity
+	return identity, nil
+}
+
+func readHeader(reader io.Reader) (string, error) {
+	header := make([]byte, len(headerMagic)+2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return "", errors.Wrap(err, "failed to read the key header")
+	}
+	identityLen := binary.BigEndian.Uint16(header[len(headerMagic):])
+	if identityLen > maxIdentityLen {
+		return "", errors.New("invalid key header")
+	}
+	identity := make([]byte, identityLen)
+	if _, err := io.ReadFull(reader, identity); err != nil {
+		return "", errors.Wrap(err, "failed to read the key header")
+	}
+	return string(identity), nil
+}
+
+// tryDecrypt decrypts the reader with the first of the keys which can decrypt the beginning of it.
+// The beginning read by the failed keys is replayed to the next one.
+func tryDecrypt(candidates []crypto.Crypter, reader io.Reader) (io.Reader, error) {
+	recorder := &recordingReader{reader: reader, recording: true}
+	var lastErr error
+	for _, candidate := range candidates {
+		replayReader := io.MultiReader(bytes.NewReader(recorder.recorded.Bytes()), recorder)
+		decrypted, err := candidate.Decrypt(replayReader)
+		var first [1]byte
+		var n int
+		if err == nil {
+			n, err = io.ReadFull(decrypted, first[:])
+			if err == io.EOF {
+				err = nil
+			}
+		}
+		if err == nil {
+			recorder.recording = false
+			return io.MultiReader(bytes.NewReader(first[:n]), decrypted), nil
+		}
+		tracelog.DebugLogger.Printf("%s key of the keyring can't decrypt the object: %v\n", candidate.Name(), err)
+		lastErr = err
+		if recorder.overflown {
+			return nil, errors.Wrapf(err, "the key failed after reading more than %d bytes, the next key can't be tried",
+				trialBufferLimit)
+		}
+	}
+	return nil, errors.Wrapf(lastErr, "none of the %d keys of the keyring can decrypt the object", len(candidates))
+}
+
+// recordingReader keeps the read data, until it's too much, to replay it for the next key
+type recordingReader struct {
+	reader    io.Reader
+	recorded  bytes.Buffer
+	recording bool
+	overflown bool
+}
+
+func (reader *recordingReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	if reader.recording && n > 0 {
+		if reader.recorded.Len()+n > trialBufferLimit {
+			reader.recording = false
+			reader.overflown = true
+		} else {
+			reader.recorded.Write(p[:n])
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,117 @@
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/crypto"
+)
+
+// testCrypter "encrypts" the data by prepending its key and counts the decryption attempts
+type testCrypter struct {
+	key      string
+	attempts int
+}
+
+func (crypter *testCrypter) Name() string {
+	return "Test"
+}
+
+func (crypter *testCrypter) KeyID() (string, error) {
+	return crypter.key, nil
+}
+
+func (crypter *testCrypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
+	if _, err := io.WriteString(writer, crypter.key); err != nil {
+		return nil, err
+	}
+	return nopWriteCloser{writer}, nil
+}
+
+func (crypter *testCrypter) Decrypt(reader io.Reader) (io.Reader, error) {
+	crypter.attempts++
+	key := make([]byte, len(crypter.key))
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+	if string(key) != crypter.key {
+		return nil, errors.New("wrong key")
+	}
+	return reader, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func encrypt(t *testing.T, crypter crypto.Crypter, data string) []byte {
+	var buffer bytes.Buffer
+	writer, err := crypter.Encrypt(&buffer)
+	assert.NoError(t, err)
+	_, err = io.WriteString(writer, data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func decrypt(crypter crypto.Crypter, data []byte) (string, error) {
+	reader, err := crypter.Decrypt(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := ioutil.ReadAll(reader)
+	return string(decrypted), err
+}
+
+func TestDecryptByHeader(t *testing.T) {
+	oldKey, newKey := &testCrypter{key: "old"}, &testCrypter{key: "new"}
+	encrypted := encrypt(t, CrypterFromKeys(oldKey, nil, true), "secret")
+	assert.True(t, bytes.HasPrefix(encrypted, []byte(headerMagic)))
+
+	decrypted, err := decrypt(CrypterFromKeys(newKey, []crypto.Crypter{oldKey}, true), encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", decrypted)
+	assert.Equal(t, 0, newKey.attempts)
+
+	_, err = decrypt(CrypterFromKeys(newKey, nil, true), encrypted)
+	assert.Error(t, err)
+}
+
+func TestDecryptByTrial(t *testing.T) {
+	oldKey, ancientKey, newKey := &testCrypter{key: "old"}, &testCrypter{key: "ancient"}, &testCrypter{key: "new"}
+	encrypted := encrypt(t, ancientKey, "secret")
+
+	keys := CrypterFromKeys(newKey, []crypto.Crypter{oldKey, ancientKey}, false)
+	decrypted, err := decrypt(keys, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", decrypted)
+	assert.Equal(t, 1, newKey.attempts)
+	assert.Equal(t, 1, oldKey.attempts)
+
+	_, err = decrypt(CrypterFromKeys(newKey, []crypto.Crypter{oldKey}, false), encrypted)
+	assert.Error(t, err)
+}
+
+func TestEncryptWithoutHeader(t *testing.T) {
+	key := &testCrypter{key: "key"}
+	encrypted := encrypt(t, CrypterFromKeys(key, nil, false), "secret")
+	assert.Equal(t, []byte("keysecret"), encrypted)
+
+	decrypted, err := decrypt(CrypterFromKeys(key, nil, false), encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", decrypted)
+}
+
+func TestKeyIdentity(t *testing.T) {
+	identity, err := crypto.GetKeyIdentity(CrypterFromKeys(&testCrypter{key: "new"}, []crypto.Crypter{&testCrypter{key: "old"}}, false))
+	assert.NoError(t, err)
+	assert.Equal(t, "Test:new", identity)
+}
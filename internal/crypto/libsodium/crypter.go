@@ -6,6 +6,8 @@ package libsodium
 import "C"
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"io/ioutil"
 	"strings"
@@ -81,6 +83,15 @@ func (crypter *Crypter) setup() (err error) {
 	return nil
 }
 
+// KeyID returns the beginning of the SHA-256 hash of the key
+func (crypter *Crypter) KeyID() (string, error) {
+	if err := crypter.setup(); err != nil {
+		return "", err
+	}
+	checksum := sha256.Sum256([]byte(crypter.Key))
+	return hex.EncodeToString(checksum[:8]), nil
+}
+
 // Encrypt creates encryption writer from ordinary writer
 func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 	if err := crypter.setup(); err != nil {
@@ -107,6 +107,17 @@ func (crypter *Crypter) setupPubKey() error {
 	return nil
 }
 
+// KeyID returns the ID of the primary key, the public key is enough to identify it
+func (crypter *Crypter) KeyID() (string, error) {
+	if err := crypter.setupPubKey(); err != nil {
+		return "", err
+	}
+	if len(crypter.PubKey) == 0 {
+		return "", errors.New("openpgp key ring is empty")
+	}
+	return crypter.PubKey[0].PrimaryKey.KeyIdString(), nil
+}
+
 // Encrypt creates encryption writer from ordinary writer
 func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 	err := crypter.setupPubKey()
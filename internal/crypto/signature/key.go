@@ -0,0 +1,180 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	HMACAlgorithm    = "hmac-sha256"
+	Ed25519Algorithm = "ed25519"
+)
+
+var ErrCantSign = errors.New("the signature key can only verify, the private key is required to sign")
+
+// Key signs the data and verifies the signatures
+type Key interface {
+	Algorithm() string
+	// KeyID identifies the key without revealing it
+	KeyID() string
+	Sign(data []byte) ([]byte, error)
+	Verify(data []byte, signature []byte) bool
+}
+
+type hmacKey struct {
+	secret []byte
+}
+
+// NewHMACKey creates the key signing with HMAC-SHA256 of the secret
+func NewHMACKey(secret []byte) Key {
+	return &hmacKey{secret: secret}
+}
+
+func (key *hmacKey) Algorithm() string {
+	return HMACAlgorithm
+}
+
+func (key *hmacKey) KeyID() string {
+	// the MAC of the constant string, since the hash of the secret itself would allow the offline guessing
+	return hex.EncodeToString(key.mac([]byte("wal-g key id"))[:8])
+}
+
+func (key *hmacKey) Sign(data []byte) ([]byte, error) {
+	return key.mac(data), nil
+}
+
+func (key *hmacKey) Verify(data []byte, signature []byte) bool {
+	return hmac.Equal(key.mac(data), signature)
+}
+
+func (key *hmacKey) mac(data []byte) []byte {
+	mac := hmac.New(sha256.New, key.secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+type ed25519Key struct {
+	public  ed25519.PublicKey
+	private ed25519.PrivateKey
+}
+
+// NewEd25519Key creates the key signing with the Ed25519 private key
+func NewEd25519Key(private ed25519.PrivateKey) Key {
+	return &ed25519Key{public: private.Public().(ed25519.PublicKey), private: private}
+}
+
+// NewEd25519PublicKey creates the key which only verifies the Ed25519 signatures
+func NewEd25519PublicKey(public ed25519.PublicKey) Key {
+	return &ed25519Key{public: public}
+}
+
+func (key *ed25519Key) Algorithm() string {
+	return Ed25519Algorithm
+}
+
+func (key *ed25519Key) KeyID() string {
+	checksum := sha256.Sum256(key.public)
+	return hex.EncodeToString(checksum[:8])
+}
+
+func (key *ed25519Key) Sign(data []byte) ([]byte, error) {
+	if key.private == nil {
+		return nil, ErrCantSign
+	}
+	return ed25519.Sign(key.private, data), nil
+}
+
+func (key *ed25519Key) Verify(data []byte, signature []byte) bool {
+	return ed25519.Verify(key.public, data, signature)
+}
+
+// LoadHMACKey reads the HMAC secret from the file, the secret is used as is
+func LoadHMACKey(path string) (Key, error) {
+	secret, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the HMAC signature key")
+	}
+	secret = []byte(strings.TrimSpace(string(secret)))
+	if len(secret) < sha256.Size {
+		return nil, errors.Errorf("the HMAC signature key should be at least %d bytes", sha256.Size)
+	}
+	return NewHMACKey(secret), nil
+}
+
+// LoadEd25519PrivateKey reads the Ed25519 private key from the file, in PEM (PKCS #8),
+// or the private key or its seed in hex or in base64
+func LoadEd25519PrivateKey(path string) (Key, error) {
+	key, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(key); block != nil {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the Ed25519 private key")
+		}
+		private, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("the signature private key is not an Ed25519 key")
+		}
+		return NewEd25519Key(private), nil
+	}
+	switch len(key) {
+	case ed25519.SeedSize:
+		return NewEd25519Key(ed25519.NewKeyFromSeed(key)), nil
+	case ed25519.PrivateKeySize:
+		return NewEd25519Key(key), nil
+	}
+	return nil, errors.Errorf("the Ed25519 private key should be %d bytes or the %d bytes seed",
+		ed25519.PrivateKeySize, ed25519.SeedSize)
+}
+
+// LoadEd25519PublicKey reads the Ed25519 public key from the file, in PEM (PKIX), in hex or in base64
+func LoadEd25519PublicKey(path string) (Key, error) {
+	key, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(key); block != nil {
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the Ed25519 public key")
+		}
+		public, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("the signature public key is not an Ed25519 key")
+		}
+		return NewEd25519PublicKey(public), nil
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("the Ed25519 public key should be %d bytes", ed25519.PublicKeySize)
+	}
+	return NewEd25519PublicKey(key), nil
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the signature key")
+	}
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, "-----BEGIN") {
+		return []byte(text), nil
+	}
+	if key, err := hex.DecodeString(text); err == nil {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil {
+		return key, nil
+	}
+	return nil, errors.Errorf("the signature key in '%s' should be in PEM, in hex or in base64", path)
+}
@@ -0,0 +1,93 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACKey(t *testing.T) {
+	key := NewHMACKey([]byte(strings.Repeat("s", 32)))
+	signature, err := key.Sign([]byte("data"))
+	assert.NoError(t, err)
+	assert.True(t, key.Verify([]byte("data"), signature))
+	assert.False(t, key.Verify([]byte("altered data"), signature))
+	assert.False(t, NewHMACKey([]byte(strings.Repeat("o", 32))).Verify([]byte("data"), signature))
+	assert.NotEqual(t, key.KeyID(), NewHMACKey([]byte(strings.Repeat("o", 32))).KeyID())
+}
+
+func TestEd25519Key(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	key := NewEd25519Key(private)
+	signature, err := key.Sign([]byte("data"))
+	assert.NoError(t, err)
+
+	publicKey := NewEd25519PublicKey(public)
+	assert.Equal(t, key.KeyID(), publicKey.KeyID())
+	assert.True(t, publicKey.Verify([]byte("data"), signature))
+	assert.False(t, publicKey.Verify([]byte("altered data"), signature))
+	_, err = publicKey.Sign([]byte("data"))
+	assert.Equal(t, ErrCantSign, err)
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signature")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	privatePath := filepath.Join(dir, "private")
+	require.NoError(t, ioutil.WriteFile(privatePath, []byte(hex.EncodeToString(private.Seed())+"\n"), 0600))
+	publicPath := filepath.Join(dir, "public")
+	require.NoError(t, ioutil.WriteFile(publicPath, []byte(base64.StdEncoding.EncodeToString(public)), 0600))
+	hmacPath := filepath.Join(dir, "hmac")
+	require.NoError(t, ioutil.WriteFile(hmacPath, []byte("short"), 0600))
+
+	privateKey, err := LoadEd25519PrivateKey(privatePath)
+	require.NoError(t, err)
+	publicKey, err := LoadEd25519PublicKey(publicPath)
+	require.NoError(t, err)
+	signature, err := privateKey.Sign([]byte("data"))
+	assert.NoError(t, err)
+	assert.True(t, publicKey.Verify([]byte("data"), signature))
+
+	_, err = LoadEd25519PublicKey(hmacPath)
+	assert.Error(t, err)
+	_, err = LoadHMACKey(hmacPath)
+	assert.Error(t, err)
+}
+
+func TestLoadPEMKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signature")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	privateDer, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDer, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	privatePath := filepath.Join(dir, "private.pem")
+	require.NoError(t, ioutil.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer}), 0600))
+	publicPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, ioutil.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}), 0600))
+
+	privateKey, err := LoadEd25519PrivateKey(privatePath)
+	require.NoError(t, err)
+	publicKey, err := LoadEd25519PublicKey(publicPath)
+	require.NoError(t, err)
+	assert.Equal(t, privateKey.KeyID(), publicKey.KeyID())
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/minio/sio"
+	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/ioextensions"
@@ -20,6 +21,15 @@ func (crypter *YcCrypter) Name() string {
 	return "YcKMC/Crypter"
 }
 
+// KeyID returns the ID of the KMS key encrypting the symmetric keys
+func (crypter *YcCrypter) KeyID() (string, error) {
+	key, ok := crypter.symmetricKey.(*ycSymmetricKey)
+	if !ok {
+		return "", errors.New("YC KMS key ID is unknown")
+	}
+	return key.keyID, nil
+}
+
 func (crypter *YcCrypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 	if crypter.symmetricKey.GetKey() == nil {
 		err := crypter.symmetricKey.CreateKey()
@@ -47,10 +57,14 @@ func (crypter *YcCrypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 
 func (crypter *YcCrypter) Decrypt(reader io.Reader) (io.Reader, error) {
 	err := crypter.symmetricKey.ReadEncryptedKey(reader)
-	tracelog.ErrorLogger.FatalfOnError("Can't read encryption key from archive file header: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read encryption key from archive file header")
+	}
 
 	err = crypter.symmetricKey.Decrypt()
-	tracelog.ErrorLogger.FatalfOnError("Can't decrypt data encryption key from archive file header: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't decrypt data encryption key from archive file header")
+	}
 
 	return sio.DecryptReader(reader, sio.Config{Key: crypter.symmetricKey.GetKey(), CipherSuites: []byte{sio.AES_256_GCM}})
 }
@@ -49,7 +49,7 @@ func serializeEncryptedKey(encryptedKey []byte) []byte {
 
 func deserializeEncryptedKey(r io.Reader) ([]byte, error) {
 	magicSchemeBytes := make([]byte, len(magic)+1)
-	_, err := r.Read(magicSchemeBytes)
+	_, err := io.ReadFull(r, magicSchemeBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +63,7 @@ func deserializeEncryptedKey(r io.Reader) ([]byte, error) {
 	}
 
 	encryptedKeyLenBytes := make([]byte, 4)
-	_, err = r.Read(encryptedKeyLenBytes)
+	_, err = io.ReadFull(r, encryptedKeyLenBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +77,7 @@ func deserializeEncryptedKey(r io.Reader) ([]byte, error) {
 	}
 
 	encryptedKey := make([]byte, encryptedKeyLen)
-	_, err = r.Read(encryptedKey)
+	_, err = io.ReadFull(r, encryptedKey)
 	if err != nil {
 		return nil, err
 	}
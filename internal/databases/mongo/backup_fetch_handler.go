@@ -14,5 +14,11 @@ func HandleBackupFetch(ctx context.Context, folder storage.Folder, backupName st
 	if err != nil {
 		return err
 	}
-	return internal.StreamBackupToCommandStdin(restoreCmd, backup)
+	if err = internal.CheckBackupSignature(backup); err != nil {
+		return err
+	}
+	if _, backup, err = internal.VerifySignedObjects(folder, backup); err != nil {
+		return err
+	}
+	return internal.StreamBackupToCommandStdinWithContext(ctx, restoreCmd, backup)
 }
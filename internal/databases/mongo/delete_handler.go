@@ -12,6 +12,7 @@ import (
 type PurgeSettings struct {
 	retainCount  *int
 	retainAfter  *time.Time
+	retainGFS    *internal.GFSPolicy
 	purgeOplog   bool
 	purgeGarbage bool
 	dryRun       bool
@@ -33,6 +34,13 @@ func PurgeRetainCount(retainCount int) PurgeOption {
 	}
 }
 
+// PurgeRetainGFS keeps the backups by the GFS policy in addition to the retain count and time
+func PurgeRetainGFS(policy internal.GFSPolicy) PurgeOption {
+	return func(args *PurgeSettings) {
+		args.retainGFS = &policy
+	}
+}
+
 // PurgeOplog ...
 func PurgeOplog(purgeOplog bool) PurgeOption {
 	return func(args *PurgeSettings) {
@@ -113,6 +121,13 @@ func HandleBackupsPurge(backupTimes []internal.BackupTime,
 	if err != nil {
 		return nil, nil, err
 	}
+	if opts.retainGFS != nil {
+		_, retainGFSBackups := internal.SplitPurgingBackupsGFS(timedBackups, *opts.retainGFS)
+		for name := range retainGFSBackups {
+			delete(purgeBackups, name)
+			retainBackups[name] = true
+		}
+	}
 
 	purge, retain = archive.SplitMongoBackups(backups, purgeBackups, retainBackups)
 	tracelog.InfoLogger.Printf("Backups selected to be deleted: %v", archive.BackupNamesFromBackups(purge))
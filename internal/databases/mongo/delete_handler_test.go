@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	mocks "github.com/wal-g/wal-g/internal/databases/mongo/archive/mocks"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+// makeDailyBackups makes the daily backups starting from Wednesday 2020-01-01
+func makeDailyBackups(days int) ([]internal.BackupTime, []models.Backup) {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	backupTimes := make([]internal.BackupTime, days)
+	backups := make([]models.Backup, days)
+	for i := 0; i < days; i++ {
+		startTime := start.AddDate(0, 0, i)
+		name := fmt.Sprintf("stream_%s", startTime.Format("20060102T150405Z"))
+		backupTimes[i] = internal.BackupTime{BackupName: name, Time: startTime}
+		backups[i] = models.Backup{BackupName: name, StartLocalTime: startTime, FinishLocalTime: startTime.Add(time.Hour)}
+	}
+	return backupTimes, backups
+}
+
+func TestHandleBackupsPurge_RetainGFS(t *testing.T) {
+	backupTimes, backups := makeDailyBackups(40)
+	backups[1].Permanent = true
+	downloader := &mocks.Downloader{}
+	downloader.On("LoadBackups", mock.Anything).Return(backups, nil).Once()
+	purger := &mocks.Purger{}
+	purger.On("DeleteBackups", mock.Anything).Return(nil).Once()
+
+	opts := PurgeSettings{}
+	PurgeRetainGFS(internal.GFSPolicy{Daily: 2, Weekly: 2, Monthly: 2})(&opts)
+	PurgeRetainCount(1)(&opts)
+	purge, retain, err := HandleBackupsPurge(backupTimes, downloader, purger, opts)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"stream_20200102T120000Z", // permanent
+		"stream_20200131T120000Z", // the latest in January
+		"stream_20200202T120000Z", // the latest in the week before
+		"stream_20200208T120000Z", // the day before
+		"stream_20200209T120000Z", // the latest one, also kept by the retain count
+	}, archive.BackupNamesFromBackups(retain))
+	assert.Len(t, purge, len(backups)-len(retain))
+	purger.AssertCalled(t, "DeleteBackups", purge)
+}
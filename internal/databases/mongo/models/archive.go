@@ -9,7 +9,7 @@ import (
 
 // Archive path constants.
 const (
-	OplogArchBasePath   = "oplog_" + utility.VersionStr + "/"
+	OplogArchBasePath   = utility.OplogPath
 	ArchNameTSDelimiter = "_"
 	ArchiveTypeOplog    = "oplog"
 	ArchiveTypeGap      = "gap"
@@ -52,6 +52,8 @@ func NewBackupDetail(backupTime internal.BackupTime, sentinel StreamSentinelDto)
 func HandleDetailedBackupList(folder storage.Folder, pretty, json bool) {
 	backupTimes, err := internal.GetBackups(folder)
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch list of backups in storage: %s", err)
+	backupTimes, err = internal.FilterSignedBackups(folder, backupTimes)
+	tracelog.ErrorLogger.FatalOnError(err)
 
 	backupDetails := make([]BackupDetail, 0, len(backupTimes))
 	for _, backupTime := range backupTimes {
@@ -40,6 +40,8 @@ func (mf GenericMetaFetcher) Fetch(backupName string, backupFolder storage.Folde
 		StartTime:        sentinel.StartLocalTime,
 		FinishTime:       sentinel.StopLocalTime,
 		IsPermanent:      sentinel.IsPermanent,
+		StorageClass:     sentinel.StorageClass,
+		EncryptionKey:    sentinel.EncryptionKey,
 		IncrementDetails: &internal.NopIncrementDetailsFetcher{},
 		UserData:         sentinel.UserData,
 	}, nil
@@ -67,6 +69,22 @@ func (ms GenericMetaSetter) SetIsPermanent(backupName string, backupFolder stora
 	return modifyBackupSentinel(backupName, backupFolder, modifier)
 }
 
+func (ms GenericMetaSetter) SetStorageClass(backupName string, backupFolder storage.Folder, storageClass string) error {
+	modifier := func(dto StreamSentinelDto) StreamSentinelDto {
+		dto.StorageClass = storageClass
+		return dto
+	}
+	return modifyBackupSentinel(backupName, backupFolder, modifier)
+}
+
+func (ms GenericMetaSetter) SetEncryptionKey(backupName string, backupFolder storage.Folder, encryptionKey string) error {
+	modifier := func(dto StreamSentinelDto) StreamSentinelDto {
+		dto.EncryptionKey = encryptionKey
+		return dto
+	}
+	return modifyBackupSentinel(backupName, backupFolder, modifier)
+}
+
 func modifyBackupSentinel(backupName string, backupFolder storage.Folder, modifier func(StreamSentinelDto) StreamSentinelDto) error {
 	backup := internal.NewBackup(backupFolder, backupName)
 	var sentinel StreamSentinelDto
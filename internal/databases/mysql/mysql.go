@@ -19,7 +19,7 @@ import (
 	"github.com/wal-g/wal-g/utility"
 )
 
-const BinlogPath = "binlog_" + utility.VersionStr + "/"
+const BinlogPath = utility.BinlogPath
 
 const TimeMysqlFormat = "2006-01-02 15:04:05"
 
@@ -145,8 +145,10 @@ type StreamSentinelDto struct {
 	CompressedSize   int64  `json:"CompressedSize,omitempty"`
 	Hostname         string `json:"Hostname,omitempty"`
 
-	IsPermanent bool        `json:"IsPermanent,omitempty"`
-	UserData    interface{} `json:"UserData,omitempty"`
+	IsPermanent   bool        `json:"IsPermanent,omitempty"`
+	UserData      interface{} `json:"UserData,omitempty"`
+	StorageClass  string      `json:"StorageClass,omitempty"`
+	EncryptionKey string      `json:"EncryptionKey,omitempty"`
 	//todo: add other fields from internal.GenericMetadata
 }
 
@@ -231,8 +231,13 @@ func (bh *BackupHandler) uploadBackup() TarFileSets {
 	bundle := bh.workers.bundle
 	// Start a new tar bundle, walk the pgDataDirectory and upload everything there.
 	tracelog.InfoLogger.Println("Starting a new tar bundle")
-	err := bundle.StartQueue(internal.NewStorageTarBallMaker(bh.curBackupInfo.name, bh.workers.uploader.Uploader))
+	tarBallMaker := internal.NewStorageTarBallMaker(bh.curBackupInfo.name, bh.workers.uploader.Uploader)
+	err := bundle.StartQueue(tarBallMaker)
 	tracelog.ErrorLogger.FatalOnError(err)
+	if viper.GetBool(internal.AdaptiveCompressionSetting) {
+		err = bundle.StartUncompressedQueue(tarBallMaker.UncompressedMaker())
+		tracelog.ErrorLogger.FatalOnError(err)
+	}
 
 	tarBallComposerMaker, err := NewTarBallComposerMaker(bh.arguments.tarBallComposerType, bh.workers.conn,
 		bh.workers.uploader.UploadingFolder, bh.curBackupInfo.name,
@@ -375,6 +380,7 @@ func NewBackupHandler(arguments BackupArguments) (bh *BackupHandler, err error)
 	if err != nil {
 		return bh, err
 	}
+	internal.ConfigureChecksumTracking(uploader.Uploader)
 	pgInfo, err := getPgServerInfo()
 	if err != nil {
 		return bh, err
@@ -397,6 +403,13 @@ func NewBackupHandler(arguments BackupArguments) (bh *BackupHandler, err error)
 	return bh, err
 }
 
+// WithContext returns a shallow copy of BackupHandler whose uploads are aborted when ctx is done
+func (bh *BackupHandler) WithContext(ctx context.Context) *BackupHandler {
+	handlerCopy := *bh
+	handlerCopy.workers.uploader = bh.workers.uploader.WithContext(ctx)
+	return &handlerCopy
+}
+
 func (bh *BackupHandler) runRemoteBackup() *StreamingBaseBackup {
 	var diskLimit int32
 	if viper.IsSet(internal.DiskRateLimitSetting) {
@@ -78,6 +78,9 @@ type ExtendedMetadataDto struct {
 	CompressedSize   int64 `json:"compressed_size"`
 
 	UserData interface{} `json:"user_data,omitempty"`
+
+	StorageClass  string `json:"storage_class,omitempty"`
+	EncryptionKey string `json:"encryption_key,omitempty"`
 }
 
 func NewExtendedMetadataDto(isPermanent bool, dataDir string, startTime time.Time,
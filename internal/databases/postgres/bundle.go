@@ -67,6 +67,9 @@ type Bundle struct {
 
 	TarBallComposer TarBallComposer
 	TarBallQueue    *internal.TarBallQueue
+	// UncompressedTarBallQueue is set up for the adaptive compression, the files
+	// which don't compress well are packed to its tarballs
+	UncompressedTarBallQueue *internal.TarBallQueue
 
 	Crypter            crypto.Crypter
 	Timeline           uint32
@@ -106,6 +109,14 @@ func (bundle *Bundle) StartQueue(tarBallMaker internal.TarBallMaker) error {
 	return bundle.TarBallQueue.StartQueue()
 }
 
+// StartUncompressedQueue starts the queue of the tarballs for the incompressible files,
+// the size of its tarballs is added to the size of the main queue
+func (bundle *Bundle) StartUncompressedQueue(tarBallMaker internal.TarBallMaker) error {
+	bundle.UncompressedTarBallQueue = internal.NewTarBallQueue(bundle.TarSizeThreshold, tarBallMaker)
+	bundle.UncompressedTarBallQueue.AllTarballsSize = bundle.TarBallQueue.AllTarballsSize
+	return bundle.UncompressedTarBallQueue.StartQueue()
+}
+
 func (bundle *Bundle) SetupComposer(composerMaker TarBallComposerMaker) (err error) {
 	tarBallComposer, err := composerMaker.Make(bundle)
 	if err != nil {
@@ -116,6 +127,12 @@ func (bundle *Bundle) SetupComposer(composerMaker TarBallComposerMaker) (err err
 }
 
 func (bundle *Bundle) FinishQueue() error {
+	if bundle.UncompressedTarBallQueue != nil {
+		err := bundle.UncompressedTarBallQueue.FinishQueue()
+		if err != nil {
+			return err
+		}
+	}
 	return bundle.TarBallQueue.FinishQueue()
 }
 
@@ -1,6 +1,9 @@
 package postgres_test
 
 import (
+	"context"
+	"fmt"
+	"path"
 	"strconv"
 	"strings"
 	"testing"
@@ -345,6 +348,11 @@ func lessByName(object1, object2 storage.Object) bool {
 	return object1.GetName() < object2.GetName()
 }
 
+// lessByBaseName compares the backups listed in the basebackups folder with the objects listed in the root folder
+func lessByBaseName(object1, object2 storage.Object) bool {
+	return path.Base(object1.GetName()) < path.Base(object2.GetName())
+}
+
 func lessByTime(object1, object2 storage.Object) bool {
 	return object1.GetLastModified().Before(object2.GetLastModified())
 }
@@ -379,3 +387,231 @@ func makeTestPermanentFunc(permanentBackups, permanentWals map[string]bool) func
 		return postgres.IsPermanent(object.GetName(), permanentBackups, permanentWals)
 	}
 }
+
+// lockedFolder locks the objects with the given names in any of its subfolders
+type lockedFolder struct {
+	storage.Folder
+	locked map[string]bool
+}
+
+func (folder lockedFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return lockedFolder{folder.Folder.GetSubFolder(subFolderRelativePath), folder.locked}
+}
+
+func (folder lockedFolder) GetObjectLock(ctx context.Context, objectRelativePath string) (storage.ObjectLock, error) {
+	if !folder.locked[objectRelativePath] {
+		return storage.ObjectLock{}, nil
+	}
+	return storage.ObjectLock{Mode: "COMPLIANCE", RetainUntil: time.Now().Add(time.Hour)}, nil
+}
+
+func TestDeleteBeforeTarget_KeepsLockedBackups(t *testing.T) {
+	folder := lockedFolder{testtools.CreateMockStorageFolderWithDeltaBackups(t), map[string]bool{
+		"base_000000010000000000000005_D_000000010000000000000003" + utility.SentinelSuffix: true,
+	}}
+	err := folder.GetSubFolder(utility.BaseBackupPath).PutObject(
+		"base_000000010000000000000001"+utility.SentinelSuffix, strings.NewReader("{}"))
+	assert.NoError(t, err)
+	deleteHandler := newTestDeleteHandler(folder, lessByBaseName)
+	target, err := deleteHandler.FindTargetBeforeName("base_000000010000000000000007", internal.NoDeleteModifier)
+	assert.NoError(t, err)
+
+	err = deleteHandler.DeleteBeforeTarget(target, true)
+	assert.NoError(t, err)
+
+	objects, err := getBackupObjects(folder)
+	assert.NoError(t, err)
+	names := make([]string, 0)
+	for _, object := range objects {
+		names = append(names, utility.StripRightmostBackupName(object.GetName()))
+	}
+	assert.ElementsMatch(t, []string{
+		"base_000000010000000000000003",
+		"base_000000010000000000000005_D_000000010000000000000003",
+		"base_000000010000000000000007",
+		"base_000000010000000000000009_D_000000010000000000000007",
+	}, names)
+}
+
+func TestDeleteBeforeTarget_DeletesNothingIfOldestBackupIsLocked(t *testing.T) {
+	folder := lockedFolder{testtools.CreateMockStorageFolderWithDeltaBackups(t), map[string]bool{
+		"base_000000010000000000000003" + utility.SentinelSuffix: true,
+	}}
+	deleteHandler := newTestDeleteHandler(folder, lessByBaseName)
+	target, err := deleteHandler.FindTargetBeforeName("base_000000010000000000000007", internal.NoDeleteModifier)
+	assert.NoError(t, err)
+
+	err = deleteHandler.DeleteBeforeTarget(target, true)
+	assert.NoError(t, err)
+
+	objects, err := getBackupObjects(folder)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(objects))
+}
+
+func TestDeleteTargets_RefusesLockedBackups(t *testing.T) {
+	folder := lockedFolder{testtools.CreateMockStorageFolderWithDeltaBackups(t), map[string]bool{
+		"base_000000010000000000000005_D_000000010000000000000003" + utility.SentinelSuffix: true,
+	}}
+	deleteHandler := newTestDeleteHandler(folder, lessByBaseName)
+	target, err := deleteHandler.FindTargetByName("base_000000010000000000000003")
+	assert.NoError(t, err)
+
+	err = deleteHandler.DeleteTargets([]internal.BackupObject{
+		TestPostgresBackupObject{target},
+		TestPostgresBackupObject{storage.NewLocalObject(
+			"base_000000010000000000000005_D_000000010000000000000003"+utility.SentinelSuffix, time.Now(), 0)},
+	}, true)
+	assert.IsType(t, utility.ForbiddenActionError{}, err)
+
+	objects, err := getBackupObjects(folder)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(objects))
+}
+
+type gfsBackupObject struct {
+	storage.Object
+	name          string
+	baseName      string
+	incrementFrom string
+}
+
+func (o gfsBackupObject) GetBackupName() string {
+	return o.name
+}
+
+func (o gfsBackupObject) GetBaseBackupName() string {
+	return o.baseName
+}
+
+func (o gfsBackupObject) GetIncrementFromName() string {
+	return o.incrementFrom
+}
+
+func (o gfsBackupObject) IsFullBackup() bool {
+	return o.incrementFrom == ""
+}
+
+func (o gfsBackupObject) GetBackupTime() time.Time {
+	return o.GetLastModified()
+}
+
+func gfsBackupName(day int) string {
+	return "base_" + gfsWalName(day)
+}
+
+func gfsWalName(day int) string {
+	return fmt.Sprintf("%08X%08X%08X", 1, 0, day+1)
+}
+
+// makeGFSBackups makes the daily backups starting from Wednesday 2020-01-01, the full backups are made weekly
+// and the deltas are incremented from them
+func makeGFSBackups(days int) []internal.BackupObject {
+	backups := make([]internal.BackupObject, 0, days)
+	baseName := ""
+	for day := 0; day < days; day++ {
+		backupTime := time.Date(2020, 1, 1+day, 12, 0, 0, 0, time.UTC)
+		backup := gfsBackupObject{name: gfsBackupName(day)}
+		if day%7 == 0 {
+			baseName = backup.name
+		} else {
+			backup.name += "_D_" + gfsWalName(day-day%7)
+			backup.incrementFrom = baseName
+		}
+		backup.baseName = baseName
+		backup.Object = storage.NewLocalObject(backup.name+utility.SentinelSuffix, backupTime, 0)
+		backups = append(backups, backup)
+	}
+	return backups
+}
+
+func lessBySegmentNo(object1, object2 storage.Object) bool {
+	_, segNo1, ok1 := postgres.TryFetchTimelineAndLogSegNo(object1.GetName())
+	_, segNo2, ok2 := postgres.TryFetchTimelineAndLogSegNo(object2.GetName())
+	return ok1 && ok2 && segNo1 < segNo2
+}
+
+func gfsBackupDays(backups []internal.BackupObject) []int {
+	days := make([]int, 0, len(backups))
+	for _, backup := range backups {
+		_, segNo, _ := postgres.TryFetchTimelineAndLogSegNo(backup.GetBackupName())
+		days = append(days, int(segNo)-1)
+	}
+	return days
+}
+
+func TestFindKeptGFS_Without_Modifier(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	deleteHandler := internal.NewDeleteHandler(folder, makeGFSBackups(40), lessBySegmentNo)
+
+	kept, err := deleteHandler.FindKeptGFS(internal.GFSPolicy{Daily: 3, Weekly: 2, Monthly: 2}, internal.NoDeleteModifier)
+	assert.NoError(t, err)
+	// the last 3 days, the Sunday of the previous week, the end of January and their base backups
+	assert.Equal(t, []int{28, 30, 32, 35, 37, 38, 39}, gfsBackupDays(kept))
+}
+
+func TestFindKeptGFS_With_FULL_Modifier(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	deleteHandler := internal.NewDeleteHandler(folder, makeGFSBackups(40), lessBySegmentNo)
+
+	kept, err := deleteHandler.FindKeptGFS(internal.GFSPolicy{Weekly: 2, Yearly: 1}, internal.FullDeleteModifier)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39}, gfsBackupDays(kept))
+}
+
+func TestFindKeptGFS_KeepsPermanentBackups(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	permanentName := gfsBackupName(10) + "_D_" + gfsWalName(7) + utility.SentinelSuffix
+	deleteHandler := internal.NewDeleteHandler(folder, makeGFSBackups(40), lessBySegmentNo,
+		internal.IsPermanentFunc(func(object storage.Object) bool {
+			return object.GetName() == permanentName
+		}))
+
+	kept, err := deleteHandler.FindKeptGFS(internal.GFSPolicy{Daily: 1}, internal.NoDeleteModifier)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7, 10, 35, 39}, gfsBackupDays(kept))
+}
+
+func TestFindKeptGFS_ReturnsForbiddenActionError_With_FIND_FULL_Modifier(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	deleteHandler := internal.NewDeleteHandler(folder, makeGFSBackups(40), lessBySegmentNo)
+
+	_, err := deleteHandler.FindKeptGFS(internal.GFSPolicy{Daily: 1}, internal.FindFullDeleteModifier)
+	assert.IsType(t, utility.ForbiddenActionError{}, err)
+}
+
+func TestDeleteAllExcept(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	backups := makeGFSBackups(40)
+	for _, backup := range backups {
+		err := folder.GetSubFolder(utility.BaseBackupPath).PutObject(backup.GetName(), strings.NewReader("{}"))
+		assert.NoError(t, err)
+	}
+	for day := 0; day < 40; day++ {
+		err := folder.GetSubFolder(utility.WalPath).PutObject(gfsWalName(day)+".lz4", strings.NewReader(""))
+		assert.NoError(t, err)
+	}
+	deleteHandler := internal.NewDeleteHandler(folder, backups, lessBySegmentNo)
+	kept, err := deleteHandler.FindKeptGFS(internal.GFSPolicy{Daily: 3, Weekly: 2, Monthly: 2}, internal.NoDeleteModifier)
+	assert.NoError(t, err)
+
+	err = deleteHandler.DeleteAllExcept(kept, true)
+	assert.NoError(t, err)
+
+	backupObjects, err := getBackupObjects(folder)
+	assert.NoError(t, err)
+	names := make([]string, 0)
+	for _, object := range backupObjects {
+		names = append(names, utility.StripRightmostBackupName(object.GetName()))
+	}
+	keptNames := make([]string, 0)
+	for _, backup := range kept {
+		keptNames = append(keptNames, backup.GetBackupName())
+	}
+	assert.ElementsMatch(t, keptNames, names)
+
+	// the WAL is kept starting from the oldest kept backup
+	walObjects, _, err := folder.GetSubFolder(utility.WalPath).ListFolder()
+	assert.NoError(t, err)
+	assert.Equal(t, 12, len(walObjects))
+}
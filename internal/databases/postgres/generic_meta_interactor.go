@@ -39,6 +39,8 @@ func (mf GenericMetaFetcher) Fetch(backupName string, backupFolder storage.Folde
 		StartTime:        meta.StartTime,
 		FinishTime:       meta.FinishTime,
 		IsPermanent:      meta.IsPermanent,
+		StorageClass:     meta.StorageClass,
+		EncryptionKey:    meta.EncryptionKey,
 		IncrementDetails: NewIncrementDetailsFetcher(backup),
 		UserData:         meta.UserData,
 	}, nil
@@ -66,6 +68,22 @@ func (ms GenericMetaSetter) SetIsPermanent(backupName string, backupFolder stora
 	return modifyBackupMetadata(backupName, backupFolder, modifier)
 }
 
+func (ms GenericMetaSetter) SetStorageClass(backupName string, backupFolder storage.Folder, storageClass string) error {
+	modifier := func(dto ExtendedMetadataDto) ExtendedMetadataDto {
+		dto.StorageClass = storageClass
+		return dto
+	}
+	return modifyBackupMetadata(backupName, backupFolder, modifier)
+}
+
+func (ms GenericMetaSetter) SetEncryptionKey(backupName string, backupFolder storage.Folder, encryptionKey string) error {
+	modifier := func(dto ExtendedMetadataDto) ExtendedMetadataDto {
+		dto.EncryptionKey = encryptionKey
+		return dto
+	}
+	return modifyBackupMetadata(backupName, backupFolder, modifier)
+}
+
 func modifyBackupMetadata(backupName string, backupFolder storage.Folder, modifier func(ExtendedMetadataDto) ExtendedMetadataDto) error {
 	backup := internal.NewBackup(backupFolder, backupName)
 	var meta ExtendedMetadataDto
@@ -38,6 +38,7 @@ func (maker *RatingTarBallComposerMaker) Make(bundle *Bundle) (TarBallComposer,
 		bundle.IncrementFromLsn,
 		bundle.DeltaMap,
 		bundle.TarBallQueue,
+		bundle.UncompressedTarBallQueue,
 		bundle.Crypter,
 		maker.fileStats,
 		maker.bundleFiles,
@@ -51,17 +52,20 @@ type RatedComposeFileInfo struct {
 	// for regular files this value should match their size on the disk
 	// for increments this value is the estimated size of the increment that is going to be created
 	expectedSize uint64
+	// incompressible files are packed to the uncompressed tarballs
+	incompressible bool
 }
 
 // TarFilesCollection stores the files which are going to be written
 // to the same tarball
 type TarFilesCollection struct {
-	files        []*RatedComposeFileInfo
-	expectedSize uint64
+	files          []*RatedComposeFileInfo
+	expectedSize   uint64
+	incompressible bool
 }
 
-func newTarFilesCollection() *TarFilesCollection {
-	return &TarFilesCollection{files: make([]*RatedComposeFileInfo, 0), expectedSize: 0}
+func newTarFilesCollection(incompressible bool) *TarFilesCollection {
+	return &TarFilesCollection{files: make([]*RatedComposeFileInfo, 0), expectedSize: 0, incompressible: incompressible}
 }
 
 func (collection *TarFilesCollection) AddFile(file *RatedComposeFileInfo) {
@@ -82,6 +86,8 @@ type RatingTarBallComposer struct {
 	tarBallQueue  *internal.TarBallQueue
 	tarFilePacker *TarBallFilePacker
 	crypter       crypto.Crypter
+	// uncompressedQueue is nil unless the adaptive compression is on
+	uncompressedQueue *internal.TarBallQueue
 
 	addFileQueue     chan *ComposeFileInfo
 	addFileWaitGroup sync.WaitGroup
@@ -101,7 +107,7 @@ type RatingTarBallComposer struct {
 
 func NewRatingTarBallComposer(
 	tarSizeThreshold uint64, updateRatingEvaluator internal.ComposeRatingEvaluator,
-	incrementBaseLsn *uint64, deltaMap PagedFileDeltaMap, tarBallQueue *internal.TarBallQueue,
+	incrementBaseLsn *uint64, deltaMap PagedFileDeltaMap, tarBallQueue, uncompressedQueue *internal.TarBallQueue,
 	crypter crypto.Crypter, fileStats RelFileStatistics, bundleFiles BundleFiles, packer *TarBallFilePacker,
 ) (*RatingTarBallComposer, error) {
 	errorGroup, _ := errgroup.WithContext(context.Background())
@@ -120,6 +126,7 @@ func NewRatingTarBallComposer(
 		deltaMapComplete:       deltaMapComplete,
 		deltaMap:               deltaMap,
 		tarBallQueue:           tarBallQueue,
+		uncompressedQueue:      uncompressedQueue,
 		crypter:                crypter,
 		fileStats:              fileStats,
 		bundleFiles:            bundleFiles,
@@ -174,7 +181,11 @@ func (c *RatingTarBallComposer) PackTarballs() (TarFileSets, error) {
 	tarFileSets[headersTarName] = headersNames
 
 	for _, tarFilesCollection := range tarFilesCollections {
-		tarBall := c.tarBallQueue.Deque()
+		tarBallQueue := c.tarBallQueue
+		if tarFilesCollection.incompressible {
+			tarBallQueue = c.uncompressedQueue
+		}
+		tarBall := tarBallQueue.Deque()
 		tarBall.SetUp(c.crypter)
 		for _, composeFileInfo := range tarFilesCollection.files {
 			tarFileSets[tarBall.Name()] = append(tarFileSets[tarBall.Name()], composeFileInfo.header.Name)
@@ -188,7 +199,7 @@ func (c *RatingTarBallComposer) PackTarballs() (TarFileSets, error) {
 					panic(err)
 				}
 			}
-			err := c.tarBallQueue.FinishTarBall(tarBall)
+			err := tarBallQueue.FinishTarBall(tarBall)
 			if err != nil {
 				panic(err)
 			}
@@ -220,7 +231,8 @@ func (c *RatingTarBallComposer) addFile(cfi *ComposeFileInfo) error {
 	}
 	updatesCount := c.fileStats.getFileUpdateCount(cfi.path)
 	updateRating := c.composeRatingEvaluator.Evaluate(cfi.path, updatesCount, cfi.wasInBase)
-	ratedComposeFileInfo := &RatedComposeFileInfo{*cfi, updateRating, updatesCount, expectedFileSize}
+	incompressible := c.uncompressedQueue != nil && cfi.isIncompressible()
+	ratedComposeFileInfo := &RatedComposeFileInfo{*cfi, updateRating, updatesCount, expectedFileSize, incompressible}
 	c.filesToComposeMutex.Lock()
 	defer c.filesToComposeMutex.Unlock()
 	c.filesToCompose = append(c.filesToCompose, ratedComposeFileInfo)
@@ -235,25 +247,43 @@ func (c *RatingTarBallComposer) sortFiles() {
 
 func (c *RatingTarBallComposer) composeFiles() ([]*tar.Header, []*TarFilesCollection) {
 	c.sortFiles()
+	compressibleFiles := make([]*RatedComposeFileInfo, 0, len(c.filesToCompose))
+	incompressibleFiles := make([]*RatedComposeFileInfo, 0)
+	for _, file := range c.filesToCompose {
+		if file.incompressible {
+			incompressibleFiles = append(incompressibleFiles, file)
+		} else {
+			compressibleFiles = append(compressibleFiles, file)
+		}
+	}
+
+	tarFilesCollections := c.composeCollections(compressibleFiles, false)
+	if len(incompressibleFiles) > 0 {
+		tarFilesCollections = append(tarFilesCollections, c.composeCollections(incompressibleFiles, true)...)
+	}
+	return c.headersToCompose, tarFilesCollections
+}
+
+// composeCollections places the files sorted by the update rating to the tar files collections
+func (c *RatingTarBallComposer) composeCollections(files []*RatedComposeFileInfo, incompressible bool) []*TarFilesCollection {
 	tarFilesCollections := make([]*TarFilesCollection, 0)
-	currentFilesCollection := newTarFilesCollection()
+	currentFilesCollection := newTarFilesCollection(incompressible)
 	prevUpdateRating := uint64(0)
 
-	for _, file := range c.filesToCompose {
+	for _, file := range files {
 		// if the estimated size of the current collection exceeds the threshold,
 		// or if the updateRating just went to non-zero from zero,
 		// start packing to the new tar files collection
 		if currentFilesCollection.expectedSize > c.tarSizeThreshold ||
 			prevUpdateRating == 0 && file.updateRating > 0 {
 			tarFilesCollections = append(tarFilesCollections, currentFilesCollection)
-			currentFilesCollection = newTarFilesCollection()
+			currentFilesCollection = newTarFilesCollection(incompressible)
 		}
 		currentFilesCollection.AddFile(file)
 		prevUpdateRating = file.updateRating
 	}
 
-	tarFilesCollections = append(tarFilesCollections, currentFilesCollection)
-	return c.headersToCompose, tarFilesCollections
+	return append(tarFilesCollections, currentFilesCollection)
 }
 
 func (c *RatingTarBallComposer) getExpectedFileSize(cfi *ComposeFileInfo) (uint64, error) {
@@ -19,10 +19,13 @@ type RegularTarBallComposer struct {
 	tarFileSets   TarFileSets
 	errorGroup    *errgroup.Group
 	ctx           context.Context
+	// uncompressedQueue is nil unless the adaptive compression is on
+	uncompressedQueue *internal.TarBallQueue
 }
 
 func NewRegularTarBallComposer(
 	tarBallQueue *internal.TarBallQueue,
+	uncompressedQueue *internal.TarBallQueue,
 	tarBallFilePacker *TarBallFilePacker,
 	files *RegularBundleFiles,
 	crypter crypto.Crypter,
@@ -36,6 +39,8 @@ func NewRegularTarBallComposer(
 		tarFileSets:   make(TarFileSets),
 		errorGroup:    errorGroup,
 		ctx:           ctx,
+
+		uncompressedQueue: uncompressedQueue,
 	}
 }
 
@@ -51,11 +56,15 @@ func (maker *RegularTarBallComposerMaker) Make(bundle *Bundle) (TarBallComposer,
 	bundleFiles := &RegularBundleFiles{}
 	tarBallFilePacker := newTarBallFilePacker(bundle.DeltaMap,
 		bundle.IncrementFromLsn, bundleFiles, maker.filePackerOptions)
-	return NewRegularTarBallComposer(bundle.TarBallQueue, tarBallFilePacker, bundleFiles, bundle.Crypter), nil
+	return NewRegularTarBallComposer(bundle.TarBallQueue, bundle.UncompressedTarBallQueue, tarBallFilePacker, bundleFiles, bundle.Crypter), nil
 }
 
 func (c *RegularTarBallComposer) AddFile(info *ComposeFileInfo) {
-	tarBall, err := c.tarBallQueue.DequeCtx(c.ctx)
+	tarBallQueue := c.tarBallQueue
+	if c.uncompressedQueue != nil && info.isIncompressible() {
+		tarBallQueue = c.uncompressedQueue
+	}
+	tarBall, err := tarBallQueue.DequeCtx(c.ctx)
 	if err != nil {
 		return
 	}
@@ -66,7 +75,7 @@ func (c *RegularTarBallComposer) AddFile(info *ComposeFileInfo) {
 		if err != nil {
 			return err
 		}
-		return c.tarBallQueue.CheckSizeAndEnqueueBack(tarBall)
+		return tarBallQueue.CheckSizeAndEnqueueBack(tarBall)
 	})
 }
 
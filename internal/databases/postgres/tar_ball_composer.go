@@ -9,9 +9,15 @@ import (
 
 	"github.com/jackc/pgx"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
 )
 
+// minSampledFileSize is the size starting from which the adaptive compression samples the files,
+// the smaller files are compressed anyway
+const minSampledFileSize = 1 << 20
+
 // TarBallComposer is used to compose files into tarballs.
 type TarBallComposer interface {
 	AddFile(info *ComposeFileInfo)
@@ -30,6 +36,26 @@ type ComposeFileInfo struct {
 	isIncremented bool
 }
 
+// isIncompressible samples the file to tell whether it should be packed without compression.
+// The increments aren't sampled, they consist of the changed pages only.
+func (info *ComposeFileInfo) isIncompressible() bool {
+	if info.isIncremented || info.fileInfo.Size() < minSampledFileSize {
+		return false
+	}
+	file, err := os.Open(info.path)
+	if err != nil {
+		// the packer is going to deal with the file which can't be opened
+		return false
+	}
+	defer utility.LoggedClose(file, "")
+	compressible, err := compression.IsReaderCompressible(file, info.fileInfo.Size())
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to sample '%s' for compression: %v\n", info.path, err)
+		return false
+	}
+	return !compressible
+}
+
 type TarFileSets map[string][]string
 
 func NewComposeFileInfo(path string, fileInfo os.FileInfo, wasInBase, isIncremented bool,
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// WalDictionarySampleSize is the size of the beginning of every segment taken to train the dictionary
+const WalDictionarySampleSize = 512 << 10
+
+// HandleWalDictionaryTrain trains the zstd dictionary on the last WAL segments in the storage
+// and makes it current for the following wal-push with WALG_ZSTD_DICTIONARY
+func HandleWalDictionaryTrain(folder storage.Folder, segmentsCount int, dictionarySize int) {
+	walFolder := folder.GetSubFolder(utility.WalPath)
+	samples, err := sampleWalSegments(walFolder, segmentsCount)
+	tracelog.ErrorLogger.FatalOnError(err)
+	if len(samples) == 0 {
+		tracelog.ErrorLogger.Fatal("No WAL segments to train the dictionary on")
+	}
+
+	dictionary := compression.TrainDictionary(samples, dictionarySize)
+	id, err := internal.UploadDictionary(walFolder, dictionary)
+	tracelog.ErrorLogger.FatalOnError(err)
+	tracelog.InfoLogger.Printf("Trained the dictionary %08x of %d bytes on %d WAL segments\n", id, len(dictionary), len(samples))
+}
+
+// sampleWalSegments reads the beginnings of the last segments. The WAL folder is listed page by page
+// and only the names of the last segments are kept, the objects are listed in the order of their names.
+func sampleWalSegments(walFolder storage.Folder, segmentsCount int) ([][]byte, error) {
+	segmentNames := make([]string, 0, segmentsCount)
+	err := storage.ListFolderPages(context.Background(), walFolder, storage.ListOptions{},
+		func(objects []storage.Object, _ []storage.Folder) error {
+			for _, object := range objects {
+				name := utility.TrimFileExtension(object.GetName())
+				if isWalFilename(name) {
+					segmentNames = append(segmentNames, name)
+				}
+			}
+			if len(segmentNames) > segmentsCount {
+				segmentNames = append(segmentNames[:0], segmentNames[len(segmentNames)-segmentsCount:]...)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the WAL folder")
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(segmentNames)))
+
+	samples := make([][]byte, 0, len(segmentNames))
+	for _, segmentName := range segmentNames {
+		reader, err := internal.DownloadAndDecompressStorageFile(walFolder, segmentName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to download the WAL segment %s", segmentName)
+		}
+		sample, err := ioutil.ReadAll(io.LimitReader(reader, WalDictionarySampleSize))
+		utility.LoggedClose(reader, "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the WAL segment %s", segmentName)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// configureWalDictionary makes the compressor use the current dictionary of the WAL folder if WALG_ZSTD_DICTIONARY is set.
+// The dictionaries are cached on the local disk, see internal.DictionaryFileCache.
+func configureWalDictionary(compressor compression.Compressor, walFolder storage.Folder) compression.Compressor {
+	if !viper.GetBool(internal.PgZstdDictionary) {
+		return compressor
+	}
+	id, content, err := getCurrentWalDictionary(walFolder)
+	if err != nil {
+		tracelog.WarningLogger.Printf("The WAL is compressed without a dictionary: %v\n", err)
+		return compressor
+	}
+	dictionaryCompressor, ok := compression.WithDictionary(compressor, id, content)
+	if !ok {
+		tracelog.WarningLogger.Printf("The %s compression doesn't use dictionaries\n", compressor.FileExtension())
+	}
+	return dictionaryCompressor
+}
+
+func getCurrentWalDictionary(walFolder storage.Folder) (uint32, []byte, error) {
+	checkInterval, err := internal.GetDurationSetting(internal.PgZstdDictionaryInterval)
+	if err != nil {
+		return 0, nil, err
+	}
+	cacheFolder, err := internal.ConfigureDictionaryCacheFolder()
+	if err != nil {
+		tracelog.WarningLogger.Printf("The dictionaries are not cached: %v\n", err)
+		return internal.GetCurrentDictionary(walFolder)
+	}
+	return internal.NewDictionaryFileCache(walFolder, cacheFolder, checkInterval).GetCurrentDictionary()
+}
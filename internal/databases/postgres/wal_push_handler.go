@@ -34,6 +34,7 @@ func (err CantOverwriteWalFileError) Error() string {
 // HandleWALPush is invoked to perform wal-g wal-push
 func HandleWALPush(uploader *WalUploader, walFilePath string) {
 	uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.WalPath)
+	uploader.Compressor = configureWalDictionary(uploader.Compressor, uploader.UploadingFolder)
 	if uploader.ArchiveStatusManager.IsWalAlreadyUploaded(walFilePath) {
 		err := uploader.ArchiveStatusManager.UnmarkWalFile(walFilePath)
 
@@ -1,7 +1,9 @@
 package postgres_test
 
 import (
+	"context"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/wal-g/wal-g/internal/databases/postgres"
@@ -10,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/asm"
+	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/testtools"
 )
 
@@ -73,3 +76,15 @@ func TestWalPush_BulkMetadataUploaderWithUploadConcurrency(t *testing.T) {
 	_, err := uploader.UploadingFolder.ReadObject(testFileName[0:len(testFileName)-1] + ".json")
 	assert.NoError(t, err)
 }
+
+func TestWalUploader_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	uploader := testtools.NewMockWalDirUploader(false, false).WithContext(ctx)
+
+	err := uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(strings.NewReader("wal"), "000000010000000000000001"))
+	assert.Error(t, err)
+	exists, err := uploader.UploadingFolder.Exists("000000010000000000000001.mock")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/pkg/errors"
@@ -107,16 +108,20 @@ func (r *WalSegmentRunner) getNextSegment() WalSegmentDescription {
 	return WalSegmentDescription{Timeline: nextTimeline, Number: nextSegmentNo}
 }
 
-// getFolderFilenames returns a set of filenames in provided storage folder
+// getFolderFilenames returns a set of filenames in provided storage folder.
+// The folder is listed page by page, so only the names are kept in memory.
 func getFolderFilenames(folder storage.Folder) ([]string, error) {
-	objects, _, err := folder.ListFolder()
+	filenames := make([]string, 0)
+	err := storage.ListFolderPages(context.Background(), folder, storage.ListOptions{},
+		func(objects []storage.Object, _ []storage.Folder) error {
+			for _, object := range objects {
+				filenames = append(filenames, object.GetName())
+			}
+			return nil
+		})
 	if err != nil {
 		return nil, err
 	}
-	filenames := make([]string, 0, len(objects))
-	for _, object := range objects {
-		filenames = append(filenames, object.GetName())
-	}
 	return filenames, nil
 }
 
@@ -34,7 +34,10 @@ func (status ScannedSegmentStatus) MarshalText() ([]byte, error) {
 	return utility.MarshalEnumToString(status)
 }
 
-// WalSegmentScanner is used to scan the WAL segments storage
+// WalSegmentScanner is used to scan the WAL segments storage.
+// It doesn't list the storage on its own: the WalSegmentRunner looks the segments up in the set of names
+// pre-fetched page by page with getFolderFilenames. The scan walks the segments backwards across the timeline
+// switches, so the set of names is kept whole instead of reading the listing as a stream.
 type WalSegmentScanner struct {
 	ScannedSegments  []ScannedSegmentDescription
 	walSegmentRunner *WalSegmentRunner
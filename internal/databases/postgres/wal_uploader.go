@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"io"
 	"path"
 
@@ -43,6 +44,14 @@ func (walUploader *WalUploader) clone() *WalUploader {
 	}
 }
 
+// WithContext returns a shallow copy of WalUploader whose uploads are aborted when ctx is done
+func (walUploader *WalUploader) WithContext(ctx context.Context) *WalUploader {
+	return &WalUploader{
+		walUploader.Uploader.WithContext(ctx),
+		walUploader.DeltaFileManager,
+	}
+}
+
 // TODO : unit tests
 func (walUploader *WalUploader) UploadWalFile(file ioextensions.NamedReader) error {
 	var walFileReader io.Reader
@@ -22,6 +22,8 @@ func HandleDetailedBackupList(folder storage.Folder, pretty bool, json bool) {
 		return
 	}
 	tracelog.ErrorLogger.FatalOnError(err)
+	backups, err = internal.FilterSignedBackups(folder, backups)
+	tracelog.ErrorLogger.FatalOnError(err)
 	// if details are requested we append content of metadata.json to each line
 
 	backupDetails, err := GetBackupsDetails(folder, backups)
@@ -522,14 +522,13 @@ func (bs *Server) HandleBlobGet(w http.ResponseWriter, req *http.Request) {
 		// TODO: enable cache
 		// r, err := idx.GetCachedReader(folder, s)
 		bs.downloadSem <- struct{}{}
-		r, err := folder.ReadObject(s.Path)
+		r, err := storage.ReadObjectRange(folder, s.Path, int64(s.Offset), int64(s.Limit))
 		<-bs.downloadSem
 		if err != nil {
 			tracelog.ErrorLogger.Printf("proxy: failed to read object from storage: %v", err)
 			break
 		}
-		r2 := io.LimitReader(NewSkipReader(r, s.Offset), int64(s.Limit))
-		_, err = io.Copy(w, r2)
+		_, err = io.Copy(w, r)
 		r.Close()
 		if err != nil {
 			tracelog.ErrorLogger.Printf("proxy: failed to copy data from storage: %v", err)
@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	DeleteGFSUsageExample = "gfs [FULL] daily weekly monthly yearly"
+	DeleteGFSExamples     = `  gfs 7 4 12 3                  keep the latest backup of each of the last 7 days, 4 weeks, 12 months and 3 years
+  gfs FULL 7 4 12 3             the same for the full backups, keeping all deltas of them`
+)
+
+// GFSPolicy is the grandfather-father-son retention policy. The latest backup of each
+// of the last Daily days, Weekly weeks, Monthly months and Yearly years with backups is kept.
+type GFSPolicy struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+	Yearly  int
+}
+
+// gfsPeriod is the retention period of the policy, the backups made in the same period have the same key
+type gfsPeriod struct {
+	count int
+	key   func(backupTime time.Time) string
+}
+
+func (policy GFSPolicy) periods() []gfsPeriod {
+	return []gfsPeriod{
+		{policy.Daily, func(backupTime time.Time) string {
+			return backupTime.Format("2006-01-02")
+		}},
+		{policy.Weekly, func(backupTime time.Time) string {
+			year, week := backupTime.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{policy.Monthly, func(backupTime time.Time) string {
+			return backupTime.Format("2006-01")
+		}},
+		{policy.Yearly, func(backupTime time.Time) string {
+			return backupTime.Format("2006")
+		}},
+	}
+}
+
+// keep returns the indices of the backup times kept by the policy, the times must be sorted from the latest
+func (policy GFSPolicy) keep(backupTimes []time.Time) map[int]bool {
+	kept := make(map[int]bool)
+	for _, period := range policy.periods() {
+		keptCount := 0
+		lastKey := ""
+		for i, backupTime := range backupTimes {
+			if keptCount == period.count {
+				break
+			}
+			// the first backup of every period is its latest backup
+			if key := period.key(backupTime.UTC()); key != lastKey {
+				kept[i] = true
+				lastKey = key
+				keptCount++
+			}
+		}
+	}
+	return kept
+}
+
+// SplitPurgingBackupsGFS splits the backups sorted by SortTimedBackup into the ones to purge
+// and the ones to retain, which are the backups kept by the policy and the permanent backups.
+func SplitPurgingBackupsGFS(backups []TimedBackup, policy GFSPolicy) (purge, retain map[string]bool) {
+	backupTimes := make([]time.Time, len(backups))
+	for i, backup := range backups {
+		backupTimes[i] = backup.StartTime()
+	}
+	kept := policy.keep(backupTimes)
+
+	purge = make(map[string]bool)
+	retain = make(map[string]bool)
+	for i, backup := range backups {
+		switch {
+		case kept[i]:
+			tracelog.DebugLogger.Printf("Preserving backup due to GFS policy: %s", backup.Name())
+			retain[backup.Name()] = true
+		case backup.IsPermanent():
+			tracelog.DebugLogger.Printf("Preserving backup due to keep permanent policy: %s", backup.Name())
+			retain[backup.Name()] = true
+		default:
+			purge[backup.Name()] = true
+		}
+	}
+	return purge, retain
+}
+
+func (h *DeleteHandler) HandleDeleteGFS(args []string, confirmed bool) {
+	modifier, policy, err := extractDeleteGFSModifierFromArgs(args)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	keptBackups, err := h.FindKeptGFS(policy, modifier)
+	tracelog.ErrorLogger.FatalOnError(err)
+	if len(keptBackups) == 0 {
+		tracelog.InfoLogger.Printf("No backup found for deletion")
+		os.Exit(0)
+	}
+	for _, backup := range keptBackups {
+		tracelog.InfoLogger.Printf("Backup %s is kept\n", backup.GetBackupName())
+	}
+
+	err = h.DeleteAllExcept(keptBackups, confirmed)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+// FindKeptGFS returns the backups kept by the policy along with the permanent and locked backups
+// and the backups they are based on. With FullDeleteModifier the policy selects among the full backups
+// and all deltas of the selected ones are kept, otherwise it selects among all the backups.
+func (h *DeleteHandler) FindKeptGFS(policy GFSPolicy, modifier int) ([]BackupObject, error) {
+	if modifier != NoDeleteModifier && modifier != FullDeleteModifier {
+		return nil, utility.NewForbiddenActionError("Not allowed modifier for 'delete gfs'")
+	}
+	candidates := make([]BackupObject, 0, len(h.backups))
+	for _, backup := range h.backups {
+		if modifier == NoDeleteModifier || backup.IsFullBackup() {
+			candidates = append(candidates, backup)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].GetBackupTime().After(candidates[j].GetBackupTime())
+	})
+
+	backupTimes := make([]time.Time, len(candidates))
+	for i, backup := range candidates {
+		backupTimes[i] = backup.GetBackupTime()
+	}
+	kept := make(map[string]bool)
+	for i := range policy.keep(backupTimes) {
+		kept[candidates[i].GetBackupName()] = true
+	}
+	if modifier == FullDeleteModifier {
+		for _, backup := range h.backups {
+			if !backup.IsFullBackup() && kept[backup.GetBaseBackupName()] {
+				kept[backup.GetBackupName()] = true
+			}
+		}
+	}
+
+	now := utility.TimeNowCrossPlatformUTC()
+	for _, backup := range h.backups {
+		if kept[backup.GetBackupName()] {
+			continue
+		}
+		if h.isPermanent(backup) {
+			kept[backup.GetBackupName()] = true
+			continue
+		}
+		lock, err := h.getObjectLock(backup)
+		if err != nil {
+			return nil, err
+		}
+		if lock.IsActive(now) {
+			tracelog.InfoLogger.Printf("Backup %s is locked in the storage %v, it will be kept\n", backup.GetName(), lock)
+			kept[backup.GetBackupName()] = true
+		}
+	}
+
+	return h.withIncrementChains(kept), nil
+}
+
+// withIncrementChains returns the kept backups along with the backups they are incremented from
+func (h *DeleteHandler) withIncrementChains(kept map[string]bool) []BackupObject {
+	backupsByName := make(map[string]BackupObject, len(h.backups))
+	for _, backup := range h.backups {
+		backupsByName[backup.GetBackupName()] = backup
+	}
+	for name := range kept {
+		for backup, ok := backupsByName[name]; ok && !backup.IsFullBackup(); {
+			kept[backup.GetBaseBackupName()] = true
+			kept[backup.GetIncrementFromName()] = true
+			backup, ok = backupsByName[backup.GetIncrementFromName()]
+		}
+	}
+
+	keptBackups := make([]BackupObject, 0, len(kept))
+	for _, backup := range h.backups {
+		if kept[backup.GetBackupName()] {
+			keptBackups = append(keptBackups, backup)
+		}
+	}
+	sort.Slice(keptBackups, func(i, j int) bool {
+		return h.less(keptBackups[i], keptBackups[j])
+	})
+	return keptBackups
+}
+
+// DeleteAllExcept deletes the backups other than the kept ones, which must include the backups they are based on.
+// Everything before the oldest kept full backup is deleted as with DeleteBeforeTarget, the later backups
+// are deleted one by one, while the WAL and the other objects after it are kept.
+func (h *DeleteHandler) DeleteAllExcept(keptBackups []BackupObject, confirmed bool) error {
+	if len(keptBackups) == 0 {
+		return errors.New("at least one backup must be kept")
+	}
+	kept := make(map[string]bool, len(keptBackups))
+	for _, backup := range keptBackups {
+		kept[backup.GetBackupName()] = true
+	}
+	backups := make([]BackupObject, len(h.backups))
+	copy(backups, h.backups)
+	sort.Slice(backups, func(i, j int) bool {
+		return h.less(backups[i], backups[j])
+	})
+
+	var target BackupObject
+	for _, backup := range backups {
+		if kept[backup.GetBackupName()] {
+			if backup.IsFullBackup() {
+				target = backup
+			}
+			break
+		}
+	}
+	if target == nil {
+		return utility.NewForbiddenActionError("The oldest kept backup is incremental, its base backup is missing")
+	}
+
+	backupsToDelete := make([]BackupObject, 0)
+	for _, backup := range backups {
+		if !kept[backup.GetBackupName()] && !h.less(backup, target) {
+			backupsToDelete = append(backupsToDelete, backup)
+		}
+	}
+
+	if err := h.DeleteBeforeTarget(target, confirmed); err != nil {
+		return err
+	}
+	if len(backupsToDelete) == 0 {
+		return nil
+	}
+	return h.DeleteTargets(backupsToDelete, confirmed)
+}
+
+func extractDeleteGFSModifierFromArgs(args []string) (int, GFSPolicy, error) {
+	modifier := NoDeleteModifier
+	if len(args) > 0 && args[0] == StringModifiers[0] {
+		modifier = FullDeleteModifier
+		args = args[1:]
+	}
+	policy, err := ParseGFSPolicy(args)
+	return modifier, policy, err
+}
+
+// ParseGFSPolicy parses the daily, weekly, monthly and yearly retention counts of the policy
+func ParseGFSPolicy(args []string) (GFSPolicy, error) {
+	if len(args) != 4 {
+		return GFSPolicy{}, fmt.Errorf("expected daily, weekly, monthly and yearly counts, got %v", args)
+	}
+	counts := make([]int, len(args))
+	keepsAny := false
+	for i, arg := range args {
+		count, err := strconv.Atoi(arg)
+		if err != nil {
+			return GFSPolicy{}, errors.Wrapf(err, "expected to get a number as retention count, but got: '%s'", arg)
+		}
+		if count < 0 {
+			return GFSPolicy{}, fmt.Errorf("retention count can't be negative, but got: %d", count)
+		}
+		keepsAny = keepsAny || count > 0
+		counts[i] = count
+	}
+	if !keepsAny {
+		return GFSPolicy{}, fmt.Errorf("cannot retain less than one backup. Check out delete everything")
+	}
+	return GFSPolicy{Daily: counts[0], Weekly: counts[1], Monthly: counts[2], Yearly: counts[3]}, nil
+}
+
+func DeleteGFSArgsValidator(cmd *cobra.Command, args []string) error {
+	_, _, err := extractDeleteGFSModifierFromArgs(args)
+	return err
+}
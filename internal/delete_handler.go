@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sort"
@@ -75,6 +76,13 @@ func IsPermanentFunc(isPermanent func(storage.Object) bool) DeleteHandlerOption
 	}
 }
 
+// DeleteWithContext makes storage calls of DeleteHandler abort when ctx is done
+func DeleteWithContext(ctx context.Context) DeleteHandlerOption {
+	return func(h *DeleteHandler) {
+		h.ctx = ctx
+	}
+}
+
 func NewDeleteHandler(
 	folder storage.Folder,
 	backups []BackupObject,
@@ -90,6 +98,7 @@ func NewDeleteHandler(
 		},
 		// by default, all storage objects are impermanent
 		isPermanent: func(storage.Object) bool { return false },
+		ctx:         context.Background(),
 	}
 
 	for _, option := range options {
@@ -107,6 +116,8 @@ type DeleteHandler struct {
 	greater func(object1, object2 storage.Object) bool
 
 	isPermanent func(object storage.Object) bool
+
+	ctx context.Context
 }
 
 func (h *DeleteHandler) HandleDeleteBefore(args []string, confirmed bool) {
@@ -323,8 +334,18 @@ func (h *DeleteHandler) FindTargetRetainAfterTime(retentionCount int, timeLine t
 }
 
 func (h *DeleteHandler) DeleteEverything(confirmed bool) {
+	for _, backup := range h.backups {
+		lock, err := h.getObjectLock(backup)
+		tracelog.ErrorLogger.FatalOnError(err)
+		if lock.IsActive(utility.TimeNowCrossPlatformUTC()) {
+			tracelog.ErrorLogger.Fatalf("Unable to delete backup %s, it is locked in the storage %v\n",
+				backup.GetName(), lock)
+		}
+	}
+	err := h.checkSignatures(h.backups)
+	tracelog.ErrorLogger.FatalOnError(err)
 	filter := func(object storage.Object) bool { return true }
-	err := storage.DeleteObjectsWhere(h.Folder, confirmed, filter)
+	err = storage.DeleteObjectsWhereWithContext(h.ctx, h.Folder, confirmed, filter)
 	tracelog.ErrorLogger.FatalOnError(err)
 }
 
@@ -333,9 +354,26 @@ func (h *DeleteHandler) DeleteBeforeTarget(target BackupObject, confirmed bool)
 		errorMessage := "%v is incremental and it's predecessors cannot be deleted. Consider FIND_FULL option."
 		return utility.NewForbiddenActionError(fmt.Sprintf(errorMessage, target.GetName()))
 	}
+	target, err := h.keepLockedBackups(target)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		tracelog.InfoLogger.Println("All the backups to delete are locked in the storage, nothing to delete")
+		return nil
+	}
+	backupsToDelete := make([]BackupObject, 0)
+	for _, backup := range h.backups {
+		if h.less(backup, target) && !h.isPermanent(backup) {
+			backupsToDelete = append(backupsToDelete, backup)
+		}
+	}
+	if err = h.checkSignatures(backupsToDelete); err != nil {
+		return err
+	}
 	tracelog.InfoLogger.Println("Start delete")
 
-	return storage.DeleteObjectsWhere(h.Folder, confirmed, func(object storage.Object) bool {
+	return storage.DeleteObjectsWhereWithContext(h.ctx, h.Folder, confirmed, func(object storage.Object) bool {
 		return h.less(object, target) && !h.isPermanent(object)
 	})
 }
@@ -346,15 +384,90 @@ func (h *DeleteHandler) DeleteTargets(targets []BackupObject, confirmed bool) er
 		if h.isPermanent(target) {
 			tracelog.ErrorLogger.Fatalf("Unable to delete permanent backup %s\n", target.GetName())
 		}
+		lock, err := h.getObjectLock(target)
+		if err != nil {
+			return err
+		}
+		if lock.IsActive(utility.TimeNowCrossPlatformUTC()) {
+			return utility.NewForbiddenActionError(fmt.Sprintf(
+				"Unable to delete backup %s, it is locked in the storage %v", target.GetName(), lock))
+		}
 		backupNamesToDelete[target.GetBackupName()] = true
 	}
+	if err := h.checkSignatures(targets); err != nil {
+		return err
+	}
 
-	return storage.DeleteObjectsWhere(h.Folder.GetSubFolder(utility.BaseBackupPath),
+	return storage.DeleteObjectsWhereWithContext(h.ctx, h.Folder.GetSubFolder(utility.BaseBackupPath),
 		confirmed, func(object storage.Object) bool {
 			return backupNamesToDelete[utility.StripLeftmostBackupName(object.GetName())] && !h.isPermanent(object)
 		})
 }
 
+// keepLockedBackups moves the target of the deletion so that the backups locked in the storage
+// are kept along with the full backups they depend on. It returns nil if there is nothing to delete.
+func (h *DeleteHandler) keepLockedBackups(target BackupObject) (BackupObject, error) {
+	candidates := make([]BackupObject, 0)
+	for _, backup := range h.backups {
+		if h.less(backup, target) {
+			candidates = append(candidates, backup)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return h.less(candidates[i], candidates[j])
+	})
+
+	now := utility.TimeNowCrossPlatformUTC()
+	for i, backup := range candidates {
+		if h.isPermanent(backup) {
+			continue
+		}
+		lock, err := h.getObjectLock(backup)
+		if err != nil {
+			return nil, err
+		}
+		if !lock.IsActive(now) {
+			continue
+		}
+		tracelog.InfoLogger.Printf("Backup %s is locked in the storage %v, it will be kept with all the later backups\n",
+			backup.GetName(), lock)
+		for j := i; j >= 0; j-- {
+			if candidates[j].IsFullBackup() {
+				return candidates[j], nil
+			}
+		}
+		return nil, nil
+	}
+	return target, nil
+}
+
+// checkSignatures verifies that the backups to delete, and so their permanence, are not altered
+// since they were signed, see CheckBackupSignature
+func (h *DeleteHandler) checkSignatures(backups []BackupObject) error {
+	baseBackupFolder := h.Folder.GetSubFolder(utility.BaseBackupPath)
+	for _, backup := range backups {
+		err := CheckBackupSignature(NewBackup(baseBackupFolder, backup.GetBackupName()))
+		if _, ok := err.(BackupNotSignedError); ok {
+			return errors.Wrapf(err, "unable to delete backup %s, set %s to delete the backups made before the signing",
+				backup.GetBackupName(), SignStrictSinceSetting)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to delete backup %s", backup.GetBackupName())
+		}
+	}
+	return nil
+}
+
+// getObjectLock returns the lock of the backup sentinel. The sentinel is uploaded last,
+// so the other objects of the backup are unlocked not later than the sentinel.
+func (h *DeleteHandler) getObjectLock(backup BackupObject) (storage.ObjectLock, error) {
+	lock, err := storage.GetObjectLock(h.ctx, h.Folder.GetSubFolder(utility.BaseBackupPath), backup.GetName())
+	if _, ok := err.(storage.ObjectNotFoundError); ok {
+		return storage.ObjectLock{}, nil
+	}
+	return lock, errors.Wrapf(err, "failed to check the lock of backup %s", backup.GetName())
+}
+
 // Find all backups related to the target.
 // All delta backups with the same base backup are considered as related.
 func (h *DeleteHandler) findRelatedBackups(target BackupObject) []BackupObject {
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -32,12 +33,18 @@ func (err ArchiveNonExistenceError) Error() string {
 
 // DownloadFile downloads, decompresses and decrypts
 func DownloadFile(folder storage.Folder, filename, ext string, writeCloser io.WriteCloser) error {
+	return DownloadFileWithContext(context.Background(), folder, filename, ext, writeCloser)
+}
+
+// DownloadFileWithContext is the cancellable version of DownloadFile
+func DownloadFileWithContext(ctx context.Context, folder storage.Folder, filename, ext string,
+	writeCloser io.WriteCloser) error {
 	decompressor := compression.FindDecompressor(ext)
 	if decompressor == nil {
 		return fmt.Errorf("decompressor for extension '%s' was not found", ext)
 	}
 	tracelog.DebugLogger.Printf("Found decompressor for %s", decompressor.FileExtension())
-	archiveReader, exists, err := TryDownloadFile(folder, filename)
+	archiveReader, exists, err := TryDownloadFileWithContext(ctx, folder, filename)
 	if err != nil {
 		return err
 	}
@@ -54,7 +61,12 @@ func DownloadFile(folder storage.Folder, filename, ext string, writeCloser io.Wr
 }
 
 func TryDownloadFile(folder storage.Folder, path string) (walFileReader io.ReadCloser, exists bool, err error) {
-	walFileReader, err = folder.ReadObject(path)
+	return TryDownloadFileWithContext(context.Background(), folder, path)
+}
+
+func TryDownloadFileWithContext(ctx context.Context, folder storage.Folder,
+	path string) (walFileReader io.ReadCloser, exists bool, err error) {
+	walFileReader, err = storage.NewContextFolder(folder).ReadObjectWithContext(ctx, path)
 	if err == nil {
 		exists = true
 		return
@@ -73,12 +85,37 @@ func DecompressDecryptBytes(dst io.Writer, archiveReader io.ReadCloser, decompre
 	}
 
 	err = decompressor.Decompress(dst, decryptReadCloser)
+	if integrityErr := checkArchiveIntegrity(archiveReader, err); integrityErr != nil {
+		return integrityErr
+	}
 	if err != nil {
 		return fmt.Errorf("failed to decompress archive reader: %w", err)
 	}
 	return nil
 }
 
+// checkArchiveIntegrity finds out whether the archive was corrupted in the storage.
+// The checksum is verified when the archive is read to the end. The decompressor may stop before the end,
+// so the rest of an archive decompressed successfully is read to verify it. The archive is not drained
+// after a decompression error, only the checksum mismatch met by the decompressor itself is reported then.
+func checkArchiveIntegrity(archiveReader io.Reader, decompressErr error) error {
+	if !storage.VerifiesChecksum(archiveReader) {
+		return nil
+	}
+	var checksumErr storage.ChecksumMismatchError
+	if errors.As(decompressErr, &checksumErr) {
+		return checksumErr
+	}
+	if decompressErr != nil {
+		return nil
+	}
+	_, err := io.Copy(ioutil.Discard, archiveReader)
+	if errors.As(err, &checksumErr) {
+		return checksumErr
+	}
+	return nil
+}
+
 func DecryptBytes(archiveReader io.ReadCloser) (io.ReadCloser, error) {
 	crypter := ConfigureCrypter()
 	if crypter == nil {
@@ -170,8 +207,14 @@ func putCachedDecompressorInFirstPlace(decompressors []compression.Decompressor)
 
 // TODO : unit tests
 func DownloadAndDecompressStorageFile(folder storage.Folder, fileName string) (io.ReadCloser, error) {
+	return DownloadAndDecompressStorageFileWithContext(context.Background(), folder, fileName)
+}
+
+// DownloadAndDecompressStorageFileWithContext is the cancellable version of DownloadAndDecompressStorageFile
+func DownloadAndDecompressStorageFileWithContext(ctx context.Context, folder storage.Folder,
+	fileName string) (io.ReadCloser, error) {
 	for _, decompressor := range putCachedDecompressorInFirstPlace(compression.Decompressors) {
-		archiveReader, exists, err := TryDownloadFile(folder, fileName+"."+decompressor.FileExtension())
+		archiveReader, exists, err := TryDownloadFileWithContext(ctx, folder, fileName+"."+decompressor.FileExtension())
 		if err != nil {
 			return nil, err
 		}
@@ -179,9 +222,11 @@ func DownloadAndDecompressStorageFile(folder storage.Folder, fileName string) (i
 			continue
 		}
 		_ = SetLastDecompressor(decompressor)
+		// the files compressed with the dictionaries refer to them by their IDs
+		dictionaryDecompressor := compression.WithDictionaries(decompressor, NewStorageDictionaryLoader(folder))
 		reader, writer := io.Pipe()
 		go func() {
-			err = DecompressDecryptBytes(&EmptyWriteIgnorer{writer}, archiveReader, decompressor)
+			err = DecompressDecryptBytes(&EmptyWriteIgnorer{writer}, archiveReader, dictionaryDecompressor)
 			_ = writer.CloseWithError(err)
 		}()
 		return reader, nil
@@ -192,13 +237,18 @@ func DownloadAndDecompressStorageFile(folder storage.Folder, fileName string) (i
 // TODO : unit tests
 // DownloadFileTo downloads a file and writes it to local file
 func DownloadFileTo(folder storage.Folder, fileName string, dstPath string) error {
+	return DownloadFileToWithContext(context.Background(), folder, fileName, dstPath)
+}
+
+// DownloadFileToWithContext is the cancellable version of DownloadFileTo
+func DownloadFileToWithContext(ctx context.Context, folder storage.Folder, fileName string, dstPath string) error {
 	// Create file as soon as possible. It may be important due to race condition in wal-prefetch for PG.
 	file, err := os.OpenFile(dstPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0666)
 	if err != nil {
 		return err
 	}
 
-	reader, err := DownloadAndDecompressStorageFile(folder, fileName)
+	reader, err := DownloadAndDecompressStorageFileWithContext(ctx, folder, fileName)
 	if err != nil {
 		// We could not start upload - remove the file totally.
 		_ = os.Remove(dstPath)
@@ -0,0 +1,51 @@
+package internal_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type bufferWriteCloser struct {
+	bytes.Buffer
+}
+
+func (buffer *bufferWriteCloser) Close() error {
+	return nil
+}
+
+func TestDownloadFile_DetectsCorruptedArchive(t *testing.T) {
+	underlying := memory.NewFolder("in_memory/", memory.NewStorage())
+	folder := storage.NewChecksumFolder(underlying)
+	data := strings.Repeat("wal-g", 100)
+	archive, err := ioutil.ReadAll(internal.CompressAndEncrypt(strings.NewReader(data), lz4.Compressor{}, nil))
+	assert.NoError(t, err)
+	assert.NoError(t, folder.PutObject("file.lz4", bytes.NewReader(archive)))
+
+	var dst bufferWriteCloser
+	assert.NoError(t, internal.DownloadFile(folder, "file.lz4", "lz4", &dst))
+	assert.Equal(t, data, dst.String())
+
+	// the archive doesn't match the stored checksum, though it can be decompressed
+	checksum := strings.Repeat("0", 64)
+	assert.NoError(t, underlying.PutObject("file.lz4"+storage.ChecksumSuffix, strings.NewReader(checksum)))
+
+	err = internal.DownloadFile(folder, "file.lz4", "lz4", &bufferWriteCloser{})
+	assert.IsType(t, storage.ChecksumMismatchError{}, err)
+	assert.Contains(t, err.Error(), "file.lz4")
+
+	// the decompression error is reported as is, the rest of the archive is not read
+	archive[len(archive)/2] ^= 1
+	assert.NoError(t, underlying.PutObject("file.lz4", bytes.NewReader(archive)))
+
+	err = internal.DownloadFile(folder, "file.lz4", "lz4", &bufferWriteCloser{})
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "is corrupted: checksum is")
+}
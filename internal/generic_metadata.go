@@ -24,6 +24,12 @@ type GenericMetadata struct {
 	IsPermanent   bool
 	IsIncremental bool
 
+	// StorageClass is the storage class the backup was moved to by backup-tier, empty if it was never moved
+	StorageClass string
+
+	// EncryptionKey identifies the key the backup was re-encrypted with by rotate-keys, empty if it was never re-encrypted
+	EncryptionKey string
+
 	// need to use separate fetcher
 	// to avoid useless sentinel load (in Postgres)
 	IncrementDetails IncrementDetailsFetcher
@@ -57,6 +63,8 @@ type GenericMetaFetcher interface {
 type GenericMetaSetter interface {
 	SetUserData(backupName string, backupFolder storage.Folder, userData interface{}) error
 	SetIsPermanent(backupName string, backupFolder storage.Folder, isPermanent bool) error
+	SetStorageClass(backupName string, backupFolder storage.Folder, storageClass string) error
+	SetEncryptionKey(backupName string, backupFolder storage.Folder, encryptionKey string) error
 }
 
 // NopIncrementDetailsFetcher is useful for databases without incremental backup support
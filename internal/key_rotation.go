@@ -0,0 +1,406 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// RotationTmpSuffix is appended to the name of the re-encrypted object until it replaces the original one
+	RotationTmpSuffix = ".rotation_tmp"
+
+	rotationStaged = "staged"
+	rotationDone   = "done"
+
+	rotationProgressStep = 100
+)
+
+// KeyRotationJournal is the local append-only log of the rotated objects, which makes the rotation resumable.
+// An object is staged when its re-encrypted copy is uploaded and done when the copy has replaced it.
+type KeyRotationJournal struct {
+	mutex  sync.Mutex
+	file   *os.File
+	states map[string]string
+}
+
+// OpenKeyRotationJournal reads the states recorded by the previous runs and opens the journal for appending
+func OpenKeyRotationJournal(journalPath string) (*KeyRotationJournal, error) {
+	file, err := os.OpenFile(journalPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open the key rotation journal '%s'", journalPath)
+	}
+	states := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 2)
+		if len(fields) != 2 || fields[0] != rotationStaged && fields[0] != rotationDone {
+			continue
+		}
+		states[fields[1]] = fields[0]
+	}
+	if err = scanner.Err(); err != nil {
+		utility.LoggedClose(file, "")
+		return nil, errors.Wrapf(err, "failed to read the key rotation journal '%s'", journalPath)
+	}
+	return &KeyRotationJournal{file: file, states: states}, nil
+}
+
+func (journal *KeyRotationJournal) state(objectPath string) string {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	return journal.states[objectPath]
+}
+
+func (journal *KeyRotationJournal) record(objectPath, state string) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	if _, err := journal.file.WriteString(state + " " + objectPath + "\n"); err != nil {
+		return errors.Wrap(err, "failed to write the key rotation journal")
+	}
+	if err := journal.file.Sync(); err != nil {
+		return errors.Wrap(err, "failed to sync the key rotation journal")
+	}
+	journal.states[objectPath] = state
+	return nil
+}
+
+func (journal *KeyRotationJournal) Close() error {
+	return journal.file.Close()
+}
+
+// KeyRotationHandler re-encrypts the objects of the storage, which were encrypted with the old key, with the new key.
+// The re-encrypted object is uploaded next to the original one and then copied over it,
+// so the original object is never left half-written.
+type KeyRotationHandler struct {
+	metaInteractor   GenericMetaInteractor
+	rootFolder       storage.Folder
+	baseBackupFolder storage.Folder
+	oldCrypter       crypto.Crypter
+	newCrypter       crypto.Crypter
+	journal          *KeyRotationJournal
+	concurrency      int
+	// rotated are the checksums of the re-encrypted objects to sign them with the backups
+	rotated *uploadedChecksums
+}
+
+func NewKeyRotationHandler(metaInteractor GenericMetaInteractor, storageRootFolder storage.Folder,
+	oldCrypter, newCrypter crypto.Crypter, journal *KeyRotationJournal, concurrency int) KeyRotationHandler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return KeyRotationHandler{
+		metaInteractor:   metaInteractor,
+		rootFolder:       storageRootFolder,
+		baseBackupFolder: storageRootFolder.GetSubFolder(utility.BaseBackupPath),
+		oldCrypter:       oldCrypter,
+		newCrypter:       newCrypter,
+		journal:          journal,
+		concurrency:      concurrency,
+		rotated:          &uploadedChecksums{checksums: make(map[string]string)},
+	}
+}
+
+// HandleKeyRotation re-encrypts the named backup and its WAL range, or the whole storage if no backup is named
+func HandleKeyRotation(folder storage.Folder, metaInteractor GenericMetaInteractor, oldCrypter, newCrypter crypto.Crypter,
+	backupName, journalPath string, concurrency int, dryRun bool) {
+	if oldCrypter == nil || newCrypter == nil {
+		tracelog.ErrorLogger.Fatal("Both the old and the new encryption keys must be configured")
+	}
+	journal, err := OpenKeyRotationJournal(journalPath)
+	tracelog.ErrorLogger.FatalOnError(err)
+	defer utility.LoggedClose(journal, "Failed to close the key rotation journal")
+
+	rotationHandler := NewKeyRotationHandler(metaInteractor, folder, oldCrypter, newCrypter, journal, concurrency)
+	var backupNames []string
+	var objects []storage.Object
+	if backupName == "" {
+		backupNames, objects, err = rotationHandler.FindAllObjects()
+	} else {
+		backupNames = []string{backupName}
+		objects, err = rotationHandler.FindBackupObjects(backupName)
+	}
+	tracelog.ErrorLogger.FatalfOnError("Failed to find the objects to re-encrypt: %v", err)
+
+	if dryRun {
+		var size int64
+		for _, object := range objects {
+			tracelog.InfoLogger.Printf("Would re-encrypt '%s'\n", object.GetName())
+			size += object.GetSize()
+		}
+		tracelog.InfoLogger.Printf("Would re-encrypt %d objects (%d bytes) of %d backups\n",
+			len(objects), size, len(backupNames))
+		return
+	}
+	err = rotationHandler.RotateObjects(objects)
+	tracelog.ErrorLogger.FatalfOnError("Failed to re-encrypt the objects: %v", err)
+	err = rotationHandler.SignRotatedBackups(backupNames)
+	tracelog.ErrorLogger.FatalfOnError("Failed to sign the re-encrypted backups: %v", err)
+	err = rotationHandler.RecordKeyIdentity(backupNames)
+	tracelog.ErrorLogger.FatalfOnError("Failed to record the encryption key: %v", err)
+}
+
+// FindAllObjects returns the names of all the backups and all the encrypted objects of the storage
+func (h *KeyRotationHandler) FindAllObjects() ([]string, []storage.Object, error) {
+	backups, err := GetBackups(h.baseBackupFolder)
+	if _, ok := err.(NoBackupsFoundError); ok {
+		err = nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	backupNames := make([]string, 0, len(backups))
+	for _, backup := range backups {
+		backupNames = append(backupNames, backup.BackupName)
+	}
+	objects, err := storage.ListFolderRecursively(h.rootFolder)
+	if err != nil {
+		return nil, nil, err
+	}
+	return backupNames, filterRotatedObjects(objects), nil
+}
+
+// FindBackupObjects returns the encrypted objects of the backup and of the WAL segments
+// from the backup start up to the start of the next backup
+func (h *KeyRotationHandler) FindBackupObjects(backupName string) ([]storage.Object, error) {
+	backups, err := GetBackups(h.baseBackupFolder)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].WalFileName < backups[j].WalFileName
+	})
+	var fromWal, toWal string
+	for i, backup := range backups {
+		if backup.BackupName != backupName {
+			continue
+		}
+		fromWal = backup.WalFileName
+		if i+1 < len(backups) {
+			toWal = backups[i+1].WalFileName
+		}
+	}
+	if fromWal == "" {
+		return nil, NewBackupNonExistenceError(backupName)
+	}
+
+	backupObjects, err := h.listSubFolder(utility.BaseBackupPath + backupName + "/")
+	if err != nil {
+		return nil, err
+	}
+	objects := filterRotatedObjects(backupObjects)
+	walObjects, err := h.listSubFolder(utility.WalPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range filterRotatedObjects(walObjects) {
+		walName := path.Base(object.GetName())
+		if walName < fromWal || toWal != "" && walName >= toWal {
+			continue
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// listSubFolder lists the subfolder recursively, the names of the objects are relative to the root folder
+func (h *KeyRotationHandler) listSubFolder(subFolderPath string) ([]storage.Object, error) {
+	objects, err := storage.ListFolderRecursively(h.rootFolder.GetSubFolder(subFolderPath))
+	if err != nil {
+		return nil, err
+	}
+	for i, object := range objects {
+		objects[i] = storage.NewLocalObject(path.Join(subFolderPath, object.GetName()), object.GetLastModified(), object.GetSize())
+	}
+	return objects, nil
+}
+
+// RotateObjects re-encrypts the objects, skipping the ones recorded as done in the journal
+func (h *KeyRotationHandler) RotateObjects(objects []storage.Object) error {
+	pending := make([]storage.Object, 0, len(objects))
+	for _, object := range objects {
+		if h.journal.state(object.GetName()) != rotationDone {
+			pending = append(pending, object)
+			continue
+		}
+		// the object is re-encrypted by the previous run, its checksum is unknown
+		h.rotated.invalidate(h.objectPath(object.GetName()))
+	}
+	if len(pending) < len(objects) {
+		tracelog.InfoLogger.Printf("Skipping %d objects already re-encrypted\n", len(objects)-len(pending))
+	}
+
+	var rotated int64
+	group, ctx := errgroup.WithContext(context.Background())
+	tickets := make(chan struct{}, h.concurrency)
+	for _, object := range pending {
+		select {
+		case tickets <- struct{}{}:
+		case <-ctx.Done():
+			return group.Wait()
+		}
+		objectName := object.GetName()
+		group.Go(func() error {
+			defer func() { <-tickets }()
+			if err := h.rotateObject(objectName); err != nil {
+				return errors.Wrapf(err, "failed to re-encrypt '%s'", objectName)
+			}
+			count := atomic.AddInt64(&rotated, 1)
+			if count%rotationProgressStep == 0 || int(count) == len(pending) {
+				tracelog.InfoLogger.Printf("Re-encrypted %d/%d objects\n", count, len(pending))
+			}
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+func (h *KeyRotationHandler) rotateObject(objectName string) error {
+	tmpName := objectName + RotationTmpSuffix
+	if h.journal.state(objectName) == rotationStaged {
+		// the previous run was interrupted while swapping the objects
+		exists, err := h.rootFolder.Exists(tmpName)
+		if err != nil {
+			return err
+		}
+		h.rotated.invalidate(h.objectPath(objectName))
+		if exists {
+			return h.swapObject(objectName, tmpName)
+		}
+		return h.journal.record(objectName, rotationDone)
+	}
+
+	tracelog.DebugLogger.Printf("Re-encrypting '%s'\n", objectName)
+	if err := h.reencryptObject(objectName, tmpName); err != nil {
+		return err
+	}
+	if err := h.journal.record(objectName, rotationStaged); err != nil {
+		return err
+	}
+	return h.swapObject(objectName, tmpName)
+}
+
+func (h *KeyRotationHandler) reencryptObject(objectName, tmpName string) error {
+	reader, err := h.rootFolder.ReadObject(objectName)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+
+	pipeReader, pipeWriter := io.Pipe()
+	if rewrapper, ok := h.newCrypter.(crypto.Rewrapper); ok && rewrapper.CanRewrap(h.oldCrypter) {
+		// only the header is re-encrypted, the data is copied as is
+		go func() {
+			pipeWriter.CloseWithError(rewrapper.Rewrap(pipeWriter, reader, h.oldCrypter))
+		}()
+	} else {
+		decrypted, err := h.oldCrypter.Decrypt(reader)
+		if err != nil {
+			return errors.Wrap(err, "failed to decrypt with the old key")
+		}
+		go func() {
+			encrypted, err := h.newCrypter.Encrypt(pipeWriter)
+			if err != nil {
+				pipeWriter.CloseWithError(errors.Wrap(err, "failed to encrypt with the new key"))
+				return
+			}
+			if _, err = utility.FastCopy(encrypted, decrypted); err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+			pipeWriter.CloseWithError(encrypted.Close())
+		}()
+	}
+	checksum := sha256.New()
+	err = h.rootFolder.PutObject(tmpName, io.TeeReader(pipeReader, checksum))
+	// unblock the encrypting goroutine if the upload has failed
+	pipeReader.CloseWithError(err)
+	if err == nil {
+		h.rotated.add(h.objectPath(objectName), checksum)
+	}
+	return err
+}
+
+func (h *KeyRotationHandler) objectPath(objectName string) string {
+	return storage.JoinPath(h.rootFolder.GetPath(), objectName)
+}
+
+// swapObject replaces the object with the re-encrypted copy, S3 copies the objects over 5 GiB in parts
+func (h *KeyRotationHandler) swapObject(objectName, tmpName string) error {
+	if err := h.rootFolder.CopyObject(tmpName, objectName); err != nil {
+		return errors.Wrap(err, "failed to replace the object with the re-encrypted one")
+	}
+	if err := h.rootFolder.DeleteObjects([]string{tmpName}); err != nil {
+		return err
+	}
+	return h.journal.record(objectName, rotationDone)
+}
+
+// SignRotatedBackups signs the backups again with the checksums of their re-encrypted objects,
+// it must be done before the backups are signed for any other reason
+func (h *KeyRotationHandler) SignRotatedBackups(backupNames []string) error {
+	rotated := h.rotated.copy()
+	for _, backupName := range backupNames {
+		backup := NewBackup(h.baseBackupFolder, backupName)
+		if err := backup.SignUploaded(rotated); err != nil {
+			return errors.Wrapf(err, "failed to sign backup %s", backupName)
+		}
+	}
+	return nil
+}
+
+// RecordKeyIdentity records the identity of the new key in the metadata of the backups
+func (h *KeyRotationHandler) RecordKeyIdentity(backupNames []string) error {
+	keyIdentity, err := crypto.GetKeyIdentity(h.newCrypter)
+	if err != nil {
+		return err
+	}
+	for _, backupName := range backupNames {
+		err = h.metaInteractor.SetEncryptionKey(backupName, h.baseBackupFolder, keyIdentity)
+		if err != nil {
+			return errors.Wrapf(err, "failed to record the encryption key of backup %s", backupName)
+		}
+	}
+	tracelog.InfoLogger.Printf("Recorded key %s in the metadata of %d backups\n", keyIdentity, len(backupNames))
+	return nil
+}
+
+// uncompressedExtensions are the extensions of the encrypted objects stored without compression,
+// the partitions and the streams which the adaptive compression has found incompressible,
+// and the compression dictionaries
+var uncompressedExtensions = map[string]bool{
+	".tar":                            true,
+	"." + UncompressedStreamExtension: true,
+	DictionarySuffix:                  true,
+}
+
+// filterRotatedObjects keeps the encrypted objects only. Every encrypted object is compressed too,
+// unless it has one of uncompressedExtensions, while the sentinels and the metadata files are stored as plain JSON.
+func filterRotatedObjects(objects []storage.Object) []storage.Object {
+	filtered := make([]storage.Object, 0, len(objects))
+	for _, object := range objects {
+		name := object.GetName()
+		extension := path.Ext(name)
+		if strings.HasSuffix(name, RotationTmpSuffix) ||
+			compression.FindDecompressor(extension) == nil && !uncompressedExtensions[extension] {
+			continue
+		}
+		filtered = append(filtered, object)
+	}
+	return filtered
+}
@@ -0,0 +1,257 @@
+package internal_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// prefixCrypter "encrypts" the data by prepending its key, so the key used is visible in the object
+type prefixCrypter struct {
+	key string
+}
+
+func (crypter prefixCrypter) Name() string {
+	return "prefix"
+}
+
+func (crypter prefixCrypter) KeyID() (string, error) {
+	return crypter.key, nil
+}
+
+func (crypter prefixCrypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
+	if _, err := io.WriteString(writer, crypter.key+":"); err != nil {
+		return nil, err
+	}
+	return nopWriteCloser{writer}, nil
+}
+
+func (crypter prefixCrypter) Decrypt(reader io.Reader) (io.Reader, error) {
+	prefix := make([]byte, len(crypter.key)+1)
+	if _, err := io.ReadFull(reader, prefix); err != nil {
+		return nil, err
+	}
+	if string(prefix) != crypter.key+":" {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return reader, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// rotationMetaInteractor keeps only the encryption keys of the backups, they are uploaded as the metadata
+// of the backups, so the backups are signed again like with the real metadata
+type rotationMetaInteractor struct {
+	keys map[string]string
+}
+
+func (mi rotationMetaInteractor) Fetch(backupName string, backupFolder storage.Folder) (internal.GenericMetadata, error) {
+	return internal.GenericMetadata{BackupName: backupName, EncryptionKey: mi.keys[backupName]}, nil
+}
+
+func (mi rotationMetaInteractor) SetUserData(backupName string, backupFolder storage.Folder, userData interface{}) error {
+	return nil
+}
+
+func (mi rotationMetaInteractor) SetIsPermanent(backupName string, backupFolder storage.Folder, isPermanent bool) error {
+	return nil
+}
+
+func (mi rotationMetaInteractor) SetStorageClass(backupName string, backupFolder storage.Folder, storageClass string) error {
+	return nil
+}
+
+func (mi rotationMetaInteractor) SetEncryptionKey(backupName string, backupFolder storage.Folder, encryptionKey string) error {
+	mi.keys[backupName] = encryptionKey
+	backup := internal.NewBackup(backupFolder, backupName)
+	return backup.UploadMetadata(map[string]string{"encryption_key": encryptionKey})
+}
+
+func makeRotationFolder(t *testing.T) storage.Folder {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	for name, content := range map[string]string{
+		utility.BaseBackupPath + "base_000000010000000000000002" + utility.SentinelSuffix:      "{}",
+		utility.BaseBackupPath + "base_000000010000000000000002/" + utility.MetadataFileName:   "{}",
+		utility.BaseBackupPath + "base_000000010000000000000002/tar_partitions/part_1.tar.lz4": "old:backup_1",
+		utility.BaseBackupPath + "base_000000010000000000000005" + utility.SentinelSuffix:      "{}",
+		utility.BaseBackupPath + "base_000000010000000000000005/tar_partitions/part_1.tar.lz4": "old:backup_2",
+		utility.BaseBackupPath + "base_000000010000000000000005/tar_partitions/part_2.tar":     "old:backup_3",
+		utility.WalPath + "000000010000000000000001.lz4":                                       "old:wal_1",
+		utility.WalPath + "000000010000000000000002.lz4":                                       "old:wal_2",
+		utility.WalPath + "000000010000000000000004.lz4":                                       "old:wal_4",
+		utility.WalPath + "000000010000000000000005.lz4":                                       "old:wal_5",
+	} {
+		require.NoError(t, folder.PutObject(name, strings.NewReader(content)))
+	}
+	return folder
+}
+
+func readObject(t *testing.T, folder storage.Folder, name string) string {
+	reader, err := folder.ReadObject(name)
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func openTestJournal(t *testing.T, dir string) *internal.KeyRotationJournal {
+	journal, err := internal.OpenKeyRotationJournal(filepath.Join(dir, "journal"))
+	require.NoError(t, err)
+	return journal
+}
+
+func TestKeyRotationHandler_FindBackupObjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	journal := openTestJournal(t, dir)
+	defer journal.Close()
+
+	folder := makeRotationFolder(t)
+	metaInteractor := rotationMetaInteractor{map[string]string{}}
+	rotationHandler := internal.NewKeyRotationHandler(metaInteractor, folder,
+		prefixCrypter{"old"}, prefixCrypter{"new"}, journal, 2)
+
+	objects, err := rotationHandler.FindBackupObjects("base_000000010000000000000002")
+	assert.NoError(t, err)
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.GetName())
+	}
+	assert.ElementsMatch(t, []string{
+		utility.BaseBackupPath + "base_000000010000000000000002/tar_partitions/part_1.tar.lz4",
+		utility.WalPath + "000000010000000000000002.lz4",
+		utility.WalPath + "000000010000000000000004.lz4",
+	}, names)
+
+	_, err = rotationHandler.FindBackupObjects("base_000000010000000000000003")
+	assert.Error(t, err)
+}
+
+func TestKeyRotationHandler_RotateObjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	journal := openTestJournal(t, dir)
+
+	folder := makeRotationFolder(t)
+	metaInteractor := rotationMetaInteractor{map[string]string{}}
+	rotationHandler := internal.NewKeyRotationHandler(metaInteractor, folder,
+		prefixCrypter{"old"}, prefixCrypter{"new"}, journal, 2)
+
+	backupNames, objects, err := rotationHandler.FindAllObjects()
+	require.NoError(t, err)
+	assert.Len(t, backupNames, 2)
+	assert.Len(t, objects, 7)
+	require.NoError(t, rotationHandler.RotateObjects(objects))
+	require.NoError(t, rotationHandler.RecordKeyIdentity(backupNames))
+	require.NoError(t, journal.Close())
+
+	assert.Equal(t, "new:wal_1", readObject(t, folder, utility.WalPath+"000000010000000000000001.lz4"))
+	assert.Equal(t, "new:backup_2",
+		readObject(t, folder, utility.BaseBackupPath+"base_000000010000000000000005/tar_partitions/part_1.tar.lz4"))
+	assert.Equal(t, "new:backup_3",
+		readObject(t, folder, utility.BaseBackupPath+"base_000000010000000000000005/tar_partitions/part_2.tar"))
+	assert.Equal(t, "{}", readObject(t, folder, utility.BaseBackupPath+"base_000000010000000000000002"+utility.SentinelSuffix))
+	assert.Equal(t, "prefix:new", metaInteractor.keys["base_000000010000000000000002"])
+	assert.Equal(t, "prefix:new", metaInteractor.keys["base_000000010000000000000005"])
+
+	objects, err = storage.ListFolderRecursively(folder)
+	require.NoError(t, err)
+	for _, object := range objects {
+		assert.False(t, strings.HasSuffix(object.GetName(), internal.RotationTmpSuffix))
+	}
+
+	// the objects recorded as done are not decrypted with the old key again
+	journal = openTestJournal(t, dir)
+	defer journal.Close()
+	rotationHandler = internal.NewKeyRotationHandler(metaInteractor, folder,
+		prefixCrypter{"old"}, prefixCrypter{"new"}, journal, 2)
+	_, objects, err = rotationHandler.FindAllObjects()
+	require.NoError(t, err)
+	assert.NoError(t, rotationHandler.RotateObjects(objects))
+}
+
+func TestKeyRotationHandler_ResumeStaged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	walName := utility.WalPath + "000000010000000000000001.lz4"
+	// the previous run has uploaded the re-encrypted object, but was interrupted before replacing the original one
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "journal"), []byte("staged "+walName+"\n"), 0600))
+	journal := openTestJournal(t, dir)
+	defer journal.Close()
+
+	folder := makeRotationFolder(t)
+	require.NoError(t, folder.PutObject(walName+internal.RotationTmpSuffix, bytes.NewBufferString("new:wal_1")))
+	// the old key is unavailable, so the staged object can only be swapped
+	rotationHandler := internal.NewKeyRotationHandler(rotationMetaInteractor{}, folder,
+		prefixCrypter{"lost"}, prefixCrypter{"new"}, journal, 1)
+	objects := []storage.Object{storage.NewLocalObject(walName, utility.TimeNowCrossPlatformUTC(), 0)}
+	assert.NoError(t, rotationHandler.RotateObjects(objects))
+	assert.Equal(t, "new:wal_1", readObject(t, folder, walName))
+	exists, err := folder.Exists(walName + internal.RotationTmpSuffix)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestKeyRotationHandler_SignedBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	journal := openTestJournal(t, dir)
+	defer journal.Close()
+	defer os.Remove(configureHMACKey(t, strings.Repeat("k", 32)))
+	defer resetToDefaults()
+
+	folder := makeRotationFolder(t)
+	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	backupName := "base_000000010000000000000005"
+	partitions := []string{"tar_partitions/part_1.tar.lz4", "tar_partitions/part_2.tar"}
+	// the backup is signed with the checksums of its objects, the re-encrypted objects have the same size
+	uploaded := make(map[string]string)
+	for _, partition := range partitions {
+		uploaded[storage.JoinPath(baseBackupFolder.GetPath(), backupName, partition)] = ""
+	}
+	backup := internal.NewBackup(baseBackupFolder, backupName)
+	require.NoError(t, backup.SignUploaded(uploaded))
+
+	rotationHandler := internal.NewKeyRotationHandler(rotationMetaInteractor{map[string]string{}}, folder,
+		prefixCrypter{"old"}, prefixCrypter{"new"}, journal, 2)
+	objects, err := rotationHandler.FindBackupObjects(backupName)
+	require.NoError(t, err)
+	require.NoError(t, rotationHandler.RotateObjects(objects))
+	require.NoError(t, rotationHandler.SignRotatedBackups([]string{backupName}))
+	require.NoError(t, rotationHandler.RecordKeyIdentity([]string{backupName}))
+
+	// the backup is fetched with its objects verified against the signed checksums
+	require.NoError(t, internal.CheckBackupSignature(backup))
+	_, verified, err := internal.VerifySignedObjects(folder, backup)
+	require.NoError(t, err)
+	for _, partition := range partitions {
+		reader, err := verified.Folder.ReadObject(backupName + "/" + partition)
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err, partition)
+		assert.True(t, strings.HasPrefix(string(content), "new:"), partition)
+		reader.Close()
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/wal-g/wal-g/pkg/storages/sh"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/pkg/storages/swift"
+	"github.com/wal-g/wal-g/pkg/storages/webdav"
 )
 
 type StorageAdapter struct {
@@ -53,4 +54,5 @@ var StorageAdapters = []StorageAdapter{
 	{"AZ_PREFIX", azure.SettingList, azure.ConfigureFolder, nil},
 	{"SWIFT_PREFIX", swift.SettingList, swift.ConfigureFolder, nil},
 	{"SSH_PREFIX", sh.SettingsList, sh.ConfigureFolder, nil},
+	{"WEBDAV_PREFIX", webdav.SettingList, webdav.ConfigureFolder, nil},
 }
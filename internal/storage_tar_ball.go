@@ -25,6 +25,8 @@ type StorageTarBall struct {
 	tarWriter   *tar.Writer
 	uploader    *Uploader
 	name        string
+	// uncompressed tarball is uploaded as is, its name has the plain .tar extension
+	uncompressed bool
 }
 
 func (tarBall *StorageTarBall) Name() string {
@@ -34,11 +36,14 @@ func (tarBall *StorageTarBall) Name() string {
 // SetUp creates a new tar writer and starts upload to storage.
 // Upload will block until the tar file is finished writing.
 // If a name for the file is not given, default name is of
-// the form `part_....tar.[Compressor file extension]`.
+// the form `part_....tar.[Compressor file extension]`,
+// or `part_....tar` if the tarball is uncompressed.
 func (tarBall *StorageTarBall) SetUp(crypter crypto.Crypter, names ...string) {
 	if tarBall.tarWriter == nil {
 		if len(names) > 0 {
 			tarBall.name = names[0]
+		} else if tarBall.uncompressed {
+			tarBall.name = fmt.Sprintf("part_%0.3d.tar", tarBall.partNumber)
 		} else {
 			tarBall.name = fmt.Sprintf("part_%0.3d.tar.%v", tarBall.partNumber, tarBall.uploader.Compressor.FileExtension())
 		}
@@ -114,6 +119,10 @@ func (tarBall *StorageTarBall) startUpload(name string, crypter crypto.Crypter)
 		writerToCompress = &utility.CascadeWriteCloser{WriteCloser: encryptedWriter, Underlying: pipeWriter}
 	}
 
+	if tarBall.uncompressed {
+		return writerToCompress
+	}
+
 	return &utility.CascadeWriteCloser{WriteCloser: uploader.Compressor.NewWriter(writerToCompress),
 		Underlying: writerToCompress}
 }
@@ -1,28 +1,38 @@
 package internal
 
+import "sync/atomic"
+
 // StorageTarBallMaker creates tarballs that are uploaded to storage.
 type StorageTarBallMaker struct {
-	partCount  int
-	backupName string
-	uploader   *Uploader
+	partCount    *int32
+	backupName   string
+	uploader     *Uploader
+	uncompressed bool
 }
 
 func NewStorageTarBallMaker(backupName string, uploader *Uploader) *StorageTarBallMaker {
-	return &StorageTarBallMaker{0, backupName, uploader}
+	return &StorageTarBallMaker{new(int32), backupName, uploader, false}
+}
+
+// UncompressedMaker returns the maker of the tarballs which are uploaded without compression.
+// The part numbers are shared with this maker, so the names of the parts don't clash.
+func (tarBallMaker *StorageTarBallMaker) UncompressedMaker() *StorageTarBallMaker {
+	return &StorageTarBallMaker{tarBallMaker.partCount, tarBallMaker.backupName, tarBallMaker.uploader, true}
 }
 
 // Make returns a tarball with required storage fields.
 func (tarBallMaker *StorageTarBallMaker) Make(dedicatedUploader bool) TarBall {
-	tarBallMaker.partCount++
+	partNumber := atomic.AddInt32(tarBallMaker.partCount, 1)
 	uploader := tarBallMaker.uploader
 	if dedicatedUploader {
 		uploader = uploader.Clone()
 	}
 	size := int64(0)
 	return &StorageTarBall{
-		partNumber: tarBallMaker.partCount,
-		backupName: tarBallMaker.backupName,
-		uploader:   uploader,
-		partSize:   &size,
+		partNumber:   int(partNumber),
+		backupName:   tarBallMaker.backupName,
+		uploader:     uploader,
+		partSize:     &size,
+		uncompressed: tarBallMaker.uncompressed,
 	}
 }
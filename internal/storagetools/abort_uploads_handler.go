@@ -0,0 +1,44 @@
+package storagetools
+
+import (
+	"context"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// HandleAbortUploads aborts the pending uploads started before the threshold, so that their parts don't occupy the storage
+func HandleAbortUploads(folder storage.Folder, olderThan time.Duration, dryRun bool) {
+	stale, err := FindStaleUploads(folder, time.Now().Add(-olderThan))
+	tracelog.ErrorLogger.FatalfOnError("Failed to list the pending uploads: %v", err)
+	if len(stale) == 0 {
+		tracelog.InfoLogger.Println("No stale uploads found")
+		return
+	}
+	for _, upload := range stale {
+		if dryRun {
+			tracelog.InfoLogger.Printf("Would abort the upload of %s started at %s\n",
+				upload.Name, upload.Initiated.Format(time.RFC3339))
+			continue
+		}
+		tracelog.InfoLogger.Printf("Aborting the upload of %s started at %s\n", upload.Name, upload.Initiated.Format(time.RFC3339))
+		err = storage.AbortPendingUpload(context.Background(), folder, upload)
+		tracelog.ErrorLogger.FatalfOnError("Failed to abort the upload: %v", err)
+	}
+}
+
+// FindStaleUploads returns the pending uploads started before the threshold
+func FindStaleUploads(folder storage.Folder, threshold time.Time) ([]storage.PendingUpload, error) {
+	uploads, err := storage.ListPendingUploads(context.Background(), folder)
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]storage.PendingUpload, 0, len(uploads))
+	for _, upload := range uploads {
+		if upload.Initiated.Before(threshold) {
+			stale = append(stale, upload)
+		}
+	}
+	return stale, nil
+}
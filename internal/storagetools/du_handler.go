@@ -0,0 +1,214 @@
+package storagetools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// otherWalFiles groups the objects of the WAL folder which don't belong to a timeline, e.g. the delta maps
+const otherWalFiles = "other"
+
+var walTimelineRegexp = regexp.MustCompile("^([0-9A-F]{8})[0-9A-F]{16}|^([0-9A-F]{8})\\.history")
+
+// permanentSettingNames are the names of the permanence flag in the sentinels and metadata of the databases
+var permanentSettingNames = []string{"is_permanent", "IsPermanent", "Permanent"}
+
+// UsageItem is the total size of a group of objects
+type UsageItem struct {
+	Name    string `json:"name,omitempty"`
+	Objects int    `json:"objects"`
+	Size    int64  `json:"size"`
+}
+
+func (item *UsageItem) add(object storage.Object) {
+	item.Objects++
+	item.Size += object.GetSize()
+}
+
+type BackupUsage struct {
+	UsageItem
+	Permanent bool `json:"permanent"`
+}
+
+// UsageReport breaks the storage usage down by the backups, WAL timelines, oplog and binlog archives.
+// Garbage is the objects in the backups folder which don't belong to any backup with a sentinel.
+type UsageReport struct {
+	Backups      []*BackupUsage `json:"backups"`
+	WalTimelines []*UsageItem   `json:"wal_timelines"`
+	Oplog        UsageItem      `json:"oplog"`
+	Binlog       UsageItem      `json:"binlog"`
+	Garbage      UsageItem      `json:"garbage"`
+	Other        UsageItem      `json:"other"`
+	Total        UsageItem      `json:"total"`
+}
+
+func HandleStorageUsage(folder storage.Folder, asJSON, pretty bool) {
+	report, err := BuildUsageReport(folder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to build the storage usage report: %v", err)
+
+	if asJSON {
+		err = internal.WriteAsJSON(report, os.Stdout, pretty)
+	} else {
+		err = WriteUsageReport(report, os.Stdout)
+	}
+	tracelog.ErrorLogger.FatalfOnError("Failed to write the storage usage report: %v", err)
+}
+
+// BuildUsageReport lists the whole storage and aggregates the sizes of the objects,
+// the sentinels and metadata of the backups are read to find the permanent ones
+func BuildUsageReport(folder storage.Folder) (*UsageReport, error) {
+	objects, err := storage.ListFolderRecursively(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make(map[string]*BackupUsage)
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.GetName(), utility.BaseBackupPath)
+		if name != object.GetName() && strings.HasSuffix(name, utility.SentinelSuffix) && !strings.Contains(name, "/") {
+			backupName := strings.TrimSuffix(name, utility.SentinelSuffix)
+			backups[backupName] = &BackupUsage{UsageItem: UsageItem{Name: backupName}}
+		}
+	}
+
+	report := &UsageReport{}
+	timelines := make(map[string]*UsageItem)
+	for _, object := range objects {
+		report.Total.add(object)
+		name := object.GetName()
+		switch {
+		case strings.HasPrefix(name, utility.BaseBackupPath):
+			backup := findBackup(backups, strings.TrimPrefix(name, utility.BaseBackupPath))
+			if backup == nil {
+				report.Garbage.add(object)
+			} else {
+				backup.add(object)
+			}
+		case strings.HasPrefix(name, utility.WalPath):
+			timeline := getWalTimeline(strings.TrimPrefix(name, utility.WalPath))
+			if _, ok := timelines[timeline]; !ok {
+				timelines[timeline] = &UsageItem{Name: timeline}
+			}
+			timelines[timeline].add(object)
+		case strings.HasPrefix(name, utility.OplogPath):
+			report.Oplog.add(object)
+		case strings.HasPrefix(name, utility.BinlogPath):
+			report.Binlog.add(object)
+		default:
+			report.Other.add(object)
+		}
+	}
+
+	backupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	for _, backup := range backups {
+		backup.Permanent = isPermanentBackup(backupFolder, backup.Name)
+		report.Backups = append(report.Backups, backup)
+	}
+	sort.Slice(report.Backups, func(i, j int) bool {
+		return report.Backups[i].Name < report.Backups[j].Name
+	})
+	for _, timeline := range timelines {
+		report.WalTimelines = append(report.WalTimelines, timeline)
+	}
+	sort.Slice(report.WalTimelines, func(i, j int) bool {
+		return report.WalTimelines[i].Name < report.WalTimelines[j].Name
+	})
+	return report, nil
+}
+
+// findBackup maps the object in the backups folder to the backup: its sentinel or any object in the backup folder
+func findBackup(backups map[string]*BackupUsage, name string) *BackupUsage {
+	if strings.HasSuffix(name, utility.SentinelSuffix) && !strings.Contains(name, "/") {
+		return backups[strings.TrimSuffix(name, utility.SentinelSuffix)]
+	}
+	if i := strings.Index(name, "/"); i >= 0 {
+		return backups[name[:i]]
+	}
+	return nil
+}
+
+func getWalTimeline(name string) string {
+	match := walTimelineRegexp.FindStringSubmatch(name)
+	if match == nil {
+		return otherWalFiles
+	}
+	if match[1] != "" {
+		return match[1]
+	}
+	return match[2]
+}
+
+// isPermanentBackup checks both the sentinel and the metadata since the databases keep the permanence flag
+// in either of them
+func isPermanentBackup(backupFolder storage.Folder, backupName string) bool {
+	backup := internal.NewBackup(backupFolder, backupName)
+	var sentinel, metadata map[string]interface{}
+	if err := backup.FetchSentinel(&sentinel); err != nil {
+		tracelog.WarningLogger.Printf("Backup %s is considered not permanent: %v\n", backupName, err)
+		return false
+	}
+	// only some databases upload the metadata
+	if exists, err := backupFolder.Exists(backupName + "/" + utility.MetadataFileName); err == nil && exists {
+		if err = backup.FetchMetadata(&metadata); err != nil {
+			tracelog.WarningLogger.Printf("Failed to check whether backup %s is permanent: %v\n", backupName, err)
+		}
+	}
+	for _, fields := range []map[string]interface{}{sentinel, metadata} {
+		for _, name := range permanentSettingNames {
+			if permanent, ok := fields[name].(bool); ok && permanent {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func WriteUsageReport(report *UsageReport, output io.Writer) error {
+	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
+	defer writer.Flush()
+	_, err := fmt.Fprintln(writer, "category\tname\tobjects\tsize")
+	if err != nil {
+		return err
+	}
+	writeItem := func(category string, item UsageItem) {
+		if err == nil {
+			_, err = fmt.Fprintf(writer, "%s\t%s\t%d\t%d\n", category, item.Name, item.Objects, item.Size)
+		}
+	}
+	var backupsTotal, permanentTotal, walTotal UsageItem
+	for _, backup := range report.Backups {
+		category := "backup"
+		if backup.Permanent {
+			category = "permanent backup"
+			permanentTotal.Objects += backup.Objects
+			permanentTotal.Size += backup.Size
+		}
+		backupsTotal.Objects += backup.Objects
+		backupsTotal.Size += backup.Size
+		writeItem(category, backup.UsageItem)
+	}
+	for _, timeline := range report.WalTimelines {
+		walTotal.Objects += timeline.Objects
+		walTotal.Size += timeline.Size
+		writeItem("wal", *timeline)
+	}
+	writeItem("backups total", backupsTotal)
+	writeItem("permanent backups total", permanentTotal)
+	writeItem("wal total", walTotal)
+	writeItem("oplog", report.Oplog)
+	writeItem("binlog", report.Binlog)
+	writeItem("garbage", report.Garbage)
+	writeItem("other", report.Other)
+	writeItem("total", report.Total)
+	return err
+}
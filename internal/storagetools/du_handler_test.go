@@ -0,0 +1,58 @@
+package storagetools_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/storagetools"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestBuildUsageReport(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	putObjects(t, folder, map[string]string{
+		"basebackups_005/base_000000010000000000000002_backup_stop_sentinel.json":     `{"IsPermanent": true}`,
+		"basebackups_005/base_000000010000000000000002/tar_partitions/part_1.tar.lz4": "12345",
+		"basebackups_005/base_000000010000000000000004_backup_stop_sentinel.json":     `{}`,
+		"basebackups_005/base_000000010000000000000004/metadata.json":                 `{"is_permanent":false}`,
+		"basebackups_005/base_000000010000000000000004/tar_partitions/part_1.tar.lz4": "123",
+		"basebackups_005/base_000000010000000000000006/tar_partitions/part_1.tar.lz4": "1234567",
+		"wal_005/000000010000000000000002.lz4":                                        "1",
+		"wal_005/000000010000000000000003.lz4":                                        "2",
+		"wal_005/000000020000000000000004.lz4":                                        "3",
+		"wal_005/00000002.history.lz4":                                                "4",
+		"wal_005/walg_data/delta.map":                                                 "5",
+		"oplog_005/oplog_1.br":                                                        "oplog",
+		"binlog_005/mysql-bin.000001.br":                                              "binlog",
+		"garbage.txt":                                                                 "other",
+	})
+
+	report, err := storagetools.BuildUsageReport(folder)
+	require.NoError(t, err)
+
+	require.Equal(t, 2, len(report.Backups))
+	assert.Equal(t, "base_000000010000000000000002", report.Backups[0].Name)
+	assert.True(t, report.Backups[0].Permanent)
+	assert.Equal(t, 2, report.Backups[0].Objects)
+	assert.Equal(t, int64(len(`{"IsPermanent": true}`)+5), report.Backups[0].Size)
+	assert.False(t, report.Backups[1].Permanent)
+	assert.Equal(t, 3, report.Backups[1].Objects)
+
+	assert.Equal(t, storagetools.UsageItem{Objects: 1, Size: 7}, report.Garbage)
+	require.Equal(t, 3, len(report.WalTimelines))
+	assert.Equal(t, storagetools.UsageItem{Name: "00000001", Objects: 2, Size: 2}, *report.WalTimelines[0])
+	assert.Equal(t, storagetools.UsageItem{Name: "00000002", Objects: 2, Size: 2}, *report.WalTimelines[1])
+	assert.Equal(t, "other", report.WalTimelines[2].Name)
+	assert.Equal(t, int64(5), report.Oplog.Size)
+	assert.Equal(t, int64(6), report.Binlog.Size)
+	assert.Equal(t, int64(5), report.Other.Size)
+	assert.Equal(t, 14, report.Total.Objects)
+
+	var output bytes.Buffer
+	require.NoError(t, storagetools.WriteUsageReport(report, &output))
+	assert.True(t, strings.Contains(output.String(), "permanent backup"))
+	assert.Equal(t, 14, strings.Count(output.String(), "\n"))
+}
@@ -0,0 +1,114 @@
+package storagetools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	MissingInMirror    = "missing"
+	UnexpectedInMirror = "unexpected"
+	SizeMismatch       = "size mismatch"
+)
+
+// MirrorDivergence describes an object which differs between the primary storage and one of its mirrors
+type MirrorDivergence struct {
+	MirrorIndex int
+	Name        string
+	Problem     string
+}
+
+func HandleMirrorCheck(folder storage.Folder) {
+	if cacheFolder, ok := folder.(*storage.CacheFolder); ok {
+		folder = cacheFolder.Folder()
+	}
+	mirrorFolder, ok := folder.(*storage.MirrorFolder)
+	if !ok {
+		tracelog.ErrorLogger.Fatal("No mirror storages are configured")
+	}
+
+	divergences, err := FindMirrorDivergences(mirrorFolder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to compare the mirrors: %v", err)
+
+	err = WriteMirrorDivergences(divergences, mirrorFolder, os.Stdout)
+	tracelog.ErrorLogger.FatalfOnError("Failed to write the divergences: %v", err)
+	if len(divergences) > 0 {
+		tracelog.ErrorLogger.Fatalf("Mirrors have diverged in %d objects", len(divergences))
+	}
+	tracelog.InfoLogger.Println("Mirrors are in sync")
+}
+
+// FindMirrorDivergences compares every mirror to the primary storage by object names and sizes
+func FindMirrorDivergences(folder *storage.MirrorFolder) ([]MirrorDivergence, error) {
+	mirrors := folder.Mirrors()
+	primarySizes, err := listObjectSizes(mirrors[0])
+	if err != nil {
+		return nil, err
+	}
+	divergences := make([]MirrorDivergence, 0)
+	for i := 1; i < len(mirrors); i++ {
+		mirrorSizes, err := listObjectSizes(mirrors[i])
+		if err != nil {
+			return nil, err
+		}
+		mirrorDivergences := make([]MirrorDivergence, 0)
+		for name, size := range primarySizes {
+			mirrorSize, exists := mirrorSizes[name]
+			if !exists {
+				mirrorDivergences = append(mirrorDivergences, MirrorDivergence{i, name, MissingInMirror})
+			} else if mirrorSize != size {
+				mirrorDivergences = append(mirrorDivergences, MirrorDivergence{i, name, SizeMismatch})
+			}
+		}
+		for name := range mirrorSizes {
+			if _, exists := primarySizes[name]; !exists {
+				mirrorDivergences = append(mirrorDivergences, MirrorDivergence{i, name, UnexpectedInMirror})
+			}
+		}
+		sort.Slice(mirrorDivergences, func(i, j int) bool {
+			return mirrorDivergences[i].Name < mirrorDivergences[j].Name
+		})
+		divergences = append(divergences, mirrorDivergences...)
+	}
+	return divergences, nil
+}
+
+func WriteMirrorDivergences(divergences []MirrorDivergence, folder *storage.MirrorFolder, output io.Writer) error {
+	mirrors := folder.Mirrors()
+	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
+	defer writer.Flush()
+	_, err := fmt.Fprintln(writer, "mirror\tproblem\tname")
+	if err != nil {
+		return err
+	}
+	for _, divergence := range divergences {
+		_, err = fmt.Fprintf(writer, "%d (%s)\t%s\t%s\n", divergence.MirrorIndex,
+			mirrors[divergence.MirrorIndex].GetPath(), divergence.Problem, divergence.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listObjectSizes(folder storage.Folder) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	err := storage.ListFolderPages(context.Background(), folder, storage.ListOptions{Recursive: true},
+		func(objects []storage.Object, _ []storage.Folder) error {
+			for _, object := range objects {
+				sizes[object.GetName()] = object.GetSize()
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
@@ -0,0 +1,31 @@
+package s3server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error is the error response of the S3 API
+type Error struct {
+	status  int
+	code    string
+	message string
+}
+
+func newError(status int, code, message string) Error {
+	return Error{status: status, code: code, message: message}
+}
+
+func (err Error) Error() string {
+	return fmt.Sprintf("%s: %s", err.code, err.message)
+}
+
+var (
+	errNotImplemented   = newError(http.StatusNotImplemented, "NotImplemented", "The operation is not supported")
+	errMethodNotAllowed = newError(http.StatusMethodNotAllowed, "MethodNotAllowed",
+		"The specified method is not allowed against this resource")
+	errReadOnly     = newError(http.StatusForbidden, "AccessDenied", "The server is read-only")
+	errNoSuchUpload = newError(http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist")
+	errInvalidPart  = newError(http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found")
+	errInvalidKey   = newError(http.StatusBadRequest, "InvalidArgument", "Invalid object key")
+)
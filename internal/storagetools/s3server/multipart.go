@@ -0,0 +1,175 @@
+package s3server
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/wal-g/tracelog"
+)
+
+// multipartUpload keeps the uploaded parts in the local directory until the upload is completed,
+// the object is uploaded to the storage at once then
+type multipartUpload struct {
+	key   string
+	dir   string
+	etags map[int]string
+}
+
+func (upload *multipartUpload) partPath(number int) string {
+	return filepath.Join(upload.dir, strconv.Itoa(number))
+}
+
+func (s *Server) HandleCreateMultipartUpload(w http.ResponseWriter, req *http.Request, key string) {
+	if err := checkUploadHeaders(req); err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	uploadID := uuid.New().String()
+	upload := &multipartUpload{key: key, dir: filepath.Join(s.uploadsDir, uploadID), etags: make(map[int]string)}
+	if err := os.Mkdir(upload.dir, 0700); err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	s.uploadsMutex.Lock()
+	s.uploads[uploadID] = upload
+	s.uploadsMutex.Unlock()
+	s.writeXML(w, http.StatusOK, XInitiateMultipartUploadResult{
+		Xmlns:    xmlNamespace,
+		Bucket:   s.options.Bucket,
+		Key:      key,
+		UploadID: uploadID,
+	})
+}
+
+func (s *Server) HandleUploadPart(w http.ResponseWriter, req *http.Request, key, uploadID string) {
+	if err := checkUploadHeaders(req); err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	number, err := strconv.Atoi(req.Form.Get("partNumber"))
+	if err != nil || number < 1 {
+		s.returnError(w, req, newError(http.StatusBadRequest, "InvalidArgument", "Invalid partNumber"))
+		return
+	}
+	upload, ok := s.getUpload(key, uploadID)
+	if !ok {
+		s.returnError(w, req, errNoSuchUpload)
+		return
+	}
+	partFile, err := os.OpenFile(upload.partPath(number), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	checksum := md5.New()
+	_, err = io.Copy(io.MultiWriter(partFile, checksum), req.Body)
+	closeErr := partFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	etag := formatETag(checksum)
+	s.uploadsMutex.Lock()
+	upload.etags[number] = etag
+	s.uploadsMutex.Unlock()
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) HandleCompleteMultipartUpload(w http.ResponseWriter, req *http.Request, key, uploadID string) {
+	upload, ok := s.getUpload(key, uploadID)
+	if !ok {
+		s.returnError(w, req, errNoSuchUpload)
+		return
+	}
+	var request XCompleteMultipartUpload
+	if err := xml.NewDecoder(req.Body).Decode(&request); err != nil {
+		s.returnError(w, req, newError(http.StatusBadRequest, "MalformedXML", err.Error()))
+		return
+	}
+	if len(request.Parts) == 0 {
+		s.returnError(w, req, errInvalidPart)
+		return
+	}
+
+	checksums := md5.New()
+	readers := make([]io.Reader, 0, len(request.Parts))
+	s.uploadsMutex.Lock()
+	for i, part := range request.Parts {
+		etag, uploaded := upload.etags[part.PartNumber]
+		if !uploaded || etag != part.ETag || i > 0 && part.PartNumber <= request.Parts[i-1].PartNumber {
+			s.uploadsMutex.Unlock()
+			s.returnError(w, req, errInvalidPart)
+			return
+		}
+		checksum, _ := hex.DecodeString(etag[1 : len(etag)-1])
+		checksums.Write(checksum)
+	}
+	s.uploadsMutex.Unlock()
+
+	for _, part := range request.Parts {
+		partFile, err := os.Open(upload.partPath(part.PartNumber))
+		if err != nil {
+			s.returnError(w, req, err)
+			return
+		}
+		defer partFile.Close()
+		readers = append(readers, partFile)
+	}
+	if err := s.folder.PutObject(key, io.MultiReader(readers...)); err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	s.removeUpload(uploadID)
+
+	s.writeXML(w, http.StatusOK, XCompleteMultipartUploadResult{
+		Xmlns:  xmlNamespace,
+		Bucket: s.options.Bucket,
+		Key:    key,
+		ETag:   fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(checksums.Sum(nil)), len(request.Parts)),
+	})
+}
+
+func (s *Server) HandleAbortMultipartUpload(w http.ResponseWriter, req *http.Request, key, uploadID string) {
+	if _, ok := s.getUpload(key, uploadID); !ok {
+		s.returnError(w, req, errNoSuchUpload)
+		return
+	}
+	s.removeUpload(uploadID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUpload finds the upload of the object, the upload of another object is not found even if its ID is given
+func (s *Server) getUpload(key, uploadID string) (*multipartUpload, bool) {
+	s.uploadsMutex.Lock()
+	defer s.uploadsMutex.Unlock()
+	upload, ok := s.uploads[uploadID]
+	if !ok || upload.key != key {
+		return nil, false
+	}
+	return upload, true
+}
+
+func (s *Server) removeUpload(uploadID string) {
+	s.uploadsMutex.Lock()
+	upload, ok := s.uploads[uploadID]
+	delete(s.uploads, uploadID)
+	s.uploadsMutex.Unlock()
+	if !ok {
+		return
+	}
+	if err := os.RemoveAll(upload.dir); err != nil {
+		tracelog.WarningLogger.Printf("s3 server: failed to remove the parts of the upload: %v", err)
+	}
+}
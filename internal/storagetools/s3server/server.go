@@ -0,0 +1,604 @@
+package s3server
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const DefaultBucket = "wal-g"
+
+const defaultMaxKeys = 1000
+
+// streamingPayload is sent by the clients signing every chunk of the body, such bodies are not supported
+const streamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+
+type Options struct {
+	// Bucket is the name of the only bucket which contains the objects of the folder
+	Bucket string
+	// Decrypt and Decompress make GetObject return the content as WAL-G fetches it
+	Decrypt    bool
+	Decompress bool
+	ReadOnly   bool
+}
+
+// Server exposes the folder through the subset of the S3 API: the path-style requests to a single bucket.
+// The requests are not authenticated.
+type Server struct {
+	folder       storage.Folder
+	options      Options
+	server       http.Server
+	uploadsDir   string
+	uploads      map[string]*multipartUpload
+	uploadsMutex sync.Mutex
+}
+
+func NewServer(folder storage.Folder, address string, options Options) (*Server, error) {
+	if options.Bucket == "" {
+		options.Bucket = DefaultBucket
+	}
+	uploadsDir, err := ioutil.TempDir("", "walg_s3_uploads")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		folder:     folder,
+		options:    options,
+		uploadsDir: uploadsDir,
+		uploads:    make(map[string]*multipartUpload),
+	}
+	s.server = http.Server{Addr: address, Handler: s}
+	return s, nil
+}
+
+func (s *Server) Run(ctx context.Context) error {
+	errs := make(chan error)
+	go func() {
+		tracelog.InfoLogger.Printf("serving bucket '%s' at %s", s.options.Bucket, s.server.Addr)
+		errs <- s.server.ListenAndServe()
+	}()
+	select {
+	case <-ctx.Done():
+		return s.Shutdown()
+	case err := <-errs:
+		s.removeUploads()
+		return err
+	}
+}
+
+func (s *Server) Shutdown() error {
+	tracelog.InfoLogger.Printf("stopping the server")
+	sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := s.server.Shutdown(sctx)
+	if err != nil {
+		tracelog.ErrorLogger.Printf("server shutdown error: %v", err)
+	}
+	s.removeUploads()
+	return err
+}
+
+// removeUploads removes the parts of the multipart uploads which are not completed
+func (s *Server) removeUploads() {
+	if err := os.RemoveAll(s.uploadsDir); err != nil {
+		tracelog.WarningLogger.Printf("failed to remove the multipart uploads: %v", err)
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer func() {
+		if err := recover(); err != nil {
+			debug.PrintStack()
+			tracelog.ErrorLogger.Printf("s3 server goroutine panic: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}()
+	tracelog.DebugLogger.Printf("s3 server: %s %s", req.Method, req.URL.String())
+	if err := req.ParseForm(); err != nil {
+		s.returnError(w, req, newError(http.StatusBadRequest, "InvalidArgument", err.Error()))
+		return
+	}
+
+	bucket, key := splitPath(req.URL.Path)
+	switch {
+	case bucket == "":
+		s.HandleService(w, req)
+	case bucket != s.options.Bucket:
+		s.returnError(w, req, newError(http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist"))
+	case key == "":
+		s.HandleBucket(w, req)
+	default:
+		if err := validateKey(key); err != nil {
+			s.returnError(w, req, err)
+			return
+		}
+		s.HandleObject(w, req, key)
+	}
+}
+
+// Service operations
+func (s *Server) HandleService(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		s.returnError(w, req, errMethodNotAllowed)
+		return
+	}
+	s.writeXML(w, http.StatusOK, XListAllMyBucketsResult{
+		Xmlns:   xmlNamespace,
+		Buckets: []XBucket{{Name: s.options.Bucket, CreationDate: formatTime(time.Unix(0, 0))}},
+	})
+}
+
+// Bucket operations
+func (s *Server) HandleBucket(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case req.Method == http.MethodGet && hasParam(req, "location"):
+		s.writeXML(w, http.StatusOK, XLocationConstraint{Xmlns: xmlNamespace})
+	case req.Method == http.MethodGet && hasParam(req, "uploads"):
+		s.returnError(w, req, errNotImplemented)
+	case req.Method == http.MethodGet:
+		s.HandleListObjects(w, req)
+	case req.Method == http.MethodPost && hasParam(req, "delete"):
+		s.HandleDeleteObjects(w, req)
+	default:
+		s.returnError(w, req, errMethodNotAllowed)
+	}
+}
+
+func (s *Server) HandleListObjects(w http.ResponseWriter, req *http.Request) {
+	v2 := req.Form.Get("list-type") == "2"
+	prefix := req.Form.Get("prefix")
+	delimiter := req.Form.Get("delimiter")
+	if delimiter != "" && delimiter != "/" {
+		s.returnError(w, req, newError(http.StatusNotImplemented, "NotImplemented", "Only the '/' delimiter is supported"))
+		return
+	}
+	maxKeys := defaultMaxKeys
+	if maxKeysStr := req.Form.Get("max-keys"); maxKeysStr != "" {
+		var err error
+		maxKeys, err = strconv.Atoi(maxKeysStr)
+		if err != nil || maxKeys < 0 {
+			s.returnError(w, req, newError(http.StatusBadRequest, "InvalidArgument", "Invalid max-keys"))
+			return
+		}
+	}
+	result := XListBucketResult{
+		Xmlns:     xmlNamespace,
+		Name:      s.options.Bucket,
+		Prefix:    prefix,
+		Delimiter: delimiter,
+		MaxKeys:   maxKeys,
+	}
+	var marker string
+	if v2 {
+		result.StartAfter = req.Form.Get("start-after")
+		result.ContinuationToken = req.Form.Get("continuation-token")
+		marker = result.StartAfter
+		if result.ContinuationToken > marker {
+			marker = result.ContinuationToken
+		}
+	} else {
+		marker = req.Form.Get("marker")
+		result.Marker = &marker
+	}
+
+	entries, err := s.listEntries(req.Context(), prefix, delimiter, marker)
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	if len(entries) > maxKeys {
+		entries = entries[:maxKeys]
+		result.IsTruncated = true
+	}
+	for _, entry := range entries {
+		if entry.object == nil {
+			result.CommonPrefixes = append(result.CommonPrefixes, XCommonPrefix{Prefix: entry.key})
+			continue
+		}
+		result.Contents = append(result.Contents, XObject{
+			Key:          entry.key,
+			LastModified: formatTime(entry.object.GetLastModified()),
+			ETag:         objectETag(entry.key, entry.object),
+			Size:         entry.object.GetSize(),
+			StorageClass: "STANDARD",
+		})
+	}
+	if result.IsTruncated {
+		lastKey := entries[len(entries)-1].key
+		if v2 {
+			result.NextContinuationToken = lastKey
+		} else if delimiter != "" {
+			result.NextMarker = lastKey
+		}
+	}
+	if v2 {
+		keyCount := len(entries)
+		result.KeyCount = &keyCount
+	}
+	s.writeXML(w, http.StatusOK, result)
+}
+
+// listEntry is either an object or a common prefix of the listing
+type listEntry struct {
+	key    string
+	object storage.Object
+}
+
+// listEntries lists the objects with the keys greater than the marker, sorted by the key.
+// The folder of the prefix is listed, recursively if there is no delimiter.
+func (s *Server) listEntries(ctx context.Context, prefix, delimiter, marker string) ([]listEntry, error) {
+	dir := prefix[:strings.LastIndex(prefix, "/")+1]
+	if err := validateKey(dir); err != nil {
+		return nil, err
+	}
+	options := storage.ListOptions{Recursive: delimiter == "", Prefix: strings.TrimPrefix(prefix, dir)}
+	if strings.HasPrefix(marker, dir) {
+		options.StartAfter = strings.TrimPrefix(marker, dir)
+	} else if marker > dir {
+		return nil, nil
+	}
+
+	folder := s.getFolder(dir)
+	entries := make([]listEntry, 0)
+	err := storage.ListFolderPages(ctx, folder, options, func(objects []storage.Object, subFolders []storage.Folder) error {
+		for _, object := range objects {
+			entries = append(entries, listEntry{key: dir + object.GetName(), object: object})
+		}
+		if options.Recursive {
+			return nil
+		}
+		for _, subFolder := range subFolders {
+			name := strings.Trim(strings.TrimPrefix(subFolder.GetPath(), folder.GetPath()), "/")
+			key := dir + name + "/"
+			if strings.HasPrefix(key, prefix) && key > marker {
+				entries = append(entries, listEntry{key: key})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return entries, nil
+}
+
+func (s *Server) HandleDeleteObjects(w http.ResponseWriter, req *http.Request) {
+	if s.options.ReadOnly {
+		s.returnError(w, req, errReadOnly)
+		return
+	}
+	var request XDelete
+	if err := xml.NewDecoder(req.Body).Decode(&request); err != nil {
+		s.returnError(w, req, newError(http.StatusBadRequest, "MalformedXML", err.Error()))
+		return
+	}
+	keys := make([]string, 0, len(request.Objects))
+	for _, object := range request.Objects {
+		if err := validateKey(object.Key); err != nil {
+			s.returnError(w, req, err)
+			return
+		}
+		keys = append(keys, object.Key)
+	}
+	result := XDeleteResult{Xmlns: xmlNamespace}
+	if err := s.folder.DeleteObjects(keys); err != nil {
+		tracelog.ErrorLogger.Printf("s3 server: failed to delete objects: %v", err)
+		for _, key := range keys {
+			result.Errors = append(result.Errors, XDeleteError{Key: key, Code: "InternalError", Message: err.Error()})
+		}
+	} else if !request.Quiet {
+		result.Deleted = request.Objects
+	}
+	s.writeXML(w, http.StatusOK, result)
+}
+
+// Object operations
+func (s *Server) HandleObject(w http.ResponseWriter, req *http.Request, key string) {
+	uploadID := req.Form.Get("uploadId")
+	switch {
+	case req.Method == http.MethodHead:
+		s.HandleGetObject(w, req, key, false)
+	case req.Method == http.MethodGet && uploadID != "":
+		s.returnError(w, req, errNotImplemented)
+	case req.Method == http.MethodGet:
+		s.HandleGetObject(w, req, key, true)
+	case s.options.ReadOnly:
+		s.returnError(w, req, errReadOnly)
+	case req.Method == http.MethodPut && uploadID != "":
+		s.HandleUploadPart(w, req, key, uploadID)
+	case req.Method == http.MethodPut && req.Header.Get("X-Amz-Copy-Source") != "":
+		s.HandleCopyObject(w, req, key)
+	case req.Method == http.MethodPut:
+		s.HandlePutObject(w, req, key)
+	case req.Method == http.MethodPost && hasParam(req, "uploads"):
+		s.HandleCreateMultipartUpload(w, req, key)
+	case req.Method == http.MethodPost && uploadID != "":
+		s.HandleCompleteMultipartUpload(w, req, key, uploadID)
+	case req.Method == http.MethodDelete && uploadID != "":
+		s.HandleAbortMultipartUpload(w, req, key, uploadID)
+	case req.Method == http.MethodDelete:
+		s.HandleDeleteObject(w, req, key)
+	default:
+		s.returnError(w, req, errMethodNotAllowed)
+	}
+}
+
+func (s *Server) HandleGetObject(w http.ResponseWriter, req *http.Request, key string, withBody bool) {
+	object, err := storage.StatObject(req.Context(), s.folder, key)
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	transformed := s.options.Decrypt || s.options.Decompress
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Last-Modified", object.GetLastModified().UTC().Format(http.TimeFormat))
+	w.Header().Set("ETag", objectETag(key, object))
+	if !transformed {
+		// the size of the decrypted and decompressed content is unknown until it is read
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.FormatInt(object.GetSize(), 10))
+	}
+
+	rangeHeader := req.Header.Get("Range")
+	if rangeHeader == "" {
+		if !withBody {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		s.writeObject(w, req, key)
+		return
+	}
+	if transformed {
+		s.returnError(w, req, newError(http.StatusNotImplemented, "NotImplemented",
+			"Range requests are not supported for the decrypted or decompressed objects"))
+		return
+	}
+	offset, length, err := parseRange(rangeHeader, object.GetSize())
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, object.GetSize()))
+	if !withBody {
+		w.WriteHeader(http.StatusPartialContent)
+		return
+	}
+	reader, err := storage.ReadObjectRange(s.folder, key, offset, length)
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	defer reader.Close()
+	w.WriteHeader(http.StatusPartialContent)
+	if _, err = io.Copy(w, reader); err != nil {
+		tracelog.ErrorLogger.Printf("s3 server: failed to copy data from storage: %v", err)
+	}
+}
+
+func (s *Server) writeObject(w http.ResponseWriter, req *http.Request, key string) {
+	reader, err := s.folder.ReadObject(key)
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	defer reader.Close()
+	content := io.Reader(reader)
+	if s.options.Decrypt {
+		content, err = internal.DecryptBytes(reader)
+		if err != nil {
+			s.returnError(w, req, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	if s.options.Decompress {
+		if decompressor := compression.FindDecompressor(path.Ext(key)); decompressor != nil {
+			err = decompressor.Decompress(w, content)
+			tracelog.ErrorLogger.PrintOnError(err)
+			return
+		}
+	}
+	if _, err = utility.FastCopy(w, content); err != nil {
+		tracelog.ErrorLogger.Printf("s3 server: failed to copy data from storage: %v", err)
+	}
+}
+
+func (s *Server) HandlePutObject(w http.ResponseWriter, req *http.Request, key string) {
+	if err := checkUploadHeaders(req); err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	checksum := md5.New()
+	err := s.folder.PutObject(key, io.TeeReader(req.Body, checksum))
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	w.Header().Set("ETag", formatETag(checksum))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) HandleCopyObject(w http.ResponseWriter, req *http.Request, key string) {
+	source, err := url.PathUnescape(req.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		s.returnError(w, req, newError(http.StatusBadRequest, "InvalidArgument", "Invalid copy source"))
+		return
+	}
+	bucket, sourceKey := splitPath("/" + strings.TrimPrefix(source, "/"))
+	if bucket != s.options.Bucket || sourceKey == "" {
+		s.returnError(w, req, newError(http.StatusNotFound, "NoSuchKey", "The specified key does not exist"))
+		return
+	}
+	if err = validateKey(sourceKey); err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	if err = s.folder.CopyObject(sourceKey, key); err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	object, err := storage.StatObject(req.Context(), s.folder, key)
+	if err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	s.writeXML(w, http.StatusOK, XCopyObjectResult{
+		Xmlns:        xmlNamespace,
+		ETag:         objectETag(key, object),
+		LastModified: formatTime(object.GetLastModified()),
+	})
+}
+
+func (s *Server) HandleDeleteObject(w http.ResponseWriter, req *http.Request, key string) {
+	if err := s.folder.DeleteObjects([]string{key}); err != nil {
+		s.returnError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// utils
+func (s *Server) returnError(w http.ResponseWriter, req *http.Request, err error) {
+	s3err, ok := err.(Error)
+	if !ok {
+		if _, notFound := err.(storage.ObjectNotFoundError); notFound {
+			s3err = newError(http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+		} else {
+			tracelog.ErrorLogger.Printf("s3 server: %s %s failed: %v", req.Method, req.URL.Path, err)
+			s3err = newError(http.StatusInternalServerError, "InternalError", err.Error())
+		}
+	}
+	if req.Method == http.MethodHead {
+		w.WriteHeader(s3err.status)
+		return
+	}
+	s.writeXML(w, s3err.status, XError{Code: s3err.code, Message: s3err.message, Resource: req.URL.Path})
+}
+
+func (s *Server) writeXML(w http.ResponseWriter, status int, value interface{}) {
+	data, err := xml.Marshal(value)
+	if err != nil {
+		tracelog.ErrorLogger.Printf("s3 server: failed to serialize the response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	data = append([]byte(xml.Header), data...)
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	tracelog.ErrorLogger.PrintOnError(err)
+}
+
+func (s *Server) getFolder(dir string) storage.Folder {
+	if dir == "" {
+		return s.folder
+	}
+	return s.folder.GetSubFolder(dir)
+}
+
+// splitPath splits the path-style request path into the bucket and the object key
+func splitPath(urlPath string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(urlPath, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// validateKey rejects the keys which are not plain relative paths, so that they can't reach the files
+// outside of the storage root in the file system storages
+func validateKey(key string) error {
+	if strings.HasPrefix(key, "/") || strings.ContainsRune(key, 0) {
+		return errInvalidKey
+	}
+	for _, segment := range strings.FieldsFunc(key, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == "." || segment == ".." {
+			return errInvalidKey
+		}
+	}
+	return nil
+}
+
+func hasParam(req *http.Request, name string) bool {
+	_, ok := req.Form[name]
+	return ok
+}
+
+func checkUploadHeaders(req *http.Request) error {
+	if req.Header.Get("X-Amz-Copy-Source") != "" {
+		return newError(http.StatusNotImplemented, "NotImplemented", "Copying the parts is not supported")
+	}
+	if req.Header.Get("X-Amz-Content-Sha256") == streamingPayload {
+		return newError(http.StatusNotImplemented, "NotImplemented", "Chunked uploads are not supported")
+	}
+	return nil
+}
+
+// parseRange parses the single range of the Range header, the range may be suffix or open-ended
+func parseRange(rangeHeader string, size int64) (offset, length int64, err error) {
+	invalidRange := newError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange",
+		"The requested range is not satisfiable")
+	if !strings.HasPrefix(rangeHeader, "bytes=") || strings.Contains(rangeHeader, ",") {
+		return 0, 0, invalidRange
+	}
+	bounds := strings.SplitN(strings.TrimPrefix(rangeHeader, "bytes="), "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, invalidRange
+	}
+	first, firstErr := strconv.ParseInt(bounds[0], 10, 64)
+	last, lastErr := strconv.ParseInt(bounds[1], 10, 64)
+	switch {
+	case bounds[0] == "" && lastErr == nil && last > 0:
+		if last > size {
+			last = size
+		}
+		return size - last, last, nil
+	case firstErr == nil && bounds[1] == "" && first < size:
+		return first, size - first, nil
+	case firstErr == nil && lastErr == nil && first <= last && first < size:
+		if last >= size {
+			last = size - 1
+		}
+		return first, last - first + 1, nil
+	}
+	return 0, 0, invalidRange
+}
+
+// objectETag identifies the version of the object, the storages don't provide the checksums of the objects
+func objectETag(key string, object storage.Object) string {
+	checksum := md5.New()
+	_, _ = fmt.Fprintf(checksum, "%s/%d/%d", key, object.GetSize(), object.GetLastModified().UnixNano())
+	return formatETag(checksum)
+}
+
+func formatETag(checksum hash.Hash) string {
+	return `"` + hex.EncodeToString(checksum.Sum(nil)) + `"`
+}
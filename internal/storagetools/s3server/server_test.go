@@ -0,0 +1,282 @@
+package s3server_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/storagetools/s3server"
+	"github.com/wal-g/wal-g/pkg/storages/fs"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	s3storage "github.com/wal-g/wal-g/pkg/storages/s3"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// testServer serves the folder until it is closed
+type testServer struct {
+	*httptest.Server
+	server *s3server.Server
+}
+
+func newTestServer(t *testing.T, folder storage.Folder, options s3server.Options) *testServer {
+	server, err := s3server.NewServer(folder, "", options)
+	require.NoError(t, err)
+	return &testServer{httptest.NewServer(server), server}
+}
+
+func (server *testServer) Close() {
+	server.Server.Close()
+	_ = server.server.Shutdown()
+}
+
+func newTestClient(t *testing.T, endpoint string) *s3.S3 {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(endpoint),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("key", "secret", ""),
+	})
+	require.NoError(t, err)
+	return s3.New(sess)
+}
+
+func TestServer_S3Folder(t *testing.T) {
+	server := newTestServer(t, memory.NewFolder("", memory.NewStorage()), s3server.Options{})
+	defer server.Close()
+	// the S3 storage takes the credentials from the environment
+	for name, value := range map[string]string{s3storage.AccessKeyIdSetting: "key", s3storage.SecretAccessKeySetting: "secret"} {
+		previous, isSet := os.LookupEnv(name)
+		require.NoError(t, os.Setenv(name, value))
+		if isSet {
+			defer os.Setenv(name, previous)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+	folder, err := s3storage.ConfigureFolder("s3://wal-g/prefix", map[string]string{
+		s3storage.EndpointSetting:          server.URL,
+		s3storage.RegionSetting:            "us-east-1",
+		s3storage.ForcePathStyleSetting:    "true",
+		s3storage.UploadConcurrencySetting: "1",
+	})
+	require.NoError(t, err)
+
+	storage.RunFolderTest(folder, t)
+}
+
+func TestServer_ListObjectsV2(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	for _, name := range []string{"a/1", "a/2", "a/b/3", "a/c/4", "d"} {
+		require.NoError(t, folder.PutObject(name, bytes.NewBufferString(name)))
+	}
+	server := newTestServer(t, folder, s3server.Options{})
+	defer server.Close()
+	client := newTestClient(t, server.URL)
+
+	var keys, prefixes []string
+	err := client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket:    aws.String(s3server.DefaultBucket),
+		Prefix:    aws.String("a/"),
+		Delimiter: aws.String("/"),
+		MaxKeys:   aws.Int64(1),
+	}, func(output *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range output.Contents {
+			keys = append(keys, *object.Key)
+		}
+		for _, prefix := range output.CommonPrefixes {
+			prefixes = append(prefixes, *prefix.Prefix)
+		}
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/1", "a/2"}, keys)
+	assert.Equal(t, []string{"a/b/", "a/c/"}, prefixes)
+
+	output, err := client.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket:     aws.String(s3server.DefaultBucket),
+		Prefix:     aws.String("a/"),
+		StartAfter: aws.String("a/b/3"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(output.Contents))
+	assert.Equal(t, "a/c/4", *output.Contents[0].Key)
+	assert.Equal(t, int64(5), *output.Contents[0].Size)
+}
+
+func TestServer_GetObjectRange(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	require.NoError(t, folder.PutObject("object", bytes.NewBufferString("0123456789")))
+	server := newTestServer(t, folder, s3server.Options{})
+	defer server.Close()
+	client := newTestClient(t, server.URL)
+
+	for rangeHeader, expected := range map[string]string{"bytes=2-4": "234", "bytes=7-": "789", "bytes=-2": "89"} {
+		output, err := client.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(s3server.DefaultBucket),
+			Key:    aws.String("object"),
+			Range:  aws.String(rangeHeader),
+		})
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(output.Body)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+
+	_, err := client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(s3server.DefaultBucket), Key: aws.String("missing")})
+	assert.Error(t, err)
+	_, err = client.GetObject(&s3.GetObjectInput{Bucket: aws.String(s3server.DefaultBucket), Key: aws.String("missing")})
+	awsErr, ok := err.(awserr.Error)
+	require.True(t, ok)
+	assert.Equal(t, s3.ErrCodeNoSuchKey, awsErr.Code())
+}
+
+func TestServer_MultipartUpload(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	server := newTestServer(t, folder, s3server.Options{})
+	defer server.Close()
+	client := newTestClient(t, server.URL)
+	bucket, key := aws.String(s3server.DefaultBucket), aws.String("dir/object")
+
+	upload, err := client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{Bucket: bucket, Key: key})
+	require.NoError(t, err)
+	// the upload can't be used for another object
+	otherKey := aws.String("dir/other")
+	_, err = client.UploadPart(&s3.UploadPartInput{
+		Bucket:     bucket,
+		Key:        otherKey,
+		UploadId:   upload.UploadId,
+		PartNumber: aws.Int64(1),
+		Body:       bytes.NewReader([]byte("other")),
+	})
+	assertNoSuchUpload(t, err)
+	_, err = client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{Bucket: bucket, Key: otherKey, UploadId: upload.UploadId})
+	assertNoSuchUpload(t, err)
+
+	var parts []*s3.CompletedPart
+	for i, data := range []string{"first ", "second"} {
+		output, err := client.UploadPart(&s3.UploadPartInput{
+			Bucket:     bucket,
+			Key:        key,
+			UploadId:   upload.UploadId,
+			PartNumber: aws.Int64(int64(i + 1)),
+			Body:       bytes.NewReader([]byte(data)),
+		})
+		require.NoError(t, err)
+		parts = append(parts, &s3.CompletedPart{ETag: output.ETag, PartNumber: aws.Int64(int64(i + 1))})
+	}
+	_, err = client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          bucket,
+		Key:             otherKey,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	assertNoSuchUpload(t, err)
+	_, err = client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          bucket,
+		Key:             key,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	require.NoError(t, err)
+
+	reader, err := folder.ReadObject("dir/object")
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "first second", string(content))
+
+	_, err = client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{Bucket: bucket, Key: key, UploadId: upload.UploadId})
+	assert.Error(t, err)
+}
+
+func assertNoSuchUpload(t *testing.T, err error) {
+	awsErr, ok := err.(awserr.Error)
+	require.True(t, ok, "expected the S3 error, got %v", err)
+	assert.Equal(t, s3.ErrCodeNoSuchUpload, awsErr.Code())
+}
+
+func TestServer_DeleteObjects(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	require.NoError(t, folder.PutObject("first", bytes.NewBufferString("1")))
+	require.NoError(t, folder.PutObject("second", bytes.NewBufferString("2")))
+	readOnlyClient := newTestClient(t, newTestServer(t, folder, s3server.Options{ReadOnly: true}).URL)
+	server := newTestServer(t, folder, s3server.Options{})
+	defer server.Close()
+	client := newTestClient(t, server.URL)
+	input := &s3.DeleteObjectsInput{
+		Bucket: aws.String(s3server.DefaultBucket),
+		Delete: &s3.Delete{Objects: []*s3.ObjectIdentifier{{Key: aws.String("first")}, {Key: aws.String("second")}}},
+	}
+
+	_, err := readOnlyClient.DeleteObjects(input)
+	assert.Error(t, err)
+
+	output, err := client.DeleteObjects(input)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(output.Deleted))
+	exists, err := folder.Exists("first")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestServer_RejectsTraversalKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "walg_s3_server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "storage")
+	require.NoError(t, os.Mkdir(root, 0700))
+	secret := filepath.Join(dir, "secret")
+	require.NoError(t, ioutil.WriteFile(secret, []byte("secret"), 0600))
+	folder := fs.NewFolder(root, "")
+	require.NoError(t, folder.PutObject("object", strings.NewReader("data")))
+	server := newTestServer(t, folder, s3server.Options{})
+	defer server.Close()
+
+	send := func(method, key string, body string, header http.Header) int {
+		req, err := http.NewRequest(method, server.URL+"/"+s3server.DefaultBucket+"/"+key, strings.NewReader(body))
+		require.NoError(t, err)
+		for name := range header {
+			req.Header.Set(name, header.Get(name))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	copyFrom := func(key string) http.Header {
+		return http.Header{"X-Amz-Copy-Source": []string{"/" + s3server.DefaultBucket + "/" + key}}
+	}
+	for _, key := range []string{"../secret", "a/../../secret", "%2E%2E/secret", "./object", "/secret", "..\\secret", "a%00b"} {
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, key, "overwritten", nil), "PUT "+key)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, key, "", nil), "GET "+key)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "copy", "", copyFrom(key)), "COPY from "+key)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, key, "", copyFrom("object")), "COPY to "+key)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodDelete, key, "", nil), "DELETE "+key)
+	}
+
+	content, err := ioutil.ReadFile(secret)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(content))
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+	exists, err := folder.Exists("copy")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// the keys with dots inside the names are fine
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, "a..b/.c", "data", nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "a..b/.c", "", nil))
+}
@@ -0,0 +1,125 @@
+package s3server
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const xmlNamespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// timeFormat is the ISO 8601 format of the times in the S3 responses
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+type XError struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource,omitempty"`
+}
+
+type XBucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type XListAllMyBucketsResult struct {
+	XMLName xml.Name  `xml:"ListAllMyBucketsResult"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Buckets []XBucket `xml:"Buckets>Bucket"`
+}
+
+type XLocationConstraint struct {
+	XMLName xml.Name `xml:"LocationConstraint"`
+	Xmlns   string   `xml:"xmlns,attr"`
+}
+
+type XObject struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type XCommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// XListBucketResult is the result of both ListObjects and ListObjectsV2, the fields of the other version are omitted
+type XListBucketResult struct {
+	XMLName               xml.Name        `xml:"ListBucketResult"`
+	Xmlns                 string          `xml:"xmlns,attr"`
+	Name                  string          `xml:"Name"`
+	Prefix                string          `xml:"Prefix"`
+	Delimiter             string          `xml:"Delimiter,omitempty"`
+	MaxKeys               int             `xml:"MaxKeys"`
+	IsTruncated           bool            `xml:"IsTruncated"`
+	Marker                *string         `xml:"Marker,omitempty"`
+	NextMarker            string          `xml:"NextMarker,omitempty"`
+	KeyCount              *int            `xml:"KeyCount,omitempty"`
+	StartAfter            string          `xml:"StartAfter,omitempty"`
+	ContinuationToken     string          `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string          `xml:"NextContinuationToken,omitempty"`
+	Contents              []XObject       `xml:"Contents"`
+	CommonPrefixes        []XCommonPrefix `xml:"CommonPrefixes"`
+}
+
+type XDeleteObject struct {
+	Key string `xml:"Key"`
+}
+
+type XDelete struct {
+	XMLName xml.Name        `xml:"Delete"`
+	Quiet   bool            `xml:"Quiet"`
+	Objects []XDeleteObject `xml:"Object"`
+}
+
+type XDeleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type XDeleteResult struct {
+	XMLName xml.Name        `xml:"DeleteResult"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Deleted []XDeleteObject `xml:"Deleted"`
+	Errors  []XDeleteError  `xml:"Error"`
+}
+
+type XCopyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	ETag         string   `xml:"ETag"`
+	LastModified string   `xml:"LastModified"`
+}
+
+type XInitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type XCompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type XCompleteMultipartUpload struct {
+	XMLName xml.Name         `xml:"CompleteMultipartUpload"`
+	Parts   []XCompletedPart `xml:"Part"`
+}
+
+type XCompleteMultipartUploadResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Bucket  string   `xml:"Bucket"`
+	Key     string   `xml:"Key"`
+	ETag    string   `xml:"ETag"`
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
@@ -0,0 +1,23 @@
+package storagetools
+
+import (
+	"context"
+	"os"
+	"syscall"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/storagetools/s3server"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// HandleServe serves the folder as an S3 bucket until the process is interrupted
+func HandleServe(folder storage.Folder, address string, options s3server.Options) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
+	defer func() { _ = signalHandler.Close() }()
+	server, err := s3server.NewServer(folder, address, options)
+	tracelog.ErrorLogger.FatalfOnError("Failed to create the server: %v", err)
+	err = server.Run(ctx)
+	tracelog.ErrorLogger.FatalfOnError("Failed to serve the storage: %v", err)
+}
@@ -0,0 +1,258 @@
+package storagetools
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+// CompareMode defines how the objects are compared to find the ones which don't need to be copied
+type CompareMode string
+
+const (
+	// CompareByModTime treats the objects as identical if they have the same size
+	// and the destination object is not older than the source one
+	CompareByModTime CompareMode = "mtime"
+	CompareBySize    CompareMode = "size"
+	// CompareByChecksum reads both objects to compare their SHA-256 checksums
+	CompareByChecksum CompareMode = "checksum"
+)
+
+func ParseCompareMode(mode string) (CompareMode, error) {
+	switch CompareMode(mode) {
+	case CompareByModTime, CompareBySize, CompareByChecksum:
+		return CompareMode(mode), nil
+	}
+	return "", errors.Errorf("compare mode should be one of '%s', '%s', '%s' but given '%s'",
+		CompareByModTime, CompareBySize, CompareByChecksum, mode)
+}
+
+// SyncOptions configure the copying of the objects between the storages
+type SyncOptions struct {
+	// CopyAll copies all the objects, otherwise the identical objects are skipped according to Compare
+	CopyAll bool
+	Compare CompareMode
+	// DeleteExtraneous deletes the destination objects which are missing in the source
+	DeleteExtraneous bool
+	DryRun           bool
+	Concurrency      int
+}
+
+// SyncAction is a change of the destination storage
+type SyncAction struct {
+	Name   string
+	Size   int64
+	Delete bool
+}
+
+func (action SyncAction) String() string {
+	if action.Delete {
+		return fmt.Sprintf("delete %s", action.Name)
+	}
+	return fmt.Sprintf("copy %s (%d bytes)", action.Name, action.Size)
+}
+
+// HandleSync copies the objects under the prefix from the storage of one config file to the storage of another one
+func HandleSync(fromConfigFile, toConfigFile, prefix string, options SyncOptions) {
+	from, err := internal.FolderFromConfig(fromConfigFile)
+	tracelog.ErrorLogger.FatalOnError(err)
+	to, err := internal.FolderFromConfig(toConfigFile)
+	tracelog.ErrorLogger.FatalOnError(err)
+	prefix = utility.SanitizePath(prefix)
+	if prefix != "" {
+		from, to = from.GetSubFolder(prefix), to.GetSubFolder(prefix)
+	}
+
+	actions, err := PlanSync(from, to, options)
+	tracelog.ErrorLogger.FatalfOnError("Failed to compare the storages: %v", err)
+	if options.DryRun {
+		for _, action := range actions {
+			fmt.Fprintln(os.Stdout, action)
+		}
+		tracelog.InfoLogger.Printf("%d changes would be made\n", len(actions))
+		return
+	}
+	err = ApplySyncActions(from, to, actions, options.Concurrency)
+	tracelog.ErrorLogger.FatalfOnError("Failed to copy the objects: %v", err)
+	tracelog.InfoLogger.Printf("%d changes are made\n", len(actions))
+}
+
+// PlanSync finds the objects to copy to the destination and to delete from it, sorted by name
+func PlanSync(from, to storage.Folder, options SyncOptions) ([]SyncAction, error) {
+	srcObjects, err := listObjects(from)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list '%s'", from.GetPath())
+	}
+	dstObjects, err := listObjects(to)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list '%s'", to.GetPath())
+	}
+
+	// the candidates are either copied or, in the checksum mode, read to compare the checksums
+	candidates := make([]storage.Object, 0, len(srcObjects))
+	for name, srcObject := range srcObjects {
+		dstObject, exists := dstObjects[name]
+		if options.CopyAll || !exists || !mayBeIdentical(srcObject, dstObject, options.Compare) ||
+			options.Compare == CompareByChecksum {
+			candidates = append(candidates, srcObject)
+		}
+	}
+
+	copied := make([]bool, len(candidates))
+	err = runConcurrently(options.Concurrency, len(candidates), func(ctx context.Context, i int) error {
+		object := candidates[i]
+		dstObject, exists := dstObjects[object.GetName()]
+		if options.CopyAll || !exists || !mayBeIdentical(object, dstObject, options.Compare) {
+			copied[i] = true
+			return nil
+		}
+		identical, err := haveSameChecksums(ctx, from, to, object.GetName())
+		copied[i] = !identical
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]SyncAction, 0)
+	for i, object := range candidates {
+		if copied[i] {
+			actions = append(actions, SyncAction{Name: object.GetName(), Size: object.GetSize()})
+		}
+	}
+	if options.DeleteExtraneous {
+		for name, dstObject := range dstObjects {
+			if _, exists := srcObjects[name]; !exists {
+				actions = append(actions, SyncAction{Name: name, Size: dstObject.GetSize(), Delete: true})
+			}
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].Name < actions[j].Name
+	})
+	return actions, nil
+}
+
+// ApplySyncActions copies and deletes the objects, the objects are copied as is, without decryption and decompression
+func ApplySyncActions(from, to storage.Folder, actions []SyncAction, concurrency int) error {
+	deletions := make([]string, 0)
+	copies := make([]SyncAction, 0, len(actions))
+	for _, action := range actions {
+		if action.Delete {
+			deletions = append(deletions, action.Name)
+		} else {
+			copies = append(copies, action)
+		}
+	}
+	err := runConcurrently(concurrency, len(copies), func(ctx context.Context, i int) error {
+		return copyObject(ctx, from, to, copies[i].Name)
+	})
+	if err != nil {
+		return err
+	}
+	if len(deletions) == 0 {
+		return nil
+	}
+	// the deletions are made after all the copies, so that a failed sync doesn't leave the destination with fewer objects
+	for _, name := range deletions {
+		tracelog.InfoLogger.Printf("Deleting %s\n", name)
+	}
+	return errors.Wrap(to.DeleteObjects(deletions), "failed to delete the extraneous objects")
+}
+
+func mayBeIdentical(srcObject, dstObject storage.Object, mode CompareMode) bool {
+	if srcObject.GetSize() != dstObject.GetSize() {
+		return false
+	}
+	if mode == CompareByModTime {
+		return !dstObject.GetLastModified().Before(srcObject.GetLastModified())
+	}
+	return true
+}
+
+func copyObject(ctx context.Context, from, to storage.Folder, name string) error {
+	reader, err := storage.NewContextFolder(from).ReadObjectWithContext(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	err = storage.NewContextFolder(to).PutObjectWithContext(ctx, name, reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy '%s'", name)
+	}
+	tracelog.InfoLogger.Printf("Copied %s\n", name)
+	return nil
+}
+
+func haveSameChecksums(ctx context.Context, from, to storage.Folder, name string) (bool, error) {
+	srcChecksum, err := readChecksum(ctx, from, name)
+	if err != nil {
+		return false, err
+	}
+	dstChecksum, err := readChecksum(ctx, to, name)
+	if err != nil {
+		return false, err
+	}
+	return srcChecksum == dstChecksum, nil
+}
+
+func readChecksum(ctx context.Context, folder storage.Folder, name string) (string, error) {
+	reader, err := storage.NewContextFolder(folder).ReadObjectWithContext(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	checksum := sha256.New()
+	if _, err = io.Copy(checksum, reader); err != nil {
+		return "", errors.Wrapf(err, "failed to read '%s' in '%s'", name, folder.GetPath())
+	}
+	return fmt.Sprintf("%x", checksum.Sum(nil)), nil
+}
+
+func listObjects(folder storage.Folder) (map[string]storage.Object, error) {
+	objects := make(map[string]storage.Object)
+	err := storage.ListFolderPages(context.Background(), folder, storage.ListOptions{Recursive: true},
+		func(page []storage.Object, _ []storage.Folder) error {
+			for _, object := range page {
+				objects[object.GetName()] = object
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// runConcurrently calls run for every index up to count with at most concurrency calls at once,
+// it stops starting new calls after the first error
+func runConcurrently(concurrency, count int, run func(ctx context.Context, i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	group, ctx := errgroup.WithContext(context.Background())
+	tickets := make(chan struct{}, concurrency)
+	for i := 0; i < count; i++ {
+		select {
+		case tickets <- struct{}{}:
+		case <-ctx.Done():
+			return group.Wait()
+		}
+		i := i
+		group.Go(func() error {
+			defer func() { <-tickets }()
+			return run(ctx, i)
+		})
+	}
+	return group.Wait()
+}
@@ -0,0 +1,93 @@
+package storagetools_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/storagetools"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func putObjects(t *testing.T, folder storage.Folder, objects map[string]string) {
+	for name, content := range objects {
+		require.NoError(t, folder.PutObject(name, bytes.NewBufferString(content)))
+	}
+}
+
+func newSyncFolders(t *testing.T) (storage.Folder, storage.Folder) {
+	from := memory.NewFolder("from/", memory.NewStorage())
+	to := memory.NewFolder("to/", memory.NewStorage())
+	putObjects(t, from, map[string]string{"same": "abc", "changed": "abc", "sub/new": "abc", "resized": "abc"})
+	putObjects(t, to, map[string]string{"same": "abc", "changed": "abd", "resized": "ab", "extra": "abc"})
+	return from, to
+}
+
+func actionNames(actions []storagetools.SyncAction) []string {
+	names := make([]string, 0, len(actions))
+	for _, action := range actions {
+		names = append(names, action.String())
+	}
+	return names
+}
+
+func TestPlanSync_CompareModes(t *testing.T) {
+	from, to := newSyncFolders(t)
+
+	actions, err := storagetools.PlanSync(from, to, storagetools.SyncOptions{Compare: storagetools.CompareBySize})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"copy resized (3 bytes)", "copy sub/new (3 bytes)"}, actionNames(actions))
+
+	actions, err = storagetools.PlanSync(from, to, storagetools.SyncOptions{Compare: storagetools.CompareByModTime})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"copy resized (3 bytes)", "copy sub/new (3 bytes)"}, actionNames(actions))
+
+	actions, err = storagetools.PlanSync(from, to, storagetools.SyncOptions{
+		Compare:          storagetools.CompareByChecksum,
+		DeleteExtraneous: true,
+		Concurrency:      2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"copy changed (3 bytes)", "delete extra", "copy resized (3 bytes)", "copy sub/new (3 bytes)"},
+		actionNames(actions))
+
+	actions, err = storagetools.PlanSync(from, to, storagetools.SyncOptions{CopyAll: true})
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(actions))
+}
+
+func TestApplySyncActions(t *testing.T) {
+	from, to := newSyncFolders(t)
+	options := storagetools.SyncOptions{Compare: storagetools.CompareByChecksum, DeleteExtraneous: true, Concurrency: 3}
+	actions, err := storagetools.PlanSync(from, to, options)
+	require.NoError(t, err)
+
+	require.NoError(t, storagetools.ApplySyncActions(from, to, actions, options.Concurrency))
+
+	for _, name := range []string{"same", "changed", "sub/new", "resized"} {
+		reader, err := to.ReadObject(name)
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "abc", string(content))
+	}
+	exists, err := to.Exists("extra")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	actions, err = storagetools.PlanSync(from, to, options)
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+}
+
+func TestParseCompareMode(t *testing.T) {
+	mode, err := storagetools.ParseCompareMode("checksum")
+	assert.NoError(t, err)
+	assert.Equal(t, storagetools.CompareByChecksum, mode)
+
+	_, err = storagetools.ParseCompareMode("hash")
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"time"
@@ -35,11 +36,11 @@ func GetLogsDstSettings(operationLogsDstEnvVariable string) (dstFolder string, e
 
 // TODO : unit tests
 // downloadAndDecompressStream downloads, decompresses and writes stream to stdout
-func downloadAndDecompressStream(backup Backup, writeCloser io.WriteCloser) error {
+func downloadAndDecompressStream(ctx context.Context, backup Backup, writeCloser io.WriteCloser) error {
 	defer utility.LoggedClose(writeCloser, "")
 
 	for _, decompressor := range compression.Decompressors {
-		archiveReader, exists, err := TryDownloadFile(
+		archiveReader, exists, err := TryDownloadFileWithContext(ctx,
 			backup.Folder, GetStreamName(backup.Name, decompressor.FileExtension()))
 		if err != nil {
 			return errors.Wrapf(err, "failed to dowload file")
@@ -55,5 +56,22 @@ func downloadAndDecompressStream(backup Backup, writeCloser io.WriteCloser) erro
 		}
 		return nil
 	}
+
+	// the stream pushed uncompressed by the adaptive compression
+	archiveReader, exists, err := TryDownloadFileWithContext(ctx,
+		backup.Folder, GetStreamName(backup.Name, UncompressedStreamExtension))
+	if err != nil {
+		return errors.Wrapf(err, "failed to dowload file")
+	}
+	if exists {
+		tracelog.DebugLogger.Printf("Found file: %s.%s", backup.Name, UncompressedStreamExtension)
+		defer utility.LoggedClose(archiveReader, "")
+		decryptReader, err := DecryptBytes(archiveReader)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decrypt file")
+		}
+		_, err = utility.FastCopy(&EmptyWriteIgnorer{WriteCloser: writeCloser}, decryptReader)
+		return errors.Wrapf(err, "failed to copy file")
+	}
 	return newArchiveNonExistenceError(fmt.Sprintf("Archive '%s' does not exist.\n", backup.Name))
 }
@@ -1,24 +1,59 @@
 package internal
 
 import (
+	"bytes"
 	"io"
 	"os"
 	"path"
 
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/utility"
 )
 
 const (
 	StreamPrefix = "stream_"
+	// UncompressedStreamExtension is the extension of the stream pushed without compression
+	UncompressedStreamExtension = "raw"
+	// streamSampleSize is the size of the beginning of the stream which is sampled by the adaptive compression
+	streamSampleSize = 4 * compression.SampleSize
 )
 
 // TODO : unit tests
-// PushStream compresses a stream and push it
+// PushStream compresses a stream and push it.
+// The stream is compressed by WALG_COMPRESSION_CONCURRENCY workers if the compression allows it.
+// With the adaptive compression the blocks of the stream which don't compress well are stored as is.
+// If the compression can't store the blocks, the stream is pushed uncompressed if its beginning doesn't compress well.
 func (uploader *Uploader) PushStream(stream io.Reader) (string, error) {
 	backupName := StreamPrefix + utility.TimeNowCrossPlatformUTC().Format(utility.BackupTimeFormat)
-	dstPath := GetStreamName(backupName, uploader.Compressor.FileExtension())
-	err := uploader.PushStreamToDestination(stream, dstPath)
+	compressor := uploader.Compressor
+	adaptive := viper.GetBool(AdaptiveCompressionSetting)
+	_, canStoreBlocks := compression.WithAdaptiveBlocks(compressor, 1)
+	if adaptive && !canStoreBlocks {
+		var compressible bool
+		var err error
+		stream, compressible, err = sampleStream(stream)
+		if err != nil {
+			return backupName, err
+		}
+		if !compressible {
+			tracelog.InfoLogger.Println("The stream doesn't compress well, pushing it uncompressed")
+			compressor = nil
+		}
+	}
+	extension := UncompressedStreamExtension
+	if compressor != nil {
+		extension = compressor.FileExtension()
+		var err error
+		compressor, err = parallelStreamCompressor(compressor, adaptive)
+		if err != nil {
+			return backupName, err
+		}
+	}
+	dstPath := GetStreamName(backupName, extension)
+	err := uploader.pushStreamToDestination(stream, dstPath, compressor)
 
 	return backupName, err
 }
@@ -26,16 +61,58 @@ func (uploader *Uploader) PushStream(stream io.Reader) (string, error) {
 // TODO : unit tests
 // PushStreamToDestination compresses a stream and push it to specifyed destination
 func (uploader *Uploader) PushStreamToDestination(stream io.Reader, dstPath string) error {
+	return uploader.pushStreamToDestination(stream, dstPath, uploader.Compressor)
+}
+
+func (uploader *Uploader) pushStreamToDestination(stream io.Reader, dstPath string, compressor compression.Compressor) error {
 	if uploader.dataSize != nil {
 		stream = NewWithSizeReader(stream, uploader.dataSize)
 	}
-	compressed := CompressAndEncrypt(stream, uploader.Compressor, ConfigureCrypter())
+	compressed := CompressAndEncrypt(stream, compressor, ConfigureCrypter())
 	err := uploader.Upload(dstPath, compressed)
 	tracelog.InfoLogger.Println("FILE PATH:", dstPath)
 
 	return err
 }
 
+// parallelStreamCompressor returns the compressor which compresses the stream
+// by the configured number of the workers, see compression.WithConcurrency.
+// With the adaptive compression the incompressible blocks are stored, see compression.WithAdaptiveBlocks.
+func parallelStreamCompressor(compressor compression.Compressor, adaptive bool) (compression.Compressor, error) {
+	concurrency, err := GetMaxCompressConcurrency()
+	if err != nil {
+		return nil, err
+	}
+	var parallelCompressor compression.Compressor
+	var ok bool
+	if adaptive {
+		parallelCompressor, ok = compression.WithAdaptiveBlocks(compressor, concurrency)
+	}
+	if !ok {
+		parallelCompressor, ok = compression.WithConcurrency(compressor, concurrency)
+	}
+	if !ok && concurrency > 1 {
+		tracelog.WarningLogger.Printf("The %s compression can't run in parallel, the stream is compressed by a single worker\n",
+			compressor.FileExtension())
+	}
+	if _, long := compression.WithoutLongWindow(compressor); ok && long {
+		tracelog.WarningLogger.Printf("%s is ignored, the stream is compressed in the separate blocks\n", ZstdLongSetting)
+	}
+	return parallelCompressor, nil
+}
+
+// sampleStream reads the beginning of the stream to estimate its compressibility,
+// the returned stream reads from the start
+func sampleStream(stream io.Reader) (io.Reader, bool, error) {
+	sample := make([]byte, streamSampleSize)
+	n, err := io.ReadFull(stream, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, false, errors.Wrap(err, "failed to sample the stream")
+	}
+	sample = sample[:n]
+	return io.MultiReader(bytes.NewReader(sample), stream), compression.IsCompressible(sample), nil
+}
+
 // FileIsPiped Check if file is piped
 func FileIsPiped(stream *os.File) bool {
 	stat, _ := stream.Stat()
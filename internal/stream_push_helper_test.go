@@ -0,0 +1,94 @@
+package internal_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestPushStream_AdaptiveCompression(t *testing.T) {
+	viper.Set(internal.AdaptiveCompressionSetting, true)
+	defer resetToDefaults()
+
+	uploader := testtools.NewStoringMockUploader(memory.NewStorage(), nil)
+	incompressible := make([]byte, 1<<20)
+	rand.Read(incompressible)
+	backupName, err := uploader.PushStream(bytes.NewReader(incompressible))
+	require.NoError(t, err)
+
+	exists, err := uploader.UploadingFolder.Exists(internal.GetStreamName(backupName, internal.UncompressedStreamExtension))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.Equal(t, incompressible, fetchStream(t, uploader.UploadingFolder, backupName))
+}
+
+func TestPushStream_AdaptiveCompressionStoresBlocks(t *testing.T) {
+	viper.Set(internal.AdaptiveCompressionSetting, true)
+	defer resetToDefaults()
+
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], memory.NewFolder("in_memory/", memory.NewStorage()))
+	incompressible := make([]byte, 1<<20)
+	rand.Read(incompressible)
+	data := append(incompressible, bytes.Repeat([]byte("wal-g "), 1<<16)...)
+	backupName, err := uploader.PushStream(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	exists, err := uploader.UploadingFolder.Exists(internal.GetStreamName(backupName, lz4.FileExtension))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.Equal(t, data, fetchStream(t, uploader.UploadingFolder, backupName))
+}
+
+func TestPushStream_AdaptiveCompressionCompressible(t *testing.T) {
+	viper.Set(internal.AdaptiveCompressionSetting, true)
+	defer resetToDefaults()
+
+	uploader := testtools.NewStoringMockUploader(memory.NewStorage(), nil)
+	backupName, err := uploader.PushStream(bytes.NewReader(bytes.Repeat([]byte("wal-g "), 1<<16)))
+	require.NoError(t, err)
+
+	exists, err := uploader.UploadingFolder.Exists(internal.GetStreamName(backupName, uploader.Compressor.FileExtension()))
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestPushStream_Concurrency(t *testing.T) {
+	viper.Set(internal.CompressConcurrencySetting, 4)
+	defer resetToDefaults()
+
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], memory.NewFolder("in_memory/", memory.NewStorage()))
+	data := bytes.Repeat([]byte("wal-g "), 3*compression.ParallelBlockSize/5)
+	backupName, err := uploader.PushStream(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, data, fetchStream(t, uploader.UploadingFolder, backupName))
+}
+
+func fetchStream(t *testing.T, folder storage.Folder, backupName string) []byte {
+	dir, err := ioutil.TempDir("", "stream")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	outputPath := filepath.Join(dir, "output")
+	cmd := exec.Command("sh", "-c", "cat > "+outputPath)
+	err = internal.StreamBackupToCommandStdin(cmd, internal.NewBackup(folder, backupName))
+	require.NoError(t, err)
+	output, err := ioutil.ReadFile(outputPath)
+	require.NoError(t, err)
+	return output
+}
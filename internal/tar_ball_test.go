@@ -4,11 +4,13 @@ import (
 	"archive/tar"
 	"bytes"
 	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
 	"github.com/wal-g/wal-g/testtools"
 )
 
@@ -109,3 +111,34 @@ func TestPackFileTo(t *testing.T) {
 	}
 	assert.Equal(t, []byte(mockData), interpreter.Out)
 }
+
+func TestUncompressedTarBall(t *testing.T) {
+	uploader := testtools.NewStoringMockUploader(memory.NewStorage(), nil)
+	tarBallMaker := internal.NewStorageTarBallMaker("mockBackup", uploader)
+	compressedTarBall := tarBallMaker.Make(false)
+	compressedTarBall.SetUp(nil)
+	tarBall := tarBallMaker.UncompressedMaker().Make(false)
+	tarBall.SetUp(nil)
+
+	assert.Equal(t, "part_001.tar."+uploader.Compressor.FileExtension(), compressedTarBall.Name())
+	assert.Equal(t, "part_002.tar", tarBall.Name())
+
+	mockData := []byte("mock")
+	err := tarBall.TarWriter().WriteHeader(&tar.Header{Name: "mock", Mode: 0600, Size: int64(len(mockData))})
+	assert.NoError(t, err)
+	_, err = tarBall.TarWriter().Write(mockData)
+	assert.NoError(t, err)
+	assert.NoError(t, compressedTarBall.CloseTar())
+	assert.NoError(t, tarBall.CloseTar())
+	tarBall.AwaitUploads()
+
+	reader, err := uploader.UploadingFolder.ReadObject("mockBackup" + internal.TarPartitionFolderName + tarBall.Name())
+	assert.NoError(t, err)
+	tarReader := tar.NewReader(reader)
+	header, err := tarReader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "mock", header.Name)
+	data, err := ioutil.ReadAll(tarReader)
+	assert.NoError(t, err)
+	assert.Equal(t, mockData, data)
+}
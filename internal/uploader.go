@@ -1,7 +1,11 @@
 package internal
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"path/filepath"
 	"sync"
@@ -26,11 +30,14 @@ type UploaderProvider interface {
 	DisableSizeTracking()
 	UploadedDataSize() (int64, error)
 	RawDataSize() (int64, error)
+	UploadedChecksums() map[string]string
+	Folder() storage.Folder
 }
 
 // Uploader contains fields associated with uploading tarballs.
 // Multiple tarballs can share one uploader.
 type Uploader struct {
+	ctx                    context.Context
 	UploadingFolder        storage.Folder
 	Compressor             compression.Compressor
 	waitGroup              *sync.WaitGroup
@@ -39,6 +46,7 @@ type Uploader struct {
 	Failed                 atomic.Value
 	tarSize                *int64
 	dataSize               *int64
+	checksums              *uploadedChecksums
 }
 
 // UploadObject
@@ -52,6 +60,7 @@ func NewUploader(
 	uploadingLocation storage.Folder,
 ) *Uploader {
 	uploader := &Uploader{
+		ctx:             context.Background(),
 		UploadingFolder: uploadingLocation,
 		Compressor:      compressor,
 		waitGroup:       &sync.WaitGroup{},
@@ -90,6 +99,7 @@ func (uploader *Uploader) Finish() {
 // Clone creates similar Uploader with new WaitGroup
 func (uploader *Uploader) Clone() *Uploader {
 	return &Uploader{
+		ctx:                  uploader.ctx,
 		UploadingFolder:      uploader.UploadingFolder,
 		Compressor:           uploader.Compressor,
 		waitGroup:            &sync.WaitGroup{},
@@ -97,6 +107,7 @@ func (uploader *Uploader) Clone() *Uploader {
 		Failed:               uploader.Failed,
 		tarSize:              uploader.tarSize,
 		dataSize:             uploader.dataSize,
+		checksums:            uploader.checksums,
 	}
 }
 
@@ -123,6 +134,40 @@ func (uploader *Uploader) DisableSizeTracking() {
 	uploader.dataSize = nil
 }
 
+// EnableChecksumTracking makes the uploader record the SHA-256 of the uploaded objects,
+// they are signed along with the backup, see Backup.SignUploaded
+func (uploader *Uploader) EnableChecksumTracking() {
+	uploader.checksums = &uploadedChecksums{checksums: make(map[string]string)}
+}
+
+// UploadedChecksums returns the SHA-256 of the uploaded objects keyed by their paths, see storage.JoinPath.
+// It returns nil when the checksum tracking is not enabled (see EnableChecksumTracking)
+func (uploader *Uploader) UploadedChecksums() map[string]string {
+	if uploader.checksums == nil {
+		return nil
+	}
+	return uploader.checksums.copy()
+}
+
+// Context returns the context which bounds all uploads of this Uploader
+func (uploader *Uploader) Context() context.Context {
+	return uploader.ctx
+}
+
+// WithContext returns a shallow copy of Uploader whose uploads are aborted when ctx is done
+func (uploader *Uploader) WithContext(ctx context.Context) *Uploader {
+	uploaderCopy := uploader.Clone()
+	uploaderCopy.waitGroup = uploader.waitGroup
+	uploaderCopy.PGArchiveStatusManager = uploader.PGArchiveStatusManager
+	uploaderCopy.ctx = ctx
+	return uploaderCopy
+}
+
+// Folder returns the folder the objects are uploaded to
+func (uploader *Uploader) Folder() storage.Folder {
+	return uploader.UploadingFolder
+}
+
 // Compression returns configured compressor
 func (uploader *Uploader) Compression() compression.Compressor {
 	return uploader.Compressor
@@ -133,8 +178,16 @@ func (uploader *Uploader) Upload(path string, content io.Reader) error {
 	if uploader.tarSize != nil {
 		content = NewWithSizeReader(content, uploader.tarSize)
 	}
-	err := uploader.UploadingFolder.PutObject(path, content)
+	var checksum hash.Hash
+	if uploader.checksums != nil {
+		checksum = sha256.New()
+		content = io.TeeReader(content, checksum)
+	}
+	err := storage.NewContextFolder(uploader.UploadingFolder).PutObjectWithContext(uploader.ctx, path, content)
 	if err == nil {
+		if checksum != nil {
+			uploader.checksums.add(storage.JoinPath(uploader.UploadingFolder.GetPath(), path), checksum)
+		}
 		return nil
 	}
 	uploader.Failed.Store(true)
@@ -155,3 +208,31 @@ func (uploader *Uploader) UploadMultiple(objects []UploadObject) error {
 	}
 	return nil
 }
+
+type uploadedChecksums struct {
+	mutex     sync.Mutex
+	checksums map[string]string
+}
+
+func (checksums *uploadedChecksums) add(path string, checksum hash.Hash) {
+	checksums.mutex.Lock()
+	defer checksums.mutex.Unlock()
+	checksums.checksums[path] = hex.EncodeToString(checksum.Sum(nil))
+}
+
+// invalidate records the object as rewritten with the unknown checksum, see Backup.SignUploaded
+func (checksums *uploadedChecksums) invalidate(path string) {
+	checksums.mutex.Lock()
+	defer checksums.mutex.Unlock()
+	checksums.checksums[path] = ""
+}
+
+func (checksums *uploadedChecksums) copy() map[string]string {
+	checksums.mutex.Lock()
+	defer checksums.mutex.Unlock()
+	result := make(map[string]string, len(checksums.checksums))
+	for path, checksum := range checksums.checksums {
+		result[path] = checksum
+	}
+	return result
+}
@@ -141,8 +141,11 @@ func (folder *Folder) GetPath() string {
 }
 
 func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
 	path := storage.JoinPath(folder.path, objectRelativePath)
-	ctx := context.Background()
 	blobURL := folder.containerURL.NewBlockBlobURL(path)
 	_, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
 	if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
@@ -155,10 +158,15 @@ func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
 }
 
 func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *Folder) ListFolderWithContext(ctx context.Context) (objects []storage.Object,
+	subFolders []storage.Folder, err error) {
 	//Marker is used for segmented iteration.
 	for marker := (azblob.Marker{}); marker.NotDone(); {
 
-		blobs, err := folder.containerURL.ListBlobsHierarchySegment(context.Background(), marker, "/", azblob.ListBlobsSegmentOptions{Prefix: folder.path})
+		blobs, err := folder.containerURL.ListBlobsHierarchySegment(ctx, marker, "/", azblob.ListBlobsSegmentOptions{Prefix: folder.path})
 		if err != nil {
 			return nil, nil, NewFolderError(err, "Unable to iterate %v", folder.path)
 		}
@@ -184,6 +192,53 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 	return
 }
 
+func (folder *Folder) ListFolderPages(ctx context.Context, options storage.ListOptions,
+	handler storage.ListPageHandler) error {
+	segmentOptions := azblob.ListBlobsSegmentOptions{Prefix: folder.path + options.Prefix}
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		var blobItems []azblob.BlobItemInternal
+		var blobPrefixes []azblob.BlobPrefix
+		if options.Recursive {
+			blobs, err := folder.containerURL.ListBlobsFlatSegment(ctx, marker, segmentOptions)
+			if err != nil {
+				return NewFolderError(err, "Unable to iterate %v", folder.path)
+			}
+			blobItems, marker = blobs.Segment.BlobItems, blobs.NextMarker
+		} else {
+			blobs, err := folder.containerURL.ListBlobsHierarchySegment(ctx, marker, "/", segmentOptions)
+			if err != nil {
+				return NewFolderError(err, "Unable to iterate %v", folder.path)
+			}
+			blobItems, blobPrefixes, marker = blobs.Segment.BlobItems, blobs.Segment.BlobPrefixes, blobs.NextMarker
+		}
+
+		// Azure can not start a listing after a given name, so the names are filtered here
+		var objects []storage.Object
+		for _, blob := range blobItems {
+			objName := strings.TrimPrefix(blob.Name, folder.path)
+			if !options.ObjectMatches(objName) {
+				continue
+			}
+			updated := time.Time(blob.Properties.LastModified)
+			objects = append(objects, storage.NewLocalObject(objName, updated, *blob.Properties.ContentLength))
+		}
+		var subFolders []storage.Folder
+		for _, blobPrefix := range blobPrefixes {
+			if !options.SubFolderMatches(strings.TrimPrefix(blobPrefix.Name, folder.path)) {
+				continue
+			}
+			subFolders = append(subFolders, NewFolder(folder.uploadStreamToBlockBlobOptions, folder.containerURL, blobPrefix.Name))
+		}
+		if len(objects) == 0 && len(subFolders) == 0 {
+			continue
+		}
+		if err := handler(objects, subFolders); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
 	return NewFolder(
 		folder.uploadStreamToBlockBlobOptions,
@@ -192,10 +247,36 @@ func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder
 }
 
 func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
+	return folder.download(ctx, objectRelativePath, 0, azblob.CountToEnd)
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *Folder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		// zero count means the end of the blob for Azure
+		return storage.ReadEmptyRange(ctx, folder, objectRelativePath)
+	}
+	if length < 0 {
+		length = azblob.CountToEnd
+	}
+	return folder.download(ctx, objectRelativePath, offset, length)
+}
+
+func (folder *Folder) download(ctx context.Context, objectRelativePath string,
+	offset, count int64) (io.ReadCloser, error) {
 	//Download blob using blobURL obtained from full path to blob
 	path := storage.JoinPath(folder.path, objectRelativePath)
 	blobURL := folder.containerURL.NewBlockBlobURL(path)
-	downloadResponse, err := blobURL.Download(context.Background(), 0, 0, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	downloadResponse, err := blobURL.Download(ctx, offset, count,
+		azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
 	if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
 		return nil, storage.NewObjectNotFoundError(path)
 	}
@@ -209,11 +290,15 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 }
 
 func (folder *Folder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
 	tracelog.DebugLogger.Printf("Put %v into %v\n", name, folder.path)
 	//Upload content to a block blob using full path
 	path := storage.JoinPath(folder.path, name)
 	blobURL := folder.containerURL.NewBlockBlobURL(path)
-	_, err := azblob.UploadStreamToBlockBlob(context.Background(), content, blobURL, folder.uploadStreamToBlockBlobOptions)
+	_, err := azblob.UploadStreamToBlockBlob(ctx, content, blobURL, folder.uploadStreamToBlockBlobOptions)
 	if err != nil {
 		return NewFolderError(err, "Unable to upload blob %v", name)
 	}
@@ -223,7 +308,11 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
-	if exists, err := folder.Exists(srcPath); !exists {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	if exists, err := folder.ExistsWithContext(ctx, srcPath); !exists {
 		if err == nil {
 			return errors.New("object do not exists")
 		} else {
@@ -233,17 +322,21 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	dst := storage.JoinPath(folder.path, dstPath)
 	source := folder.containerURL.NewBlockBlobURL(storage.JoinPath(folder.path, srcPath))
 	blobURL := folder.containerURL.NewBlockBlobURL(dst)
-	_, err := blobURL.StartCopyFromURL(context.Background(), source.URL(), nil, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{}, azblob.AccessTierHot, nil)
+	_, err := blobURL.StartCopyFromURL(ctx, source.URL(), nil, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{}, azblob.AccessTierHot, nil)
 	return err
 }
 
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *Folder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
 	for _, objectRelativePath := range objectRelativePaths {
 		//Delete blob using blobURL obtained from full path to blob
 		path := storage.JoinPath(folder.path, objectRelativePath)
 		blobURL := folder.containerURL.NewBlockBlobURL(path)
 		tracelog.DebugLogger.Printf("Delete %v\n", path)
-		_, err := blobURL.Delete(context.Background(), azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+		_, err := blobURL.Delete(ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
 		if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
 			continue
 		}
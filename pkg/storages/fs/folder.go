@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -43,6 +44,14 @@ func (folder *Folder) GetPath() string {
 }
 
 func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *Folder) ListFolderWithContext(ctx context.Context) (objects []storage.Object,
+	subFolders []storage.Folder, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	files, err := ioutil.ReadDir(path.Join(folder.rootPath, folder.subpath))
 	if err != nil {
 		return nil, nil, NewError(err, "Unable to read folder")
@@ -59,8 +68,54 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 	return
 }
 
+func (folder *Folder) ListFolderPages(ctx context.Context, options storage.ListOptions,
+	handler storage.ListPageHandler) error {
+	pager := storage.NewListPager(handler)
+	if err := folder.listPages(ctx, "", options, pager); err != nil {
+		return err
+	}
+	return pager.Flush()
+}
+
+func (folder *Folder) listPages(ctx context.Context, relativePath string, options storage.ListOptions,
+	pager *storage.ListPager) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(path.Join(folder.rootPath, folder.subpath, relativePath))
+	if err != nil {
+		return NewError(err, "Unable to read folder")
+	}
+	storage.SortFileInfos(files)
+	for _, fileInfo := range files {
+		name := path.Join(relativePath, fileInfo.Name())
+		if !fileInfo.IsDir() {
+			if options.ObjectMatches(name) {
+				err = pager.AddObject(storage.NewLocalObject(name, fileInfo.ModTime(), fileInfo.Size()))
+			}
+		} else if options.SubFolderMatches(name) {
+			if options.Recursive {
+				err = folder.listPages(ctx, name, options, pager)
+			} else {
+				err = pager.AddSubFolder(NewFolder(folder.rootPath, path.Join(folder.subpath, name)+"/"))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *Folder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
 	for _, fileName := range objectRelativePaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		err := os.RemoveAll(folder.GetFilePath(fileName))
 		if os.IsNotExist(err) {
 			continue
@@ -73,6 +128,13 @@ func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
 }
 
 func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 	_, err := os.Stat(folder.GetFilePath(objectRelativePath))
 	if os.IsNotExist(err) {
 		return false, nil
@@ -83,6 +145,21 @@ func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
 	return true, nil
 
 }
+func (folder *Folder) StatObject(ctx context.Context, objectRelativePath string) (storage.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	filePath := folder.GetFilePath(objectRelativePath)
+	fileInfo, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, storage.NewObjectNotFoundError(filePath)
+	}
+	if err != nil {
+		return nil, NewError(err, "Unable to stat object %v", objectRelativePath)
+	}
+	return storage.NewLocalObject(objectRelativePath, fileInfo.ModTime(), fileInfo.Size()), nil
+}
+
 func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
 	sf := Folder{folder.rootPath, path.Join(folder.subpath, subFolderRelativePath)}
 	_ = sf.EnsureExists()
@@ -94,6 +171,13 @@ func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder
 }
 
 func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	filePath := folder.GetFilePath(objectRelativePath)
 	file, err := os.Open(filePath)
 	if os.IsNotExist(err) {
@@ -102,17 +186,49 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	if err != nil {
 		return nil, NewError(err, "Unable to read object %v", filePath)
 	}
-	return file, nil
+	return storage.NewContextReadCloser(ctx, file), nil
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *Folder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	filePath := folder.GetFilePath(objectRelativePath)
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, storage.NewObjectNotFoundError(filePath)
+	}
+	if err != nil {
+		return nil, NewError(err, "Unable to read object %v", filePath)
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		_ = file.Close()
+		return nil, NewError(err, "Unable to seek object %v to %d", filePath, offset)
+	}
+	return storage.NewContextReadCloser(ctx, storage.NewLimitedReadCloser(file, length)), nil
 }
 
 func (folder *Folder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	tracelog.DebugLogger.Printf("Put %v into %v\n", name, folder.subpath)
 	filePath := folder.GetFilePath(name)
 	file, err := OpenFileWithDir(filePath)
 	if err != nil {
 		return NewError(err, "Unable to open file %v", filePath)
 	}
-	_, err = io.Copy(file, content)
+	_, err = io.Copy(file, storage.NewContextReader(ctx, content))
 	if err != nil {
 		closerErr := file.Close()
 		if closerErr != nil {
@@ -128,6 +244,10 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
 	src := path.Join(folder.rootPath, srcPath)
 	srcStat, err := os.Stat(src)
 	if err != nil {
@@ -140,7 +260,8 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	if err != nil {
 		return err
 	}
-	err = folder.PutObject(dstPath, file)
+	defer file.Close()
+	err = folder.PutObjectWithContext(ctx, dstPath, file)
 	return err
 }
 
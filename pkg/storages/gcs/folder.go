@@ -26,6 +26,7 @@ const (
 	EncryptionKey   = "GCS_ENCRYPTION_KEY"
 	MaxChunkSize    = "GCS_MAX_CHUNK_SIZE"
 	MaxRetries      = "GCS_MAX_RETRIES"
+	UploadStateDir  = "UPLOAD_STATE_DIR"
 
 	defaultContextTimeout = 60 * 60 // 1 hour
 	maxRetryDelay         = 5 * time.Minute
@@ -45,6 +46,7 @@ var (
 		EncryptionKey,
 		MaxChunkSize,
 		MaxRetries,
+		UploadStateDir,
 	}
 )
 
@@ -157,6 +159,14 @@ func getUploaderOptions(settings map[string]string) ([]UploaderOption, error) {
 		uploaderOptions = append(uploaderOptions, func(uploader *Uploader) { uploader.maxUploadRetries = maxRetries })
 	}
 
+	if stateDir, ok := settings[UploadStateDir]; ok {
+		states, err := storage.NewUploadStateStore(stateDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid upload state directory setting")
+		}
+		uploaderOptions = append(uploaderOptions, WithUploadStates(states))
+	}
+
 	return uploaderOptions, nil
 }
 
@@ -186,8 +196,13 @@ func (folder *Folder) BuildObjectHandle(path string) *gcs.ObjectHandle {
 }
 
 func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *Folder) ListFolderWithContext(ctx context.Context) (objects []storage.Object,
+	subFolders []storage.Folder, err error) {
 	prefix := storage.AddDelimiterToPath(folder.path)
-	ctx, cancel := folder.createTimeoutContext()
+	ctx, cancel := folder.createTimeoutContext(ctx)
 	defer cancel()
 	it := folder.bucket.Objects(ctx, &gcs.Query{Delimiter: "/", Prefix: prefix})
 	for {
@@ -198,43 +213,95 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 		if err != nil {
 			return nil, nil, NewError(err, "Unable to iterate %v", folder.path)
 		}
-		if objAttrs.Prefix != "" {
-
-			if objAttrs.Prefix != prefix+"/" {
-				// Sometimes GCS returns "//" folder - skip it
-				subFolders = append(subFolders,
-					NewFolder(
-						folder.bucket,
-						objAttrs.Prefix,
-						folder.contextTimeout,
-						folder.normalizePrefix,
-						folder.encryptionKey,
-						folder.uploaderOptions,
-					))
+		object, subFolder := folder.toListEntry(prefix, objAttrs)
+		if object != nil {
+			objects = append(objects, object)
+		}
+		if subFolder != nil {
+			subFolders = append(subFolders, subFolder)
+		}
+	}
+	return
+}
+
+func (folder *Folder) ListFolderPages(ctx context.Context, options storage.ListOptions,
+	handler storage.ListPageHandler) error {
+	prefix := storage.AddDelimiterToPath(folder.path)
+	query := &gcs.Query{Prefix: prefix + options.Prefix}
+	if !options.Recursive {
+		query.Delimiter = "/"
+	}
+	ctx, cancel := folder.createTimeoutContext(ctx)
+	defer cancel()
+	pager := iterator.NewPager(folder.bucket.Objects(ctx, query), storage.DefaultListPageSize, "")
+	for {
+		var attrsPage []*gcs.ObjectAttrs
+		nextPageToken, err := pager.NextPage(&attrsPage)
+		if err != nil {
+			return NewError(err, "Unable to iterate %v", folder.path)
+		}
+		var objects []storage.Object
+		var subFolders []storage.Folder
+		for _, objAttrs := range attrsPage {
+			object, subFolder := folder.toListEntry(prefix, objAttrs)
+			// the query has no start offset in this client version, so the names are filtered here
+			if object != nil && options.ObjectMatches(object.GetName()) && !strings.HasSuffix(object.GetName(), "/") {
+				objects = append(objects, object)
 			}
-		} else {
-			objName := strings.TrimPrefix(objAttrs.Name, prefix)
-			if objName != "" {
-				// GCS returns the current directory - skip it.
-				objects = append(objects, storage.NewLocalObject(objName, objAttrs.Updated, objAttrs.Size))
+			if subFolder != nil && options.SubFolderMatches(strings.TrimPrefix(objAttrs.Prefix, prefix)) {
+				subFolders = append(subFolders, subFolder)
+			}
+		}
+		if len(objects) > 0 || len(subFolders) > 0 {
+			if err = handler(objects, subFolders); err != nil {
+				return err
 			}
 		}
+		if nextPageToken == "" {
+			return nil
+		}
 	}
-	return
 }
 
-func (folder *Folder) createTimeoutContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), time.Second*time.Duration(folder.contextTimeout))
+func (folder *Folder) toListEntry(prefix string, objAttrs *gcs.ObjectAttrs) (storage.Object, storage.Folder) {
+	if objAttrs.Prefix != "" {
+		if objAttrs.Prefix == prefix+"/" {
+			// Sometimes GCS returns "//" folder - skip it
+			return nil, nil
+		}
+		return nil, NewFolder(
+			folder.bucket,
+			objAttrs.Prefix,
+			folder.contextTimeout,
+			folder.normalizePrefix,
+			folder.encryptionKey,
+			folder.uploaderOptions,
+		)
+	}
+	objName := strings.TrimPrefix(objAttrs.Name, prefix)
+	if objName == "" {
+		// GCS returns the current directory - skip it.
+		return nil, nil
+	}
+	return storage.NewLocalObject(objName, objAttrs.Updated, objAttrs.Size), nil
+}
+
+func (folder *Folder) createTimeoutContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Second*time.Duration(folder.contextTimeout))
 }
 
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *Folder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
 	for _, objectRelativePath := range objectRelativePaths {
 		path := folder.joinPath(folder.path, objectRelativePath)
 		object := folder.BuildObjectHandle(path)
 		tracelog.DebugLogger.Printf("Delete %v\n", path)
-		ctx, cancel := folder.createTimeoutContext()
-		defer cancel()
-		err := object.Delete(ctx)
+		deleteCtx, cancel := folder.createTimeoutContext(ctx)
+		err := object.Delete(deleteCtx)
+		cancel()
 		if err != nil && err != gcs.ErrObjectNotExist {
 			return NewError(err, "Unable to delete object %v", path)
 		}
@@ -243,9 +310,13 @@ func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
 }
 
 func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
 	path := folder.joinPath(folder.path, objectRelativePath)
 	object := folder.BuildObjectHandle(path)
-	ctx, cancel := folder.createTimeoutContext()
+	ctx, cancel := folder.createTimeoutContext(ctx)
 	defer cancel()
 	_, err := object.Attrs(ctx)
 	if err == gcs.ErrObjectNotExist {
@@ -257,6 +328,21 @@ func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
 	return true, nil
 }
 
+func (folder *Folder) StatObject(ctx context.Context, objectRelativePath string) (storage.Object, error) {
+	path := folder.joinPath(folder.path, objectRelativePath)
+	object := folder.BuildObjectHandle(path)
+	ctx, cancel := folder.createTimeoutContext(ctx)
+	defer cancel()
+	objAttrs, err := object.Attrs(ctx)
+	if err == gcs.ErrObjectNotExist {
+		return nil, storage.NewObjectNotFoundError(path)
+	}
+	if err != nil {
+		return nil, NewError(err, "Unable to stat object %v", path)
+	}
+	return storage.NewLocalObject(objectRelativePath, objAttrs.Updated, objAttrs.Size), nil
+}
+
 func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
 	return NewFolder(
 		folder.bucket,
@@ -269,27 +355,62 @@ func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder
 }
 
 func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
 	path := folder.joinPath(folder.path, objectRelativePath)
 	object := folder.BuildObjectHandle(path)
-	reader, err := object.NewReader(context.Background())
+	reader, err := object.NewReader(ctx)
 	if err == gcs.ErrObjectNotExist {
 		return nil, storage.NewObjectNotFoundError(path)
 	}
 	return ioutil.NopCloser(reader), err
 }
 
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *Folder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	path := folder.joinPath(folder.path, objectRelativePath)
+	object := folder.BuildObjectHandle(path)
+	if length < 0 {
+		length = -1
+	}
+	reader, err := object.NewRangeReader(ctx, offset, length)
+	if err == gcs.ErrObjectNotExist {
+		return nil, storage.NewObjectNotFoundError(path)
+	}
+	if err != nil {
+		return nil, NewError(err, "Unable to read range of object %v", path)
+	}
+	return reader, nil
+}
+
 func (folder *Folder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+// PutObjectWithContext uploads the object by chunks and composes it from them.
+// The chunks of the resumable uploads are kept until the object is composed, so that the interrupted upload
+// can be resumed from any chunk.
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
 	tracelog.DebugLogger.Printf("Put %v into %v\n", name, folder.path)
 	object := folder.BuildObjectHandle(folder.joinPath(folder.path, name))
 
-	ctx, cancel := folder.createTimeoutContext()
+	ctx, cancel := folder.createTimeoutContext(ctx)
 	defer cancel()
 
+	objectUploader := NewUploader(object, folder.uploaderOptions...)
+	progress := newChunksProgress(objectUploader.states, object, objectUploader.maxChunkSize)
+
 	chunkNum := 0
 	tmpChunks := make([]*gcs.ObjectHandle, 0)
 
 	for {
-		tmpChunkName := folder.joinPath(name+"_chunks", "chunk"+strconv.Itoa(chunkNum))
+		tmpChunkName := folder.joinPath(name+chunksSuffix, "chunk"+strconv.Itoa(chunkNum))
 		objectChunk := folder.BuildObjectHandle(folder.joinPath(folder.path, tmpChunkName))
 		chunkUploader := NewUploader(objectChunk, folder.uploaderOptions...)
 		dataChunk := chunkUploader.allocateBuffer()
@@ -311,8 +432,16 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 			size:  n,
 		}
 
-		if err := chunkUploader.UploadChunk(ctx, chunk); err != nil {
-			return NewError(err, "Unable to upload an object chunk")
+		uploaded := newUploadedChunk(dataChunk[:n])
+		if progress.isUploaded(ctx, chunkNum, uploaded, objectChunk) {
+			tracelog.DebugLogger.Printf("Chunk %d of %s is already uploaded\n", chunkNum, name)
+			// Since compose sources must not have an encryption key, clean up it.
+			*objectChunk = *objectChunk.Key(nil)
+		} else {
+			if err := chunkUploader.UploadChunk(ctx, chunk); err != nil {
+				return NewError(err, "Unable to upload an object chunk")
+			}
+			progress.setUploaded(chunkNum, uploaded)
 		}
 
 		tmpChunks = append(tmpChunks, objectChunk)
@@ -325,12 +454,18 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 
 		if len(tmpChunks) == composeChunkLimit {
 			// Since there is a limit to the number of components that can be composed in a single operation, merge chunks partially.
-			compositeChunkName := folder.joinPath(name+"_chunks", "composite"+strconv.Itoa(chunkNum))
+			compositeChunkName := folder.joinPath(name+chunksSuffix, "composite"+strconv.Itoa(chunkNum))
 			compositeChunk := folder.BuildObjectHandle(folder.joinPath(folder.path, compositeChunkName))
 
 			tracelog.DebugLogger.Printf("Compose temporary chunks into an intermediate chunk %v\n", compositeChunkName)
 
-			if err := composeChunks(ctx, NewUploader(compositeChunk, folder.uploaderOptions...), tmpChunks); err != nil {
+			compositeUploader := NewUploader(compositeChunk, folder.uploaderOptions...)
+			if progress != nil {
+				err = compositeUploader.ComposeObject(ctx, tmpChunks)
+			} else {
+				err = composeChunks(ctx, compositeUploader, tmpChunks)
+			}
+			if err != nil {
 				return NewError(err, "Failed to compose temporary chunks into an intermediate chunk")
 			}
 
@@ -340,17 +475,28 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 
 	tracelog.DebugLogger.Printf("Compose file %v from chunks\n", object.ObjectName())
 
-	if err := composeChunks(ctx, NewUploader(object, folder.uploaderOptions...), tmpChunks); err != nil {
+	if err := composeChunks(ctx, objectUploader, tmpChunks); err != nil {
 		return NewError(err, "Failed to compose temporary chunks into an object")
 	}
 
+	if progress != nil {
+		if err := folder.deleteChunks(ctx, name); err != nil {
+			tracelog.WarningLogger.Printf("Unable to delete the temporary chunks of %v: %v", name, err)
+		}
+		progress.finish()
+	}
+
 	tracelog.DebugLogger.Printf("Put %v done\n", name)
 
 	return nil
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
-	if exists, err := folder.Exists(srcPath); !exists {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	if exists, err := folder.ExistsWithContext(ctx, srcPath); !exists {
 		if err == nil {
 			return errors.New("object does not exist")
 		} else {
@@ -359,8 +505,9 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	}
 	source := path.Join(folder.path, srcPath)
 	dst := path.Join(folder.path, dstPath)
-	
-	ctx := context.Background()
+
+	ctx, cancel := folder.createTimeoutContext(ctx)
+	defer cancel()
 	_, err := folder.bucket.Object(dst).CopierFrom(folder.bucket.Object(source)).Run(ctx)
 	return err
 }
@@ -0,0 +1,166 @@
+package gcs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	gcs "cloud.google.com/go/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"google.golang.org/api/iterator"
+)
+
+// chunksSuffix is appended to the object name to get the folder of its temporary chunks
+const chunksSuffix = "_chunks"
+
+var chunkNameRegexp = regexp.MustCompile("^(.+)" + chunksSuffix + "/(chunk|composite)[0-9]+$")
+
+// chunksUploadState is saved after every uploaded chunk
+type chunksUploadState struct {
+	ChunkSize int64
+	// Chunks are indexed by the chunk number
+	Chunks []uploadedChunk
+}
+
+type uploadedChunk struct {
+	Size int
+	MD5  string
+}
+
+func newUploadedChunk(data []byte) uploadedChunk {
+	checksum := md5.Sum(data)
+	return uploadedChunk{len(data), hex.EncodeToString(checksum[:])}
+}
+
+// chunksProgress keeps the progress of the resumable upload: the chunks matching the saved ones
+// by size and MD5 are not uploaded again if they are still in the storage.
+// The methods of the nil progress do nothing, it is used for the uploads which are not resumable.
+type chunksProgress struct {
+	states   *storage.UploadStateStore
+	stateKey string
+	state    chunksUploadState
+}
+
+func newChunksProgress(states *storage.UploadStateStore, object *gcs.ObjectHandle, chunkSize int64) *chunksProgress {
+	if states == nil {
+		return nil
+	}
+	progress := &chunksProgress{
+		states:   states,
+		stateKey: "gs://" + object.BucketName() + "/" + object.ObjectName(),
+	}
+	resumed, err := states.Load(progress.stateKey, &progress.state)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to load the state of the previous upload, uploading from scratch: %v\n", err)
+	}
+	if resumed && progress.state.ChunkSize == chunkSize {
+		tracelog.InfoLogger.Printf("Resuming the upload of '%s'\n", object.ObjectName())
+	} else {
+		progress.state = chunksUploadState{ChunkSize: chunkSize}
+	}
+	return progress
+}
+
+// isUploaded tells whether the chunk with the same content is already in the storage
+func (progress *chunksProgress) isUploaded(ctx context.Context, index int, chunk uploadedChunk,
+	object *gcs.ObjectHandle) bool {
+	if progress == nil || index >= len(progress.state.Chunks) || progress.state.Chunks[index] != chunk {
+		return false
+	}
+	_, err := object.Attrs(ctx)
+	return err == nil
+}
+
+func (progress *chunksProgress) setUploaded(index int, chunk uploadedChunk) {
+	if progress == nil {
+		return
+	}
+	for len(progress.state.Chunks) <= index {
+		progress.state.Chunks = append(progress.state.Chunks, uploadedChunk{})
+	}
+	progress.state.Chunks[index] = chunk
+	if err := progress.states.Save(progress.stateKey, progress.state); err != nil {
+		tracelog.WarningLogger.Println(err)
+	}
+}
+
+func (progress *chunksProgress) finish() {
+	if progress == nil {
+		return
+	}
+	if err := progress.states.Delete(progress.stateKey); err != nil {
+		tracelog.WarningLogger.Println(err)
+	}
+}
+
+// ListPendingUploads finds the objects whose temporary chunks are left in the folder and its subfolders
+func (folder *Folder) ListPendingUploads(ctx context.Context) ([]storage.PendingUpload, error) {
+	prefix := storage.AddDelimiterToPath(folder.path)
+	ctx, cancel := folder.createTimeoutContext(ctx)
+	defer cancel()
+	var uploads []storage.PendingUpload
+	uploadIndexes := make(map[string]int)
+	iter := folder.bucket.Objects(ctx, &gcs.Query{Prefix: prefix})
+	for {
+		objAttrs, err := iter.Next()
+		if err == iterator.Done {
+			return uploads, nil
+		}
+		if err != nil {
+			return nil, NewError(err, "Unable to iterate %v", folder.path)
+		}
+		match := chunkNameRegexp.FindStringSubmatch(strings.TrimPrefix(objAttrs.Name, prefix))
+		if match == nil {
+			continue
+		}
+		name := match[1]
+		if i, ok := uploadIndexes[name]; ok {
+			if objAttrs.Created.Before(uploads[i].Initiated) {
+				uploads[i].Initiated = objAttrs.Created
+			}
+			continue
+		}
+		uploadIndexes[name] = len(uploads)
+		uploads = append(uploads, storage.PendingUpload{Name: name, ID: name + chunksSuffix, Initiated: objAttrs.Created})
+	}
+}
+
+// AbortPendingUpload removes the temporary chunks of the object
+func (folder *Folder) AbortPendingUpload(ctx context.Context, upload storage.PendingUpload) error {
+	if upload.ID != upload.Name+chunksSuffix {
+		// not an upload of GCS, e.g. the one listed by another mirror storage
+		return nil
+	}
+	return folder.deleteChunks(ctx, upload.Name)
+}
+
+func (folder *Folder) deleteChunks(ctx context.Context, name string) error {
+	chunksPrefix := folder.joinPath(folder.path, name+chunksSuffix) + "/"
+	ctx, cancel := folder.createTimeoutContext(ctx)
+	defer cancel()
+	iter := folder.bucket.Objects(ctx, &gcs.Query{Prefix: chunksPrefix})
+	for {
+		objAttrs, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return NewError(err, "Unable to iterate %v", chunksPrefix)
+		}
+		tracelog.DebugLogger.Printf("Delete %v\n", objAttrs.Name)
+		err = folder.bucket.Object(objAttrs.Name).Delete(ctx)
+		if err != nil && err != gcs.ErrObjectNotExist {
+			return NewError(err, "Unable to delete object %v", objAttrs.Name)
+		}
+	}
+}
+
+// WithUploadStates makes the uploads resumable, their progress is kept in the states
+func WithUploadStates(states *storage.UploadStateStore) UploaderOption {
+	return func(uploader *Uploader) {
+		uploader.states = states
+	}
+}
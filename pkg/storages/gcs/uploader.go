@@ -10,6 +10,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
+	walgstorage "github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
 const (
@@ -27,6 +28,8 @@ type Uploader struct {
 	baseRetryDelay   time.Duration
 	maxRetryDelay    time.Duration
 	maxUploadRetries int
+	// states is set if the progress of the uploads is saved to resume them after failures
+	states *walgstorage.UploadStateStore
 }
 
 type UploaderOption func(*Uploader)
@@ -2,10 +2,12 @@ package memory
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"io/ioutil"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -27,15 +29,42 @@ func NewError(err error, format string, args ...interface{}) storage.Error {
 }
 
 func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 	_, exists := folder.Storage.Load(path.Join(folder.path, objectRelativePath))
 	return exists, nil
 }
 
+func (folder *Folder) StatObject(ctx context.Context, objectRelativePath string) (storage.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectPath := path.Join(folder.path, objectRelativePath)
+	value, exists := folder.Storage.Load(objectPath)
+	if !exists {
+		return nil, storage.NewObjectNotFoundError(objectPath)
+	}
+	return storage.NewLocalObject(objectRelativePath, value.Timestamp, int64(value.Size)), nil
+}
+
 func (folder *Folder) GetPath() string {
 	return folder.path
 }
 
 func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *Folder) ListFolderWithContext(ctx context.Context) (objects []storage.Object,
+	subFolders []storage.Folder, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	subFolderNames := sync.Map{}
 	folder.Storage.Range(func(key string, value TimeStampedData) bool {
 		if !strings.HasPrefix(key, folder.path) {
@@ -58,8 +87,60 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 	return
 }
 
+func (folder *Folder) ListFolderPages(ctx context.Context, options storage.ListOptions,
+	handler storage.ListPageHandler) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var objects []storage.Object
+	subFolderNames := make(map[string]bool)
+	folder.Storage.Range(func(key string, value TimeStampedData) bool {
+		if !strings.HasPrefix(key, folder.path) {
+			return true
+		}
+		name := strings.TrimPrefix(key, folder.path)
+		if !options.Recursive && strings.Contains(name, "/") {
+			subFolderNames[strings.Split(name, "/")[0]] = true
+		} else if options.ObjectMatches(name) {
+			objects = append(objects, storage.NewLocalObject(name, value.Timestamp, int64(value.Size)))
+		}
+		return true
+	})
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].GetName() < objects[j].GetName()
+	})
+	names := make([]string, 0, len(subFolderNames))
+	for name := range subFolderNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pager := storage.NewListPager(handler)
+	for _, object := range objects {
+		if err := pager.AddObject(object); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		if !options.SubFolderMatches(name) {
+			continue
+		}
+		if err := pager.AddSubFolder(NewFolder(path.Join(folder.path, name)+"/", folder.Storage)); err != nil {
+			return err
+		}
+	}
+	return pager.Flush()
+}
+
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *Folder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
 	for _, objectName := range objectRelativePaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		folder.Storage.Delete(storage.JoinPath(folder.path, objectName))
 	}
 	return nil
@@ -70,16 +151,52 @@ func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder
 }
 
 func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	objectAbsPath := path.Join(folder.path, objectRelativePath)
 	object, exists := folder.Storage.Load(objectAbsPath)
 	if !exists {
 		return nil, storage.NewObjectNotFoundError(objectAbsPath)
 	}
-	return ioutil.NopCloser(&object.Data), nil
+	return storage.NewContextReadCloser(ctx, ioutil.NopCloser(&object.Data)), nil
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *Folder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	objectAbsPath := path.Join(folder.path, objectRelativePath)
+	object, exists := folder.Storage.Load(objectAbsPath)
+	if !exists {
+		return nil, storage.NewObjectNotFoundError(objectAbsPath)
+	}
+	data := object.Data.Bytes()
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return storage.NewContextReadCloser(ctx, ioutil.NopCloser(bytes.NewReader(data))), nil
 }
 
 func (folder *Folder) PutObject(name string, content io.Reader) error {
-	data, err := ioutil.ReadAll(content)
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	data, err := ioutil.ReadAll(storage.NewContextReader(ctx, content))
 	objectPath := path.Join(folder.path, name)
 	if err != nil {
 		return errors.Wrapf(err, "failed to put '%s' in memory storage", objectPath)
@@ -89,18 +206,22 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
-	if exists, err := folder.Exists(srcPath); !exists {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	if exists, err := folder.ExistsWithContext(ctx, srcPath); !exists {
 		if err == nil {
 			return errors.New("object does not exist")
 		} else {
 			return err
 		}
 	}
-	file, err := folder.ReadObject(srcPath)
+	file, err := folder.ReadObjectWithContext(ctx, srcPath)
 	if err != nil {
 		return err
 	}
-	err = folder.PutObject(dstPath, file)
+	err = folder.PutObjectWithContext(ctx, dstPath, file)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+var (
+	// maxCopyObjectSize is the largest object S3 copies with a single CopyObject request
+	maxCopyObjectSize int64 = 5 * 1024 * 1024 * 1024
+	// copyPartSize is the part size of the larger objects, it is enough for the objects of 5 TiB in 10000 parts
+	copyPartSize int64 = 512 * 1024 * 1024
+)
+
+// copyObject copies the object described by input with a single CopyObject request if S3 allows it
+// and part by part with UploadPartCopy otherwise. The metadata of the object is kept in both cases.
+// The errors of reading the source object are returned as is, so that the missing objects can be told.
+func (folder *Folder) copyObject(ctx context.Context, srcKey string, input *s3.CopyObjectInput) error {
+	head, err := folder.S3API.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket:               folder.Bucket,
+		Key:                  aws.String(srcKey),
+		SSECustomerAlgorithm: input.CopySourceSSECustomerAlgorithm,
+		SSECustomerKey:       input.CopySourceSSECustomerKey,
+		SSECustomerKeyMD5:    input.CopySourceSSECustomerKeyMD5,
+	})
+	if err != nil {
+		return err
+	}
+	size := aws.Int64Value(head.ContentLength)
+	if size <= maxCopyObjectSize {
+		_, err = folder.S3API.CopyObjectWithContext(ctx, input)
+		return err
+	}
+
+	tracelog.DebugLogger.Printf("Copying '%s' of %d bytes in parts\n", srcKey, size)
+	output, err := folder.S3API.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    input.Bucket,
+		Key:                       input.Key,
+		StorageClass:              input.StorageClass,
+		ServerSideEncryption:      input.ServerSideEncryption,
+		SSECustomerAlgorithm:      input.SSECustomerAlgorithm,
+		SSECustomerKey:            input.SSECustomerKey,
+		SSECustomerKeyMD5:         input.SSECustomerKeyMD5,
+		SSEKMSKeyId:               input.SSEKMSKeyId,
+		ObjectLockMode:            input.ObjectLockMode,
+		ObjectLockRetainUntilDate: input.ObjectLockRetainUntilDate,
+		// a multipart upload doesn't copy the metadata on its own
+		Metadata:           head.Metadata,
+		ContentType:        head.ContentType,
+		ContentEncoding:    head.ContentEncoding,
+		ContentDisposition: head.ContentDisposition,
+		CacheControl:       head.CacheControl,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to start the copy of '%s'", srcKey)
+	}
+	completedParts, err := folder.copyParts(ctx, input, *output.UploadId, size)
+	if err == nil {
+		_, err = folder.S3API.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          input.Bucket,
+			Key:             input.Key,
+			UploadId:        output.UploadId,
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: completedParts},
+		})
+	}
+	if err != nil {
+		abortErr := abortMultipartUpload(ctx, folder.S3API, *input.Bucket, *input.Key, *output.UploadId)
+		if abortErr != nil {
+			tracelog.WarningLogger.Printf("Failed to abort the copy of '%s': %v\n", srcKey, abortErr)
+		}
+		return errors.Wrapf(err, "failed to copy '%s' in parts", srcKey)
+	}
+	return nil
+}
+
+func (folder *Folder) copyParts(ctx context.Context, input *s3.CopyObjectInput,
+	uploadID string, size int64) ([]*s3.CompletedPart, error) {
+	var completedParts []*s3.CompletedPart
+	for offset, number := int64(0), int64(1); offset < size; offset, number = offset+copyPartSize, number+1 {
+		end := offset + copyPartSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		output, err := folder.S3API.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+			Bucket:                         input.Bucket,
+			Key:                            input.Key,
+			UploadId:                       aws.String(uploadID),
+			PartNumber:                     aws.Int64(number),
+			CopySource:                     input.CopySource,
+			CopySourceRange:                aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+			SSECustomerAlgorithm:           input.SSECustomerAlgorithm,
+			SSECustomerKey:                 input.SSECustomerKey,
+			SSECustomerKeyMD5:              input.SSECustomerKeyMD5,
+			CopySourceSSECustomerAlgorithm: input.CopySourceSSECustomerAlgorithm,
+			CopySourceSSECustomerKey:       input.CopySourceSSECustomerKey,
+			CopySourceSSECustomerKeyMD5:    input.CopySourceSSECustomerKeyMD5,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to copy part %d", number)
+		}
+		completedParts = append(completedParts, &s3.CompletedPart{
+			ETag:       output.CopyPartResult.ETag,
+			PartNumber: aws.Int64(number),
+		})
+	}
+	return completedParts, nil
+}
@@ -1,6 +1,8 @@
 package s3
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"path"
 	"strconv"
@@ -37,6 +39,9 @@ const (
 	EndpointPortSetting      = "S3_ENDPOINT_PORT"
 	LogLevel                 = "S3_LOG_LEVEL"
 	UseListObjectsV1         = "S3_USE_LIST_OBJECTS_V1"
+	ObjectLockModeSetting    = "S3_OBJECT_LOCK_MODE"
+	ObjectLockDaysSetting    = "S3_OBJECT_LOCK_DAYS"
+	UploadStateDirSetting    = "UPLOAD_STATE_DIR"
 )
 
 var (
@@ -61,6 +66,9 @@ var (
 		s3CertFile,
 		MaxPartSize,
 		UseListObjectsV1,
+		ObjectLockModeSetting,
+		ObjectLockDaysSetting,
+		UploadStateDirSetting,
 	}
 )
 
@@ -131,13 +139,17 @@ func ConfigureFolder(prefix string, settings map[string]string) (storage.Folder,
 }
 
 func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
 	objectPath := folder.Path + objectRelativePath
 	stopSentinelObjectInput := &s3.HeadObjectInput{
 		Bucket: folder.Bucket,
 		Key:    aws.String(objectPath),
 	}
 
-	_, err := folder.S3API.HeadObject(stopSentinelObjectInput)
+	_, err := folder.S3API.HeadObjectWithContext(ctx, stopSentinelObjectInput)
 	if err != nil {
 		if isAwsNotExist(err) {
 			return false, nil
@@ -148,35 +160,193 @@ func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
 }
 
 func (folder *Folder) PutObject(name string, content io.Reader) error {
-	return folder.uploader.upload(*folder.Bucket, folder.Path+name, content)
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	return folder.uploader.upload(ctx, *folder.Bucket, folder.Path+name, content)
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
-	if exists, err := folder.Exists(srcPath); !exists {
-		if err == nil {
-			return errors.New("object does not exist")
-		} else {
-			return err
-		}
-	}
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+// CopyObjectWithContext copies the objects larger than 5 GiB in parts
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
 	source := path.Join(*folder.Bucket, folder.Path, srcPath)
 	dst := path.Join(folder.Path, dstPath)
 	input := &s3.CopyObjectInput{CopySource: &source, Bucket: folder.Bucket, Key: &dst}
-	_, err := folder.S3API.CopyObject(input)
+	if folder.uploader.ObjectLockMode != "" {
+		input.ObjectLockMode = aws.String(folder.uploader.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = folder.uploader.objectLockRetainUntil()
+	}
+	err := folder.copyObject(ctx, path.Join(folder.Path, srcPath), input)
+	if isAwsNotExist(err) {
+		return errors.New("object does not exist")
+	}
+	return err
+}
+
+// GetObjectLock reads the Object Lock retention and legal hold of the object
+func (folder *Folder) GetObjectLock(ctx context.Context, objectRelativePath string) (storage.ObjectLock, error) {
+	objectPath := folder.Path + objectRelativePath
+	output, err := folder.S3API.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: folder.Bucket,
+		Key:    aws.String(objectPath),
+	})
+	if err != nil {
+		if isAwsNotExist(err) {
+			return storage.ObjectLock{}, storage.NewObjectNotFoundError(objectPath)
+		}
+		return storage.ObjectLock{}, errors.Wrapf(err, "failed to get the lock of s3 object '%s'", objectPath)
+	}
+	lock := storage.ObjectLock{
+		Mode:      aws.StringValue(output.ObjectLockMode),
+		LegalHold: aws.StringValue(output.ObjectLockLegalHoldStatus) == s3.ObjectLockLegalHoldStatusOn,
+	}
+	if output.ObjectLockRetainUntilDate != nil {
+		lock.RetainUntil = *output.ObjectLockRetainUntilDate
+	}
+	return lock, nil
+}
+
+// StatObject gets the size and the modification time of the object with a single HEAD request
+func (folder *Folder) StatObject(ctx context.Context, objectRelativePath string) (storage.Object, error) {
+	objectPath := folder.Path + objectRelativePath
+	output, err := folder.S3API.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: folder.Bucket,
+		Key:    aws.String(objectPath),
+	})
+	if err != nil {
+		if isAwsNotExist(err) {
+			return nil, storage.NewObjectNotFoundError(objectPath)
+		}
+		return nil, errors.Wrapf(err, "failed to stat s3 object '%s'", objectPath)
+	}
+	return storage.NewLocalObject(objectRelativePath, aws.TimeValue(output.LastModified), aws.Int64Value(output.ContentLength)), nil
+}
+
+// GetStorageClass reads the storage class of the object and checks whether the archived object is restored
+func (folder *Folder) GetStorageClass(ctx context.Context, objectRelativePath string) (storage.StorageClass, error) {
+	objectPath := folder.Path + objectRelativePath
+	output, err := folder.S3API.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: folder.Bucket,
+		Key:    aws.String(objectPath),
+	})
 	if err != nil {
-		return err
+		if isAwsNotExist(err) {
+			return storage.StorageClass{}, storage.NewObjectNotFoundError(objectPath)
+		}
+		return storage.StorageClass{}, errors.Wrapf(err, "failed to get the storage class of s3 object '%s'", objectPath)
+	}
+	// S3 omits the default storage class
+	name := s3.StorageClassStandard
+	if output.StorageClass != nil {
+		name = *output.StorageClass
+	}
+	restored := output.Restore != nil && !strings.Contains(*output.Restore, `ongoing-request="true"`)
+	return storage.StorageClass{
+		Name:         name,
+		NeedsRestore: isArchiveStorageClass(name) && !restored,
+	}, nil
+}
+
+// SetStorageClass copies the object onto itself with the new storage class, the object metadata is kept.
+// The objects larger than 5 GiB are copied in parts.
+// The archived objects must be restored before they can be moved.
+func (folder *Folder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	objectPath := folder.Path + objectRelativePath
+	input := &s3.CopyObjectInput{
+		CopySource:        aws.String(path.Join(*folder.Bucket, objectPath)),
+		Bucket:            folder.Bucket,
+		Key:               aws.String(objectPath),
+		StorageClass:      aws.String(storageClass),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+	}
+	folder.uploader.setCopyEncryption(input)
+	if folder.uploader.ObjectLockMode != "" {
+		input.ObjectLockMode = aws.String(folder.uploader.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = folder.uploader.objectLockRetainUntil()
+	}
+	err := folder.copyObject(ctx, objectPath, input)
+	if err != nil {
+		if isAwsNotExist(err) {
+			return storage.NewObjectNotFoundError(objectPath)
+		}
+		return errors.Wrapf(err, "failed to move s3 object '%s' to storage class %s", objectPath, storageClass)
 	}
 	return nil
 }
 
+// ListPendingUploads returns the multipart uploads of the objects in the folder which are neither completed nor aborted
+func (folder *Folder) ListPendingUploads(ctx context.Context) ([]storage.PendingUpload, error) {
+	var uploads []storage.PendingUpload
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: folder.Bucket,
+		Prefix: aws.String(folder.Path),
+	}
+	err := folder.S3API.ListMultipartUploadsPagesWithContext(ctx, input,
+		func(output *s3.ListMultipartUploadsOutput, _ bool) bool {
+			for _, upload := range output.Uploads {
+				uploads = append(uploads, storage.PendingUpload{
+					Name:      strings.TrimPrefix(*upload.Key, folder.Path),
+					ID:        *upload.UploadId,
+					Initiated: aws.TimeValue(upload.Initiated),
+				})
+			}
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the multipart uploads in '%s'", folder.Path)
+	}
+	return uploads, nil
+}
+
+func (folder *Folder) AbortPendingUpload(ctx context.Context, upload storage.PendingUpload) error {
+	objectPath := folder.Path + upload.Name
+	err := abortMultipartUpload(ctx, folder.S3API, *folder.Bucket, objectPath, upload.ID)
+	return errors.Wrapf(err, "failed to abort the multipart upload of '%s'", objectPath)
+}
+
+func isArchiveStorageClass(storageClass string) bool {
+	return storageClass == s3.StorageClassGlacier || storageClass == s3.StorageClassDeepArchive
+}
+
 func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
+	return folder.getObject(ctx, objectRelativePath, nil)
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *Folder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		// an empty range can not be expressed with the Range header
+		return storage.ReadEmptyRange(ctx, folder, objectRelativePath)
+	}
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += strconv.FormatInt(offset+length-1, 10)
+	}
+	return folder.getObject(ctx, objectRelativePath, aws.String(byteRange))
+}
+
+func (folder *Folder) getObject(ctx context.Context, objectRelativePath string,
+	byteRange *string) (io.ReadCloser, error) {
 	objectPath := folder.Path + objectRelativePath
 	input := &s3.GetObjectInput{
 		Bucket: folder.Bucket,
 		Key:    aws.String(objectPath),
+		Range:  byteRange,
 	}
 
-	object, err := folder.S3API.GetObject(input)
+	object, err := folder.S3API.GetObjectWithContext(ctx, input)
 	if err != nil {
 		if isAwsNotExist(err) {
 			return nil, storage.NewObjectNotFoundError(objectPath)
@@ -196,29 +366,24 @@ func (folder *Folder) GetPath() string {
 }
 
 func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
-	listFunc := func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object) {
-		for _, prefix := range commonPrefixes {
-			subFolders = append(subFolders, NewFolder(folder.uploader, folder.S3API, *folder.Bucket,
-				*prefix.Prefix, folder.useListObjectsV1))
-		}
-		for _, object := range contents {
-			// Some storages return root tar_partitions folder as a Key.
-			// We do not want to fail restoration due to this fact.
-			// Keep in mind that skipping files is very dangerous and any decision here must be weighted.
-			if *object.Key == folder.Path {
-				continue
-			}
-			objectRelativePath := strings.TrimPrefix(*object.Key, folder.Path)
-			objects = append(objects, storage.NewLocalObject(objectRelativePath, *object.LastModified, *object.Size))
-		}
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *Folder) ListFolderWithContext(ctx context.Context) (objects []storage.Object,
+	subFolders []storage.Folder, err error) {
+	listFunc := func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object) bool {
+		pageObjects, pageSubFolders := folder.toListPage(commonPrefixes, contents)
+		objects = append(objects, pageObjects...)
+		subFolders = append(subFolders, pageSubFolders...)
+		return true
 	}
 
 	prefix := aws.String(folder.Path)
 	delimiter := aws.String("/")
 	if folder.useListObjectsV1 {
-		err = folder.listObjectsPagesV1(prefix, delimiter, listFunc)
+		err = folder.listObjectsPagesV1(ctx, prefix, delimiter, nil, listFunc)
 	} else {
-		err = folder.listObjectsPagesV2(prefix, delimiter, listFunc)
+		err = folder.listObjectsPagesV2(ctx, prefix, delimiter, nil, listFunc)
 	}
 
 	if err != nil {
@@ -227,42 +392,108 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 	return objects, subFolders, nil
 }
 
-func (folder *Folder) listObjectsPagesV1(prefix *string, delimiter *string,
-	listFunc func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object)) error {
+func (folder *Folder) ListFolderPages(ctx context.Context, options storage.ListOptions,
+	handler storage.ListPageHandler) error {
+	var handlerErr error
+	listFunc := func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object) bool {
+		objects, subFolders := folder.toListPage(commonPrefixes, contents)
+		if len(objects) == 0 && len(subFolders) == 0 {
+			return true
+		}
+		handlerErr = handler(objects, subFolders)
+		return handlerErr == nil
+	}
+
+	prefix := aws.String(folder.Path + options.Prefix)
+	var delimiter, startAfter *string
+	if !options.Recursive {
+		delimiter = aws.String("/")
+	}
+	if options.StartAfter != "" {
+		startAfter = aws.String(folder.Path + options.StartAfter)
+	}
+	var err error
+	if folder.useListObjectsV1 {
+		err = folder.listObjectsPagesV1(ctx, prefix, delimiter, startAfter, listFunc)
+	} else {
+		err = folder.listObjectsPagesV2(ctx, prefix, delimiter, startAfter, listFunc)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to list s3 folder: '%s'", folder.Path)
+	}
+	return handlerErr
+}
+
+func (folder *Folder) toListPage(commonPrefixes []*s3.CommonPrefix,
+	contents []*s3.Object) (objects []storage.Object, subFolders []storage.Folder) {
+	for _, prefix := range commonPrefixes {
+		subFolders = append(subFolders, NewFolder(folder.uploader, folder.S3API, *folder.Bucket,
+			*prefix.Prefix, folder.useListObjectsV1))
+	}
+	for _, object := range contents {
+		// Some storages return root tar_partitions folder as a Key.
+		// We do not want to fail restoration due to this fact.
+		// Keep in mind that skipping files is very dangerous and any decision here must be weighted.
+		if *object.Key == folder.Path {
+			continue
+		}
+		// Keys of the nested folders themselves show up in the recursive listings only
+		if strings.HasSuffix(*object.Key, "/") {
+			continue
+		}
+		objectRelativePath := strings.TrimPrefix(*object.Key, folder.Path)
+		objects = append(objects, storage.NewLocalObject(objectRelativePath, *object.LastModified, *object.Size))
+	}
+	return objects, subFolders
+}
+
+func (folder *Folder) listObjectsPagesV1(ctx context.Context, prefix *string, delimiter *string, startAfter *string,
+	listFunc func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object) bool) error {
 	s3Objects := &s3.ListObjectsInput{
 		Bucket:    folder.Bucket,
 		Prefix:    prefix,
 		Delimiter: delimiter,
+		Marker:    startAfter,
 	}
-	return folder.S3API.ListObjectsPages(s3Objects, func(files *s3.ListObjectsOutput, lastPage bool) bool {
-		listFunc(files.CommonPrefixes, files.Contents)
-		return true
+	return folder.S3API.ListObjectsPagesWithContext(ctx, s3Objects, func(files *s3.ListObjectsOutput, lastPage bool) bool {
+		return listFunc(files.CommonPrefixes, files.Contents)
 	})
 }
 
-func (folder *Folder) listObjectsPagesV2(prefix *string, delimiter *string,
-	listFunc func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object)) error {
+func (folder *Folder) listObjectsPagesV2(ctx context.Context, prefix *string, delimiter *string, startAfter *string,
+	listFunc func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object) bool) error {
 	s3Objects := &s3.ListObjectsV2Input{
-		Bucket:    folder.Bucket,
-		Prefix:    prefix,
-		Delimiter: delimiter,
+		Bucket:     folder.Bucket,
+		Prefix:     prefix,
+		Delimiter:  delimiter,
+		StartAfter: startAfter,
 	}
-	return folder.S3API.ListObjectsV2Pages(s3Objects, func(files *s3.ListObjectsV2Output, lastPage bool) bool {
-		listFunc(files.CommonPrefixes, files.Contents)
-		return true
+	return folder.S3API.ListObjectsV2PagesWithContext(ctx, s3Objects, func(files *s3.ListObjectsV2Output, lastPage bool) bool {
+		return listFunc(files.CommonPrefixes, files.Contents)
 	})
 }
 
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *Folder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
 	parts := partitionStrings(objectRelativePaths, 1000)
 	for _, part := range parts {
 		input := &s3.DeleteObjectsInput{Bucket: folder.Bucket, Delete: &s3.Delete{
 			Objects: folder.partitionToObjects(part),
 		}}
-		_, err := folder.S3API.DeleteObjects(input)
+		output, err := folder.S3API.DeleteObjectsWithContext(ctx, input)
 		if err != nil {
 			return errors.Wrapf(err, "failed to delete s3 object: '%s'", part)
 		}
+		// e.g. the objects retained by Object Lock fail individually
+		if len(output.Errors) > 0 {
+			deleteErr := output.Errors[0]
+			return errors.Errorf("failed to delete %d of s3 objects, '%s': %s %s", len(output.Errors),
+				aws.StringValue(deleteErr.Key), aws.StringValue(deleteErr.Code), aws.StringValue(deleteErr.Message))
+		}
 	}
 	return nil
 }
@@ -1,12 +1,108 @@
 package s3
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
+// fakeS3API keeps the objects in memory
+type fakeS3API struct {
+	s3iface.S3API
+	objects map[string][]byte
+	// storageClasses and metadata are kept only for the copied objects
+	storageClasses map[string]string
+	metadata       map[string]map[string]*string
+	copyRequests   int
+	uploads        map[string]*fakeMultipartUpload
+}
+
+type fakeMultipartUpload struct {
+	key          string
+	storageClass string
+	metadata     map[string]*string
+	parts        map[int64][]byte
+}
+
+func newFakeS3API(objects map[string][]byte) *fakeS3API {
+	return &fakeS3API{
+		objects:        objects,
+		storageClasses: map[string]string{},
+		metadata:       map[string]map[string]*string{},
+		uploads:        map[string]*fakeMultipartUpload{},
+	}
+}
+
+func (api *fakeS3API) HeadObjectWithContext(_ aws.Context, input *s3.HeadObjectInput,
+	_ ...request.Option) (*s3.HeadObjectOutput, error) {
+	object, ok := api.objects[*input.Key]
+	if !ok {
+		return nil, awserr.New(NotFoundAWSErrorCode, "object not found", nil)
+	}
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(object))), Metadata: api.metadata[*input.Key]}, nil
+}
+
+func (api *fakeS3API) CopyObjectWithContext(_ aws.Context, input *s3.CopyObjectInput,
+	_ ...request.Option) (*s3.CopyObjectOutput, error) {
+	api.copyRequests++
+	object, ok := api.objects[strings.TrimPrefix(*input.CopySource, *input.Bucket+"/")]
+	if !ok {
+		return nil, awserr.New(NoSuchKeyAWSErrorCode, "object not found", nil)
+	}
+	api.objects[*input.Key] = object
+	api.storageClasses[*input.Key] = aws.StringValue(input.StorageClass)
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (api *fakeS3API) CreateMultipartUploadWithContext(_ aws.Context, input *s3.CreateMultipartUploadInput,
+	_ ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	uploadID := fmt.Sprintf("upload-%d", len(api.uploads))
+	api.uploads[uploadID] = &fakeMultipartUpload{
+		key:          *input.Key,
+		storageClass: aws.StringValue(input.StorageClass),
+		metadata:     input.Metadata,
+		parts:        map[int64][]byte{},
+	}
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(uploadID)}, nil
+}
+
+func (api *fakeS3API) UploadPartCopyWithContext(_ aws.Context, input *s3.UploadPartCopyInput,
+	_ ...request.Option) (*s3.UploadPartCopyOutput, error) {
+	object := api.objects[strings.TrimPrefix(*input.CopySource, *input.Bucket+"/")]
+	var start, end int
+	if _, err := fmt.Sscanf(*input.CopySourceRange, "bytes=%d-%d", &start, &end); err != nil {
+		return nil, err
+	}
+	api.uploads[*input.UploadId].parts[*input.PartNumber] = object[start : end+1]
+	etag := fmt.Sprintf("etag-%d", *input.PartNumber)
+	return &s3.UploadPartCopyOutput{CopyPartResult: &s3.CopyPartResult{ETag: aws.String(etag)}}, nil
+}
+
+func (api *fakeS3API) CompleteMultipartUploadWithContext(_ aws.Context, input *s3.CompleteMultipartUploadInput,
+	_ ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
+	upload := api.uploads[*input.UploadId]
+	var object []byte
+	for _, part := range input.MultipartUpload.Parts {
+		object = append(object, upload.parts[*part.PartNumber]...)
+	}
+	api.objects[upload.key] = object
+	api.storageClasses[upload.key] = upload.storageClass
+	api.metadata[upload.key] = upload.metadata
+	delete(api.uploads, *input.UploadId)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
 func TestS3Folder(t *testing.T) {
 	t.Skip("Credentials needed to run S3 tests")
 
@@ -38,3 +134,74 @@ func TestS3FolderEndpointSource(t *testing.T) {
 
 	storage.RunFolderTest(storageFolder, t)
 }
+
+func TestReadObjectRange_EmptyRange(t *testing.T) {
+	api := newFakeS3API(map[string][]byte{"folder/object": []byte("content")})
+	folder := NewFolder(Uploader{}, api, "bucket", "folder", false)
+
+	reader, err := folder.ReadObjectRange("object", 1, 0)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Empty(t, content)
+
+	_, err = folder.ReadObjectRange("missing", 1, 0)
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
+
+func TestStatObject(t *testing.T) {
+	api := newFakeS3API(map[string][]byte{"folder/object": []byte("content")})
+	folder := NewFolder(Uploader{}, api, "bucket", "folder", false)
+
+	object, err := storage.StatObject(context.Background(), folder, "object")
+	require.NoError(t, err)
+	assert.Equal(t, "object", object.GetName())
+	assert.Equal(t, int64(7), object.GetSize())
+
+	_, err = storage.StatObject(context.Background(), folder, "missing")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
+
+func TestSetStorageClass_CopiesLargeObjectsInParts(t *testing.T) {
+	defer func(maxSize, partSize int64) {
+		maxCopyObjectSize, copyPartSize = maxSize, partSize
+	}(maxCopyObjectSize, copyPartSize)
+	maxCopyObjectSize, copyPartSize = 8, 3
+
+	api := newFakeS3API(map[string][]byte{
+		"folder/small": []byte("content"),
+		"folder/large": []byte("large content"),
+	})
+	api.metadata["folder/large"] = map[string]*string{"Walg-Key": aws.String("value")}
+	folder := NewFolder(Uploader{}, api, "bucket", "folder", false)
+
+	require.NoError(t, folder.SetStorageClass(context.Background(), "small", s3.StorageClassStandardIa))
+	assert.Equal(t, 1, api.copyRequests)
+	assert.Equal(t, s3.StorageClassStandardIa, api.storageClasses["folder/small"])
+
+	require.NoError(t, folder.SetStorageClass(context.Background(), "large", s3.StorageClassGlacier))
+	assert.Equal(t, 1, api.copyRequests)
+	assert.Equal(t, "large content", string(api.objects["folder/large"]))
+	assert.Equal(t, s3.StorageClassGlacier, api.storageClasses["folder/large"])
+	assert.Equal(t, "value", aws.StringValue(api.metadata["folder/large"]["Walg-Key"]))
+	assert.Empty(t, api.uploads)
+
+	err := folder.SetStorageClass(context.Background(), "missing", s3.StorageClassGlacier)
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
+
+func TestCopyObject_CopiesLargeObjectsInParts(t *testing.T) {
+	defer func(maxSize, partSize int64) {
+		maxCopyObjectSize, copyPartSize = maxSize, partSize
+	}(maxCopyObjectSize, copyPartSize)
+	maxCopyObjectSize, copyPartSize = 8, 3
+
+	api := newFakeS3API(map[string][]byte{"folder/tmp": []byte("re-encrypted content")})
+	folder := NewFolder(Uploader{}, api, "bucket", "folder", false)
+
+	require.NoError(t, folder.CopyObject("tmp", "object"))
+	assert.Equal(t, 0, api.copyRequests)
+	assert.Equal(t, "re-encrypted content", string(api.objects["folder/object"]))
+
+	assert.Error(t, folder.CopyObject("missing", "object"))
+}
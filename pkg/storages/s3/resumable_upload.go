@@ -0,0 +1,290 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const NoSuchUploadAWSErrorCode = "NoSuchUpload"
+
+// resumableUploader uploads the objects in parts and keeps the progress of every upload in the state store.
+// If the upload of the object is interrupted, the next upload of the same object continues the same multipart upload:
+// the content is read again and the parts which match the already uploaded ones by size and MD5 are not sent again.
+type resumableUploader struct {
+	s3API       s3iface.S3API
+	states      *storage.UploadStateStore
+	partSize    int64
+	concurrency int
+}
+
+// multipartUploadState is saved after every uploaded part
+type multipartUploadState struct {
+	UploadID string
+	PartSize int64
+	// Parts are indexed by the part number minus one
+	Parts []uploadedPart
+}
+
+type uploadedPart struct {
+	ETag string
+	Size int64
+	MD5  string
+}
+
+func (uploader *Uploader) uploadResumable(ctx context.Context, bucket, path string, content io.Reader) error {
+	resumable := uploader.resumable
+	stateKey := "s3://" + bucket + "/" + path
+	firstPart, lastPart, err := readPart(content, resumable.partSize)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the content of '%s'", path)
+	}
+
+	var state multipartUploadState
+	resumed, err := resumable.states.Load(stateKey, &state)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to load the state of the previous upload, uploading from scratch: %v\n", err)
+		resumed = false
+	}
+	if lastPart {
+		// the small objects are uploaded at once
+		if resumed {
+			resumable.abandon(ctx, bucket, path, stateKey, state.UploadID)
+		}
+		input := uploader.createUploadInput(bucket, path, bytes.NewReader(firstPart))
+		_, err = uploader.uploaderAPI.UploadWithContext(ctx, input)
+		return errors.Wrapf(err, "failed to upload '%s' to bucket '%s'", path, bucket)
+	}
+
+	var uploadedETags map[int64]string
+	if resumed {
+		uploadedETags, err = resumable.listUploadedParts(ctx, bucket, path, state)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Unable to resume the upload of '%s', uploading from scratch: %v\n", path, err)
+			resumed = false
+		}
+	}
+	if !resumed {
+		output, err := resumable.s3API.CreateMultipartUploadWithContext(ctx, uploader.createMultipartUploadInput(bucket, path))
+		if err != nil {
+			return errors.Wrapf(err, "failed to start the upload of '%s' to bucket '%s'", path, bucket)
+		}
+		state = multipartUploadState{UploadID: *output.UploadId, PartSize: resumable.partSize}
+		uploadedETags = map[int64]string{}
+		if err = resumable.states.Save(stateKey, state); err != nil {
+			tracelog.WarningLogger.Printf("The upload of '%s' won't be resumable: %v\n", path, err)
+		}
+	} else {
+		tracelog.InfoLogger.Printf("Resuming the upload of '%s'\n", path)
+	}
+
+	progress := &partsProgress{state: state, uploadedETags: uploadedETags, states: resumable.states, stateKey: stateKey}
+	partsCount, err := uploader.uploadParts(ctx, bucket, path, content, firstPart, progress)
+	if err != nil {
+		tracelog.InfoLogger.Printf("The upload of '%s' is interrupted, it will be resumed by the next upload\n", path)
+		return errors.Wrapf(err, "failed to upload '%s' to bucket '%s'", path, bucket)
+	}
+
+	completedParts := make([]*s3.CompletedPart, partsCount)
+	for i := range completedParts {
+		completedParts[i] = &s3.CompletedPart{
+			ETag:       aws.String(progress.state.Parts[i].ETag),
+			PartNumber: aws.Int64(int64(i + 1)),
+		}
+	}
+	_, err = resumable.s3API.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(path),
+		UploadId:        aws.String(state.UploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to complete the upload of '%s' to bucket '%s'", path, bucket)
+	}
+	if err = resumable.states.Delete(stateKey); err != nil {
+		tracelog.WarningLogger.Println(err)
+	}
+	return nil
+}
+
+// partsProgress tracks the parts uploaded by the concurrent part uploads and saves them to the state store
+type partsProgress struct {
+	mutex         sync.Mutex
+	state         multipartUploadState
+	uploadedETags map[int64]string
+	states        *storage.UploadStateStore
+	stateKey      string
+}
+
+// isUploaded tells whether the part with the same content is already in the storage
+func (progress *partsProgress) isUploaded(number int64, part uploadedPart) bool {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	if number > int64(len(progress.state.Parts)) {
+		return false
+	}
+	saved := progress.state.Parts[number-1]
+	return saved.Size == part.Size && saved.MD5 == part.MD5 && progress.uploadedETags[number] == saved.ETag
+}
+
+// setUploaded saves the uploaded part, the saves are serialized so that an older state never replaces a newer one
+func (progress *partsProgress) setUploaded(number int64, part uploadedPart) error {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	for int64(len(progress.state.Parts)) < number {
+		progress.state.Parts = append(progress.state.Parts, uploadedPart{})
+	}
+	progress.state.Parts[number-1] = part
+	return progress.states.Save(progress.stateKey, progress.state)
+}
+
+// uploadParts uploads the content part by part, firstPart is the already read beginning of the content.
+// It returns the number of parts.
+func (uploader *Uploader) uploadParts(ctx context.Context, bucket, path string, content io.Reader,
+	firstPart []byte, progress *partsProgress) (int64, error) {
+	resumable := uploader.resumable
+	uploadID := progress.state.UploadID
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var uploadErr error
+	fail := func(err error) {
+		errOnce.Do(func() {
+			uploadErr = err
+			cancel()
+		})
+	}
+	concurrency := resumable.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	workers := make(chan struct{}, concurrency)
+
+	data, lastPart := firstPart, false
+	number := int64(0)
+	for {
+		number++
+		checksum := md5.Sum(data)
+		part := uploadedPart{Size: int64(len(data)), MD5: hex.EncodeToString(checksum[:])}
+		if progress.isUploaded(number, part) {
+			tracelog.DebugLogger.Printf("Part %d of '%s' is already uploaded\n", number, path)
+		} else {
+			select {
+			case workers <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(number int64, data []byte, part uploadedPart) {
+				defer wg.Done()
+				defer func() { <-workers }()
+				output, err := resumable.s3API.UploadPartWithContext(ctx,
+					uploader.createUploadPartInput(bucket, path, uploadID, number, data))
+				if err != nil {
+					fail(errors.Wrapf(err, "failed to upload part %d", number))
+					return
+				}
+				part.ETag = *output.ETag
+				if err = progress.setUploaded(number, part); err != nil {
+					tracelog.WarningLogger.Println(err)
+				}
+			}(number, data, part)
+		}
+		if lastPart {
+			break
+		}
+		var err error
+		data, lastPart, err = readPart(content, resumable.partSize)
+		if err != nil {
+			fail(errors.Wrap(err, "failed to read the content"))
+			break
+		}
+		if len(data) == 0 {
+			// the previous part was the last one
+			break
+		}
+	}
+	wg.Wait()
+	if uploadErr != nil {
+		return 0, uploadErr
+	}
+	return number, ctx.Err()
+}
+
+// listUploadedParts checks that the saved upload may be resumed and returns the ETags of its parts in the storage
+func (resumable *resumableUploader) listUploadedParts(ctx context.Context, bucket, path string,
+	state multipartUploadState) (map[int64]string, error) {
+	if state.PartSize != resumable.partSize {
+		resumable.abandon(ctx, bucket, path, "", state.UploadID)
+		return nil, errors.Errorf("the part size has changed from %d to %d", state.PartSize, resumable.partSize)
+	}
+	uploadedETags := map[int64]string{}
+	err := resumable.s3API.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(path),
+		UploadId: aws.String(state.UploadID),
+	}, func(output *s3.ListPartsOutput, _ bool) bool {
+		for _, part := range output.Parts {
+			uploadedETags[*part.PartNumber] = *part.ETag
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uploadedETags, nil
+}
+
+// abandon aborts the previous upload of the object which can't be resumed
+func (resumable *resumableUploader) abandon(ctx context.Context, bucket, path, stateKey, uploadID string) {
+	err := abortMultipartUpload(ctx, resumable.s3API, bucket, path, uploadID)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to abort the previous upload of '%s': %v\n", path, err)
+	}
+	if stateKey == "" {
+		return
+	}
+	if err = resumable.states.Delete(stateKey); err != nil {
+		tracelog.WarningLogger.Println(err)
+	}
+}
+
+func abortMultipartUpload(ctx context.Context, s3API s3iface.S3API, bucket, path, uploadID string) error {
+	_, err := s3API.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(path),
+		UploadId: aws.String(uploadID),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == NoSuchUploadAWSErrorCode {
+		return nil
+	}
+	return err
+}
+
+// readPart reads the part of the content, it returns true if the content has ended
+func readPart(content io.Reader, partSize int64) ([]byte, bool, error) {
+	data := make([]byte, partSize)
+	n, err := io.ReadFull(content, data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return data[:n], true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, false, nil
+}
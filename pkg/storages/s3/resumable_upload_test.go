@@ -0,0 +1,247 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const testPartSize = 4
+
+// fakeMultipartAPI keeps the multipart uploads in memory, the part uploads fail while failPart is uploaded
+type fakeMultipartAPI struct {
+	s3iface.S3API
+	s3manageriface.UploaderAPI
+
+	mutex       sync.Mutex
+	uploads     map[string]map[int64][]byte
+	keys        map[string]string
+	objects     map[string][]byte
+	partUploads map[int64]int
+	failPart    int64
+}
+
+func newFakeMultipartAPI() *fakeMultipartAPI {
+	return &fakeMultipartAPI{
+		uploads:     map[string]map[int64][]byte{},
+		keys:        map[string]string{},
+		objects:     map[string][]byte{},
+		partUploads: map[int64]int{},
+	}
+}
+
+func (api *fakeMultipartAPI) CreateMultipartUploadWithContext(_ aws.Context, input *s3.CreateMultipartUploadInput,
+	_ ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	uploadID := fmt.Sprintf("upload%d", len(api.keys))
+	api.uploads[uploadID] = map[int64][]byte{}
+	api.keys[uploadID] = *input.Key
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(uploadID)}, nil
+}
+
+func (api *fakeMultipartAPI) UploadPartWithContext(_ aws.Context, input *s3.UploadPartInput,
+	_ ...request.Option) (*s3.UploadPartOutput, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	if *input.PartNumber == api.failPart {
+		return nil, awserr.New("InternalError", "part upload failed", nil)
+	}
+	parts, ok := api.uploads[*input.UploadId]
+	if !ok {
+		return nil, awserr.New(NoSuchUploadAWSErrorCode, "no such upload", nil)
+	}
+	data, _ := ioutil.ReadAll(input.Body)
+	parts[*input.PartNumber] = data
+	api.partUploads[*input.PartNumber]++
+	return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag-%s", data))}, nil
+}
+
+func (api *fakeMultipartAPI) ListPartsPagesWithContext(_ aws.Context, input *s3.ListPartsInput,
+	handler func(*s3.ListPartsOutput, bool) bool, _ ...request.Option) error {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	parts, ok := api.uploads[*input.UploadId]
+	if !ok {
+		return awserr.New(NoSuchUploadAWSErrorCode, "no such upload", nil)
+	}
+	output := &s3.ListPartsOutput{}
+	for number, data := range parts {
+		output.Parts = append(output.Parts, &s3.Part{
+			PartNumber: aws.Int64(number),
+			ETag:       aws.String(fmt.Sprintf("etag-%s", data)),
+		})
+	}
+	handler(output, true)
+	return nil
+}
+
+func (api *fakeMultipartAPI) CompleteMultipartUploadWithContext(_ aws.Context, input *s3.CompleteMultipartUploadInput,
+	_ ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	parts := api.uploads[*input.UploadId]
+	var object []byte
+	for i, part := range input.MultipartUpload.Parts {
+		if *part.PartNumber != int64(i+1) || *part.ETag != fmt.Sprintf("etag-%s", parts[*part.PartNumber]) {
+			return nil, awserr.New("InvalidPart", "invalid part", nil)
+		}
+		object = append(object, parts[*part.PartNumber]...)
+	}
+	api.objects[*input.Key] = object
+	delete(api.uploads, *input.UploadId)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (api *fakeMultipartAPI) AbortMultipartUploadWithContext(_ aws.Context, input *s3.AbortMultipartUploadInput,
+	_ ...request.Option) (*s3.AbortMultipartUploadOutput, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	if _, ok := api.uploads[*input.UploadId]; !ok {
+		return nil, awserr.New(NoSuchUploadAWSErrorCode, "no such upload", nil)
+	}
+	delete(api.uploads, *input.UploadId)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (api *fakeMultipartAPI) ListMultipartUploadsPagesWithContext(_ aws.Context, _ *s3.ListMultipartUploadsInput,
+	handler func(*s3.ListMultipartUploadsOutput, bool) bool, _ ...request.Option) error {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	output := &s3.ListMultipartUploadsOutput{}
+	for uploadID := range api.uploads {
+		output.Uploads = append(output.Uploads, &s3.MultipartUpload{
+			Key:       aws.String(api.keys[uploadID]),
+			UploadId:  aws.String(uploadID),
+			Initiated: aws.Time(time.Now()),
+		})
+	}
+	handler(output, true)
+	return nil
+}
+
+func (api *fakeMultipartAPI) UploadWithContext(_ aws.Context, input *s3manager.UploadInput,
+	_ ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	data, _ := ioutil.ReadAll(input.Body)
+	api.objects[*input.Key] = data
+	return &s3manager.UploadOutput{}, nil
+}
+
+func newResumableTestFolder(t *testing.T, api *fakeMultipartAPI, concurrency int) (*Folder, string) {
+	stateDir, err := ioutil.TempDir("", "upload_state")
+	require.NoError(t, err)
+	states, err := storage.NewUploadStateStore(stateDir)
+	require.NoError(t, err)
+	uploader := NewUploader(api, "", "", "", "STANDARD")
+	uploader.resumable = &resumableUploader{api, states, testPartSize, concurrency}
+	return NewFolder(*uploader, api, "bucket", "path/", false), stateDir
+}
+
+func countStates(t *testing.T, stateDir string) int {
+	files, err := ioutil.ReadDir(stateDir)
+	require.NoError(t, err)
+	return len(files)
+}
+
+func TestResumableUpload_ResumesInterruptedUpload(t *testing.T) {
+	api := newFakeMultipartAPI()
+	folder, stateDir := newResumableTestFolder(t, api, 1)
+	defer os.RemoveAll(stateDir)
+	content := []byte("aaaabbbbccccdddde")
+
+	api.failPart = 3
+	err := folder.PutObject("backup", bytes.NewReader(content))
+	assert.Error(t, err)
+	assert.Equal(t, 1, countStates(t, stateDir))
+	assert.Equal(t, 1, len(api.uploads))
+
+	api.failPart = 0
+	err = folder.PutObject("backup", bytes.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, content, api.objects["path/backup"])
+	assert.Equal(t, map[int64]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 1}, api.partUploads)
+	assert.Equal(t, 0, countStates(t, stateDir))
+	assert.Equal(t, 0, len(api.uploads))
+}
+
+func TestResumableUpload_ReuploadsChangedParts(t *testing.T) {
+	api := newFakeMultipartAPI()
+	folder, stateDir := newResumableTestFolder(t, api, 2)
+	defer os.RemoveAll(stateDir)
+
+	api.failPart = 4
+	err := folder.PutObject("backup", bytes.NewReader([]byte("aaaabbbbccccdddd")))
+	assert.Error(t, err)
+
+	api.failPart = 0
+	content := []byte("aaaaBBBBccccddddeeee")
+	err = folder.PutObject("backup", bytes.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, content, api.objects["path/backup"])
+	assert.Equal(t, 1, api.partUploads[1])
+	assert.Equal(t, 2, api.partUploads[2])
+	assert.Equal(t, 1, api.partUploads[4])
+}
+
+func TestResumableUpload_UploadsSmallObjectsAtOnce(t *testing.T) {
+	api := newFakeMultipartAPI()
+	folder, stateDir := newResumableTestFolder(t, api, 2)
+	defer os.RemoveAll(stateDir)
+
+	err := folder.PutObject("small", bytes.NewReader([]byte("abc")))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("abc"), api.objects["path/small"])
+	assert.Equal(t, 0, len(api.partUploads))
+
+	// the size multiple of the part size is completed by an empty read
+	err = folder.PutObject("even", bytes.NewReader([]byte("aaaabbbb")))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("aaaabbbb"), api.objects["path/even"])
+}
+
+func TestFolder_AbortPendingUpload(t *testing.T) {
+	api := newFakeMultipartAPI()
+	folder, stateDir := newResumableTestFolder(t, api, 1)
+	defer os.RemoveAll(stateDir)
+
+	api.failPart = 2
+	assert.Error(t, folder.PutObject("first", bytes.NewReader([]byte("aaaabbbb1"))))
+	assert.Error(t, folder.PutObject("second", bytes.NewReader([]byte("aaaabbbb2"))))
+
+	uploads, err := folder.ListPendingUploads(context.Background())
+	assert.NoError(t, err)
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].Name < uploads[j].Name
+	})
+	assert.Equal(t, 2, len(uploads))
+	assert.Equal(t, "first", uploads[0].Name)
+
+	assert.NoError(t, folder.AbortPendingUpload(context.Background(), uploads[0]))
+	// the upload which is not pending anymore is ignored
+	assert.NoError(t, folder.AbortPendingUpload(context.Background(), uploads[0]))
+	assert.Equal(t, 1, len(api.uploads))
+
+	// the aborted upload can't be resumed, so it is started again
+	api.failPart = 0
+	assert.NoError(t, folder.PutObject("first", bytes.NewReader([]byte("aaaabbbb1"))))
+	assert.Equal(t, []byte("aaaabbbb1"), api.objects["path/first"])
+}
@@ -1,11 +1,15 @@
 package s3
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -14,6 +18,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
 const (
@@ -38,10 +43,21 @@ type Uploader struct {
 	SSECustomerKey       string
 	SSEKMSKeyId          string
 	StorageClass         string
+	// ObjectLockMode enables S3 Object Lock of the uploaded objects for ObjectLockRetention
+	ObjectLockMode      string
+	ObjectLockRetention time.Duration
+	// resumable is set if the progress of the uploads is saved to resume them after failures
+	resumable *resumableUploader
 }
 
 func NewUploader(uploaderAPI s3manageriface.UploaderAPI, serverSideEncryption, sseCustomerKey, sseKmsKeyId, storageClass string) *Uploader {
-	return &Uploader{uploaderAPI, serverSideEncryption, sseCustomerKey, sseKmsKeyId, storageClass}
+	return &Uploader{
+		uploaderAPI:          uploaderAPI,
+		serverSideEncryption: serverSideEncryption,
+		SSECustomerKey:       sseCustomerKey,
+		SSEKMSKeyId:          sseKmsKeyId,
+		StorageClass:         storageClass,
+	}
 }
 
 // TODO : unit tests
@@ -70,12 +86,78 @@ func (uploader *Uploader) createUploadInput(bucket, path string, content io.Read
 		}
 	}
 
+	if uploader.ObjectLockMode != "" {
+		uploadInput.ObjectLockMode = aws.String(uploader.ObjectLockMode)
+		uploadInput.ObjectLockRetainUntilDate = uploader.objectLockRetainUntil()
+	}
+
 	return uploadInput
 }
 
-func (uploader *Uploader) upload(bucket, path string, content io.Reader) error {
+func (uploader *Uploader) createMultipartUploadInput(bucket, path string) *s3.CreateMultipartUploadInput {
+	uploadInput := uploader.createUploadInput(bucket, path, nil)
+	return &s3.CreateMultipartUploadInput{
+		Bucket:                    uploadInput.Bucket,
+		Key:                       uploadInput.Key,
+		StorageClass:              uploadInput.StorageClass,
+		ServerSideEncryption:      uploadInput.ServerSideEncryption,
+		SSECustomerAlgorithm:      uploadInput.SSECustomerAlgorithm,
+		SSECustomerKey:            uploadInput.SSECustomerKey,
+		SSECustomerKeyMD5:         uploadInput.SSECustomerKeyMD5,
+		SSEKMSKeyId:               uploadInput.SSEKMSKeyId,
+		ObjectLockMode:            uploadInput.ObjectLockMode,
+		ObjectLockRetainUntilDate: uploadInput.ObjectLockRetainUntilDate,
+	}
+}
+
+// createUploadPartInput repeats the customer key of the upload, since S3 requires it for every part
+func (uploader *Uploader) createUploadPartInput(bucket, path, uploadID string, number int64, data []byte) *s3.UploadPartInput {
+	uploadInput := uploader.createUploadInput(bucket, path, nil)
+	return &s3.UploadPartInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(path),
+		UploadId:             aws.String(uploadID),
+		PartNumber:           aws.Int64(number),
+		Body:                 bytes.NewReader(data),
+		SSECustomerAlgorithm: uploadInput.SSECustomerAlgorithm,
+		SSECustomerKey:       uploadInput.SSECustomerKey,
+		SSECustomerKeyMD5:    uploadInput.SSECustomerKeyMD5,
+	}
+}
+
+// setCopyEncryption makes the copy of the object encrypted the same way as the uploaded objects
+func (uploader *Uploader) setCopyEncryption(input *s3.CopyObjectInput) {
+	if uploader.serverSideEncryption == "" {
+		return
+	}
+	if uploader.SSECustomerKey != "" {
+		hash := md5.Sum([]byte(uploader.SSECustomerKey))
+		customerKeyMD5 := base64.StdEncoding.EncodeToString(hash[:])
+		input.SSECustomerAlgorithm = aws.String(uploader.serverSideEncryption)
+		input.SSECustomerKey = aws.String(uploader.SSECustomerKey)
+		input.SSECustomerKeyMD5 = aws.String(customerKeyMD5)
+		input.CopySourceSSECustomerAlgorithm = aws.String(uploader.serverSideEncryption)
+		input.CopySourceSSECustomerKey = aws.String(uploader.SSECustomerKey)
+		input.CopySourceSSECustomerKeyMD5 = aws.String(customerKeyMD5)
+	} else {
+		input.ServerSideEncryption = aws.String(uploader.serverSideEncryption)
+	}
+	if uploader.SSEKMSKeyId != "" {
+		input.SSEKMSKeyId = aws.String(uploader.SSEKMSKeyId)
+	}
+}
+
+// objectLockRetainUntil is the end of the retention of the objects uploaded now
+func (uploader *Uploader) objectLockRetainUntil() *time.Time {
+	return aws.Time(time.Now().Add(uploader.ObjectLockRetention))
+}
+
+func (uploader *Uploader) upload(ctx context.Context, bucket, path string, content io.Reader) error {
+	if uploader.resumable != nil {
+		return uploader.uploadResumable(ctx, bucket, path, content)
+	}
 	input := uploader.createUploadInput(bucket, path, content)
-	_, err := uploader.uploaderAPI.Upload(input)
+	_, err := uploader.uploaderAPI.UploadWithContext(ctx, input)
 	return errors.Wrapf(err, "failed to upload '%s' to bucket '%s'", path, bucket)
 }
 
@@ -102,6 +184,24 @@ func configureServerSideEncryption(settings map[string]string) (serverSideEncryp
 	return
 }
 
+func configureObjectLock(settings map[string]string) (mode string, retention time.Duration, err error) {
+	mode = strings.ToUpper(settings[ObjectLockModeSetting])
+	daysStr, daysSet := settings[ObjectLockDaysSetting]
+	if mode == "" && !daysSet {
+		return "", 0, nil
+	}
+	if mode != s3.ObjectLockModeGovernance && mode != s3.ObjectLockModeCompliance {
+		return "", 0, NewFolderError(errors.Errorf("invalid mode '%s'", mode),
+			"%s should be one of %s, %s", ObjectLockModeSetting, s3.ObjectLockModeGovernance, s3.ObjectLockModeCompliance)
+	}
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days <= 0 {
+		return "", 0, NewFolderError(errors.Errorf("invalid retention '%s'", daysStr),
+			"%s should be a positive number of days", ObjectLockDaysSetting)
+	}
+	return mode, time.Duration(days) * 24 * time.Hour, nil
+}
+
 // TODO : unit tests
 func partitionStrings(strings []string, blockSize int) [][]string {
 	// I've unsuccessfully tried this with interface{} but there was too much of casting
@@ -151,5 +251,17 @@ func configureUploader(s3Client *s3.S3, settings map[string]string) (*Uploader,
 	if storageClass, ok = settings[StorageClassSetting]; !ok {
 		storageClass = "STANDARD"
 	}
-	return NewUploader(uploaderApi, serverSideEncryption, sseCustomerKey, sseKmsKeyId, storageClass), nil
+	uploader := NewUploader(uploaderApi, serverSideEncryption, sseCustomerKey, sseKmsKeyId, storageClass)
+	uploader.ObjectLockMode, uploader.ObjectLockRetention, err = configureObjectLock(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure object lock")
+	}
+	if stateDir, ok := settings[UploadStateDirSetting]; ok {
+		states, err := storage.NewUploadStateStore(stateDir)
+		if err != nil {
+			return nil, NewFolderError(err, "Invalid upload state directory setting")
+		}
+		uploader.resumable = &resumableUploader{s3Client, states, int64(maxPartSize), concurrency}
+	}
+	return uploader, nil
 }
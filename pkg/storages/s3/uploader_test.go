@@ -0,0 +1,62 @@
+package s3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureObjectLock(t *testing.T) {
+	mode, retention, err := configureObjectLock(map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", mode)
+	assert.Equal(t, time.Duration(0), retention)
+
+	mode, retention, err = configureObjectLock(map[string]string{
+		ObjectLockModeSetting: "governance",
+		ObjectLockDaysSetting: "30",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "GOVERNANCE", mode)
+	assert.Equal(t, 30*24*time.Hour, retention)
+
+	_, _, err = configureObjectLock(map[string]string{ObjectLockModeSetting: "COMPLIANCE"})
+	assert.Error(t, err)
+	_, _, err = configureObjectLock(map[string]string{ObjectLockDaysSetting: "30"})
+	assert.Error(t, err)
+	_, _, err = configureObjectLock(map[string]string{ObjectLockModeSetting: "LEGAL", ObjectLockDaysSetting: "30"})
+	assert.Error(t, err)
+}
+
+func TestCreateUploadInput_ObjectLock(t *testing.T) {
+	uploader := NewUploader(nil, "", "", "", "STANDARD")
+	input := uploader.createUploadInput("bucket", "path", nil)
+	assert.Nil(t, input.ObjectLockMode)
+	assert.Nil(t, input.ObjectLockRetainUntilDate)
+
+	uploader.ObjectLockMode = "COMPLIANCE"
+	uploader.ObjectLockRetention = time.Hour
+	input = uploader.createUploadInput("bucket", "path", nil)
+	assert.Equal(t, "COMPLIANCE", aws.StringValue(input.ObjectLockMode))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), aws.TimeValue(input.ObjectLockRetainUntilDate), time.Minute)
+}
+
+func TestSetCopyEncryption(t *testing.T) {
+	input := &s3.CopyObjectInput{}
+	NewUploader(nil, "", "", "", "STANDARD").setCopyEncryption(input)
+	assert.Nil(t, input.ServerSideEncryption)
+
+	NewUploader(nil, "aws:kms", "", "key", "STANDARD").setCopyEncryption(input)
+	assert.Equal(t, "aws:kms", aws.StringValue(input.ServerSideEncryption))
+	assert.Equal(t, "key", aws.StringValue(input.SSEKMSKeyId))
+
+	input = &s3.CopyObjectInput{}
+	NewUploader(nil, "AES256", "customer key", "", "STANDARD").setCopyEncryption(input)
+	assert.Nil(t, input.ServerSideEncryption)
+	assert.Equal(t, "customer key", aws.StringValue(input.SSECustomerKey))
+	assert.Equal(t, "customer key", aws.StringValue(input.CopySourceSSECustomerKey))
+	assert.Equal(t, aws.StringValue(input.SSECustomerKeyMD5), aws.StringValue(input.CopySourceSSECustomerKeyMD5))
+}
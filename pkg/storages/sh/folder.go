@@ -2,6 +2,7 @@ package sh
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -109,6 +110,16 @@ func (folder *Folder) GetPath() string {
 }
 
 func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+// sftp client does not accept contexts, so cancellation is checked between requests
+// and while transferring object bodies
+func (folder *Folder) ListFolderWithContext(ctx context.Context) (objects []storage.Object,
+	subFolders []storage.Folder, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	client := folder.client
 	path := folder.path
 
@@ -148,13 +159,76 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 	return
 }
 
+func (folder *Folder) ListFolderPages(ctx context.Context, options storage.ListOptions,
+	handler storage.ListPageHandler) error {
+	pager := storage.NewListPager(handler)
+	if err := folder.listPages(ctx, "", options, pager); err != nil {
+		return err
+	}
+	return pager.Flush()
+}
+
+func (folder *Folder) listPages(ctx context.Context, relativePath string, options storage.ListOptions,
+	pager *storage.ListPager) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client := folder.client
+	dirPath := client.Join(folder.path, relativePath)
+
+	filesInfo, err := client.ReadDir(dirPath)
+
+	if os.IsNotExist(err) {
+		// Folder does not exists, it means where are no objects in folder
+		tracelog.InfoLogger.Println("\tskipped " + dirPath + ": " + err.Error())
+		return nil
+	}
+
+	if err != nil {
+		return NewFolderError(err, "Fail read folder '%s'", dirPath)
+	}
+
+	storage.SortFileInfos(filesInfo)
+	for _, fileInfo := range filesInfo {
+		name := fileInfo.Name()
+		if relativePath != "" {
+			name = relativePath + "/" + name
+		}
+		if !fileInfo.IsDir() {
+			if options.ObjectMatches(name) {
+				err = pager.AddObject(storage.NewLocalObject(name, fileInfo.ModTime(), fileInfo.Size()))
+			}
+		} else if options.SubFolderMatches(name) {
+			if options.Recursive {
+				err = folder.listPages(ctx, name, options, pager)
+			} else {
+				err = pager.AddSubFolder(&Folder{client, client.Join(folder.path, name)})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *Folder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
 	client := folder.client
 
 	for _, relativePath := range objectRelativePaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		path := client.Join(folder.path, relativePath)
 
 		stat, err := client.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
 		if err != nil {
 			return NewFolderError(err, "Fail to get object stat '%s': %v", path, err)
 		}
@@ -174,6 +248,13 @@ func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
 }
 
 func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 	path := filepath.Join(folder.path, objectRelativePath)
 	_, err := folder.client.Stat(path)
 
@@ -198,6 +279,13 @@ func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder
 }
 
 func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	path := folder.client.Join(folder.path, objectRelativePath)
 	file, err := folder.client.OpenFile(path)
 
@@ -208,10 +296,54 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	return struct {
 		io.Reader
 		io.Closer
-	}{bufio.NewReaderSize(file, defaultBufferSize), file}, nil
+	}{storage.NewContextReader(ctx, bufio.NewReaderSize(file, defaultBufferSize)), file}, nil
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *Folder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path := folder.client.Join(folder.path, objectRelativePath)
+	file, err := folder.client.OpenFile(path)
+
+	if err != nil {
+		return nil, storage.NewObjectNotFoundError(path)
+	}
+
+	// sftp files are seekable, the other readers are rewound by skipping the bytes
+	if seeker, ok := file.(io.Seeker); ok {
+		_, err = seeker.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(ioutil.Discard, file, offset)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, NewFolderError(err, "Fail to seek object '%s' to %d", path, offset)
+	}
+
+	var reader io.Reader = bufio.NewReaderSize(file, defaultBufferSize)
+	if length >= 0 {
+		reader = io.LimitReader(reader, length)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{storage.NewContextReader(ctx, reader), file}, nil
 }
 
 func (folder *Folder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	client := folder.client
 	absolutePath := filepath.Join(folder.path, name)
 
@@ -232,7 +364,7 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 		)
 	}
 
-	_, err = io.Copy(file, content)
+	_, err = io.Copy(file, storage.NewContextReader(ctx, content))
 	if err != nil {
 		closerErr := file.Close()
 		if closerErr != nil {
@@ -254,18 +386,23 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
-	if exists, err := folder.Exists(srcPath); !exists {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	if exists, err := folder.ExistsWithContext(ctx, srcPath); !exists {
 		if err == nil {
 			return errors.New("object does not exist")
 		} else {
 			return err
 		}
 	}
-	file, err := folder.ReadObject(srcPath)
+	file, err := folder.ReadObjectWithContext(ctx, srcPath)
 	if err != nil {
 		return err
 	}
-	err = folder.PutObject(dstPath, file)
+	defer file.Close()
+	err = folder.PutObjectWithContext(ctx, dstPath, file)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,367 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+const diskCacheTempPrefix = ".tmp-"
+
+// DiskCache keeps the recently read objects in a local directory.
+// The least recently used objects are evicted when the total size exceeds the capacity.
+// The directory may be shared by several processes, every one of them evicts the files it knows about.
+type DiskCache struct {
+	directory string
+	capacity  int64
+
+	mutex   sync.Mutex
+	size    int64
+	entries map[string]*list.Element
+	// the most recently used entries go first
+	lru *list.List
+}
+
+type diskCacheEntry struct {
+	key  string
+	size int64
+}
+
+// NewDiskCache creates the cache directory if needed and picks up the objects cached there earlier
+func NewDiskCache(directory string, capacity int64) (*DiskCache, error) {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create cache directory '%s'", directory)
+	}
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read cache directory '%s'", directory)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	cache := &DiskCache{
+		directory: directory,
+		capacity:  capacity,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), diskCacheTempPrefix) {
+			continue
+		}
+		cache.addEntry(file.Name(), file.Size())
+	}
+	cache.evict()
+	return cache, nil
+}
+
+// Size is the total size of the cached objects known to this process
+func (cache *DiskCache) Size() int64 {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.size
+}
+
+// open returns the cached object, it marks the object as the most recently used one
+func (cache *DiskCache) open(key string) (*os.File, bool) {
+	file, err := os.Open(cache.path(key))
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if err != nil {
+		// the file may be evicted by another process
+		cache.removeEntry(key)
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(cache.path(key), now, now)
+	if element, ok := cache.entries[key]; ok {
+		cache.lru.MoveToFront(element)
+	} else if stat, err := file.Stat(); err == nil {
+		// cached by another process
+		cache.addEntry(key, stat.Size())
+	}
+	return file, true
+}
+
+// add moves the fully written temporary file into the cache
+func (cache *DiskCache) add(key string, tempPath string, size int64) error {
+	if err := os.Rename(tempPath, cache.path(key)); err != nil {
+		return err
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.removeEntry(key)
+	cache.addEntry(key, size)
+	cache.evict()
+	return nil
+}
+
+func (cache *DiskCache) addEntry(key string, size int64) {
+	cache.entries[key] = cache.lru.PushFront(&diskCacheEntry{key, size})
+	cache.size += size
+}
+
+func (cache *DiskCache) removeEntry(key string) {
+	element, ok := cache.entries[key]
+	if !ok {
+		return
+	}
+	cache.lru.Remove(element)
+	delete(cache.entries, key)
+	cache.size -= element.Value.(*diskCacheEntry).size
+}
+
+func (cache *DiskCache) evict() {
+	for cache.size > cache.capacity && cache.lru.Len() > 0 {
+		entry := cache.lru.Back().Value.(*diskCacheEntry)
+		err := os.Remove(cache.path(entry.key))
+		if err != nil && !os.IsNotExist(err) {
+			tracelog.WarningLogger.Printf("Failed to evict '%s' from storage cache: %v\n", entry.key, err)
+		}
+		cache.removeEntry(entry.key)
+	}
+}
+
+func (cache *DiskCache) path(key string) string {
+	return filepath.Join(cache.directory, key)
+}
+
+// CacheFolder is a read-through cache of the objects of the folder.
+// Objects are cached by their path, size and modification time, so a rewritten object is never
+// served from the cache. Only the objects read from the beginning to the end are cached,
+// ranged reads are served from the cache but do not populate it.
+type CacheFolder struct {
+	folder ContextFolder
+	cache  *DiskCache
+}
+
+func NewCacheFolder(folder Folder, cache *DiskCache) *CacheFolder {
+	return &CacheFolder{NewContextFolder(folder), cache}
+}
+
+// Folder returns the underlying folder
+func (folder *CacheFolder) Folder() Folder {
+	return folder.folder
+}
+
+func (folder *CacheFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *CacheFolder) GetSubFolder(subFolderRelativePath string) Folder {
+	return NewCacheFolder(folder.folder.GetSubFolder(subFolderRelativePath), folder.cache)
+}
+
+func (folder *CacheFolder) ListFolder() (objects []Object, subFolders []Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *CacheFolder) ListFolderWithContext(ctx context.Context) (objects []Object,
+	subFolders []Folder, err error) {
+	objects, subFolders, err = folder.folder.ListFolderWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range subFolders {
+		subFolders[i] = NewCacheFolder(subFolders[i], folder.cache)
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *CacheFolder) ListFolderPages(ctx context.Context, options ListOptions, handler ListPageHandler) error {
+	return ListFolderPages(ctx, folder.folder, options, func(objects []Object, subFolders []Folder) error {
+		for i := range subFolders {
+			subFolders[i] = NewCacheFolder(subFolders[i], folder.cache)
+		}
+		return handler(objects, subFolders)
+	})
+}
+
+func (folder *CacheFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *CacheFolder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	return folder.folder.DeleteObjectsWithContext(ctx, objectRelativePaths)
+}
+
+func (folder *CacheFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *CacheFolder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	return folder.folder.ExistsWithContext(ctx, objectRelativePath)
+}
+
+func (folder *CacheFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *CacheFolder) ReadObjectWithContext(ctx context.Context,
+	objectRelativePath string) (io.ReadCloser, error) {
+	object, key := folder.lookup(ctx, objectRelativePath)
+	if object == nil {
+		return folder.folder.ReadObjectWithContext(ctx, objectRelativePath)
+	}
+	if file, ok := folder.cache.open(key); ok {
+		tracelog.DebugLogger.Printf("Reading '%s' from storage cache\n", objectRelativePath)
+		return file, nil
+	}
+	reader, err := folder.folder.ReadObjectWithContext(ctx, objectRelativePath)
+	if err != nil || object.GetSize() > folder.cache.capacity {
+		return reader, err
+	}
+	tempFile, err := ioutil.TempFile(folder.cache.directory, diskCacheTempPrefix)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to cache '%s': %v\n", objectRelativePath, err)
+		return reader, nil
+	}
+	return &cachingReader{reader, tempFile, folder.cache, key, object.GetSize(), 0}, nil
+}
+
+func (folder *CacheFolder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *CacheFolder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	object, key := folder.lookup(ctx, objectRelativePath)
+	if object != nil {
+		if file, ok := folder.cache.open(key); ok {
+			if _, err := file.Seek(offset, io.SeekStart); err == nil {
+				return NewLimitedReadCloser(file, length), nil
+			}
+			_ = file.Close()
+		}
+	}
+	return ReadObjectRangeWithContext(ctx, folder.folder, objectRelativePath, offset, length)
+}
+
+func (folder *CacheFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *CacheFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	return folder.folder.PutObjectWithContext(ctx, name, content)
+}
+
+func (folder *CacheFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *CacheFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	return folder.folder.CopyObjectWithContext(ctx, srcPath, dstPath)
+}
+
+func (folder *CacheFolder) StatObject(ctx context.Context, objectRelativePath string) (Object, error) {
+	return StatObject(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *CacheFolder) GetObjectLock(ctx context.Context, objectRelativePath string) (ObjectLock, error) {
+	return GetObjectLock(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *CacheFolder) GetStorageClass(ctx context.Context, objectRelativePath string) (StorageClass, error) {
+	return GetStorageClass(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *CacheFolder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	return SetStorageClass(ctx, folder.folder, objectRelativePath, storageClass)
+}
+
+func (folder *CacheFolder) ListPendingUploads(ctx context.Context) ([]PendingUpload, error) {
+	return ListPendingUploads(ctx, folder.folder)
+}
+
+func (folder *CacheFolder) AbortPendingUpload(ctx context.Context, upload PendingUpload) error {
+	return AbortPendingUpload(ctx, folder.folder, upload)
+}
+
+// lookup gets the size and the modification time of the object with a single request and builds its cache key.
+// It returns nil if the object is not found, the storage itself decides what to do with the read then.
+func (folder *CacheFolder) lookup(ctx context.Context, objectRelativePath string) (Object, string) {
+	object, err := StatObject(ctx, folder.folder, objectRelativePath)
+	if _, ok := err.(ObjectNotFoundError); ok {
+		return nil, ""
+	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to look up '%s' for storage cache: %v\n", objectRelativePath, err)
+		return nil, ""
+	}
+	fullPath := JoinPath(folder.GetPath(), objectRelativePath)
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d",
+		fullPath, object.GetSize(), object.GetLastModified().UnixNano())))
+	return object, hex.EncodeToString(hash[:])
+}
+
+// cachingReader copies the object to a temporary file and puts it to the cache once the object is read completely
+type cachingReader struct {
+	io.ReadCloser
+	tempFile *os.File
+	cache    *DiskCache
+	key      string
+	size     int64
+	written  int64
+}
+
+func (reader *cachingReader) Read(p []byte) (int, error) {
+	n, err := reader.ReadCloser.Read(p)
+	if n > 0 && reader.tempFile != nil {
+		written, writeErr := reader.tempFile.Write(p[:n])
+		reader.written += int64(written)
+		if writeErr != nil {
+			tracelog.WarningLogger.Printf("Failed to write storage cache: %v\n", writeErr)
+			reader.discard()
+		}
+	}
+	if err == io.EOF && reader.tempFile != nil {
+		reader.commit()
+	}
+	return n, err
+}
+
+func (reader *cachingReader) Close() error {
+	reader.discard()
+	return reader.ReadCloser.Close()
+}
+
+func (reader *cachingReader) VerifiesChecksum() bool {
+	return VerifiesChecksum(reader.ReadCloser)
+}
+
+func (reader *cachingReader) commit() {
+	tempFile := reader.tempFile
+	reader.tempFile = nil
+	if err := tempFile.Close(); err != nil || reader.written != reader.size {
+		_ = os.Remove(tempFile.Name())
+		return
+	}
+	if err := reader.cache.add(reader.key, tempFile.Name(), reader.size); err != nil {
+		tracelog.WarningLogger.Printf("Failed to add an object to storage cache: %v\n", err)
+		_ = os.Remove(tempFile.Name())
+	}
+}
+
+func (reader *cachingReader) discard() {
+	if reader.tempFile == nil {
+		return
+	}
+	_ = reader.tempFile.Close()
+	_ = os.Remove(reader.tempFile.Name())
+	reader.tempFile = nil
+}
@@ -0,0 +1,145 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// countingFolder counts the reads, stats and listings which reach the storage
+type countingFolder struct {
+	storage.Folder
+	reads int
+	stats int
+	lists int
+}
+
+func (folder *countingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	folder.reads++
+	return folder.Folder.ReadObject(objectRelativePath)
+}
+
+func (folder *countingFolder) StatObject(ctx context.Context, objectRelativePath string) (storage.Object, error) {
+	folder.stats++
+	return storage.StatObject(ctx, folder.Folder, objectRelativePath)
+}
+
+func (folder *countingFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	folder.lists++
+	return folder.Folder.ListFolder()
+}
+
+func newTestDiskCache(t *testing.T, capacity int64) (*storage.DiskCache, string) {
+	dir, err := ioutil.TempDir("", "walg_cache")
+	assert.NoError(t, err)
+	cache, err := storage.NewDiskCache(dir, capacity)
+	assert.NoError(t, err)
+	return cache, dir
+}
+
+func TestCacheFolder(t *testing.T) {
+	cache, dir := newTestDiskCache(t, 1024*1024)
+	defer os.RemoveAll(dir)
+	storage.RunFolderTest(storage.NewCacheFolder(memory.NewFolder("in_memory/", memory.NewStorage()), cache), t)
+}
+
+func TestCacheFolder_ReadsFromCache(t *testing.T) {
+	cache, dir := newTestDiskCache(t, 1024)
+	defer os.RemoveAll(dir)
+	underlying := &countingFolder{Folder: memory.NewFolder("in_memory/", memory.NewStorage())}
+	folder := storage.NewCacheFolder(underlying, cache)
+	assert.NoError(t, folder.PutObject("sub/file", strings.NewReader("data")))
+
+	assert.Equal(t, "data", readAll(t, folder, "sub/file"))
+	assert.Equal(t, "data", readAll(t, folder.GetSubFolder("sub"), "file"))
+	assert.Equal(t, 1, underlying.reads)
+	assert.Equal(t, int64(4), cache.Size())
+
+	reader, err := storage.ReadObjectRange(folder, "sub/file", 1, 2)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "at", string(data))
+	assert.Equal(t, 1, underlying.reads)
+	// every cached read costs a single stat of the object instead of a listing
+	assert.Equal(t, 2, underlying.stats)
+	assert.Equal(t, 0, underlying.lists)
+
+	// the cache survives restarts
+	restartedCache, err := storage.NewDiskCache(dir, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), restartedCache.Size())
+}
+
+func TestCacheFolder_RewrittenObject(t *testing.T) {
+	cache, dir := newTestDiskCache(t, 1024)
+	defer os.RemoveAll(dir)
+	folder := storage.NewCacheFolder(memory.NewFolder("in_memory/", memory.NewStorage()), cache)
+
+	assert.NoError(t, folder.PutObject("file", strings.NewReader("data")))
+	assert.Equal(t, "data", readAll(t, folder, "file"))
+	assert.NoError(t, folder.PutObject("file", strings.NewReader("new data")))
+	assert.Equal(t, "new data", readAll(t, folder, "file"))
+}
+
+func TestCacheFolder_PartialReadIsNotCached(t *testing.T) {
+	cache, dir := newTestDiskCache(t, 1024)
+	defer os.RemoveAll(dir)
+	folder := storage.NewCacheFolder(memory.NewFolder("in_memory/", memory.NewStorage()), cache)
+	assert.NoError(t, folder.PutObject("file", strings.NewReader("data")))
+
+	reader, err := folder.ReadObject("file")
+	assert.NoError(t, err)
+	_, err = reader.Read(make([]byte, 2))
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, int64(0), cache.Size())
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestCacheFolder_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, dir := newTestDiskCache(t, 10)
+	defer os.RemoveAll(dir)
+	underlying := &countingFolder{Folder: memory.NewFolder("in_memory/", memory.NewStorage())}
+	folder := storage.NewCacheFolder(underlying, cache)
+	assert.NoError(t, folder.PutObject("a", strings.NewReader("aaaa")))
+	assert.NoError(t, folder.PutObject("b", strings.NewReader("bbbb")))
+	assert.NoError(t, folder.PutObject("c", strings.NewReader("cccc")))
+
+	readAll(t, folder, "a")
+	readAll(t, folder, "b")
+	readAll(t, folder, "a")
+	readAll(t, folder, "c")
+	assert.Equal(t, 3, underlying.reads)
+	assert.Equal(t, int64(8), cache.Size())
+
+	// "b" is evicted, "a" is still cached
+	readAll(t, folder, "a")
+	assert.Equal(t, 3, underlying.reads)
+	readAll(t, folder, "b")
+	assert.Equal(t, 4, underlying.reads)
+}
+
+func TestStatObject_ListsFolderWithoutStat(t *testing.T) {
+	folder := struct{ storage.Folder }{memory.NewFolder("in_memory/", memory.NewStorage())}
+	assert.NoError(t, folder.PutObject("sub/file", strings.NewReader("data")))
+	assert.NoError(t, folder.PutObject("sub/file2", strings.NewReader("more data")))
+
+	object, err := storage.StatObject(context.Background(), folder, "sub/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "sub/file", object.GetName())
+	assert.Equal(t, int64(4), object.GetSize())
+
+	_, err = storage.StatObject(context.Background(), folder, "sub/fil")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
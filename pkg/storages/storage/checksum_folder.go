@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// ChecksumSuffix is appended to the object name to get the name of the object holding its checksum
+const ChecksumSuffix = ".sha256"
+
+// ChecksumFolder stores the SHA-256 checksum of every uploaded object next to it
+// and verifies the objects against their checksums when they are read to the end.
+// Objects uploaded without checksums, e.g. before the checksums were enabled, are read without verification
+// and with a warning. The checksum is read only after the object itself is opened.
+// The checksum objects are hidden from the listings and are deleted and copied along with their objects.
+type ChecksumFolder struct {
+	folder ContextFolder
+}
+
+func NewChecksumFolder(folder Folder) *ChecksumFolder {
+	return &ChecksumFolder{NewContextFolder(folder)}
+}
+
+func IsChecksumObject(name string) bool {
+	return strings.HasSuffix(name, ChecksumSuffix)
+}
+
+func (folder *ChecksumFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *ChecksumFolder) GetSubFolder(subFolderRelativePath string) Folder {
+	return NewChecksumFolder(folder.folder.GetSubFolder(subFolderRelativePath))
+}
+
+func (folder *ChecksumFolder) ListFolder() (objects []Object, subFolders []Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *ChecksumFolder) ListFolderWithContext(ctx context.Context) (objects []Object,
+	subFolders []Folder, err error) {
+	objects, subFolders, err = folder.folder.ListFolderWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return withoutChecksumObjects(objects), wrapChecksumFolders(subFolders), nil
+}
+
+func (folder *ChecksumFolder) ListFolderPages(ctx context.Context, options ListOptions,
+	handler ListPageHandler) error {
+	return ListFolderPages(ctx, folder.folder, options, func(objects []Object, subFolders []Folder) error {
+		objects = withoutChecksumObjects(objects)
+		if len(objects) == 0 && len(subFolders) == 0 {
+			return nil
+		}
+		return handler(objects, wrapChecksumFolders(subFolders))
+	})
+}
+
+func (folder *ChecksumFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *ChecksumFolder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	paths := make([]string, 0, 2*len(objectRelativePaths))
+	for _, path := range objectRelativePaths {
+		paths = append(paths, path)
+		if !IsChecksumObject(path) {
+			paths = append(paths, path+ChecksumSuffix)
+		}
+	}
+	return folder.folder.DeleteObjectsWithContext(ctx, paths)
+}
+
+func (folder *ChecksumFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *ChecksumFolder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	return folder.folder.ExistsWithContext(ctx, objectRelativePath)
+}
+
+func (folder *ChecksumFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *ChecksumFolder) ReadObjectWithContext(ctx context.Context,
+	objectRelativePath string) (io.ReadCloser, error) {
+	reader, err := folder.folder.ReadObjectWithContext(ctx, objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	// the checksum is read only once the object is known to exist
+	expected, err := folder.readChecksum(ctx, objectRelativePath)
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+	if expected == "" {
+		return reader, nil
+	}
+	return &checksumReader{reader, sha256.New(), expected, JoinPath(folder.GetPath(), objectRelativePath)}, nil
+}
+
+// ReadObjectRange can not verify the parts of objects, only the whole objects are verified
+func (folder *ChecksumFolder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *ChecksumFolder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if offset == 0 && length < 0 {
+		return folder.ReadObjectWithContext(ctx, objectRelativePath)
+	}
+	return ReadObjectRangeWithContext(ctx, folder.folder, objectRelativePath, offset, length)
+}
+
+func (folder *ChecksumFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *ChecksumFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	checksum := sha256.New()
+	err := folder.folder.PutObjectWithContext(ctx, name, io.TeeReader(content, checksum))
+	if err != nil {
+		return err
+	}
+	err = folder.folder.PutObjectWithContext(ctx, name+ChecksumSuffix,
+		strings.NewReader(hex.EncodeToString(checksum.Sum(nil))))
+	return errors.Wrapf(err, "failed to upload the checksum of '%s'", name)
+}
+
+func (folder *ChecksumFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *ChecksumFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	err := folder.folder.CopyObjectWithContext(ctx, srcPath, dstPath)
+	if err != nil {
+		return err
+	}
+	exists, err := folder.folder.ExistsWithContext(ctx, srcPath+ChecksumSuffix)
+	if err != nil || !exists {
+		return err
+	}
+	return folder.folder.CopyObjectWithContext(ctx, srcPath+ChecksumSuffix, dstPath+ChecksumSuffix)
+}
+
+func (folder *ChecksumFolder) StatObject(ctx context.Context, objectRelativePath string) (Object, error) {
+	return StatObject(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *ChecksumFolder) GetObjectLock(ctx context.Context, objectRelativePath string) (ObjectLock, error) {
+	return GetObjectLock(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *ChecksumFolder) GetStorageClass(ctx context.Context, objectRelativePath string) (StorageClass, error) {
+	return GetStorageClass(ctx, folder.folder, objectRelativePath)
+}
+
+// SetStorageClass keeps the checksum in the original storage class, so that it can be read before the object is restored
+func (folder *ChecksumFolder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	return SetStorageClass(ctx, folder.folder, objectRelativePath, storageClass)
+}
+
+func (folder *ChecksumFolder) ListPendingUploads(ctx context.Context) ([]PendingUpload, error) {
+	return ListPendingUploads(ctx, folder.folder)
+}
+
+func (folder *ChecksumFolder) AbortPendingUpload(ctx context.Context, upload PendingUpload) error {
+	return AbortPendingUpload(ctx, folder.folder, upload)
+}
+
+// readChecksum returns an empty checksum if the object was uploaded without it
+func (folder *ChecksumFolder) readChecksum(ctx context.Context, objectRelativePath string) (string, error) {
+	reader, err := folder.folder.ReadObjectWithContext(ctx, objectRelativePath+ChecksumSuffix)
+	if _, ok := err.(ObjectNotFoundError); ok {
+		tracelog.WarningLogger.Printf("No checksum is stored for '%s', it is read without verification\n", objectRelativePath)
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the checksum of '%s'", objectRelativePath)
+	}
+	defer reader.Close()
+	checksum, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the checksum of '%s'", objectRelativePath)
+	}
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+func withoutChecksumObjects(objects []Object) []Object {
+	filtered := objects[:0]
+	for _, object := range objects {
+		if !IsChecksumObject(object.GetName()) {
+			filtered = append(filtered, object)
+		}
+	}
+	return filtered
+}
+
+func wrapChecksumFolders(subFolders []Folder) []Folder {
+	for i := range subFolders {
+		subFolders[i] = NewChecksumFolder(subFolders[i])
+	}
+	return subFolders
+}
+
+// ChecksumVerifier is implemented by the readers which verify the checksum of the object once it is read to the end.
+// The readers wrapping such readers forward the call to them.
+type ChecksumVerifier interface {
+	VerifiesChecksum() bool
+}
+
+// VerifiesChecksum checks whether reading the reader to the end verifies the checksum of the object
+func VerifiesChecksum(reader io.Reader) bool {
+	verifier, ok := reader.(ChecksumVerifier)
+	return ok && verifier.VerifiesChecksum()
+}
+
+// checksumReader fails the read of the last byte if the object does not match its checksum
+type checksumReader struct {
+	io.ReadCloser
+	checksum hash.Hash
+	expected string
+	path     string
+}
+
+func (reader *checksumReader) Read(p []byte) (int, error) {
+	n, err := reader.ReadCloser.Read(p)
+	reader.checksum.Write(p[:n])
+	if err == io.EOF {
+		if actual := hex.EncodeToString(reader.checksum.Sum(nil)); actual != reader.expected {
+			return n, NewChecksumMismatchError(reader.path, reader.expected, actual)
+		}
+	}
+	return n, err
+}
+
+func (reader *checksumReader) VerifiesChecksum() bool {
+	return true
+}
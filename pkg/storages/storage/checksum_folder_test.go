@@ -0,0 +1,86 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func TestChecksumFolder(t *testing.T) {
+	storage.RunFolderTest(storage.NewChecksumFolder(memory.NewFolder("in_memory/", memory.NewStorage())), t)
+}
+
+func TestChecksumFolder_StoresChecksums(t *testing.T) {
+	underlying := memory.NewFolder("in_memory/", memory.NewStorage())
+	folder := storage.NewChecksumFolder(underlying)
+	assert.NoError(t, folder.PutObject("sub/file", strings.NewReader("data")))
+
+	// sha256 of "data"
+	assert.Equal(t, "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7",
+		readAll(t, underlying, "sub/file"+storage.ChecksumSuffix))
+	assert.Equal(t, "data", readAll(t, folder, "sub/file"))
+
+	objects, _, err := folder.GetSubFolder("sub").ListFolder()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(objects))
+	assert.Equal(t, "file", objects[0].GetName())
+
+	assert.NoError(t, folder.CopyObject("sub/file", "copy"))
+	assert.Equal(t, "data", readAll(t, folder, "copy"))
+	exists, err := underlying.Exists("copy" + storage.ChecksumSuffix)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, folder.DeleteObjects([]string{"sub/file"}))
+	exists, err = underlying.Exists("sub/file" + storage.ChecksumSuffix)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestChecksumFolder_DetectsCorruption(t *testing.T) {
+	underlying := memory.NewFolder("in_memory/", memory.NewStorage())
+	folder := storage.NewChecksumFolder(underlying)
+	assert.NoError(t, folder.PutObject("file", strings.NewReader("data")))
+	assert.NoError(t, underlying.PutObject("file", strings.NewReader("dada")))
+
+	reader, err := folder.ReadObject("file")
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(reader)
+	assert.IsType(t, storage.ChecksumMismatchError{}, err)
+	assert.Contains(t, err.Error(), "in_memory/file")
+}
+
+func TestChecksumFolder_ReadsObjectsWithoutChecksums(t *testing.T) {
+	underlying := memory.NewFolder("in_memory/", memory.NewStorage())
+	assert.NoError(t, underlying.PutObject("file", strings.NewReader("data")))
+
+	assert.Equal(t, "data", readAll(t, storage.NewChecksumFolder(underlying), "file"))
+}
+
+func TestChecksumFolder_ReadsChecksumOfExistingObjectsOnly(t *testing.T) {
+	underlying := &countingFolder{Folder: memory.NewFolder("in_memory/", memory.NewStorage())}
+	folder := storage.NewChecksumFolder(underlying)
+
+	_, err := folder.ReadObject("missing")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+	assert.Equal(t, 1, underlying.reads)
+
+	assert.NoError(t, folder.PutObject("file", strings.NewReader("data")))
+	reader, err := folder.ReadObject("file")
+	assert.NoError(t, err)
+	assert.True(t, storage.VerifiesChecksum(reader))
+	assert.Equal(t, 3, underlying.reads)
+}
+
+func TestVerifiesChecksum_ObjectsWithoutChecksums(t *testing.T) {
+	underlying := memory.NewFolder("in_memory/", memory.NewStorage())
+	assert.NoError(t, underlying.PutObject("file", strings.NewReader("data")))
+
+	reader, err := storage.NewChecksumFolder(underlying).ReadObject("file")
+	assert.NoError(t, err)
+	assert.False(t, storage.VerifiesChecksum(reader))
+}
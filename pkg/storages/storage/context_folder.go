@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// ContextFolder is a Folder whose operations can be cancelled or bounded by a deadline.
+// Plain Folder methods of a ContextFolder are expected to behave like their
+// context-aware counterparts called with context.Background().
+type ContextFolder interface {
+	Folder
+
+	ListFolderWithContext(ctx context.Context) (objects []Object, subFolders []Folder, err error)
+
+	DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error
+
+	ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error)
+
+	// Should return ObjectNotFoundError in case, there is no such object.
+	// Cancelling ctx also aborts reading of the returned body.
+	ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error)
+
+	PutObjectWithContext(ctx context.Context, name string, content io.Reader) error
+
+	CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error
+}
+
+// NewContextFolder returns folder itself if it supports contexts natively.
+// Legacy implementations are wrapped into an adapter which stops waiting for
+// the underlying call as soon as the context is done.
+func NewContextFolder(folder Folder) ContextFolder {
+	if contextFolder, ok := folder.(ContextFolder); ok {
+		return contextFolder
+	}
+	return &contextFolderAdapter{folder}
+}
+
+// contextFolderAdapter runs calls of a legacy Folder in separate goroutines.
+// Abandoned calls keep running in background, but their results are dropped.
+type contextFolderAdapter struct {
+	Folder
+}
+
+func (adapter *contextFolderAdapter) GetSubFolder(subFolderRelativePath string) Folder {
+	return NewContextFolder(adapter.Folder.GetSubFolder(subFolderRelativePath))
+}
+
+func (adapter *contextFolderAdapter) ListFolderWithContext(ctx context.Context) (objects []Object,
+	subFolders []Folder, err error) {
+	err = runWithContext(ctx, func() error {
+		var listErr error
+		objects, subFolders, listErr = adapter.Folder.ListFolder()
+		return listErr
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return objects, subFolders, nil
+}
+
+func (adapter *contextFolderAdapter) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	return runWithContext(ctx, func() error {
+		return adapter.Folder.DeleteObjects(objectRelativePaths)
+	})
+}
+
+func (adapter *contextFolderAdapter) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	var exists bool
+	err := runWithContext(ctx, func() error {
+		var existsErr error
+		exists, existsErr = adapter.Folder.Exists(objectRelativePath)
+		return existsErr
+	})
+	return exists, err
+}
+
+func (adapter *contextFolderAdapter) ReadObjectWithContext(ctx context.Context,
+	objectRelativePath string) (io.ReadCloser, error) {
+	if ctx.Done() == nil {
+		return adapter.Folder.ReadObject(objectRelativePath)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type readResult struct {
+		reader io.ReadCloser
+		err    error
+	}
+	resultChan := make(chan readResult, 1)
+	go func() {
+		reader, err := adapter.Folder.ReadObject(objectRelativePath)
+		resultChan <- readResult{reader, err}
+	}()
+	select {
+	case result := <-resultChan:
+		if result.err != nil {
+			return nil, result.err
+		}
+		return NewContextReadCloser(ctx, result.reader), nil
+	case <-ctx.Done():
+		go func() {
+			// the reader is not needed anymore, but it still has to be released
+			if result := <-resultChan; result.err == nil {
+				_ = result.reader.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+func (adapter *contextFolderAdapter) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	if ctx.Done() == nil {
+		// ctx can never be cancelled, keep the content as is to preserve io.ReaderAt, io.Seeker and alike
+		return adapter.Folder.PutObject(name, content)
+	}
+	return runWithContext(ctx, func() error {
+		return adapter.Folder.PutObject(name, NewContextReader(ctx, content))
+	})
+}
+
+func (adapter *contextFolderAdapter) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	return runWithContext(ctx, func() error {
+		return adapter.Folder.CopyObject(srcPath, dstPath)
+	})
+}
+
+// StatObject keeps the native stat of the legacy folder available through the adapter
+func (adapter *contextFolderAdapter) StatObject(ctx context.Context, objectRelativePath string) (Object, error) {
+	return StatObject(ctx, adapter.Folder, objectRelativePath)
+}
+
+// runWithContext waits for call to finish or for ctx to be done, whichever happens first
+func runWithContext(ctx context.Context, call func() error) error {
+	if ctx.Done() == nil {
+		return call()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- call()
+	}()
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// NewContextReader returns a reader which fails with the context error once ctx is done.
+// It allows to interrupt streaming to the storages which do not accept contexts.
+func NewContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx, reader}
+}
+
+func (reader *contextReader) Read(p []byte) (n int, err error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.reader.Read(p)
+}
+
+type contextReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// NewContextReadCloser is the io.ReadCloser version of NewContextReader
+func NewContextReadCloser(ctx context.Context, readCloser io.ReadCloser) io.ReadCloser {
+	return &contextReadCloser{NewContextReader(ctx, readCloser), readCloser}
+}
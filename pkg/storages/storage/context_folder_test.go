@@ -0,0 +1,74 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// legacyFolder hides context-aware methods of the underlying folder
+type legacyFolder struct {
+	storage.Folder
+}
+
+// blockingFolder never finishes reads until released
+type blockingFolder struct {
+	storage.Folder
+	release chan struct{}
+}
+
+func (folder *blockingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	<-folder.release
+	return folder.Folder.ReadObject(objectRelativePath)
+}
+
+func TestNewContextFolder_ReturnsNativeImplementation(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	assert.Equal(t, storage.ContextFolder(folder), storage.NewContextFolder(folder))
+}
+
+func TestContextFolderAdapter(t *testing.T) {
+	storage.RunFolderTest(legacyFolder{memory.NewFolder("in_memory/", memory.NewStorage())}, t)
+}
+
+func TestContextFolderAdapter_AbortsHungCall(t *testing.T) {
+	folder := &blockingFolder{memory.NewFolder("in_memory/", memory.NewStorage()), make(chan struct{})}
+	defer close(folder.release)
+	err := folder.PutObject("file", strings.NewReader("data"))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = storage.NewContextFolder(folder).ReadObjectWithContext(ctx, "file")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestTimeoutFolder(t *testing.T) {
+	storage.RunFolderTest(storage.NewTimeoutFolder(memory.NewFolder("in_memory/", memory.NewStorage()), time.Minute), t)
+}
+
+func TestTimeoutFolder_Expires(t *testing.T) {
+	folder := &blockingFolder{memory.NewFolder("in_memory/", memory.NewStorage()), make(chan struct{})}
+	defer close(folder.release)
+	err := folder.PutObject("file", &bytes.Buffer{})
+	assert.NoError(t, err)
+
+	_, err = storage.NewTimeoutFolder(folder, 10*time.Millisecond).ReadObject("file")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := storage.NewContextReader(ctx, strings.NewReader("data"))
+	cancel()
+	_, err := ioutil.ReadAll(reader)
+	assert.Equal(t, context.Canceled, err)
+}
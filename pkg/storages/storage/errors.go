@@ -30,3 +30,21 @@ func NewError(err error, storageName, format string, args ...interface{}) Error
 func (err Error) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
+
+func (err Error) Unwrap() error {
+	return err.error
+}
+
+// ChecksumMismatchError means the object read from the storage differs from the uploaded one
+type ChecksumMismatchError struct {
+	error
+}
+
+func NewChecksumMismatchError(path, expected, actual string) ChecksumMismatchError {
+	return ChecksumMismatchError{errors.Errorf(
+		"object '%s' is corrupted: checksum is %s while %s was stored at upload", path, actual, expected)}
+}
+
+func (err ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
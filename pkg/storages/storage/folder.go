@@ -1,9 +1,8 @@
 package storage
 
 import (
+	"context"
 	"io"
-	"path"
-	"strings"
 
 	"github.com/wal-g/tracelog"
 )
@@ -32,53 +31,54 @@ type Folder interface {
 }
 
 func DeleteObjectsWhere(folder Folder, confirm bool, filter func(object1 Object) bool) error {
-	relativePathObjects, err := ListFolderRecursively(folder)
-	if err != nil {
-		return err
-	}
-	filteredRelativePaths := make([]string, 0)
+	return DeleteObjectsWhereWithContext(context.Background(), folder, confirm, filter)
+}
+
+// DeleteObjectsWhereWithContext removes the matching objects page by page while the folder is being listed
+func DeleteObjectsWhereWithContext(ctx context.Context, folder Folder, confirm bool,
+	filter func(object1 Object) bool) error {
+	contextFolder := NewContextFolder(folder)
+	anyFiltered := false
 	tracelog.InfoLogger.Println("Objects in folder:")
-	for _, object := range relativePathObjects {
-		if filter(object) {
-			tracelog.InfoLogger.Println("\twill be deleted: " + object.GetName())
-			filteredRelativePaths = append(filteredRelativePaths, object.GetName())
-		} else {
-			tracelog.DebugLogger.Println("\tskipped: " + object.GetName())
+	err := ListFolderPages(ctx, folder, ListOptions{Recursive: true}, func(objects []Object, _ []Folder) error {
+		filteredRelativePaths := make([]string, 0)
+		for _, object := range objects {
+			if filter(object) {
+				tracelog.InfoLogger.Println("\twill be deleted: " + object.GetName())
+				filteredRelativePaths = append(filteredRelativePaths, object.GetName())
+			} else {
+				tracelog.DebugLogger.Println("\tskipped: " + object.GetName())
+			}
 		}
+		if len(filteredRelativePaths) == 0 {
+			return nil
+		}
+		anyFiltered = true
+		if !confirm {
+			return nil
+		}
+		return contextFolder.DeleteObjectsWithContext(ctx, filteredRelativePaths)
+	})
+	if err != nil {
+		return err
 	}
-	if len(filteredRelativePaths) == 0 {
-		return nil
-	}
-	if confirm {
-		return folder.DeleteObjects(filteredRelativePaths)
-	} else {
+	if anyFiltered && !confirm {
 		tracelog.InfoLogger.Println("Dry run, nothing were deleted")
 	}
 	return nil
 }
 
 func ListFolderRecursively(folder Folder) (relativePathObjects []Object, err error) {
-	queue := make([]Folder, 0)
-	queue = append(queue, folder)
-	for len(queue) > 0 {
-		subFolder := queue[0]
-		queue = queue[1:]
-		objects, subFolders, err := subFolder.ListFolder()
-		folderPrefix := strings.TrimPrefix(subFolder.GetPath(), folder.GetPath())
-		relativePathObjects = append(relativePathObjects, addPrefixToNames(objects, folderPrefix)...)
-		if err != nil {
-			return nil, err
-		}
-		queue = append(queue, subFolders...)
-	}
-	return relativePathObjects, nil
+	return ListFolderRecursivelyWithContext(context.Background(), folder)
 }
 
-func addPrefixToNames(objects []Object, folderPrefix string) []Object {
-	relativePathObjects := make([]Object, len(objects))
-	for i, object := range objects {
-		relativePath := path.Join(folderPrefix, object.GetName())
-		relativePathObjects[i] = NewLocalObject(relativePath, object.GetLastModified(), object.GetSize())
+func ListFolderRecursivelyWithContext(ctx context.Context, folder Folder) (relativePathObjects []Object, err error) {
+	err = ListFolderPages(ctx, folder, ListOptions{Recursive: true}, func(objects []Object, _ []Folder) error {
+		relativePathObjects = append(relativePathObjects, objects...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return relativePathObjects
+	return relativePathObjects, nil
 }
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ObjectLock describes the protection of an object from deletion by the storage itself,
+// e.g. by S3 Object Lock.
+type ObjectLock struct {
+	// Mode is the storage specific retention mode, e.g. GOVERNANCE or COMPLIANCE for S3
+	Mode string
+	// RetainUntil is the time until which the object can not be deleted, zero if the object is not retained
+	RetainUntil time.Time
+	// LegalHold prevents the deletion until the hold is removed, regardless of RetainUntil
+	LegalHold bool
+}
+
+// IsActive checks whether the object can not be deleted at the moment
+func (lock ObjectLock) IsActive(now time.Time) bool {
+	return lock.LegalHold || lock.RetainUntil.After(now)
+}
+
+func (lock ObjectLock) String() string {
+	if lock.LegalHold {
+		return "under legal hold"
+	}
+	return fmt.Sprintf("in %s mode until %s", lock.Mode, lock.RetainUntil.Format(time.RFC3339))
+}
+
+// LockFolder is a Folder whose objects may be locked against deletion.
+type LockFolder interface {
+	Folder
+
+	// GetObjectLock returns the lock of the object, the zero ObjectLock means the object is not locked.
+	// Should return ObjectNotFoundError in case, there is no such object.
+	GetObjectLock(ctx context.Context, objectRelativePath string) (ObjectLock, error)
+}
+
+// GetObjectLock returns the lock of the object if the folder supports locks, the other folders never lock objects
+func GetObjectLock(ctx context.Context, folder Folder, objectRelativePath string) (ObjectLock, error) {
+	if lockFolder, ok := folder.(LockFolder); ok {
+		return lockFolder.GetObjectLock(ctx, objectRelativePath)
+	}
+	return ObjectLock{}, nil
+}
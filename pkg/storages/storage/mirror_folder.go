@@ -0,0 +1,393 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+type MirrorWritePolicy string
+
+const (
+	// MirrorWriteAll requires every mirror to complete a write
+	MirrorWriteAll MirrorWritePolicy = "all"
+	// MirrorWriteQuorum requires the majority of mirrors to complete a write
+	MirrorWriteQuorum MirrorWritePolicy = "quorum"
+
+	mirrorPutBufferSize = 32 * 1024
+)
+
+// MirrorFolder keeps the same contents in several storages.
+// Writes go to every mirror, reads are served by the first mirror which responds without an error,
+// so the first mirror is the primary one and the others are used on its failures only.
+type MirrorFolder struct {
+	mirrors []ContextFolder
+	policy  MirrorWritePolicy
+}
+
+func NewMirrorFolder(policy MirrorWritePolicy, mirrors ...Folder) *MirrorFolder {
+	contextMirrors := make([]ContextFolder, len(mirrors))
+	for i, mirror := range mirrors {
+		contextMirrors[i] = NewContextFolder(mirror)
+	}
+	return &MirrorFolder{contextMirrors, policy}
+}
+
+// Mirrors returns the underlying folders, the primary one goes first
+func (folder *MirrorFolder) Mirrors() []Folder {
+	mirrors := make([]Folder, len(folder.mirrors))
+	for i, mirror := range folder.mirrors {
+		mirrors[i] = mirror
+	}
+	return mirrors
+}
+
+func (folder *MirrorFolder) GetPath() string {
+	return folder.mirrors[0].GetPath()
+}
+
+func (folder *MirrorFolder) GetSubFolder(subFolderRelativePath string) Folder {
+	subFolders := make([]Folder, len(folder.mirrors))
+	for i, mirror := range folder.mirrors {
+		subFolders[i] = mirror.GetSubFolder(subFolderRelativePath)
+	}
+	return NewMirrorFolder(folder.policy, subFolders...)
+}
+
+func (folder *MirrorFolder) ListFolder() (objects []Object, subFolders []Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *MirrorFolder) ListFolderWithContext(ctx context.Context) (objects []Object,
+	subFolders []Folder, err error) {
+	err = folder.readFromAny(ctx, "list folder", func(mirrorIndex int, mirror ContextFolder) error {
+		var listErr error
+		objects, subFolders, listErr = mirror.ListFolderWithContext(ctx)
+		if listErr == nil {
+			subFolders = folder.mirrorSubFolders(mirrorIndex, subFolders)
+		}
+		return listErr
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *MirrorFolder) ListFolderPages(ctx context.Context, options ListOptions, handler ListPageHandler) error {
+	return folder.readFromAny(ctx, "list folder", func(mirrorIndex int, mirror ContextFolder) error {
+		pagesHandled := false
+		err := ListFolderPages(ctx, mirror, options, func(objects []Object, subFolders []Folder) error {
+			pagesHandled = true
+			return handler(objects, folder.mirrorSubFolders(mirrorIndex, subFolders))
+		})
+		if err != nil && pagesHandled {
+			// the handler has seen a part of the listing already, it can not be restarted on another mirror
+			return &mirrorFatalError{err}
+		}
+		return err
+	})
+}
+
+func (folder *MirrorFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *MirrorFolder) ExistsWithContext(ctx context.Context, objectRelativePath string) (exists bool, err error) {
+	err = folder.readFromAny(ctx, "check "+objectRelativePath, func(_ int, mirror ContextFolder) error {
+		var existsErr error
+		exists, existsErr = mirror.ExistsWithContext(ctx, objectRelativePath)
+		if existsErr == nil && !exists {
+			return NewObjectNotFoundError(objectRelativePath)
+		}
+		return existsErr
+	})
+	if _, ok := err.(ObjectNotFoundError); ok {
+		return false, nil
+	}
+	return exists, err
+}
+
+func (folder *MirrorFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *MirrorFolder) ReadObjectWithContext(ctx context.Context,
+	objectRelativePath string) (reader io.ReadCloser, err error) {
+	err = folder.readFromAny(ctx, "read "+objectRelativePath, func(_ int, mirror ContextFolder) error {
+		var readErr error
+		reader, readErr = mirror.ReadObjectWithContext(ctx, objectRelativePath)
+		return readErr
+	})
+	return reader, err
+}
+
+func (folder *MirrorFolder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *MirrorFolder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (reader io.ReadCloser, err error) {
+	err = folder.readFromAny(ctx, "read "+objectRelativePath, func(_ int, mirror ContextFolder) error {
+		var readErr error
+		reader, readErr = ReadObjectRangeWithContext(ctx, mirror, objectRelativePath, offset, length)
+		return readErr
+	})
+	return reader, err
+}
+
+func (folder *MirrorFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+// PutObjectWithContext streams the content to all the mirrors at once.
+// A mirror which fails in the middle of the upload is dropped, the others keep receiving the content.
+func (folder *MirrorFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	if len(folder.mirrors) == 1 {
+		return folder.mirrors[0].PutObjectWithContext(ctx, name, content)
+	}
+	errs := make([]error, len(folder.mirrors))
+	writers := make([]*io.PipeWriter, len(folder.mirrors))
+	var wg sync.WaitGroup
+	for i, mirror := range folder.mirrors {
+		reader, writer := io.Pipe()
+		writers[i] = writer
+		wg.Add(1)
+		go func(i int, mirror ContextFolder) {
+			defer wg.Done()
+			errs[i] = mirror.PutObjectWithContext(ctx, name, reader)
+			// unblock the writes of the content which will not be read anymore
+			_ = reader.CloseWithError(errors.New("mirror upload is over"))
+		}(i, mirror)
+	}
+
+	readErr := fanOut(content, writers)
+	for _, writer := range writers {
+		_ = writer.CloseWithError(readErr)
+	}
+	wg.Wait()
+	if readErr != nil {
+		return errors.Wrapf(readErr, "failed to read the content of '%s'", name)
+	}
+	return folder.checkWrite("put "+name, errs)
+}
+
+// fanOut copies the content to every writer, the writers which failed are skipped
+func fanOut(content io.Reader, writers []*io.PipeWriter) error {
+	alive := make([]io.Writer, len(writers))
+	for i, writer := range writers {
+		alive[i] = writer
+	}
+	buffer := make([]byte, mirrorPutBufferSize)
+	for {
+		n, err := content.Read(buffer)
+		if n > 0 {
+			aliveCount := 0
+			for i, writer := range alive {
+				if writer == nil {
+					continue
+				}
+				if _, writeErr := writer.Write(buffer[:n]); writeErr != nil {
+					alive[i] = nil
+					continue
+				}
+				aliveCount++
+			}
+			if aliveCount == 0 {
+				// every mirror has failed, their errors tell the reason
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (folder *MirrorFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *MirrorFolder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	errs := folder.writeToAll(func(mirror ContextFolder) error {
+		return mirror.DeleteObjectsWithContext(ctx, objectRelativePaths)
+	})
+	return folder.checkWrite("delete objects", errs)
+}
+
+func (folder *MirrorFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *MirrorFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	errs := folder.writeToAll(func(mirror ContextFolder) error {
+		return mirror.CopyObjectWithContext(ctx, srcPath, dstPath)
+	})
+	return folder.checkWrite("copy "+srcPath+" to "+dstPath, errs)
+}
+
+// StatObject returns the object from the first mirror which has it, since reads are served from it
+func (folder *MirrorFolder) StatObject(ctx context.Context, objectRelativePath string) (Object, error) {
+	var err error
+	for _, mirror := range folder.mirrors {
+		var object Object
+		object, err = StatObject(ctx, mirror, objectRelativePath)
+		if _, ok := err.(ObjectNotFoundError); !ok {
+			return object, err
+		}
+	}
+	return nil, err
+}
+
+// GetObjectLock combines the locks of the object in all the mirrors, since it can't be deleted while any of them holds
+func (folder *MirrorFolder) GetObjectLock(ctx context.Context, objectRelativePath string) (ObjectLock, error) {
+	var combined ObjectLock
+	for _, mirror := range folder.mirrors {
+		lock, err := GetObjectLock(ctx, mirror, objectRelativePath)
+		if _, ok := err.(ObjectNotFoundError); ok {
+			continue
+		}
+		if err != nil {
+			return ObjectLock{}, err
+		}
+		if lock.RetainUntil.After(combined.RetainUntil) {
+			combined.Mode = lock.Mode
+			combined.RetainUntil = lock.RetainUntil
+		}
+		combined.LegalHold = combined.LegalHold || lock.LegalHold
+	}
+	return combined, nil
+}
+
+// GetStorageClass returns the storage class of the object in the first mirror which has it, since reads are served from it
+func (folder *MirrorFolder) GetStorageClass(ctx context.Context, objectRelativePath string) (StorageClass, error) {
+	var err error
+	for _, mirror := range folder.mirrors {
+		var storageClass StorageClass
+		storageClass, err = GetStorageClass(ctx, mirror, objectRelativePath)
+		if _, ok := err.(ObjectNotFoundError); !ok {
+			return storageClass, err
+		}
+	}
+	return StorageClass{}, err
+}
+
+func (folder *MirrorFolder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	errs := folder.writeToAll(func(mirror ContextFolder) error {
+		return SetStorageClass(ctx, mirror, objectRelativePath, storageClass)
+	})
+	return folder.checkWrite("set storage class of "+objectRelativePath, errs)
+}
+
+// ListPendingUploads returns the pending uploads of all the mirrors
+func (folder *MirrorFolder) ListPendingUploads(ctx context.Context) ([]PendingUpload, error) {
+	var uploads []PendingUpload
+	for _, mirror := range folder.mirrors {
+		mirrorUploads, err := ListPendingUploads(ctx, mirror)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, mirrorUploads...)
+	}
+	return uploads, nil
+}
+
+// AbortPendingUpload aborts the upload in every mirror, the mirrors which don't have the upload ignore it
+func (folder *MirrorFolder) AbortPendingUpload(ctx context.Context, upload PendingUpload) error {
+	errs := folder.writeToAll(func(mirror ContextFolder) error {
+		return AbortPendingUpload(ctx, mirror, upload)
+	})
+	return folder.checkWrite("abort upload of "+upload.Name, errs)
+}
+
+func (folder *MirrorFolder) writeToAll(write func(mirror ContextFolder) error) []error {
+	errs := make([]error, len(folder.mirrors))
+	var wg sync.WaitGroup
+	for i, mirror := range folder.mirrors {
+		wg.Add(1)
+		go func(i int, mirror ContextFolder) {
+			defer wg.Done()
+			errs[i] = write(mirror)
+		}(i, mirror)
+	}
+	wg.Wait()
+	return errs
+}
+
+// checkWrite reports the mirrors which diverged and decides whether the write succeeded according to the policy
+func (folder *MirrorFolder) checkWrite(operation string, errs []error) error {
+	var firstErr error
+	succeeded := 0
+	for i, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		tracelog.WarningLogger.Printf("Mirror '%s' diverged, failed to %s: %v\n",
+			folder.mirrors[i].GetPath(), operation, err)
+	}
+	required := len(folder.mirrors)
+	if folder.policy == MirrorWriteQuorum {
+		required = len(folder.mirrors)/2 + 1
+	}
+	if succeeded >= required {
+		return nil
+	}
+	return errors.Wrapf(firstErr, "failed to %s: succeeded on %d of %d mirrors, %d required",
+		operation, succeeded, len(folder.mirrors), required)
+}
+
+// readFromAny tries the mirrors one by one until the read succeeds. The object may be missing on some mirrors,
+// e.g. with the quorum write policy, so it is not found only if none of the mirrors has it.
+func (folder *MirrorFolder) readFromAny(ctx context.Context, operation string,
+	read func(mirrorIndex int, mirror ContextFolder) error) error {
+	var err, failure error
+	for i, mirror := range folder.mirrors {
+		err = read(i, mirror)
+		if err == nil {
+			return nil
+		}
+		if fatalErr, ok := err.(*mirrorFatalError); ok {
+			return fatalErr.error
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if _, ok := err.(ObjectNotFoundError); ok {
+			continue
+		}
+		failure = err
+		if i+1 < len(folder.mirrors) {
+			tracelog.WarningLogger.Printf("Mirror '%s' failed to %s, trying the next one: %v\n",
+				mirror.GetPath(), operation, err)
+		}
+	}
+	if failure != nil {
+		// the failed mirror may have the object, which the others are missing
+		return failure
+	}
+	return err
+}
+
+// mirrorSubFolders turns the subfolders listed in a single mirror into the mirrored subfolders
+func (folder *MirrorFolder) mirrorSubFolders(mirrorIndex int, subFolders []Folder) []Folder {
+	mirrored := make([]Folder, len(subFolders))
+	for i, subFolder := range subFolders {
+		mirrored[i] = folder.GetSubFolder(relativeFolderName(folder.mirrors[mirrorIndex], subFolder))
+	}
+	return mirrored
+}
+
+// mirrorFatalError stops the failover to the next mirror
+type mirrorFatalError struct {
+	error
+}
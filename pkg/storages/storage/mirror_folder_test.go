@@ -0,0 +1,134 @@
+package storage_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+var errBrokenStorage = errors.New("broken storage")
+
+// brokenFolder fails every read and write
+type brokenFolder struct {
+	storage.Folder
+}
+
+func (folder brokenFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return brokenFolder{folder.Folder.GetSubFolder(subFolderRelativePath)}
+}
+
+func (folder brokenFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	return nil, nil, errBrokenStorage
+}
+
+func (folder brokenFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return nil, errBrokenStorage
+}
+
+func (folder brokenFolder) PutObject(name string, content io.Reader) error {
+	return errBrokenStorage
+}
+
+func readAll(t *testing.T, folder storage.Folder, name string) string {
+	reader, err := folder.ReadObject(name)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestMirrorFolder(t *testing.T) {
+	storage.RunFolderTest(storage.NewMirrorFolder(storage.MirrorWriteAll,
+		memory.NewFolder("in_memory/", memory.NewStorage()),
+		memory.NewFolder("in_memory/", memory.NewStorage())), t)
+}
+
+func TestMirrorFolder_WritesToAllMirrors(t *testing.T) {
+	primary := memory.NewFolder("in_memory/", memory.NewStorage())
+	mirror := memory.NewFolder("in_memory/", memory.NewStorage())
+	folder := storage.NewMirrorFolder(storage.MirrorWriteAll, primary, mirror)
+
+	token := make([]byte, 1024*1024)
+	rand.Read(token)
+	err := folder.GetSubFolder("sub").PutObject("file", bytes.NewReader(token))
+	assert.NoError(t, err)
+	assert.Equal(t, string(token), readAll(t, primary, "sub/file"))
+	assert.Equal(t, string(token), readAll(t, mirror, "sub/file"))
+
+	err = folder.DeleteObjects([]string{"sub/file"})
+	assert.NoError(t, err)
+	exists, err := mirror.Exists("sub/file")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMirrorFolder_ReadsFromNextMirrorOnFailure(t *testing.T) {
+	mirror := memory.NewFolder("in_memory/", memory.NewStorage())
+	assert.NoError(t, mirror.PutObject("sub/file", strings.NewReader("data")))
+	folder := storage.NewMirrorFolder(storage.MirrorWriteAll, brokenFolder{mirror}, mirror)
+
+	assert.Equal(t, "data", readAll(t, folder, "sub/file"))
+
+	objects, subFolders, err := folder.GetSubFolder("sub").ListFolder()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(objects))
+	assert.Equal(t, 0, len(subFolders))
+}
+
+func TestMirrorFolder_ReadsFromNextMirrorIfNotFound(t *testing.T) {
+	primary := memory.NewFolder("in_memory/", memory.NewStorage())
+	mirror := memory.NewFolder("in_memory/", memory.NewStorage())
+	assert.NoError(t, mirror.PutObject("file", strings.NewReader("data")))
+	folder := storage.NewMirrorFolder(storage.MirrorWriteQuorum, primary, mirror)
+
+	assert.Equal(t, "data", readAll(t, folder, "file"))
+	exists, err := folder.Exists("file")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestMirrorFolder_NotFoundOnAllMirrors(t *testing.T) {
+	folder := storage.NewMirrorFolder(storage.MirrorWriteQuorum,
+		memory.NewFolder("in_memory/", memory.NewStorage()),
+		memory.NewFolder("in_memory/", memory.NewStorage()))
+
+	_, err := folder.ReadObject("file")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+	exists, err := folder.Exists("file")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	folder = storage.NewMirrorFolder(storage.MirrorWriteQuorum,
+		memory.NewFolder("in_memory/", memory.NewStorage()),
+		brokenFolder{memory.NewFolder("in_memory/", memory.NewStorage())})
+	_, err = folder.ReadObject("file")
+	assert.Equal(t, errBrokenStorage, err)
+}
+
+func TestMirrorFolder_WritePolicies(t *testing.T) {
+	newMirrors := func() []storage.Folder {
+		return []storage.Folder{
+			memory.NewFolder("in_memory/", memory.NewStorage()),
+			brokenFolder{memory.NewFolder("in_memory/", memory.NewStorage())},
+			memory.NewFolder("in_memory/", memory.NewStorage()),
+		}
+	}
+
+	mirrors := newMirrors()
+	err := storage.NewMirrorFolder(storage.MirrorWriteAll, mirrors...).PutObject("file", strings.NewReader("data"))
+	assert.Error(t, err)
+
+	mirrors = newMirrors()
+	err = storage.NewMirrorFolder(storage.MirrorWriteQuorum, mirrors...).PutObject("file", strings.NewReader("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, "data", readAll(t, mirrors[0], "file"))
+	assert.Equal(t, "data", readAll(t, mirrors[2], "file"))
+}
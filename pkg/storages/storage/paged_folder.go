@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultListPageSize is the size of the listing pages of the storages without native paging
+const DefaultListPageSize = 1000
+
+// ListOptions narrow down the listing of a folder.
+type ListOptions struct {
+	// Recursive makes the listing include objects of all the nested subfolders instead of the subfolders
+	// themselves. Objects are named relative to the listed folder then, e.g. "sub/object".
+	Recursive bool
+	// Prefix limits the listing to the names which start with it.
+	Prefix string
+	// StartAfter limits the listing to the names which are lexicographically greater than it.
+	// It allows to resume an interrupted listing from the last received object.
+	StartAfter string
+}
+
+// ObjectMatches checks whether the object with the relative name should be listed
+func (options ListOptions) ObjectMatches(name string) bool {
+	return strings.HasPrefix(name, options.Prefix) && name > options.StartAfter
+}
+
+// SubFolderMatches checks whether the subfolder with the relative name may contain listed names
+func (options ListOptions) SubFolderMatches(name string) bool {
+	name = AddDelimiterToPath(strings.TrimSuffix(name, "/"))
+	matchesPrefix := strings.HasPrefix(name, options.Prefix) || strings.HasPrefix(options.Prefix, name)
+	matchesStart := name > options.StartAfter || strings.HasPrefix(options.StartAfter, name)
+	return matchesPrefix && matchesStart
+}
+
+// ListPageHandler receives the folder listing page by page.
+// The listing is stopped if the handler returns an error, the error is returned from the listing then.
+type ListPageHandler func(objects []Object, subFolders []Folder) error
+
+// PagedFolder is able to list its contents page by page,
+// so the whole listing of a huge folder never has to be kept in memory.
+type PagedFolder interface {
+	Folder
+
+	// ListFolderPages passes the listing to the handler page by page.
+	// Objects are passed in lexicographical order of their names.
+	ListFolderPages(ctx context.Context, options ListOptions, handler ListPageHandler) error
+}
+
+// ListFolderPages lists the folder page by page, natively if the folder supports it.
+// Other folders are listed folder by folder in breadth-first order.
+func ListFolderPages(ctx context.Context, folder Folder, options ListOptions, handler ListPageHandler) error {
+	if pagedFolder, ok := folder.(PagedFolder); ok {
+		return pagedFolder.ListFolderPages(ctx, options, handler)
+	}
+	type queuedFolder struct {
+		folder Folder
+		prefix string
+	}
+	filtered := options.Prefix != "" || options.StartAfter != ""
+	pager := NewListPager(handler)
+	queue := []queuedFolder{{folder, ""}}
+	for len(queue) > 0 {
+		subFolder := queue[0]
+		queue = queue[1:]
+		objects, subFolders, err := NewContextFolder(subFolder.folder).ListFolderWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		sort.Slice(objects, func(i, j int) bool {
+			return objects[i].GetName() < objects[j].GetName()
+		})
+		for _, object := range objects {
+			if !options.ObjectMatches(subFolder.prefix + object.GetName()) {
+				continue
+			}
+			if subFolder.prefix != "" {
+				object = NewLocalObject(subFolder.prefix+object.GetName(), object.GetLastModified(), object.GetSize())
+			}
+			if err = pager.AddObject(object); err != nil {
+				return err
+			}
+		}
+		for _, nestedFolder := range subFolders {
+			// relative names are built only when needed, not every folder has a meaningful path
+			var nestedName string
+			if options.Recursive || filtered {
+				nestedName = relativeFolderName(folder, nestedFolder)
+				if !options.SubFolderMatches(nestedName) {
+					continue
+				}
+			}
+			if options.Recursive {
+				queue = append(queue, queuedFolder{nestedFolder, nestedName + "/"})
+			} else if err = pager.AddSubFolder(nestedFolder); err != nil {
+				return err
+			}
+		}
+	}
+	return pager.Flush()
+}
+
+// SortFileInfos orders directory entries the way their full paths are ordered in object storages,
+// i.e. the directory "a" goes after the file "a-b" since "a/" > "a-b"
+func SortFileInfos(files []os.FileInfo) {
+	sortKey := func(file os.FileInfo) string {
+		if file.IsDir() {
+			return file.Name() + "/"
+		}
+		return file.Name()
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return sortKey(files[i]) < sortKey(files[j])
+	})
+}
+
+func relativeFolderName(folder Folder, subFolder Folder) string {
+	return strings.Trim(strings.TrimPrefix(subFolder.GetPath(), folder.GetPath()), "/")
+}
+
+// ListPager collects the listing of the storages without native paging into pages
+type ListPager struct {
+	handler    ListPageHandler
+	objects    []Object
+	subFolders []Folder
+}
+
+func NewListPager(handler ListPageHandler) *ListPager {
+	return &ListPager{handler: handler}
+}
+
+func (pager *ListPager) AddObject(object Object) error {
+	pager.objects = append(pager.objects, object)
+	return pager.flushIfFull()
+}
+
+func (pager *ListPager) AddSubFolder(subFolder Folder) error {
+	pager.subFolders = append(pager.subFolders, subFolder)
+	return pager.flushIfFull()
+}
+
+// Flush passes the collected entries to the handler, it has to be called when the listing is over
+func (pager *ListPager) Flush() error {
+	if len(pager.objects) == 0 && len(pager.subFolders) == 0 {
+		return nil
+	}
+	objects, subFolders := pager.objects, pager.subFolders
+	pager.objects, pager.subFolders = nil, nil
+	return pager.handler(objects, subFolders)
+}
+
+func (pager *ListPager) flushIfFull() error {
+	if len(pager.objects)+len(pager.subFolders) < DefaultListPageSize {
+		return nil
+	}
+	return pager.Flush()
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// PendingUpload is an upload which was started but neither completed nor aborted,
+// e.g. the upload of a killed process. Its parts occupy the storage until it is aborted.
+type PendingUpload struct {
+	// Name is the path of the uploaded object relative to the listed folder
+	Name string
+	// ID identifies the upload in the storage
+	ID        string
+	Initiated time.Time
+}
+
+// PendingUploadFolder is a Folder which uploads the objects in parts,
+// so that the interrupted uploads leave the parts in the storage.
+type PendingUploadFolder interface {
+	Folder
+
+	// ListPendingUploads returns the pending uploads of the objects in the folder and all its subfolders
+	ListPendingUploads(ctx context.Context) ([]PendingUpload, error)
+
+	// AbortPendingUpload removes the uploaded parts, aborting the upload which is not pending anymore is not an error
+	AbortPendingUpload(ctx context.Context, upload PendingUpload) error
+}
+
+// ListPendingUploads returns the pending uploads if the folder uploads the objects in parts,
+// the uploads of the other folders are never left pending.
+func ListPendingUploads(ctx context.Context, folder Folder) ([]PendingUpload, error) {
+	if uploadFolder, ok := folder.(PendingUploadFolder); ok {
+		return uploadFolder.ListPendingUploads(ctx)
+	}
+	return nil, nil
+}
+
+// AbortPendingUpload aborts the upload if the folder uploads the objects in parts, there is nothing to abort otherwise
+func AbortPendingUpload(ctx context.Context, folder Folder, upload PendingUpload) error {
+	if uploadFolder, ok := folder.(PendingUploadFolder); ok {
+		return uploadFolder.AbortPendingUpload(ctx, upload)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// RangeFolder is a Folder able to read a part of an object without downloading the whole object.
+type RangeFolder interface {
+	Folder
+
+	// ReadObjectRange reads length bytes of the object starting at offset.
+	// A negative length means reading up to the end of the object.
+	// Should return ObjectNotFoundError in case, there is no such object.
+	ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error)
+
+	ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+		offset, length int64) (io.ReadCloser, error)
+}
+
+// ReadObjectRange reads a part of the object, natively if the folder supports it.
+func ReadObjectRange(folder Folder, objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return ReadObjectRangeWithContext(context.Background(), folder, objectRelativePath, offset, length)
+}
+
+// ReadObjectRangeWithContext reads a part of the object, natively if the folder supports it.
+// Otherwise the object is read from the beginning and the bytes before offset are discarded.
+func ReadObjectRangeWithContext(ctx context.Context, folder Folder, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if rangeFolder, ok := folder.(RangeFolder); ok {
+		return rangeFolder.ReadObjectRangeWithContext(ctx, objectRelativePath, offset, length)
+	}
+	reader, err := NewContextFolder(folder).ReadObjectWithContext(ctx, objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		_, err = io.CopyN(ioutil.Discard, reader, offset)
+		if err != nil && err != io.EOF {
+			_ = reader.Close()
+			return nil, err
+		}
+	}
+	return NewLimitedReadCloser(reader, length), nil
+}
+
+// NewLimitedReadCloser stops reading after length bytes, a negative length leaves readCloser unlimited.
+// It is a helper for RangeFolder implementations which are not able to limit the length on their own.
+func NewLimitedReadCloser(readCloser io.ReadCloser, length int64) io.ReadCloser {
+	if length < 0 {
+		return readCloser
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(readCloser, length), readCloser}
+}
+
+// ReadEmptyRange checks that the object exists and returns the empty reader.
+// It is a helper for RangeFolder implementations which are not able to request the empty range.
+func ReadEmptyRange(ctx context.Context, folder Folder, objectRelativePath string) (io.ReadCloser, error) {
+	exists, err := NewContextFolder(folder).ExistsWithContext(ctx, objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, NewObjectNotFoundError(JoinPath(folder.GetPath(), objectRelativePath))
+	}
+	return ioutil.NopCloser(strings.NewReader("")), nil
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// RequestLimiters limit the rates of the requests of every kind, a nil limiter doesn't limit its requests
+type RequestLimiters struct {
+	List   *rate.Limiter
+	Read   *rate.Limiter
+	Write  *rate.Limiter
+	Delete *rate.Limiter
+}
+
+// NewRequestLimiter allows requestsPerSecond requests on average, and as many requests at once
+func NewRequestLimiter(requestsPerSecond float64) *rate.Limiter {
+	burst := int(requestsPerSecond)
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+}
+
+// RateLimitFolder delays the storage requests so that their rates don't exceed the limits.
+// The subfolders share the limiters of their parent, so the limits apply to the whole storage.
+// Checks of object existence, locks and storage classes count as reads, copies and changes of storage classes as writes,
+// every page of a paged listing counts as a separate list request and every DeleteObjects call as a single delete.
+type RateLimitFolder struct {
+	folder   ContextFolder
+	limiters RequestLimiters
+}
+
+func NewRateLimitFolder(folder Folder, limiters RequestLimiters) *RateLimitFolder {
+	return &RateLimitFolder{NewContextFolder(folder), limiters}
+}
+
+func (folder *RateLimitFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *RateLimitFolder) GetSubFolder(subFolderRelativePath string) Folder {
+	return NewRateLimitFolder(folder.folder.GetSubFolder(subFolderRelativePath), folder.limiters)
+}
+
+func (folder *RateLimitFolder) ListFolder() (objects []Object, subFolders []Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *RateLimitFolder) ListFolderWithContext(ctx context.Context) (objects []Object,
+	subFolders []Folder, err error) {
+	if err = waitForRequest(ctx, folder.limiters.List); err != nil {
+		return nil, nil, err
+	}
+	objects, subFolders, err = folder.folder.ListFolderWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range subFolders {
+		subFolders[i] = NewRateLimitFolder(subFolders[i], folder.limiters)
+	}
+	return objects, subFolders, nil
+}
+
+// ListFolderPages waits before the listing and after every page, since the next page is requested after it is handled
+func (folder *RateLimitFolder) ListFolderPages(ctx context.Context, options ListOptions, handler ListPageHandler) error {
+	if err := waitForRequest(ctx, folder.limiters.List); err != nil {
+		return err
+	}
+	return ListFolderPages(ctx, folder.folder, options, func(objects []Object, subFolders []Folder) error {
+		for i := range subFolders {
+			subFolders[i] = NewRateLimitFolder(subFolders[i], folder.limiters)
+		}
+		if err := handler(objects, subFolders); err != nil {
+			return err
+		}
+		return waitForRequest(ctx, folder.limiters.List)
+	})
+}
+
+func (folder *RateLimitFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *RateLimitFolder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	if err := waitForRequest(ctx, folder.limiters.Delete); err != nil {
+		return err
+	}
+	return folder.folder.DeleteObjectsWithContext(ctx, objectRelativePaths)
+}
+
+func (folder *RateLimitFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *RateLimitFolder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	if err := waitForRequest(ctx, folder.limiters.Read); err != nil {
+		return false, err
+	}
+	return folder.folder.ExistsWithContext(ctx, objectRelativePath)
+}
+
+func (folder *RateLimitFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *RateLimitFolder) ReadObjectWithContext(ctx context.Context,
+	objectRelativePath string) (io.ReadCloser, error) {
+	if err := waitForRequest(ctx, folder.limiters.Read); err != nil {
+		return nil, err
+	}
+	return folder.folder.ReadObjectWithContext(ctx, objectRelativePath)
+}
+
+func (folder *RateLimitFolder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *RateLimitFolder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if err := waitForRequest(ctx, folder.limiters.Read); err != nil {
+		return nil, err
+	}
+	return ReadObjectRangeWithContext(ctx, folder.folder, objectRelativePath, offset, length)
+}
+
+func (folder *RateLimitFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *RateLimitFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	if err := waitForRequest(ctx, folder.limiters.Write); err != nil {
+		return err
+	}
+	return folder.folder.PutObjectWithContext(ctx, name, content)
+}
+
+func (folder *RateLimitFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *RateLimitFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	if err := waitForRequest(ctx, folder.limiters.Write); err != nil {
+		return err
+	}
+	return folder.folder.CopyObjectWithContext(ctx, srcPath, dstPath)
+}
+
+func (folder *RateLimitFolder) StatObject(ctx context.Context, objectRelativePath string) (Object, error) {
+	if err := waitForRequest(ctx, folder.limiters.Read); err != nil {
+		return nil, err
+	}
+	return StatObject(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *RateLimitFolder) GetObjectLock(ctx context.Context, objectRelativePath string) (ObjectLock, error) {
+	if err := waitForRequest(ctx, folder.limiters.Read); err != nil {
+		return ObjectLock{}, err
+	}
+	return GetObjectLock(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *RateLimitFolder) GetStorageClass(ctx context.Context, objectRelativePath string) (StorageClass, error) {
+	if err := waitForRequest(ctx, folder.limiters.Read); err != nil {
+		return StorageClass{}, err
+	}
+	return GetStorageClass(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *RateLimitFolder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	if err := waitForRequest(ctx, folder.limiters.Write); err != nil {
+		return err
+	}
+	return SetStorageClass(ctx, folder.folder, objectRelativePath, storageClass)
+}
+
+func (folder *RateLimitFolder) ListPendingUploads(ctx context.Context) ([]PendingUpload, error) {
+	if err := waitForRequest(ctx, folder.limiters.List); err != nil {
+		return nil, err
+	}
+	return ListPendingUploads(ctx, folder.folder)
+}
+
+func (folder *RateLimitFolder) AbortPendingUpload(ctx context.Context, upload PendingUpload) error {
+	if err := waitForRequest(ctx, folder.limiters.Delete); err != nil {
+		return err
+	}
+	return AbortPendingUpload(ctx, folder.folder, upload)
+}
+
+func waitForRequest(ctx context.Context, limiter *rate.Limiter) error {
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
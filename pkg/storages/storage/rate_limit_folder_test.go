@@ -0,0 +1,43 @@
+package storage_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitFolder(t *testing.T) {
+	storage.RunFolderTest(storage.NewRateLimitFolder(memory.NewFolder("in_memory/", memory.NewStorage()),
+		storage.RequestLimiters{Read: storage.NewRequestLimiter(1000)}), t)
+}
+
+func TestRateLimitFolder_DelaysRequests(t *testing.T) {
+	limiters := storage.RequestLimiters{Write: rate.NewLimiter(rate.Every(50*time.Millisecond), 1)}
+	folder := storage.NewRateLimitFolder(memory.NewFolder("in_memory/", memory.NewStorage()), limiters)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		// the subfolders share the limit of the storage
+		err := folder.GetSubFolder("sub").PutObject("file", strings.NewReader("data"))
+		assert.NoError(t, err)
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+}
+
+func TestRateLimitFolder_StopsWaitingOnCancel(t *testing.T) {
+	limiters := storage.RequestLimiters{Delete: rate.NewLimiter(rate.Every(time.Hour), 1)}
+	folder := storage.NewRateLimitFolder(memory.NewFolder("in_memory/", memory.NewStorage()), limiters)
+	contextFolder := storage.NewContextFolder(folder)
+
+	assert.NoError(t, contextFolder.DeleteObjectsWithContext(context.Background(), []string{"file"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, contextFolder.DeleteObjectsWithContext(ctx, []string{"file"}))
+}
@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// RetryPolicy defines how many times and how often failed storage operations are repeated.
+// The pause between attempts starts at MinBackoff and doubles after every attempt up to MaxBackoff,
+// every pause is randomized so that concurrent clients don't retry simultaneously.
+type RetryPolicy struct {
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MinBackoff: 100 * time.Millisecond,
+	MaxBackoff: 10 * time.Second,
+}
+
+// backoff returns the pause before the retry number attempt (starting from 0)
+func (policy RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := policy.MinBackoff
+	for i := 0; i < attempt && backoff < policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	// "equal jitter": keep at least a half of the pause, randomize the rest
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// RetryStats counts the retries made by RetryFolders, it is safe for concurrent use
+type RetryStats struct {
+	retries  int64
+	failures int64
+}
+
+func NewRetryStats() *RetryStats {
+	return &RetryStats{}
+}
+
+// Retries is the number of repeated operation attempts
+func (stats *RetryStats) Retries() int64 {
+	return atomic.LoadInt64(&stats.retries)
+}
+
+// Failures is the number of operations which failed after all the retries
+func (stats *RetryStats) Failures() int64 {
+	return atomic.LoadInt64(&stats.failures)
+}
+
+// IsRetriableError tells whether the failed storage operation may succeed if it is repeated.
+// Missing objects, access errors and client errors reported by the storage API are permanent.
+func IsRetriableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var notFoundErr ObjectNotFoundError
+	if errors.As(err, &notFoundErr) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if os.IsNotExist(errors.Cause(err)) || os.IsPermission(errors.Cause(err)) {
+		return false
+	}
+	var temporaryErr interface{ Temporary() bool }
+	if errors.As(err, &temporaryErr) && temporaryErr.Temporary() {
+		return true
+	}
+	// the errors of the S3 and similar HTTP APIs
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode()
+		if code >= 400 && code < 500 {
+			return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+		}
+	}
+	return true
+}
+
+// RetryFolder repeats the storage operations which failed with transient errors.
+// Uploads are repeated only if the content is an io.Seeker, since otherwise
+// the part of the content read by the failed attempt is lost.
+// Reads are repeated until the object is opened, the failures in the middle of the body are not retried.
+type RetryFolder struct {
+	folder ContextFolder
+	policy RetryPolicy
+	stats  *RetryStats
+}
+
+// NewRetryFolder wraps the folder, the retries are counted in stats which may be shared by several folders
+func NewRetryFolder(folder Folder, policy RetryPolicy, stats *RetryStats) *RetryFolder {
+	return &RetryFolder{NewContextFolder(folder), policy, stats}
+}
+
+func (folder *RetryFolder) Stats() *RetryStats {
+	return folder.stats
+}
+
+func (folder *RetryFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *RetryFolder) GetSubFolder(subFolderRelativePath string) Folder {
+	return NewRetryFolder(folder.folder.GetSubFolder(subFolderRelativePath), folder.policy, folder.stats)
+}
+
+func (folder *RetryFolder) ListFolder() (objects []Object, subFolders []Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *RetryFolder) ListFolderWithContext(ctx context.Context) (objects []Object,
+	subFolders []Folder, err error) {
+	err = folder.retry(ctx, "list folder", func() error {
+		var listErr error
+		objects, subFolders, listErr = folder.folder.ListFolderWithContext(ctx)
+		return listErr
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range subFolders {
+		subFolders[i] = NewRetryFolder(subFolders[i], folder.policy, folder.stats)
+	}
+	return objects, subFolders, nil
+}
+
+// ListFolderPages repeats the listing only until the first page is passed to the handler
+func (folder *RetryFolder) ListFolderPages(ctx context.Context, options ListOptions, handler ListPageHandler) error {
+	return folder.retry(ctx, "list folder", func() error {
+		pagesHandled := false
+		err := ListFolderPages(ctx, folder.folder, options, func(objects []Object, subFolders []Folder) error {
+			pagesHandled = true
+			for i := range subFolders {
+				subFolders[i] = NewRetryFolder(subFolders[i], folder.policy, folder.stats)
+			}
+			return handler(objects, subFolders)
+		})
+		if err != nil && pagesHandled {
+			return &permanentError{err}
+		}
+		return err
+	})
+}
+
+func (folder *RetryFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *RetryFolder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	return folder.retry(ctx, "delete objects", func() error {
+		return folder.folder.DeleteObjectsWithContext(ctx, objectRelativePaths)
+	})
+}
+
+func (folder *RetryFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *RetryFolder) ExistsWithContext(ctx context.Context, objectRelativePath string) (exists bool, err error) {
+	err = folder.retry(ctx, "check "+objectRelativePath, func() error {
+		var existsErr error
+		exists, existsErr = folder.folder.ExistsWithContext(ctx, objectRelativePath)
+		return existsErr
+	})
+	return exists, err
+}
+
+func (folder *RetryFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *RetryFolder) ReadObjectWithContext(ctx context.Context,
+	objectRelativePath string) (reader io.ReadCloser, err error) {
+	err = folder.retry(ctx, "read "+objectRelativePath, func() error {
+		var readErr error
+		reader, readErr = folder.folder.ReadObjectWithContext(ctx, objectRelativePath)
+		return readErr
+	})
+	return reader, err
+}
+
+func (folder *RetryFolder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *RetryFolder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (reader io.ReadCloser, err error) {
+	err = folder.retry(ctx, "read "+objectRelativePath, func() error {
+		var readErr error
+		reader, readErr = ReadObjectRangeWithContext(ctx, folder.folder, objectRelativePath, offset, length)
+		return readErr
+	})
+	return reader, err
+}
+
+func (folder *RetryFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *RetryFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	seeker, ok := content.(io.Seeker)
+	if !ok {
+		return folder.folder.PutObjectWithContext(ctx, name, content)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return folder.folder.PutObjectWithContext(ctx, name, content)
+	}
+	attempt := 0
+	return folder.retry(ctx, "put "+name, func() error {
+		if attempt > 0 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return &permanentError{errors.Wrapf(err, "failed to rewind the content of '%s'", name)}
+			}
+		}
+		attempt++
+		return folder.folder.PutObjectWithContext(ctx, name, content)
+	})
+}
+
+func (folder *RetryFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *RetryFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	return folder.retry(ctx, "copy "+srcPath+" to "+dstPath, func() error {
+		return folder.folder.CopyObjectWithContext(ctx, srcPath, dstPath)
+	})
+}
+
+func (folder *RetryFolder) StatObject(ctx context.Context, objectRelativePath string) (object Object, err error) {
+	err = folder.retry(ctx, "stat "+objectRelativePath, func() error {
+		var statErr error
+		object, statErr = StatObject(ctx, folder.folder, objectRelativePath)
+		return statErr
+	})
+	return object, err
+}
+
+func (folder *RetryFolder) GetObjectLock(ctx context.Context, objectRelativePath string) (lock ObjectLock, err error) {
+	err = folder.retry(ctx, "get lock of "+objectRelativePath, func() error {
+		var lockErr error
+		lock, lockErr = GetObjectLock(ctx, folder.folder, objectRelativePath)
+		return lockErr
+	})
+	return lock, err
+}
+
+func (folder *RetryFolder) GetStorageClass(ctx context.Context,
+	objectRelativePath string) (storageClass StorageClass, err error) {
+	err = folder.retry(ctx, "get storage class of "+objectRelativePath, func() error {
+		var classErr error
+		storageClass, classErr = GetStorageClass(ctx, folder.folder, objectRelativePath)
+		return classErr
+	})
+	return storageClass, err
+}
+
+func (folder *RetryFolder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	return folder.retry(ctx, "set storage class of "+objectRelativePath, func() error {
+		return SetStorageClass(ctx, folder.folder, objectRelativePath, storageClass)
+	})
+}
+
+func (folder *RetryFolder) ListPendingUploads(ctx context.Context) (uploads []PendingUpload, err error) {
+	err = folder.retry(ctx, "list pending uploads", func() error {
+		var listErr error
+		uploads, listErr = ListPendingUploads(ctx, folder.folder)
+		return listErr
+	})
+	return uploads, err
+}
+
+func (folder *RetryFolder) AbortPendingUpload(ctx context.Context, upload PendingUpload) error {
+	return folder.retry(ctx, "abort upload of "+upload.Name, func() error {
+		return AbortPendingUpload(ctx, folder.folder, upload)
+	})
+}
+
+func (folder *RetryFolder) retry(ctx context.Context, operation string, op func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		if permanentErr, ok := err.(*permanentError); ok {
+			return permanentErr.error
+		}
+		// an expired deadline of a single attempt is worth retrying, unless it is the deadline of the caller
+		if !IsRetriableError(err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= folder.policy.MaxRetries {
+			atomic.AddInt64(&folder.stats.failures, 1)
+			return errors.Wrapf(err, "failed to %s after %d attempts", operation, attempt+1)
+		}
+		backoff := folder.policy.backoff(attempt)
+		tracelog.WarningLogger.Printf("Failed to %s in '%s', retrying in %v: %v\n",
+			operation, folder.GetPath(), backoff, err)
+		atomic.AddInt64(&folder.stats.retries, 1)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// permanentError stops the retries of an operation
+type permanentError struct {
+	error
+}
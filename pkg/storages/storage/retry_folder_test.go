@@ -0,0 +1,121 @@
+package storage_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+var testRetryPolicy = storage.RetryPolicy{
+	MaxRetries: 2,
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 2 * time.Millisecond,
+}
+
+// flakyFolder fails the first failures calls of every operation
+type flakyFolder struct {
+	storage.Folder
+	failures int
+	calls    map[string]int
+}
+
+func newFlakyFolder(failures int) *flakyFolder {
+	return &flakyFolder{memory.NewFolder("in_memory/", memory.NewStorage()), failures, map[string]int{}}
+}
+
+func (folder *flakyFolder) fail(operation string) bool {
+	folder.calls[operation]++
+	return folder.calls[operation] <= folder.failures
+}
+
+func (folder *flakyFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	if folder.fail("read") {
+		return nil, errBrokenStorage
+	}
+	return folder.Folder.ReadObject(objectRelativePath)
+}
+
+func (folder *flakyFolder) PutObject(name string, content io.Reader) error {
+	if folder.fail("put") {
+		// consume a part of the content like an interrupted upload does
+		_, _ = io.CopyN(ioutil.Discard, content, 2)
+		return errBrokenStorage
+	}
+	return folder.Folder.PutObject(name, content)
+}
+
+type statusError struct {
+	code int
+}
+
+func (err statusError) Error() string {
+	return http.StatusText(err.code)
+}
+
+func (err statusError) StatusCode() int {
+	return err.code
+}
+
+func TestRetryFolder(t *testing.T) {
+	storage.RunFolderTest(storage.NewRetryFolder(memory.NewFolder("in_memory/", memory.NewStorage()),
+		testRetryPolicy, storage.NewRetryStats()), t)
+}
+
+func TestRetryFolder_RetriesTransientErrors(t *testing.T) {
+	flaky := newFlakyFolder(2)
+	folder := storage.NewRetryFolder(flaky, testRetryPolicy, storage.NewRetryStats())
+
+	err := folder.PutObject("file", strings.NewReader("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, "data", readAll(t, folder, "file"))
+	assert.Equal(t, 3, flaky.calls["put"])
+	assert.Equal(t, 3, flaky.calls["read"])
+	assert.Equal(t, int64(4), folder.Stats().Retries())
+	assert.Equal(t, int64(0), folder.Stats().Failures())
+}
+
+func TestRetryFolder_GivesUp(t *testing.T) {
+	flaky := newFlakyFolder(3)
+	folder := storage.NewRetryFolder(flaky, testRetryPolicy, storage.NewRetryStats())
+
+	_, err := folder.ReadObject("file")
+	assert.Error(t, err)
+	assert.Equal(t, 3, flaky.calls["read"])
+	assert.Equal(t, int64(1), folder.Stats().Failures())
+}
+
+func TestRetryFolder_DoesNotRetryMissingObjects(t *testing.T) {
+	flaky := newFlakyFolder(0)
+	folder := storage.NewRetryFolder(flaky, testRetryPolicy, storage.NewRetryStats())
+
+	_, err := folder.ReadObject("file")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+	assert.Equal(t, 1, flaky.calls["read"])
+	assert.Equal(t, int64(0), folder.Stats().Retries())
+}
+
+func TestRetryFolder_DoesNotRetryUnseekableContent(t *testing.T) {
+	flaky := newFlakyFolder(1)
+	folder := storage.NewRetryFolder(flaky, testRetryPolicy, storage.NewRetryStats())
+
+	err := folder.PutObject("file", ioutil.NopCloser(bytes.NewReader([]byte("data"))))
+	assert.Error(t, err)
+	assert.Equal(t, 1, flaky.calls["put"])
+}
+
+func TestIsRetriableError(t *testing.T) {
+	assert.False(t, storage.IsRetriableError(nil))
+	assert.False(t, storage.IsRetriableError(storage.NewObjectNotFoundError("file")))
+	assert.False(t, storage.IsRetriableError(storage.NewError(statusError{http.StatusForbidden}, "S3", "failed")))
+	assert.True(t, storage.IsRetriableError(storage.NewError(statusError{http.StatusTooManyRequests}, "S3", "failed")))
+	assert.True(t, storage.IsRetriableError(statusError{http.StatusServiceUnavailable}))
+	assert.True(t, storage.IsRetriableError(errBrokenStorage))
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// StatFolder is a Folder able to get the size and the modification time of a single object
+// with one request, e.g. by S3 HEAD, instead of listing the folder.
+type StatFolder interface {
+	Folder
+
+	// StatObject returns the object named by its relative path.
+	// Should return ObjectNotFoundError in case, there is no such object.
+	StatObject(ctx context.Context, objectRelativePath string) (Object, error)
+}
+
+// StatObject gets the object natively if the folder supports it.
+// Otherwise the folder is listed with the object path as a prefix.
+func StatObject(ctx context.Context, folder Folder, objectRelativePath string) (Object, error) {
+	if statFolder, ok := folder.(StatFolder); ok {
+		return statFolder.StatObject(ctx, objectRelativePath)
+	}
+	var found Object
+	errFound := errors.New("object found")
+	options := ListOptions{Recursive: true, Prefix: objectRelativePath}
+	err := ListFolderPages(ctx, folder, options, func(objects []Object, _ []Folder) error {
+		for _, object := range objects {
+			if object.GetName() == objectRelativePath {
+				found = object
+				return errFound
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errFound {
+		return nil, err
+	}
+	if found == nil {
+		return nil, NewObjectNotFoundError(JoinPath(folder.GetPath(), objectRelativePath))
+	}
+	return found, nil
+}
@@ -2,8 +2,11 @@ package storage
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io/ioutil"
 	"math/rand"
+	"sort"
 	"strings"
 	"testing"
 
@@ -96,4 +99,119 @@ func RunFolderTest(storageFolder Folder, t *testing.T) {
 
 	_, err = sub1.ReadObject("Tumba Yumba")
 	assert.Error(t, err.(ObjectNotFoundError))
+
+	runContextFolderTest(storageFolder, t)
+	runRangeFolderTest(storageFolder, t)
+	runPagedFolderTest(storageFolder, t)
+}
+
+func runContextFolderTest(storageFolder Folder, t *testing.T) {
+	contextFolder := NewContextFolder(storageFolder)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := contextFolder.PutObjectWithContext(ctx, "file3", strings.NewReader("data3"))
+	assert.NoError(t, err)
+	readCloser, err := contextFolder.ReadObjectWithContext(ctx, "file3")
+	assert.NoError(t, err)
+	all, err := ioutil.ReadAll(readCloser)
+	assert.NoError(t, err)
+	assert.Equal(t, "data3", string(all))
+	assert.NoError(t, readCloser.Close())
+
+	cancel()
+	_, err = contextFolder.ReadObjectWithContext(ctx, "file3")
+	assert.Error(t, err)
+	err = contextFolder.PutObjectWithContext(ctx, "file4", strings.NewReader("data4"))
+	assert.Error(t, err)
+	_, _, err = contextFolder.ListFolderWithContext(ctx)
+	assert.Error(t, err)
+
+	err = contextFolder.DeleteObjects([]string{"file3", "file4"})
+	assert.NoError(t, err)
+}
+
+func runRangeFolderTest(storageFolder Folder, t *testing.T) {
+	err := storageFolder.PutObject("file5", strings.NewReader("0123456789"))
+	assert.NoError(t, err)
+
+	for _, testCase := range []struct {
+		offset   int64
+		length   int64
+		expected string
+	}{
+		{0, -1, "0123456789"},
+		{0, 3, "012"},
+		{4, 3, "456"},
+		{7, -1, "789"},
+		{7, 100, "789"},
+		{5, 0, ""},
+	} {
+		readCloser, err := ReadObjectRange(storageFolder, "file5", testCase.offset, testCase.length)
+		assert.NoError(t, err)
+		all, err := ioutil.ReadAll(readCloser)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, string(all))
+		assert.NoError(t, readCloser.Close())
+	}
+
+	_, err = ReadObjectRange(storageFolder, "Tumba Yumba", 1, 1)
+	assert.Error(t, err.(ObjectNotFoundError))
+	_, err = ReadObjectRange(storageFolder, "Tumba Yumba", 1, 0)
+	assert.Error(t, err.(ObjectNotFoundError))
+
+	err = storageFolder.DeleteObjects([]string{"file5"})
+	assert.NoError(t, err)
+}
+
+func runPagedFolderTest(storageFolder Folder, t *testing.T) {
+	for _, name := range []string{"page/a", "page/b-1", "page/b/c", "page/b/d/e", "page/f"} {
+		err := storageFolder.PutObject(name, strings.NewReader(name))
+		assert.NoError(t, err)
+	}
+	pageFolder := storageFolder.GetSubFolder("page")
+
+	listNames := func(options ListOptions) (objectNames []string, subFolderCount int) {
+		err := ListFolderPages(context.Background(), pageFolder, options,
+			func(objects []Object, subFolders []Folder) error {
+				for _, object := range objects {
+					objectNames = append(objectNames, object.GetName())
+				}
+				subFolderCount += len(subFolders)
+				return nil
+			})
+		assert.NoError(t, err)
+		sort.Strings(objectNames)
+		return objectNames, subFolderCount
+	}
+
+	names, subFolderCount := listNames(ListOptions{})
+	assert.Equal(t, []string{"a", "b-1", "f"}, names)
+	assert.Equal(t, 1, subFolderCount)
+
+	names, subFolderCount = listNames(ListOptions{Recursive: true})
+	assert.Equal(t, []string{"a", "b-1", "b/c", "b/d/e", "f"}, names)
+	assert.Equal(t, 0, subFolderCount)
+
+	names, _ = listNames(ListOptions{Recursive: true, Prefix: "b"})
+	assert.Equal(t, []string{"b-1", "b/c", "b/d/e"}, names)
+
+	names, _ = listNames(ListOptions{Recursive: true, StartAfter: "b/c"})
+	assert.Equal(t, []string{"b/d/e", "f"}, names)
+
+	stopErr := errors.New("stop")
+	err := ListFolderPages(context.Background(), pageFolder, ListOptions{Recursive: true},
+		func(objects []Object, subFolders []Folder) error {
+			return stopErr
+		})
+	assert.Equal(t, stopErr, err)
+
+	err = DeleteObjectsWhere(pageFolder, true, func(object Object) bool {
+		return strings.HasPrefix(object.GetName(), "b")
+	})
+	assert.NoError(t, err)
+	names, _ = listNames(ListOptions{Recursive: true})
+	assert.Equal(t, []string{"a", "f"}, names)
+
+	err = pageFolder.DeleteObjects([]string{"a", "f"})
+	assert.NoError(t, err)
 }
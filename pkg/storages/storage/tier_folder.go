@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// StorageClass describes the storage tier the object is kept in
+type StorageClass struct {
+	// Name is the storage specific name of the class, e.g. STANDARD or GLACIER for S3
+	Name string
+	// NeedsRestore is set for the objects of the archive classes which must be restored before they can be read
+	NeedsRestore bool
+}
+
+// TierFolder is a Folder whose objects may be moved between the storage classes,
+// e.g. to the cheaper ones as the objects age.
+type TierFolder interface {
+	Folder
+
+	// GetStorageClass returns the storage class of the object.
+	// Should return ObjectNotFoundError in case, there is no such object.
+	GetStorageClass(ctx context.Context, objectRelativePath string) (StorageClass, error)
+
+	// SetStorageClass moves the object to the storage class keeping its name and content
+	SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error
+}
+
+// GetStorageClass returns the storage class of the object if the folder supports storage classes,
+// the objects of the other folders are always ready to be read.
+func GetStorageClass(ctx context.Context, folder Folder, objectRelativePath string) (StorageClass, error) {
+	if tierFolder, ok := folder.(TierFolder); ok {
+		return tierFolder.GetStorageClass(ctx, objectRelativePath)
+	}
+	return StorageClass{}, nil
+}
+
+// SetStorageClass moves the object to the storage class, it fails if the folder does not support storage classes
+func SetStorageClass(ctx context.Context, folder Folder, objectRelativePath string, storageClass string) error {
+	if tierFolder, ok := folder.(TierFolder); ok {
+		return tierFolder.SetStorageClass(ctx, objectRelativePath, storageClass)
+	}
+	return errors.Errorf("storage folder '%s' does not support storage classes", folder.GetPath())
+}
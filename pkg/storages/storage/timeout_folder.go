@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// TimeoutFolder limits the duration of every storage operation.
+// The deadline covers the whole operation, including transfer of the object body,
+// so the timeout has to be large enough for the biggest object to be uploaded or read.
+type TimeoutFolder struct {
+	folder  ContextFolder
+	timeout time.Duration
+}
+
+func NewTimeoutFolder(folder Folder, timeout time.Duration) *TimeoutFolder {
+	return &TimeoutFolder{NewContextFolder(folder), timeout}
+}
+
+func (folder *TimeoutFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *TimeoutFolder) GetSubFolder(subFolderRelativePath string) Folder {
+	return NewTimeoutFolder(folder.folder.GetSubFolder(subFolderRelativePath), folder.timeout)
+}
+
+func (folder *TimeoutFolder) ListFolder() (objects []Object, subFolders []Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *TimeoutFolder) ListFolderWithContext(ctx context.Context) (objects []Object,
+	subFolders []Folder, err error) {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	objects, subFolders, err = folder.folder.ListFolderWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range subFolders {
+		subFolders[i] = NewTimeoutFolder(subFolders[i], folder.timeout)
+	}
+	return objects, subFolders, nil
+}
+
+// ListFolderPages limits the duration of the whole listing, not of a single page
+func (folder *TimeoutFolder) ListFolderPages(ctx context.Context, options ListOptions, handler ListPageHandler) error {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return ListFolderPages(ctx, folder.folder, options, func(objects []Object, subFolders []Folder) error {
+		for i := range subFolders {
+			subFolders[i] = NewTimeoutFolder(subFolders[i], folder.timeout)
+		}
+		return handler(objects, subFolders)
+	})
+}
+
+func (folder *TimeoutFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *TimeoutFolder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return folder.folder.DeleteObjectsWithContext(ctx, objectRelativePaths)
+}
+
+func (folder *TimeoutFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *TimeoutFolder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return folder.folder.ExistsWithContext(ctx, objectRelativePath)
+}
+
+func (folder *TimeoutFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *TimeoutFolder) ReadObjectWithContext(ctx context.Context,
+	objectRelativePath string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	reader, err := folder.folder.ReadObjectWithContext(ctx, objectRelativePath)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the deadline must outlive this call, it is released on Close()
+	return &cancelOnCloseReader{reader, cancel}, nil
+}
+
+func (folder *TimeoutFolder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *TimeoutFolder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	reader, err := ReadObjectRangeWithContext(ctx, folder.folder, objectRelativePath, offset, length)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelOnCloseReader{reader, cancel}, nil
+}
+
+func (folder *TimeoutFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *TimeoutFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return folder.folder.PutObjectWithContext(ctx, name, content)
+}
+
+func (folder *TimeoutFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *TimeoutFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return folder.folder.CopyObjectWithContext(ctx, srcPath, dstPath)
+}
+
+func (folder *TimeoutFolder) StatObject(ctx context.Context, objectRelativePath string) (Object, error) {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return StatObject(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *TimeoutFolder) GetObjectLock(ctx context.Context, objectRelativePath string) (ObjectLock, error) {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return GetObjectLock(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *TimeoutFolder) GetStorageClass(ctx context.Context, objectRelativePath string) (StorageClass, error) {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return GetStorageClass(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *TimeoutFolder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return SetStorageClass(ctx, folder.folder, objectRelativePath, storageClass)
+}
+
+func (folder *TimeoutFolder) ListPendingUploads(ctx context.Context) ([]PendingUpload, error) {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return ListPendingUploads(ctx, folder.folder)
+}
+
+func (folder *TimeoutFolder) AbortPendingUpload(ctx context.Context, upload PendingUpload) error {
+	ctx, cancel := context.WithTimeout(ctx, folder.timeout)
+	defer cancel()
+	return AbortPendingUpload(ctx, folder.folder, upload)
+}
+
+type cancelOnCloseReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (reader *cancelOnCloseReader) Close() error {
+	defer reader.cancel()
+	return reader.ReadCloser.Close()
+}
+
+func (reader *cancelOnCloseReader) VerifiesChecksum() bool {
+	return VerifiesChecksum(reader.ReadCloser)
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// UploadStateStore keeps the progress of the uploads in local files, one file per uploaded object,
+// so that the upload interrupted by a failure or a killed process can be resumed by the next upload of the same object.
+type UploadStateStore struct {
+	directory string
+}
+
+func NewUploadStateStore(directory string) (*UploadStateStore, error) {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create the upload state directory '%s'", directory)
+	}
+	return &UploadStateStore{directory}, nil
+}
+
+// Load reads the saved state of the upload into state, it returns false if there is no saved state
+func (store *UploadStateStore) Load(key string, state interface{}) (bool, error) {
+	data, err := ioutil.ReadFile(store.path(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read the upload state of '%s'", key)
+	}
+	if err = json.Unmarshal(data, state); err != nil {
+		return false, errors.Wrapf(err, "failed to parse the upload state of '%s'", key)
+	}
+	return true, nil
+}
+
+// Save replaces the saved state of the upload, the file is replaced atomically so that a crash never leaves it partial
+func (store *UploadStateStore) Save(key string, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal the upload state of '%s'", key)
+	}
+	file, err := ioutil.TempFile(store.directory, ".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to save the upload state of '%s'", key)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), store.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return errors.Wrapf(err, "failed to save the upload state of '%s'", key)
+	}
+	return nil
+}
+
+// Delete forgets the upload once it is completed
+func (store *UploadStateStore) Delete(key string) error {
+	err := os.Remove(store.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to delete the upload state of '%s'", key)
+	}
+	return nil
+}
+
+// path names the file by the hash of the key, since the keys are storage paths which may be too long for file names
+func (store *UploadStateStore) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(store.directory, hex.EncodeToString(hash[:])+".json")
+}
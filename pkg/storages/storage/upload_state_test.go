@@ -0,0 +1,47 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type testUploadState struct {
+	UploadID string
+	Parts    []string
+}
+
+func TestUploadStateStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upload_state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := storage.NewUploadStateStore(dir)
+	require.NoError(t, err)
+
+	var state testUploadState
+	loaded, err := store.Load("s3://bucket/path/object", &state)
+	assert.NoError(t, err)
+	assert.False(t, loaded)
+
+	saved := testUploadState{"upload", []string{"part1", "part2"}}
+	assert.NoError(t, store.Save("s3://bucket/path/object", saved))
+	assert.NoError(t, store.Save("s3://bucket/path/other", testUploadState{UploadID: "other"}))
+	loaded, err = store.Load("s3://bucket/path/object", &state)
+	assert.NoError(t, err)
+	assert.True(t, loaded)
+	assert.Equal(t, saved, state)
+
+	assert.NoError(t, store.Delete("s3://bucket/path/object"))
+	assert.NoError(t, store.Delete("s3://bucket/path/object"))
+	loaded, err = store.Load("s3://bucket/path/object", &state)
+	assert.NoError(t, err)
+	assert.False(t, loaded)
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+)
+
+// VerifyingFolder verifies the objects with the known SHA-256 checksums when they are read to the end,
+// e.g. the objects of a backup against the checksums signed with it. The checksums are keyed
+// by the paths of the objects, see JoinPath, so the subfolders verify the same objects.
+// The other objects and the parts of the objects are read without verification.
+type VerifyingFolder struct {
+	folder    ContextFolder
+	checksums map[string]string
+}
+
+func NewVerifyingFolder(folder Folder, checksums map[string]string) *VerifyingFolder {
+	return &VerifyingFolder{NewContextFolder(folder), checksums}
+}
+
+func (folder *VerifyingFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *VerifyingFolder) GetSubFolder(subFolderRelativePath string) Folder {
+	return NewVerifyingFolder(folder.folder.GetSubFolder(subFolderRelativePath), folder.checksums)
+}
+
+func (folder *VerifyingFolder) ListFolder() (objects []Object, subFolders []Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *VerifyingFolder) ListFolderWithContext(ctx context.Context) (objects []Object,
+	subFolders []Folder, err error) {
+	objects, subFolders, err = folder.folder.ListFolderWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return objects, folder.wrapSubFolders(subFolders), nil
+}
+
+func (folder *VerifyingFolder) ListFolderPages(ctx context.Context, options ListOptions,
+	handler ListPageHandler) error {
+	return ListFolderPages(ctx, folder.folder, options, func(objects []Object, subFolders []Folder) error {
+		return handler(objects, folder.wrapSubFolders(subFolders))
+	})
+}
+
+func (folder *VerifyingFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *VerifyingFolder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	return folder.folder.DeleteObjectsWithContext(ctx, objectRelativePaths)
+}
+
+func (folder *VerifyingFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *VerifyingFolder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	return folder.folder.ExistsWithContext(ctx, objectRelativePath)
+}
+
+func (folder *VerifyingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *VerifyingFolder) ReadObjectWithContext(ctx context.Context,
+	objectRelativePath string) (io.ReadCloser, error) {
+	reader, err := folder.folder.ReadObjectWithContext(ctx, objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	path := JoinPath(folder.GetPath(), objectRelativePath)
+	expected, ok := folder.checksums[path]
+	if !ok {
+		return reader, nil
+	}
+	return &checksumReader{reader, sha256.New(), expected, path}, nil
+}
+
+func (folder *VerifyingFolder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *VerifyingFolder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if offset == 0 && length < 0 {
+		return folder.ReadObjectWithContext(ctx, objectRelativePath)
+	}
+	return ReadObjectRangeWithContext(ctx, folder.folder, objectRelativePath, offset, length)
+}
+
+func (folder *VerifyingFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *VerifyingFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	return folder.folder.PutObjectWithContext(ctx, name, content)
+}
+
+func (folder *VerifyingFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *VerifyingFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	return folder.folder.CopyObjectWithContext(ctx, srcPath, dstPath)
+}
+
+func (folder *VerifyingFolder) StatObject(ctx context.Context, objectRelativePath string) (Object, error) {
+	return StatObject(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *VerifyingFolder) GetObjectLock(ctx context.Context, objectRelativePath string) (ObjectLock, error) {
+	return GetObjectLock(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *VerifyingFolder) GetStorageClass(ctx context.Context, objectRelativePath string) (StorageClass, error) {
+	return GetStorageClass(ctx, folder.folder, objectRelativePath)
+}
+
+func (folder *VerifyingFolder) SetStorageClass(ctx context.Context, objectRelativePath string, storageClass string) error {
+	return SetStorageClass(ctx, folder.folder, objectRelativePath, storageClass)
+}
+
+func (folder *VerifyingFolder) ListPendingUploads(ctx context.Context) ([]PendingUpload, error) {
+	return ListPendingUploads(ctx, folder.folder)
+}
+
+func (folder *VerifyingFolder) AbortPendingUpload(ctx context.Context, upload PendingUpload) error {
+	return AbortPendingUpload(ctx, folder.folder, upload)
+}
+
+func (folder *VerifyingFolder) wrapSubFolders(subFolders []Folder) []Folder {
+	for i := range subFolders {
+		subFolders[i] = NewVerifyingFolder(subFolders[i], folder.checksums)
+	}
+	return subFolders
+}
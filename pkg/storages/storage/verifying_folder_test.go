@@ -0,0 +1,47 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// sha256 of "data"
+const dataSha256 = "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"
+
+func TestVerifyingFolder(t *testing.T) {
+	storage.RunFolderTest(storage.NewVerifyingFolder(memory.NewFolder("in_memory/", memory.NewStorage()), nil), t)
+}
+
+func TestVerifyingFolder_VerifiesKnownObjects(t *testing.T) {
+	underlying := memory.NewFolder("in_memory/", memory.NewStorage())
+	require.NoError(t, underlying.PutObject("sub/good", strings.NewReader("data")))
+	require.NoError(t, underlying.PutObject("sub/bad", strings.NewReader("dada")))
+	require.NoError(t, underlying.PutObject("sub/unknown", strings.NewReader("dada")))
+	folder := storage.NewVerifyingFolder(underlying, map[string]string{
+		"in_memory/sub/good": dataSha256,
+		"in_memory/sub/bad":  dataSha256,
+	})
+
+	assert.Equal(t, "data", readAll(t, folder, "sub/good"))
+	assert.Equal(t, "data", readAll(t, folder.GetSubFolder("sub"), "good"))
+	assert.Equal(t, "dada", readAll(t, folder, "sub/unknown"))
+
+	reader, err := folder.GetSubFolder("sub").ReadObject("bad")
+	require.NoError(t, err)
+	assert.True(t, storage.VerifiesChecksum(reader))
+	_, err = ioutil.ReadAll(reader)
+	assert.IsType(t, storage.ChecksumMismatchError{}, err)
+	assert.Contains(t, err.Error(), "in_memory/sub/bad")
+
+	reader, err = folder.ReadObjectRange("sub/bad", 1, 2)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "ad", string(content))
+}
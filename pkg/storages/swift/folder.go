@@ -1,9 +1,12 @@
 package swift
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"errors"
 
@@ -73,6 +76,14 @@ func (folder *Folder) GetPath() string {
 }
 
 func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+// swift library does not accept contexts, so cancellation is checked between requests
+func (folder *Folder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 	path := storage.JoinPath(folder.path, objectRelativePath)
 	_, _, err := folder.connection.Object(folder.container.Name, path)
 	if err == swift.ObjectNotFound {
@@ -85,8 +96,16 @@ func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
 }
 
 func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *Folder) ListFolderWithContext(ctx context.Context) (objects []storage.Object,
+	subFolders []storage.Folder, err error) {
 	//Iterate
 	err = folder.connection.ObjectsWalk(folder.container.Name, &swift.ObjectsOpts{Delimiter: int32('/'), Prefix: folder.path}, func(opts *swift.ObjectsOpts) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
 		objectNames, err := folder.connection.ObjectNames(folder.container.Name, opts)
 		if err != nil {
@@ -118,11 +137,64 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 	return
 }
 
+func (folder *Folder) ListFolderPages(ctx context.Context, options storage.ListOptions,
+	handler storage.ListPageHandler) error {
+	opts := &swift.ObjectsOpts{Prefix: folder.path + options.Prefix}
+	if !options.Recursive {
+		opts.Delimiter = '/'
+	}
+	if options.StartAfter != "" {
+		opts.Marker = folder.path + options.StartAfter
+	}
+	var handlerErr error
+	err := folder.connection.ObjectsWalk(folder.container.Name, opts, func(opts *swift.ObjectsOpts) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		swiftObjects, err := folder.connection.Objects(folder.container.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		var objects []storage.Object
+		var subFolders []storage.Folder
+		for _, swiftObject := range swiftObjects {
+			if swiftObject.PseudoDirectory {
+				subFolders = append(subFolders, NewFolder(folder.connection, folder.container, swiftObject.Name))
+				continue
+			}
+			if strings.HasSuffix(swiftObject.Name, "/") {
+				// directory markers are not objects
+				continue
+			}
+			objName := strings.TrimPrefix(swiftObject.Name, folder.path)
+			objects = append(objects, storage.NewLocalObject(objName, swiftObject.LastModified, swiftObject.Bytes))
+		}
+		if len(objects) > 0 || len(subFolders) > 0 {
+			handlerErr = handler(objects, subFolders)
+		}
+		return swiftObjects, handlerErr
+	})
+	if handlerErr != nil {
+		return handlerErr
+	}
+	if err != nil {
+		return NewError(err, "Unable to iterate %v", folder.path)
+	}
+	return nil
+}
+
 func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
 	return NewFolder(folder.connection, folder.container, storage.AddDelimiterToPath(storage.JoinPath(folder.path, subFolderRelativePath)))
 }
 
 func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	path := storage.JoinPath(folder.path, objectRelativePath)
 	//get the object from the cloud using full path
 	readContents, _, err := folder.connection.ObjectOpen(folder.container.Name, path, true, nil)
@@ -134,14 +206,48 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	} else {
 		//retrieved object from  the cloud
 	}
-	return ioutil.NopCloser(readContents), nil
+	return storage.NewContextReadCloser(ctx, ioutil.NopCloser(readContents)), nil
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *Folder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		// an empty range can not be expressed with the Range header
+		return storage.ReadEmptyRange(ctx, folder, objectRelativePath)
+	}
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += strconv.FormatInt(offset+length-1, 10)
+	}
+	path := storage.JoinPath(folder.path, objectRelativePath)
+	// hash of a part can not be checked against the hash of the whole object
+	readContents, _, err := folder.connection.ObjectOpen(folder.container.Name, path, false,
+		swift.Headers{"Range": byteRange})
+	if err == swift.ObjectNotFound {
+		return nil, storage.NewObjectNotFoundError(path)
+	}
+	if err != nil {
+		return nil, NewError(err, "Unable to OPEN Object %v", path)
+	}
+	return storage.NewContextReadCloser(ctx, readContents), nil
 }
 
 func (folder *Folder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
 	tracelog.DebugLogger.Printf("Put %v into %v\n", name, folder.path)
 	path := storage.JoinPath(folder.path, name)
 	//put the object in the cloud using full path
-	_, err := folder.connection.ObjectPut(folder.container.Name, path, content, false, "", "", nil)
+	_, err := folder.connection.ObjectPut(folder.container.Name, path, storage.NewContextReader(ctx, content), false, "", "", nil)
 	if err != nil {
 		return NewError(err, "Unable to write content.")
 	} else {
@@ -151,7 +257,11 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
-	if exists, err := folder.Exists(srcPath); !exists {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	if exists, err := folder.ExistsWithContext(ctx, srcPath); !exists {
 		if err == nil {
 			return errors.New("object does not exist")
 		} else {
@@ -163,7 +273,14 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 }
 
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *Folder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
 	for _, objectRelativePath := range objectRelativePaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		path := storage.JoinPath(folder.path, objectRelativePath)
 		tracelog.DebugLogger.Printf("Delete object %v\n", path)
 		err := folder.connection.ObjectDelete(folder.container.Name, path)
@@ -0,0 +1,222 @@
+package webdav
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StatusError is the unexpected response of the WebDAV server
+type StatusError struct {
+	method string
+	path   string
+	code   int
+	status string
+}
+
+func (err StatusError) Error() string {
+	return fmt.Sprintf("%s '%s' failed: %s", err.method, err.path, err.status)
+}
+
+// StatusCode allows to tell permanent errors from transient ones
+func (err StatusError) StatusCode() int {
+	return err.code
+}
+
+// Client makes WebDAV requests to a single server
+type Client struct {
+	httpClient *http.Client
+	baseURL    *url.URL
+	username   string
+	password   string
+
+	// the collections known to exist, so that they are not created before every upload
+	collections sync.Map
+}
+
+func NewClient(baseURL *url.URL, username, password string, httpClient *http.Client) *Client {
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    baseURL,
+		username:   username,
+		password:   password,
+	}
+}
+
+// newHTTPClient trusts the certificates of the CA file in addition to the system ones
+func newHTTPClient(caCertFile string) (*http.Client, error) {
+	if caCertFile == "" {
+		return &http.Client{}, nil
+	}
+	caCert, err := ioutil.ReadFile(caCertFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, errors.Errorf("no certificates found in '%s'", caCertFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: transport}, nil
+}
+
+func (client *Client) url(resourcePath string) string {
+	resourceURL := *client.baseURL
+	resourceURL.Path = resourcePath
+	resourceURL.RawPath = ""
+	return resourceURL.String()
+}
+
+func (client *Client) do(ctx context.Context, method, resourcePath string, body io.Reader,
+	headers map[string]string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, client.url(resourcePath), body)
+	if err != nil {
+		return nil, err
+	}
+	if client.username != "" || client.password != "" {
+		request.SetBasicAuth(client.username, client.password)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	return client.httpClient.Do(request)
+}
+
+// doAndClose makes the request which response body is not needed, the response status is checked then
+func (client *Client) doAndClose(ctx context.Context, method, resourcePath string, body io.Reader,
+	headers map[string]string, expectedCodes ...int) (int, error) {
+	response, err := client.do(ctx, method, resourcePath, body, headers)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	_ = response.Body.Close()
+	for _, code := range expectedCodes {
+		if response.StatusCode == code {
+			return code, nil
+		}
+	}
+	return response.StatusCode, newStatusError(method, resourcePath, response)
+}
+
+func newStatusError(method, resourcePath string, response *http.Response) StatusError {
+	return StatusError{method, resourcePath, response.StatusCode, response.Status}
+}
+
+// davEntry is a file or a collection listed by PROPFIND
+type davEntry struct {
+	name         string
+	isCollection bool
+	size         int64
+	lastModified time.Time
+}
+
+type multiStatus struct {
+	Responses []struct {
+		Href      string `xml:"href"`
+		PropStats []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength string `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+const propFindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/><d:getlastmodified/></d:prop></d:propfind>`
+
+// listCollection returns the members of the collection, it returns false if there is no such collection
+func (client *Client) listCollection(ctx context.Context, collectionPath string) ([]davEntry, bool, error) {
+	response, err := client.do(ctx, "PROPFIND", collectionPath, strings.NewReader(propFindBody),
+		map[string]string{"Depth": "1", "Content-Type": "application/xml; charset=utf-8"})
+	if err != nil {
+		return nil, false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if response.StatusCode != http.StatusMultiStatus {
+		return nil, false, newStatusError("PROPFIND", collectionPath, response)
+	}
+
+	var status multiStatus
+	if err = xml.NewDecoder(response.Body).Decode(&status); err != nil {
+		return nil, false, errors.Wrapf(err, "failed to parse the listing of '%s'", collectionPath)
+	}
+	entries := make([]davEntry, 0, len(status.Responses))
+	for _, entryResponse := range status.Responses {
+		href, err := url.Parse(entryResponse.Href)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "failed to parse the listing of '%s'", collectionPath)
+		}
+		entryPath := strings.TrimSuffix(href.Path, "/")
+		if entryPath == strings.TrimSuffix(collectionPath, "/") {
+			continue
+		}
+		entry := davEntry{name: path.Base(entryPath)}
+		for _, propStat := range entryResponse.PropStats {
+			if !strings.Contains(propStat.Status, " 200 ") {
+				continue
+			}
+			prop := propStat.Prop
+			entry.isCollection = entry.isCollection || prop.ResourceType.Collection != nil
+			if prop.ContentLength != "" {
+				entry.size, _ = strconv.ParseInt(prop.ContentLength, 10, 64)
+			}
+			if prop.LastModified != "" {
+				entry.lastModified, _ = http.ParseTime(prop.LastModified)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, true, nil
+}
+
+// makeCollections creates the collection and all its missing parents
+func (client *Client) makeCollections(ctx context.Context, collectionPath string) error {
+	collectionPath = strings.TrimSuffix(collectionPath, "/") + "/"
+	if _, ok := client.collections.Load(collectionPath); ok || collectionPath == "/" {
+		return nil
+	}
+	// an existing collection can not be created again, the conflict means the parent is missing
+	code, err := client.doAndClose(ctx, "MKCOL", collectionPath, nil, nil,
+		http.StatusCreated, http.StatusMethodNotAllowed, http.StatusConflict)
+	if err != nil {
+		return err
+	}
+	if code == http.StatusConflict {
+		if err = client.makeCollections(ctx, path.Dir(strings.TrimSuffix(collectionPath, "/"))); err != nil {
+			return err
+		}
+		_, err = client.doAndClose(ctx, "MKCOL", collectionPath, nil, nil,
+			http.StatusCreated, http.StatusMethodNotAllowed)
+		if err != nil {
+			return err
+		}
+	}
+	client.collections.Store(collectionPath, true)
+	return nil
+}
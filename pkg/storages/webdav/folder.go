@@ -0,0 +1,267 @@
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	UsernameSetting   = "WEBDAV_USERNAME"
+	PasswordSetting   = "WEBDAV_PASSWORD"
+	CACertFileSetting = "WEBDAV_CA_CERT_FILE"
+)
+
+var SettingList = []string{
+	UsernameSetting,
+	PasswordSetting,
+	CACertFileSetting,
+}
+
+func NewFolderError(err error, format string, args ...interface{}) storage.Error {
+	return storage.NewError(err, "WebDAV", format, args...)
+}
+
+// Folder is a WebDAV collection, the objects are its files and the subfolders are its nested collections
+type Folder struct {
+	client *Client
+	path   string
+}
+
+func NewFolder(client *Client, path string) *Folder {
+	return &Folder{client, storage.AddDelimiterToPath(path)}
+}
+
+// ConfigureFolder accepts the http(s) URL of the collection as the prefix
+func ConfigureFolder(prefix string, settings map[string]string) (storage.Folder, error) {
+	baseURL, err := url.Parse(prefix)
+	if err != nil {
+		return nil, NewFolderError(err, "Unable to parse WebDAV prefix %v", prefix)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, NewFolderError(errors.New("unsupported scheme"),
+			"WebDAV prefix %v should be an http or https URL", prefix)
+	}
+	httpClient, err := newHTTPClient(settings[CACertFileSetting])
+	if err != nil {
+		return nil, NewFolderError(err, "Unable to read CA certificate")
+	}
+	folderPath := baseURL.Path
+	baseURL.Path, baseURL.RawPath = "", ""
+	client := NewClient(baseURL, settings[UsernameSetting], settings[PasswordSetting], httpClient)
+	return NewFolder(client, "/"+strings.TrimPrefix(folderPath, "/")), nil
+}
+
+func (folder *Folder) GetPath() string {
+	return folder.path
+}
+
+func (folder *Folder) objectPath(objectRelativePath string) string {
+	return folder.path + strings.TrimPrefix(objectRelativePath, "/")
+}
+
+func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return NewFolder(folder.client, folder.path+strings.Trim(subFolderRelativePath, "/"))
+}
+
+func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	return folder.ListFolderWithContext(context.Background())
+}
+
+func (folder *Folder) ListFolderWithContext(ctx context.Context) (objects []storage.Object,
+	subFolders []storage.Folder, err error) {
+	entries, _, err := folder.client.listCollection(ctx, folder.path)
+	if err != nil {
+		return nil, nil, NewFolderError(err, "Unable to list folder '%s'", folder.path)
+	}
+	for _, entry := range entries {
+		if entry.isCollection {
+			subFolders = append(subFolders, folder.GetSubFolder(entry.name))
+		} else {
+			objects = append(objects, storage.NewLocalObject(entry.name, entry.lastModified, entry.size))
+		}
+	}
+	return objects, subFolders, nil
+}
+
+// ListFolderPages lists the nested collections one by one, since many servers forbid the listings of infinite depth
+func (folder *Folder) ListFolderPages(ctx context.Context, options storage.ListOptions,
+	handler storage.ListPageHandler) error {
+	pager := storage.NewListPager(handler)
+	if err := folder.listPages(ctx, "", options, pager); err != nil {
+		return err
+	}
+	return pager.Flush()
+}
+
+func (folder *Folder) listPages(ctx context.Context, relativePath string, options storage.ListOptions,
+	pager *storage.ListPager) error {
+	collectionPath := folder.objectPath(relativePath)
+	entries, _, err := folder.client.listCollection(ctx, collectionPath)
+	if err != nil {
+		return NewFolderError(err, "Unable to list folder '%s'", collectionPath)
+	}
+	// the same order as the full paths in object storages, see storage.SortFileInfos
+	sortKey := func(entry davEntry) string {
+		if entry.isCollection {
+			return entry.name + "/"
+		}
+		return entry.name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortKey(entries[i]) < sortKey(entries[j])
+	})
+	for _, entry := range entries {
+		name := path.Join(relativePath, entry.name)
+		if !entry.isCollection {
+			if options.ObjectMatches(name) {
+				err = pager.AddObject(storage.NewLocalObject(name, entry.lastModified, entry.size))
+			}
+		} else if options.SubFolderMatches(name) {
+			if options.Recursive {
+				err = folder.listPages(ctx, name, options, pager)
+			} else {
+				err = pager.AddSubFolder(folder.GetSubFolder(name))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.DeleteObjectsWithContext(context.Background(), objectRelativePaths)
+}
+
+func (folder *Folder) DeleteObjectsWithContext(ctx context.Context, objectRelativePaths []string) error {
+	for _, objectRelativePath := range objectRelativePaths {
+		objectPath := folder.objectPath(objectRelativePath)
+		_, err := folder.client.doAndClose(ctx, http.MethodDelete, objectPath, nil, nil,
+			http.StatusOK, http.StatusNoContent, http.StatusAccepted, http.StatusNotFound)
+		if err != nil {
+			return NewFolderError(err, "Unable to delete object '%s'", objectPath)
+		}
+	}
+	return nil
+}
+
+func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	return folder.ExistsWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ExistsWithContext(ctx context.Context, objectRelativePath string) (bool, error) {
+	objectPath := folder.objectPath(objectRelativePath)
+	code, err := folder.client.doAndClose(ctx, http.MethodHead, objectPath, nil, nil,
+		http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return false, NewFolderError(err, "Unable to check object existence '%s'", objectPath)
+	}
+	return code == http.StatusOK, nil
+}
+
+func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectWithContext(context.Background(), objectRelativePath)
+}
+
+func (folder *Folder) ReadObjectWithContext(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(ctx, objectRelativePath, 0, -1)
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return folder.ReadObjectRangeWithContext(context.Background(), objectRelativePath, offset, length)
+}
+
+func (folder *Folder) ReadObjectRangeWithContext(ctx context.Context, objectRelativePath string,
+	offset, length int64) (io.ReadCloser, error) {
+	objectPath := folder.objectPath(objectRelativePath)
+	if length == 0 {
+		// an empty range can not be expressed with the Range header
+		return storage.ReadEmptyRange(ctx, folder, objectRelativePath)
+	}
+	headers := map[string]string{}
+	if offset > 0 || length >= 0 {
+		byteRange := fmt.Sprintf("bytes=%d-", offset)
+		if length >= 0 {
+			byteRange += fmt.Sprint(offset + length - 1)
+		}
+		headers["Range"] = byteRange
+	}
+	response, err := folder.client.do(ctx, http.MethodGet, objectPath, nil, headers)
+	if err != nil {
+		return nil, NewFolderError(err, "Unable to read object '%s'", objectPath)
+	}
+	switch response.StatusCode {
+	case http.StatusOK:
+		// the server may ignore the range, then the preceding bytes are skipped
+		if _, err = io.CopyN(ioutil.Discard, response.Body, offset); err != nil && err != io.EOF {
+			_ = response.Body.Close()
+			return nil, NewFolderError(err, "Unable to read object '%s'", objectPath)
+		}
+		return storage.NewLimitedReadCloser(response.Body, length), nil
+	case http.StatusPartialContent:
+		return storage.NewLimitedReadCloser(response.Body, length), nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		_ = response.Body.Close()
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	_ = response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, storage.NewObjectNotFoundError(objectPath)
+	}
+	return nil, NewFolderError(newStatusError(http.MethodGet, objectPath, response),
+		"Unable to read object '%s'", objectPath)
+}
+
+func (folder *Folder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	objectPath := folder.objectPath(name)
+	if err := folder.client.makeCollections(ctx, path.Dir(objectPath)); err != nil {
+		return NewFolderError(err, "Unable to create folder for object '%s'", objectPath)
+	}
+	_, err := folder.client.doAndClose(ctx, http.MethodPut, objectPath, content, nil,
+		http.StatusOK, http.StatusCreated, http.StatusNoContent)
+	if err != nil {
+		return NewFolderError(err, "Unable to write object '%s'", objectPath)
+	}
+	return nil
+}
+
+func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath)
+}
+
+// CopyObjectWithContext copies the object on the server side
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string) error {
+	srcObjectPath := folder.objectPath(srcPath)
+	dstObjectPath := folder.objectPath(dstPath)
+	if err := folder.client.makeCollections(ctx, path.Dir(dstObjectPath)); err != nil {
+		return NewFolderError(err, "Unable to create folder for object '%s'", dstObjectPath)
+	}
+	headers := map[string]string{
+		"Destination": folder.client.url(dstObjectPath),
+		"Overwrite":   "T",
+	}
+	code, err := folder.client.doAndClose(ctx, "COPY", srcObjectPath, nil, headers,
+		http.StatusCreated, http.StatusNoContent, http.StatusNotFound)
+	if err != nil {
+		return NewFolderError(err, "Unable to copy object '%s' to '%s'", srcObjectPath, dstObjectPath)
+	}
+	if code == http.StatusNotFound {
+		return storage.NewObjectNotFoundError(srcObjectPath)
+	}
+	return nil
+}
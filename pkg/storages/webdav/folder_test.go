@@ -0,0 +1,89 @@
+package webdav
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"golang.org/x/net/webdav"
+)
+
+func newTestHandler() http.Handler {
+	return &webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+}
+
+func TestWebDAVFolder(t *testing.T) {
+	server := httptest.NewServer(newTestHandler())
+	defer server.Close()
+
+	folder, err := ConfigureFolder(server.URL+"/walg/", map[string]string{})
+	assert.NoError(t, err)
+
+	storage.RunFolderTest(folder, t)
+}
+
+func TestWebDAVFolder_BasicAuth(t *testing.T) {
+	handler := newTestHandler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	folder, err := ConfigureFolder(server.URL+"/walg", map[string]string{
+		UsernameSetting: "user",
+		PasswordSetting: "secret",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, folder.PutObject("sub/file", strings.NewReader("data")))
+
+	folder, err = ConfigureFolder(server.URL+"/walg", map[string]string{
+		UsernameSetting: "user",
+		PasswordSetting: "wrong",
+	})
+	assert.NoError(t, err)
+	_, err = folder.ReadObject("sub/file")
+	assert.Error(t, err)
+	assert.False(t, storage.IsRetriableError(err))
+}
+
+func TestWebDAVFolder_CACertFile(t *testing.T) {
+	server := httptest.NewTLSServer(newTestHandler())
+	defer server.Close()
+
+	folder, err := ConfigureFolder(server.URL+"/walg", map[string]string{})
+	assert.NoError(t, err)
+	assert.Error(t, folder.PutObject("file", strings.NewReader("data")))
+
+	tmpDir, err := ioutil.TempDir("", "webdav")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	caCertFile := filepath.Join(tmpDir, "ca.pem")
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, ioutil.WriteFile(caCertFile, caCert, 0600))
+
+	folder, err = ConfigureFolder(server.URL+"/walg", map[string]string{CACertFileSetting: caCertFile})
+	assert.NoError(t, err)
+	assert.NoError(t, folder.PutObject("file", strings.NewReader("data")))
+	exists, err := folder.Exists("file")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestConfigureFolder_RejectsUnsupportedScheme(t *testing.T) {
+	_, err := ConfigureFolder("ftp://example.com/walg", map[string]string{})
+	assert.Error(t, err)
+}
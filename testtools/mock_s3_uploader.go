@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
@@ -70,3 +71,8 @@ func (uploader *MockS3Uploader) Upload(input *s3manager.UploadInput,
 
 	return output, nil
 }
+
+func (uploader *MockS3Uploader) UploadWithContext(_ aws.Context, input *s3manager.UploadInput,
+	f ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	return uploader.Upload(input, f...)
+}
@@ -45,6 +45,8 @@ const (
 	BaseBackupPath   = "basebackups_" + VersionStr + "/"
 	CatchupPath      = "catchup_" + VersionStr + "/"
 	WalPath          = "wal_" + VersionStr + "/"
+	BinlogPath       = "binlog_" + VersionStr + "/"
+	OplogPath        = "oplog_" + VersionStr + "/"
 	BackupNamePrefix = "base_"
 	BackupTimeFormat = "20060102T150405Z" // timestamps in that format should be lexicographically sorted
 
@@ -53,6 +55,7 @@ const (
 	CompressedBlockMaxSize = 20 << 20
 	CopiedBlockMaxSize     = CompressedBlockMaxSize
 	MetadataFileName       = "metadata.json"
+	SignatureFileName      = "backup_signature.json"
 	PathSeparator          = string(os.PathSeparator)
 	Mebibyte               = 1024 * 1024
 )